@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/urfave/cli"
+	"os"
+)
+
+func GetGenerateNodeKeyCommand() cli.Command {
+	return cli.Command {
+		Name:	"generate-nodekey",
+		Usage:	"generate a new node key, which identifies the node towards its peers",
+		Action:	func(c *cli.Context) error {
+			filename := c.String("file")
+			//A new key changes the NodeID, peers which know the node by its ID can't reach it anymore
+			if _, err := os.Stat(filename); !os.IsNotExist(err) {
+				if !c.Bool("force") {
+					return errors.New(fmt.Sprintf("Node key %v already exists, use --force to replace it.", filename))
+				}
+				if err := os.Remove(filename); err != nil {
+					return err
+				}
+			}
+
+			privKey, err := crypto.ExtractEDPrivKeyFromFile(filename)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Node key generated successfully.\n")
+			fmt.Printf("NodeID: %x\n", privKey[32:])
+
+			return nil
+		},
+		Flags:	[]cli.Flag {
+			cli.StringFlag {
+				Name: 	"file",
+				Usage: 	"the new node key's `FILE` name",
+				Value: 	"nodekey.txt",
+			},
+			cli.BoolFlag {
+				Name: 	"force",
+				Usage: 	"replace the node key if the file already exists",
+			},
+		},
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/bazo-blockchain/bazo-miner/miner"
 	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -15,12 +16,14 @@ import (
 type startArgs struct {
 	dbname 					string
 	myNodeAddress			string
+	bindAddress				string
 	bootstrapNodeAddress	string
 	walletFile				string
 	multisigFile			string
 	commitmentFile			string
 	rootKeyFile				string
 	rootCommitmentFile		string
+	nodeKeyFile				string
 }
 
 func GetStartCommand(logger *log.Logger) cli.Command {
@@ -31,12 +34,14 @@ func GetStartCommand(logger *log.Logger) cli.Command {
 			args := &startArgs {
 				dbname: 				c.String("database"),
 				myNodeAddress: 			c.String("address"),
+				bindAddress: 			c.String("bind"),
 				bootstrapNodeAddress: 	c.String("bootstrap"),
 				walletFile: 			c.String("wallet"),
 				multisigFile: 			c.String("multisig"),
 				commitmentFile:			c.String("commitment"),
 				rootKeyFile:			c.String("rootwallet"),
 				rootCommitmentFile: 	c.String("rootcommitment"),
+				nodeKeyFile:			c.String("nodekey"),
 			}
 
 			if !c.IsSet("bootstrap") {
@@ -64,12 +69,16 @@ func GetStartCommand(logger *log.Logger) cli.Command {
 			},
 			cli.StringFlag {
 				Name: 	"address, a",
-				Usage: 	"start node at `IP:PORT`",
+				Usage: 	"start node at `IP:PORT`, the address other miners connect to",
 				Value: 	"localhost:8000",
 			},
+			cli.StringFlag {
+				Name: 	"bind",
+				Usage: 	"listen for incoming connections at `IP:PORT` (default: all interfaces and the port of --address)",
+			},
 			cli.StringFlag {
 				Name: 	"bootstrap, b",
-				Usage: 	"connect to bootstrap node at `IP:PORT`",
+				Usage: 	"connect to bootstrap node at `[NODEID@]IP:PORT`",
 				Value: 	"localhost:8000",
 			},
 			cli.StringFlag {
@@ -96,6 +105,11 @@ func GetStartCommand(logger *log.Logger) cli.Command {
 				Usage: 	"load root's RSA public-private key from `FILE`",
 				Value: 	"commitment.txt",
 			},
+			cli.StringFlag {
+				Name: 	"nodekey",
+				Usage: 	"load the node key identifying this node towards its peers from `FILE`",
+				Value: 	"nodekey.txt",
+			},
 			cli.BoolFlag {
 				Name: 	"confirm",
 				Usage: 	"user must press enter before starting the miner",
@@ -105,10 +119,15 @@ func GetStartCommand(logger *log.Logger) cli.Command {
 }
 
 func Start(args *startArgs, logger *log.Logger) error {
-	storage.Init(args.dbname, args.bootstrapNodeAddress)
-	p2p.Init(args.myNodeAddress)
+	bootstrapNodeID, bootstrapIpport, err := p2p.ParseNodeAddress(args.bootstrapNodeAddress)
+	if err != nil {
+		logger.Printf("%v\n", err)
+		return err
+	}
 
-	validatorPubKey, err := crypto.ExtractEDPublicKeyFromFile(args.walletFile)
+	storage.Init(args.dbname, bootstrapIpport)
+
+	nodeKey, err := crypto.ExtractEDPrivKeyFromFile(args.nodeKeyFile)
 	if err != nil {
 		logger.Printf("%v\n", err)
 		return err
@@ -120,6 +139,16 @@ func Start(args *startArgs, logger *log.Logger) error {
 		return err
 	}
 
+	//The chain is identified by its root account, peers with another root account are disconnected
+	chainID := protocol.SerializeHashContent(crypto.GetAddressFromPubKeyED(ed25519.PublicKey(rootPrivKey[32:])))
+	node := p2p.Init(args.myNodeAddress, args.bindAddress, bootstrapNodeID, nodeKey, chainID)
+
+	validatorPubKey, err := crypto.ExtractEDPublicKeyFromFile(args.walletFile)
+	if err != nil {
+		logger.Printf("%v\n", err)
+		return err
+	}
+
 	var multisigPubKey ed25519.PublicKey
 	if len(args.multisigFile) > 0 {
 		multisigPubKey, err = crypto.ExtractEDPublicKeyFromFile(args.multisigFile)
@@ -142,7 +171,7 @@ func Start(args *startArgs, logger *log.Logger) error {
 		logger.Printf("%v\n", err)
 		return err
 	}
-	miner.Init(validatorPubKey, multisigPubKey, rootPrivKey, commPrivKey, rootCommPrivKey)
+	miner.Init(node, validatorPubKey, multisigPubKey, rootPrivKey, commPrivKey, rootCommPrivKey)
 	return nil
 }
 
@@ -175,6 +204,10 @@ func (args startArgs) ValidateInput() error {
 		return errors.New("argument missing: rootCommitmentFile")
 	}
 
+	if len(args.nodeKeyFile) == 0 {
+		return errors.New("argument missing: nodeKeyFile")
+	}
+
 	return nil
 }
 
@@ -182,18 +215,22 @@ func (args startArgs) String() string {
 	return fmt.Sprintf("Starting bazo miner with arguments \n" +
 			"- Database Name:\t\t %v\n" +
 			"- My Address:\t\t\t %v\n" +
+			"- Bind Address:\t\t %v\n" +
 			"- Bootstrap Address:\t\t %v\n" +
 			"- Wallet File:\t\t\t %v\n" +
 			"- Multisig File:\t\t %v\n" +
 			"- Commitment File:\t\t %v\n" +
 			"- Root Wallet File:\t\t %v\n" +
-			"- Root Commitment File:\t %v\n",
+			"- Root Commitment File:\t %v\n" +
+			"- Node Key File:\t\t %v\n",
 		args.dbname,
 		args.myNodeAddress,
+		args.bindAddress,
 		args.bootstrapNodeAddress,
 		args.walletFile,
 		args.multisigFile,
 		args.commitmentFile,
 		args.rootKeyFile,
-		args.rootCommitmentFile)
+		args.rootCommitmentFile,
+		args.nodeKeyFile)
 }
\ No newline at end of file
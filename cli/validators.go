@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/urfave/cli"
+	"golang.org/x/crypto/ed25519"
+)
+
+func GetValidatorsCommand() cli.Command {
+	return cli.Command {
+		Name:	"validators",
+		Usage:	"list the validator registry of a running miner",
+		Action:	func(c *cli.Context) error {
+			var account []byte
+			if c.IsSet("account") {
+				var err error
+				if account, err = hex.DecodeString(c.String("account")); err != nil || len(account) != 32 {
+					return errors.New("The account must be given as 32 bytes in hex.")
+				}
+			}
+
+			key, err := crypto.ExtractEDPrivKeyFromFile(c.String("nodekey"))
+			if err != nil {
+				return err
+			}
+
+			set, err := requestValidators(c.String("address"), key)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Validators after block %v:\n", set.Height)
+			for _, validator := range set.Validators {
+				if account != nil && !bytes.Equal(validator.Account[:], account) {
+					continue
+				}
+				if c.IsSet("status") && protocol.StatusName(validator.Status) != c.String("status") {
+					continue
+				}
+				fmt.Println(validator)
+			}
+
+			return nil
+		},
+		Flags:	[]cli.Flag {
+			cli.StringFlag {
+				Name: 	"address, a",
+				Usage: 	"query the miner at `IP:PORT`",
+				Value: 	"localhost:8000",
+			},
+			cli.StringFlag {
+				Name: 	"nodekey",
+				Usage: 	"load the node key identifying this client towards the miner from `FILE`",
+				Value: 	"nodekey.txt",
+			},
+			cli.StringFlag {
+				Name: 	"account",
+				Usage: 	"only list the validator with the account `HASH`",
+			},
+			cli.StringFlag {
+				Name: 	"status",
+				Usage: 	"only list validators with the `STATUS` active, waiting, inactive or slashed",
+			},
+		},
+	}
+}
+
+func requestValidators(address string, key ed25519.PrivateKey) (*protocol.ValidatorSet, error) {
+	p2p.InitLogging()
+
+	conn := p2p.Connect(address, key)
+	if conn == nil {
+		return nil, errors.New(fmt.Sprintf("Could not connect to the miner at %v.", address))
+	}
+	defer conn.Close()
+
+	conn.Write(p2p.BuildPacket(p2p.VALIDATORS_REQ, nil))
+
+	header, payload, err := p2p.RcvData_(conn)
+	if err != nil {
+		return nil, err
+	}
+	if header.TypeID != p2p.VALIDATORS_RES {
+		return nil, errors.New("The miner has no validator registry.")
+	}
+
+	var set *protocol.ValidatorSet
+	if set = set.Decode(payload); set == nil {
+		return nil, errors.New("Invalid validator registry received.")
+	}
+
+	return set, nil
+}
@@ -0,0 +1,95 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/nacl/box"
+	"math/big"
+)
+
+const (
+	//Ephemeral X25519 public key and Poly1305 tag which are prepended to every encrypted payload
+	ENCRYPTION_OVERHEAD = box.AnonymousOverhead
+)
+
+//Prime of curve25519, p = 2^255 - 19
+var curve25519P, _ = new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+
+//Converts an ed25519 public key (Edwards y coordinate) to the X25519 public key (Montgomery u coordinate)
+//of the same key pair, u = (1 + y) / (1 - y) mod p.
+func EDPubKeyToX25519(pubKey ed25519.PublicKey) (xPubKey [32]byte, err error) {
+	if len(pubKey) != ed25519.PublicKeySize {
+		return xPubKey, errors.New(fmt.Sprintf("Invalid ed25519 public key length: %v", len(pubKey)))
+	}
+
+	//Little endian, the highest bit is the sign of x and not part of y
+	var yBytes [32]byte
+	for i := 0; i < 32; i++ {
+		yBytes[i] = pubKey[31-i]
+	}
+	yBytes[0] &= 0x7f
+	y := new(big.Int).SetBytes(yBytes[:])
+
+	one := big.NewInt(1)
+	if y.Cmp(curve25519P) >= 0 || y.Cmp(one) == 0 {
+		return xPubKey, errors.New("Invalid ed25519 public key.")
+	}
+
+	numerator := new(big.Int).Add(one, y)
+	denominator := new(big.Int).Sub(one, y)
+	denominator.Mod(denominator, curve25519P)
+	denominator.ModInverse(denominator, curve25519P)
+	u := numerator.Mul(numerator, denominator)
+	u.Mod(u, curve25519P)
+
+	uBytes := u.Bytes()
+	for i := 0; i < len(uBytes); i++ {
+		xPubKey[i] = uBytes[len(uBytes)-1-i]
+	}
+
+	return xPubKey, nil
+}
+
+//The X25519 private key is the (clamped) scalar ed25519 derives from its seed.
+func EDPrivKeyToX25519(privKey ed25519.PrivateKey) (xPrivKey [32]byte) {
+	digest := sha512.Sum512(privKey[:ed25519.SeedSize])
+	copy(xPrivKey[:], digest[:32])
+	xPrivKey[0] &= 248
+	xPrivKey[31] &= 127
+	xPrivKey[31] |= 64
+	return xPrivKey
+}
+
+//Encrypts IoT data to the receiver's account address (its ed25519 public key). Only the receiver can decrypt it,
+//the sender uses a new ephemeral key for every payload.
+func EncryptIoTData(receiverAddress [32]byte, data []byte) (encrypted []byte, err error) {
+	xPubKey, err := EDPubKeyToX25519(GetPubKeyFromAddressED(receiverAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	return box.SealAnonymous(nil, data, &xPubKey, rand.Reader)
+}
+
+//Decrypts IoT data which was encrypted to the account of privKey.
+func DecryptIoTData(privKey ed25519.PrivateKey, encrypted []byte) (data []byte, err error) {
+	if len(privKey) != ed25519.PrivateKeySize {
+		return nil, errors.New(fmt.Sprintf("Invalid ed25519 private key length: %v", len(privKey)))
+	}
+
+	xPubKey, err := EDPubKeyToX25519(privKey.Public().(ed25519.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	xPrivKey := EDPrivKeyToX25519(privKey)
+
+	data, ok := box.OpenAnonymous(nil, encrypted, &xPubKey, &xPrivKey)
+	if !ok {
+		return nil, errors.New("Could not decrypt IoT data.")
+	}
+
+	return data, nil
+}
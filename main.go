@@ -20,6 +20,8 @@ func main() {
 		cli.GetStartCommand(logger),
 		cli.GetGenerateWalletCommand(),
 		cli.GetGenerateCommitmentCommand(),
+		cli.GetGenerateNodeKeyCommand(),
+		cli.GetValidatorsCommand(),
 	}
 
 	err := app.Run(os.Args)
@@ -0,0 +1,82 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+var baseFee uint64 = INITIAL_BASE_FEE //Base fee of the block after the last one
+
+//Base fee of the block after a block with the given size. It rises if the block was fuller than the target and falls
+//otherwise, by at most 1/BASE_FEE_CHANGE_DENOMINATOR of the base fee.
+func nextBaseFee(baseFee, size, blockSize uint64) uint64 {
+	target := mulDiv(blockSize, BASE_FEE_TARGET_PERCENT, 100)
+	if target == 0 || size == target {
+		return baseFee
+	}
+
+	if size > target {
+		//The base fee rises at least by one coin, otherwise a base fee of 0 could never rise again
+		delta := mulDiv(baseFee, size-target, target) / BASE_FEE_CHANGE_DENOMINATOR
+		if delta == 0 {
+			delta = 1
+		}
+		if baseFee+delta > MAX_MONEY {
+			return MAX_MONEY
+		}
+		return baseFee + delta
+	}
+
+	return baseFee - mulDiv(baseFee, target-size, target)/BASE_FEE_CHANGE_DENOMINATOR
+}
+
+//Part of the fee which goes to the validator. Txs paying less than the base fee are not included by honest validators,
+//if they are anyways the whole fee is burned.
+func txTip(fee uint64) uint64 {
+	if fee < baseFee {
+		return 0
+	}
+
+	return fee - baseFee
+}
+
+//The tip is credited to the validator, the base fee is burned.
+func payTxFee(minerAcc *protocol.Account, fee uint64) {
+	tip := txTip(fee)
+	minerAcc.Balance += tip
+	supplyChange.Burned += fee - tip
+}
+
+func payTxFeeRollback(minerAcc *protocol.Account, fee uint64) {
+	minerAcc.Balance -= txTip(fee)
+}
+
+//Computes the base fee of the next block from the size of the block and stores it with the block. blockSize is the
+//block size in force at the height of the block.
+func commitBaseFee(block *protocol.Block, blockSize uint64) {
+	//The genesis block is empty, the first block is validated with the initial base fee
+	if block.Height > 0 {
+		baseFee = nextBaseFee(baseFee, block.GetSize(), blockSize)
+	}
+
+	storage.WriteBaseFee(block.Hash, baseFee)
+}
+
+//The base fee the block was validated with becomes the current one again. Returns false if the block was never
+//committed.
+func revertBaseFee(block *protocol.Block) bool {
+	if _, found := storage.ReadBaseFee(block.Hash); !found {
+		return false
+	}
+
+	//Without a stored base fee before the block it was validated with the initial one
+	prevBaseFee, found := storage.ReadBaseFee(block.PrevHash)
+	if !found {
+		prevBaseFee = INITIAL_BASE_FEE
+	}
+
+	baseFee = prevBaseFee
+	storage.DeleteBaseFee(block.Hash)
+
+	return true
+}
@@ -0,0 +1,90 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func TestNextBaseFee(t *testing.T) {
+	//The target is half of the block size, the base fee changes by at most 1/8
+	for _, test := range []struct{ baseFee, size, expected uint64 }{
+		{800, 500, 800},
+		{800, 1000, 900},
+		{800, 750, 850},
+		{800, 0, 700},
+		{800, 250, 750},
+		{0, 1000, 1},
+		{1, 0, 1},
+	} {
+		if next := nextBaseFee(test.baseFee, test.size, 1000); next != test.expected {
+			t.Errorf("Base fee after %v with a block of size %v: %v vs. %v\n", test.baseFee, test.size, next, test.expected)
+		}
+	}
+
+	if next := nextBaseFee(MAX_MONEY, 1000, 1000); next != MAX_MONEY {
+		t.Errorf("Base fee exceeds the maximum: %v\n", next)
+	}
+}
+
+//The base fee part of tx fees is burned, txs paying less than the base fee are not added to blocks
+func TestBaseFeeBurning(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	minerHash := protocol.SerializeHashContent(validatorAcc.Address)
+	baseFee = 10
+
+	tooLow, _ := protocol.ConstrFundsTx(0x01, 100, 9, 0, accAHash, accBHash, PrivKeyAccA, nil)
+	b := newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	if err := addTx(b, tooLow); err == nil {
+		t.Error("Tx paying less than the base fee added to the block.")
+	}
+
+	balanceA, balanceMiner := accA.Balance, validatorAcc.Balance
+	tx := &protocol.FundsTx{Header: 0x01, Amount: 100, Fee: 25, TxCnt: 0, From: accAHash, To: accBHash}
+	if err := collectTxFees(nil, []*protocol.FundsTx{tx}, nil, nil, nil, nil, nil, nil, nil, nil, nil, minerHash); err != nil {
+		t.Fatalf("Collecting the fee failed: %v\n", err)
+	}
+	if accA.Balance != balanceA-25 || validatorAcc.Balance != balanceMiner+15 || supplyChange.Burned != 10 {
+		t.Errorf("Base fee not burned: sender %v, miner %v, burned %v\n", balanceA-accA.Balance, validatorAcc.Balance-balanceMiner, supplyChange.Burned)
+	}
+
+	collectTxFeesRollback(nil, []*protocol.FundsTx{tx}, nil, nil, nil, nil, nil, nil, nil, nil, minerHash)
+	if accA.Balance != balanceA || validatorAcc.Balance != balanceMiner {
+		t.Errorf("Fee not rolled back: %v, %v\n", accA.Balance, validatorAcc.Balance)
+	}
+}
+
+//The base fee is stored with every block and restored when a block is rolled back
+func TestBaseFeeCommitAndRevert(t *testing.T) {
+	cleanAndPrepare()
+
+	commitBaseFee(genesisBlock, activeParameters.Block_size)
+	if current, found := storage.ReadBaseFee(genesisBlock.Hash); !found || current != INITIAL_BASE_FEE {
+		t.Errorf("Base fee after the genesis block not stored: %v\n", current)
+	}
+
+	//An empty block is below the target, a small block size makes it full
+	b := newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b.Hash = [32]byte{0x01}
+	baseFee = 800
+	storage.WriteBaseFee(genesisBlock.Hash, baseFee)
+	commitBaseFee(b, b.GetSize())
+	if baseFee != 900 {
+		t.Errorf("Base fee did not rise after a full block: %v\n", baseFee)
+	}
+	if stored, _ := storage.ReadBaseFee(b.Hash); stored != baseFee {
+		t.Errorf("Base fee not stored with the block: %v\n", stored)
+	}
+
+	if !revertBaseFee(b) || baseFee != 800 {
+		t.Errorf("Base fee not reverted: %v\n", baseFee)
+	}
+	if _, found := storage.ReadBaseFee(b.Hash); found || revertBaseFee(b) {
+		t.Error("Base fee of a rolled back block still stored.")
+	}
+}
@@ -25,6 +25,11 @@ type blockData struct {
 	stakeTxSlice  		  []*protocol.StakeTx
 	aggTxSlice	  []*protocol.AggTx
 	iotTxSlice				[]*protocol.IotTx
+	contractTxSlice			[]*protocol.ContractTx
+	iotBatchTxSlice			[]*protocol.IotBatchTx
+	delegateTxSlice			[]*protocol.DelegateTx
+	evidenceTxSlice			[]*protocol.EvidenceTx
+	governanceTxSlice		[]*protocol.GovernanceTx
 	block        		  *protocol.Block
 }
 
@@ -83,13 +88,16 @@ func finalizeBlock(block *protocol.Block) error {
 
 	prevProofs := GetLatestProofs(activeParameters.num_included_prev_proofs, block)
 
-	nonce, err := proofOfStake(getDifficulty(), block.PrevHash, prevProofs, block.Height, validatorAcc.Balance, commitmentProof)
+	nonce, err := proofOfStake(getTarget(), block.PrevHash, prevProofs, block.Height, validatorAcc.EffectiveStake(), commitmentProof)
 	if err != nil {
-		//Delete created AggTx From OpenTx.
+		//Delete created AggTx and IotAggTx From OpenTx.
 		if nonce == -2 {
 			for _, txHash := range block.AggTxData {
 				storage.DeleteOpenTxWithHash(txHash)
 			}
+			for _, txHash := range block.IoTAggTxData {
+				storage.DeleteOpenTxWithHash(txHash)
+			}
 		}
 		return err
 	}
@@ -110,10 +118,23 @@ func finalizeBlock(block *protocol.Block) error {
 	block.NrStakeTx = uint16(len(block.StakeTxData))
 	block.NrAggTx = uint16(len(block.AggTxData))
 	block.NrIoTTx = uint16(len(block.IoTTxData))
+	block.NrContractTx = uint16(len(block.ContractTxData))
+	block.NrIoTBatchTx = uint16(len(block.IoTBatchTxData))
+	block.NrIoTAggTx = uint16(len(block.IoTAggTxData))
+	block.NrDelegateTx = uint16(len(block.DelegateTxData))
+	block.NrEvidenceTx = uint16(len(block.EvidenceTxData))
+	block.NrGovernanceTx = uint16(len(block.GovernanceTxData))
 
 
 	copy(block.CommitmentProof[0:crypto.COMM_KEY_LENGTH], commitmentProof[:])
 
+	//The validator signs the header, such that proposing conflicting blocks can be proven with the headers alone.
+	header := block.SignedHeader()
+	block.ValidatorSignature, err = crypto.SignMessageWithRSAKey(commPrivKey, header.SigningMessage())
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -121,14 +142,19 @@ func finalizeBlock(block *protocol.Block) error {
 //We do not operate global state because the work might get interrupted by receiving a block that needs validation
 //which is done on the global state.
 func addTx(b *protocol.Block, tx protocol.Transaction) error {
-	//ActiveParameters is a datastructure that stores the current system parameters, gets only changed when
-	//configTxs are broadcast in the network.
-	if tx.TxFee() < activeParameters.Fee_minimum {
-		logger.Printf("Transaction fee too low: %v (minimum is: %v)\n", tx.TxFee(), activeParameters.Fee_minimum)
-		err := fmt.Sprintf("Transaction fee too low: %v (minimum is: %v)\n", tx.TxFee(), activeParameters.Fee_minimum)
+	//The system parameters in force at the height of the block, they change with configTxs and accepted proposals
+	//from their activation height on.
+	parameters := parametersAt(b.Height)
+	if tx.TxFee() < parameters.Fee_minimum {
+		logger.Printf("Transaction fee too low: %v (minimum is: %v)\n", tx.TxFee(), parameters.Fee_minimum)
+		err := fmt.Sprintf("Transaction fee too low: %v (minimum is: %v)\n", tx.TxFee(), parameters.Fee_minimum)
 		return errors.New(err)
 	}
 
+	if tx.TxFee() < baseFee {
+		return errors.New(fmt.Sprintf("Transaction fee below the base fee: %v (base fee is: %v)\n", tx.TxFee(), baseFee))
+	}
+
 	//There is a trade-off what tests can be made now and which have to be delayed (when dynamic state is needed
 	//for inspection. The decision made is to check whether accTx and configTx have been signed with rootAcc. This
 	//is a dynamic test because it needs to have access to the rootAcc state. The other option would be to include
@@ -137,6 +163,7 @@ func addTx(b *protocol.Block, tx protocol.Transaction) error {
 	//the txs depend on each other.
 	if !verify(tx) {
 		//logger.Printf("Transaction could not be verified: %v", tx)
+		p2pNode.ReportInvalidTx(tx.Hash())
 		return errors.New("Transaction could not be verified.")
 	}
 
@@ -173,6 +200,36 @@ func addTx(b *protocol.Block, tx protocol.Transaction) error {
 			//logger.Printf("Adding iotTx (%x) failed (%v): %v\n",tx.Hash(), err, tx.(*protocol.IotTx))
 			return err
 		}
+	case *protocol.ContractTx:
+		err := addContractTx(b, tx.(*protocol.ContractTx))
+		if err != nil {
+			logger.Printf("Adding contractTx (%x) failed (%v): %v\n",tx.Hash(), err, tx.(*protocol.ContractTx))
+			return err
+		}
+	case *protocol.IotBatchTx:
+		err := addIoTBatchTx(b, tx.(*protocol.IotBatchTx))
+		if err != nil {
+			logger.Printf("Adding iotBatchTx (%x) failed (%v): %v\n",tx.Hash(), err, tx.(*protocol.IotBatchTx))
+			return err
+		}
+	case *protocol.DelegateTx:
+		err := addDelegateTx(b, tx.(*protocol.DelegateTx))
+		if err != nil {
+			logger.Printf("Adding delegateTx (%x) failed (%v): %v\n",tx.Hash(), err, tx.(*protocol.DelegateTx))
+			return err
+		}
+	case *protocol.EvidenceTx:
+		err := addEvidenceTx(b, tx.(*protocol.EvidenceTx))
+		if err != nil {
+			logger.Printf("Adding evidenceTx (%x) failed (%v): %v\n",tx.Hash(), err, tx.(*protocol.EvidenceTx))
+			return err
+		}
+	case *protocol.GovernanceTx:
+		err := addGovernanceTx(b, tx.(*protocol.GovernanceTx))
+		if err != nil {
+			logger.Printf("Adding governanceTx (%x) failed (%v): %v\n",tx.Hash(), err, tx.(*protocol.GovernanceTx))
+			return err
+		}
 	default:
 		return errors.New("Transaction type not recognized.")
 	}
@@ -231,15 +288,18 @@ func addIoTTx(b *protocol.Block, tx *protocol.IotTx) error {
 			return errors.New("Not enough funds to complete the IoT transaction!")
 		}
 	}
+	//Transaction count need to match the state, preventing replay attacks.
 	if b.StateCopy[tx.From].TxCnt != tx.TxCnt {
-		//TODO @ilecipi revert check TxCnt
-		//err := fmt.Sprintf("Sender txCnt IoT does not match: %v (tx.txCnt) vs. %v (state txCnt)", tx.TxCnt, b.StateCopy[tx.From].TxCnt)
-		//return errors.New(err)
+		err := fmt.Sprintf("Sender txCnt IoT does not match: %v (tx.txCnt) vs. %v (state txCnt)", tx.TxCnt, b.StateCopy[tx.From].TxCnt)
+		return errors.New(err)
 	}
+
+	//Update state copy. Fees of root accounts are created from thin air.
 	accSender := b.StateCopy[tx.From]
 	accSender.TxCnt += 1
-	//TODO @ilecipi fix Fee
-	accSender.Balance -= tx.Fee
+	if !storage.IsRootKey(tx.From) {
+		accSender.Balance -= tx.Fee
+	}
 	//b.SizeIoTData += tx.Size()
 	b.IoTTxData = append(b.IoTTxData, tx.Hash())
 	//logger.Printf("Added tx (%x) to the IoTTxData slice: %v", tx.Hash(), *tx)
@@ -247,6 +307,91 @@ func addIoTTx(b *protocol.Block, tx *protocol.IotTx) error {
 	}
 
 
+//Like addIoTTx, but a single TxCnt and fee cover all readings of the batch.
+func addIoTBatchTx(b *protocol.Block, tx *protocol.IotBatchTx) error {
+	if _, exists := b.StateCopy[tx.From]; !exists {
+		if acc := storage.State[tx.From]; acc != nil {
+			newAcc := *acc
+			b.StateCopy[tx.From] = &newAcc
+		} else {
+			return errors.New(fmt.Sprintf("Sender account not present in the state: %x\n", tx.From))
+		}
+	}
+
+	if _, exists := b.StateCopy[tx.To]; !exists {
+		if acc := storage.State[tx.To]; acc != nil {
+			newAcc := *acc
+			b.StateCopy[tx.To] = &newAcc
+		} else {
+			return errors.New(fmt.Sprintf("Receiver account not present in the state: %x\n", tx.To))
+		}
+	}
+
+	if !storage.IsRootKey(tx.From) && tx.Fee > b.StateCopy[tx.From].Balance {
+		return errors.New("Not enough funds to complete the IoT batch transaction!")
+	}
+
+	//Transaction count need to match the state, preventing replay attacks.
+	if b.StateCopy[tx.From].TxCnt != tx.TxCnt {
+		err := fmt.Sprintf("Sender txCnt IoT batch does not match: %v (tx.txCnt) vs. %v (state txCnt)", tx.TxCnt, b.StateCopy[tx.From].TxCnt)
+		return errors.New(err)
+	}
+
+	//Update state copy. Fees of root accounts are created from thin air.
+	accSender := b.StateCopy[tx.From]
+	accSender.TxCnt += 1
+	if !storage.IsRootKey(tx.From) {
+		accSender.Balance -= tx.Fee
+	}
+
+	//Only the batch hash ends up in the block, the readings count against the block size nonetheless.
+	b.IoTBatchTxData = append(b.IoTBatchTxData, tx.Hash())
+	b.SizeIoTData += tx.Size()
+	return nil
+}
+
+//Readings can't be taken after the block they are included in.
+func checkIotBatchTimestamps(tx *protocol.IotBatchTx, timestamp int64) error {
+	for _, reading := range tx.Readings {
+		if reading.Timestamp > timestamp {
+			return errors.New(fmt.Sprintf("IoT batch %x contains readings after the block timestamp.", tx.Hash()))
+		}
+	}
+
+	return nil
+}
+
+//All batches of a device in the block are replaced by one IotAggTx, like fundsTxs are aggregated into AggTxs. Batches
+//which can't be read from the open storage and single batches of a device stay in the block as they are.
+func aggregateIotBatchTxs(b *protocol.Block) {
+	var loose, senders [][32]byte
+	batchesBySender := make(map[[32]byte][][32]byte)
+
+	for _, txHash := range b.IoTBatchTxData {
+		tx, ok := storage.ReadOpenTx(txHash).(*protocol.IotBatchTx)
+		if !ok {
+			loose = append(loose, txHash)
+			continue
+		}
+		if _, exists := batchesBySender[tx.From]; !exists {
+			senders = append(senders, tx.From)
+		}
+		batchesBySender[tx.From] = append(batchesBySender[tx.From], txHash)
+	}
+
+	for _, sender := range senders {
+		if len(batchesBySender[sender]) < 2 {
+			loose = append(loose, batchesBySender[sender]...)
+			continue
+		}
+		iotAggTx := protocol.ConstrIotAggTx(sender, batchesBySender[sender])
+		storage.WriteOpenTx(iotAggTx)
+		b.IoTAggTxData = append(b.IoTAggTxData, iotAggTx.Hash())
+	}
+
+	b.IoTBatchTxData = loose
+}
+
 func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 	//Checking if the sender account is already in the local state copy. If not and account exist, create local copy.
 	//If account does not exist in state, abort.
@@ -285,6 +430,11 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 		}
 	}
 
+	//Frozen (or self-destructed) contracts neither send nor receive funds anymore.
+	if b.StateCopy[tx.From].IsFrozen || b.StateCopy[tx.To].IsFrozen {
+		return errors.New("Sender or receiver account is frozen.")
+	}
+
 	//Transaction count need to match the state, preventing replay attacks.
 	if b.StateCopy[tx.From].TxCnt != tx.TxCnt {
 		//TODO @ilecipi revert check TxCnt
@@ -544,12 +694,150 @@ func sortFundsTxBeforeAggregation(Slice []*protocol.FundsTx) {
 }
 
 func addConfigTx(b *protocol.Block, tx *protocol.ConfigTx) error {
-	//No further checks needed, static checks were already done with verify().
+	//Besides governance and the activation height, no further checks needed, static checks were already done with verify().
+	if err := checkConfigTx(tx, b.Height); err != nil {
+		return err
+	}
+
 	b.ConfigTxData = append(b.ConfigTxData, tx.Hash())
 	logger.Printf("Added tx (%x) to the ConfigTxData slice: %v", tx.Hash(), *tx)
 	return nil
 }
 
+//The root key only changes parameters until validators locked stake, from then on they decide with GovernanceTxs.
+//Parameter changes can't be put in force retroactively, a change scheduled for the block after the including one is
+//fine.
+func checkConfigTx(tx *protocol.ConfigTx, height uint32) error {
+	if governanceActive() {
+		return errors.New("Parameters are changed by governance, ConfigTxs are not accepted anymore.")
+	}
+	if tx.ActivationHeight != 0 && tx.ActivationHeight <= height {
+		return errors.New(fmt.Sprintf("ConfigTx activation height %v is not after block height %v.", tx.ActivationHeight, height))
+	}
+
+	return nil
+}
+
+func addContractTx(b *protocol.Block, tx *protocol.ContractTx) error {
+	issuerHash := protocol.SerializeHashContent(tx.Issuer)
+
+	//Same as for the other tx types, the involved accounts are copied into the local state copy first.
+	involved := [][32]byte{tx.Account, issuerHash}
+	if tx.Header == protocol.CONTRACT_SELFDESTRUCT {
+		involved = append(involved, tx.Beneficiary)
+	}
+	for _, accHash := range involved {
+		if _, exists := b.StateCopy[accHash]; !exists {
+			if acc := storage.State[accHash]; acc != nil {
+				newAcc := protocol.Account{}
+				newAcc = *acc
+				b.StateCopy[accHash] = &newAcc
+			} else {
+				return errors.New(fmt.Sprintf("Account not present in the state: %x\n", accHash))
+			}
+		}
+	}
+
+	acc := b.StateCopy[tx.Account]
+	if err := checkContractTx(tx, acc, b.StateCopy[issuerHash], b.StateCopy[tx.Beneficiary]); err != nil {
+		return err
+	}
+
+	//Update state copy.
+	applyContractTx(tx, acc, b.StateCopy[tx.Beneficiary])
+	if !storage.IsRootKey(issuerHash) {
+		b.StateCopy[issuerHash].Balance -= tx.Fee
+	}
+
+	b.ContractTxData = append(b.ContractTxData, tx.Hash())
+	logger.Printf("Added tx (%x) to the ContractTxData slice: %v", tx.Hash(), *tx)
+	return nil
+}
+
+func addDelegateTx(b *protocol.Block, tx *protocol.DelegateTx) error {
+	for _, accHash := range [][32]byte{tx.From, tx.Validator} {
+		if acc, exists := b.StateCopy[accHash]; exists {
+			//Copies made by other tx types share the delegations with the state, which are changed below.
+			b.StateCopy[accHash] = acc.Copy()
+		} else if acc := storage.State[accHash]; acc != nil {
+			b.StateCopy[accHash] = acc.Copy()
+		} else {
+			return errors.New(fmt.Sprintf("Account not present in the state: %x\n", accHash))
+		}
+	}
+
+	acc := b.StateCopy[tx.From]
+	if err := checkDelegateTx(tx, acc, b.StateCopy[tx.Validator]); err != nil {
+		return err
+	}
+
+	//Update state copy.
+	applyDelegateTx(tx, acc, b.StateCopy[tx.Validator])
+
+	b.DelegateTxData = append(b.DelegateTxData, tx.Hash())
+	logger.Printf("Added tx (%x) to the DelegateTxData slice: %v", tx.Hash(), *tx)
+	return nil
+}
+
+func addEvidenceTx(b *protocol.Block, tx *protocol.EvidenceTx) error {
+	for _, accHash := range [][32]byte{tx.From, tx.Offender} {
+		if _, exists := b.StateCopy[accHash]; !exists {
+			if acc := storage.State[accHash]; acc != nil {
+				newAcc := *acc
+				b.StateCopy[accHash] = &newAcc
+			} else {
+				return errors.New(fmt.Sprintf("Account not present in the state: %x\n", accHash))
+			}
+		}
+	}
+
+	acc := b.StateCopy[tx.From]
+	if err := checkEvidenceTx(tx, acc, b.StateCopy[tx.Offender]); err != nil {
+		return err
+	}
+
+	//Update state copy.
+	applyEvidenceTx(tx, acc, b.StateCopy[tx.Offender])
+
+	b.EvidenceTxData = append(b.EvidenceTxData, tx.Hash())
+	logger.Printf("Added tx (%x) to the EvidenceTxData slice: %v", tx.Hash(), *tx)
+	return nil
+}
+
+func addGovernanceTx(b *protocol.Block, tx *protocol.GovernanceTx) error {
+	if _, exists := b.StateCopy[tx.From]; !exists {
+		if acc := storage.State[tx.From]; acc != nil {
+			newAcc := *acc
+			b.StateCopy[tx.From] = &newAcc
+		} else {
+			return errors.New(fmt.Sprintf("Sender account not present in the state: %x\n", tx.From))
+		}
+	}
+
+	acc := b.StateCopy[tx.From]
+	if err := checkGovernanceTx(tx, acc, b.Height); err != nil {
+		return err
+	}
+
+	//The votes of the block are only counted during validation, a second vote for the same proposal would render the
+	//whole block invalid.
+	if tx.Header == protocol.GOVERNANCE_VOTE {
+		for _, txHash := range b.GovernanceTxData {
+			if other, ok := storage.ReadOpenTx(txHash).(*protocol.GovernanceTx); ok && other.From == tx.From && other.ProposalHash == tx.ProposalHash {
+				return errors.New("Validator already voted for the proposal in this block.")
+			}
+		}
+	}
+
+	//Update state copy.
+	acc.TxCnt += 1
+	acc.Balance -= tx.Fee
+
+	b.GovernanceTxData = append(b.GovernanceTxData, tx.Hash())
+	logger.Printf("Added tx (%x) to the GovernanceTxData slice: %v", tx.Hash(), *tx)
+	return nil
+}
+
 func addStakeTx(b *protocol.Block, tx *protocol.StakeTx) error {
 	//Checking if the sender account is already in the local state copy. If not and account exist, create local copy
 	//If account does not exist in state, abort.
@@ -566,81 +854,368 @@ func addStakeTx(b *protocol.Block, tx *protocol.StakeTx) error {
 		}
 	}
 
-	//Root accounts are exempt from balance requirements. All other accounts need to have (at least)
-	//fee + minimum amount that is required for staking.
-	if !storage.IsRootKey(protocol.SerializeHashContent(tx.Account)) {
-		if (tx.Fee + activeParameters.Staking_minimum) >= b.StateCopy[tx.Account].Balance {
-			return errors.New("Not enough funds to complete the transaction!")
+	//Update state copy.
+	accSender := b.StateCopy[tx.Account]
+	if err := checkStakeTx(tx, accSender, b.Height); err != nil {
+		return err
+	}
+	applyStakeTx(tx, accSender, b.Height)
+
+	//No further checks needed, static checks were already done with verify().
+	b.StakeTxData = append(b.StakeTxData, tx.Hash())
+	logger.Printf("Added tx (%x) to the StakeTxData slice: %v", tx.Hash(), *tx)
+	return nil
+}
+//Requests all txs which are neither closed nor open (nor in the invalid stash and valid by now) with a single
+//TX_BATCH_REQ, such that a block with many missing txs is fetched in one round-trip.
+func fetchMissingTxs(txHashes [][32]byte, reqType uint8) (fetched map[[32]byte]protocol.Transaction, err error) {
+	reqs := make(map[[32]byte]uint8)
+	for _, txHash := range txHashes {
+		if storage.ReadClosedTx(txHash) != nil || storage.ReadOpenTx(txHash) != nil {
+			continue
+		}
+		if txINVALID := storage.ReadINVALIDOpenTx(txHash); txINVALID != nil && verify(txINVALID) {
+			continue
+		}
+		reqs[txHash] = reqType
+	}
+
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	batchChan, err := p2pNode.TxBatchReq(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	//Blocking Wait, limit the waiting time for TXFETCH_TIMEOUT seconds.
+	select {
+	case batch := <-batchChan:
+		if len(batch.NotFound) > 0 {
+			return nil, errors.New(fmt.Sprintf("%v of %v txs could not be fetched, e.g. %x", len(batch.NotFound), len(reqs), batch.NotFound[0]))
+		}
+		return batch.Txs, nil
+	case <-time.After(TXFETCH_TIMEOUT * time.Second):
+		return nil, errors.New(fmt.Sprintf("Fetching %v txs timed out.", len(reqs)))
+	}
+}
+
+func fetchIotTxData(block *protocol.Block, iotTxSlice []*protocol.IotTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.IoTTxData, p2p.IOTTX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("IoTTx could not be read: %v", err))
+		return
+	}
+
+	for cnt, txHash := range block.IoTTxData {
+		var tx protocol.Transaction
+		var IoTTx *protocol.IotTx
+
+		closedTx := storage.ReadClosedTx(txHash)
+		if closedTx != nil {
+			if initialSetup {
+				IoTTx = closedTx.(*protocol.IotTx)
+				iotTxSlice[cnt] = IoTTx
+				continue
+			} else {
+				//Reject blocks that have txs which have already been validated.
+				errChan <- errors.New("Block validation had accTx that was already in a previous block.")
+				return
+			}
+		}
+
+		//Tx is either in open storage or was fetched from the network. The p2p package makes sure that fetched txs
+		//correspond to our request.
+		if tx = storage.ReadOpenTx(txHash); tx == nil {
+			tx = fetched[txHash]
+		}
+		IoTTx, ok := tx.(*protocol.IotTx)
+		if !ok {
+			errChan <- errors.New("IoTTx could not be read.")
+			return
+		}
+
+		iotTxSlice[cnt] = IoTTx
+	}
+
+	errChan <- nil
+}
+
+func fetchContractTxData(block *protocol.Block, contractTxSlice []*protocol.ContractTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.ContractTxData, p2p.CONTRACTTX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("ContractTx could not be read: %v", err))
+		return
+	}
+
+	for cnt, txHash := range block.ContractTxData {
+		var tx protocol.Transaction
+		var contractTx *protocol.ContractTx
+
+		closedTx := storage.ReadClosedTx(txHash)
+		if closedTx != nil {
+			if initialSetup {
+				contractTx = closedTx.(*protocol.ContractTx)
+				contractTxSlice[cnt] = contractTx
+				continue
+			} else {
+				//Reject blocks that have txs which have already been validated.
+				errChan <- errors.New("Block validation had contractTx that was already in a previous block.")
+				return
+			}
+		}
+
+		//Tx is either in open storage or was fetched from the network.
+		if tx = storage.ReadOpenTx(txHash); tx == nil {
+			tx = fetched[txHash]
+		}
+		contractTx, ok := tx.(*protocol.ContractTx)
+		if !ok {
+			errChan <- errors.New("ContractTx could not be read.")
+			return
+		}
+
+		contractTxSlice[cnt] = contractTx
+	}
+
+	errChan <- nil
+}
+
+//We use slices (not maps) because order is now important.
+func fetchIotBatchTxData(block *protocol.Block, iotBatchTxSlice []*protocol.IotBatchTx, initialSetup bool, errChan chan error) {
+	iotBatchTxs, err := fetchIotBatchTxs(block.IoTBatchTxData, initialSetup)
+	if err != nil {
+		errChan <- err
+		return
+	}
+
+	copy(iotBatchTxSlice, iotBatchTxs)
+	errChan <- nil
+}
+
+//Fetches the batches listed in the block as well as the batches aggregated by an IotAggTx.
+func fetchIotBatchTxs(txHashes [][32]byte, initialSetup bool) (iotBatchTxSlice []*protocol.IotBatchTx, err error) {
+	fetched, err := fetchMissingTxs(txHashes, p2p.IOTBATCHTX_REQ)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("IotBatchTx could not be read: %v", err))
+	}
+
+	for _, txHash := range txHashes {
+		var tx protocol.Transaction
+
+		closedTx := storage.ReadClosedTx(txHash)
+		if closedTx != nil {
+			if initialSetup {
+				iotBatchTxSlice = append(iotBatchTxSlice, closedTx.(*protocol.IotBatchTx))
+				continue
+			} else {
+				//Reject blocks that have txs which have already been validated.
+				return nil, errors.New("Block validation had iotBatchTx that was already in a previous block.")
+			}
+		}
+
+		//Tx is either in open storage or was fetched from the network.
+		if tx = storage.ReadOpenTx(txHash); tx == nil {
+			tx = fetched[txHash]
+			//The hash only covers the readings root, therefore the readings of fetched txs are checked too.
+			if fetchedTx, ok := tx.(*protocol.IotBatchTx); ok && fetchedTx.CalcReadingsRoot() != fetchedTx.ReadingsRoot {
+				return nil, errors.New("Received IotBatchTxHash did not correspond to our request.")
+			}
+		}
+		iotBatchTx, ok := tx.(*protocol.IotBatchTx)
+		if !ok {
+			return nil, errors.New("IotBatchTx could not be read.")
+		}
+
+		iotBatchTxSlice = append(iotBatchTxSlice, iotBatchTx)
+	}
+
+	return iotBatchTxSlice, nil
+}
+
+//Fetches the IotAggTxs of the block together with the batches they aggregate. Fetched IotAggTxs are kept in the open
+//storage like AggTxs, such that postValidate can close them.
+func fetchIotAggTxData(block *protocol.Block, iotAggTxSlice []*protocol.IotAggTx, aggregatedIotBatchTxSlice [][]*protocol.IotBatchTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.IoTAggTxData, p2p.IOTAGGTX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("IotAggTx could not be read: %v", err))
+		return
+	}
+
+	for cnt, txHash := range block.IoTAggTxData {
+		var iotAggTx *protocol.IotAggTx
+
+		if closedTx := storage.ReadClosedTx(txHash); closedTx != nil {
+			if !initialSetup {
+				errChan <- errors.New("Block validation had iotAggTx that was already in a previous block.")
+				return
+			}
+			iotAggTx = closedTx.(*protocol.IotAggTx)
+		} else if tx, ok := storage.ReadOpenTx(txHash).(*protocol.IotAggTx); ok {
+			iotAggTx = tx
+		} else if fetchedTx, ok := fetched[txHash].(*protocol.IotAggTx); ok && fetchedTx.Hash() == txHash {
+			iotAggTx = fetchedTx
+			storage.WriteOpenTx(iotAggTx)
+			if initialSetup {
+				storage.WriteBootstrapTxReceived(iotAggTx)
+			}
+		} else {
+			errChan <- errors.New("IotAggTx could not be read.")
+			return
+		}
+
+		//Single batches are not aggregated, all aggregated batches belong to the same device.
+		if len(iotAggTx.AggregatedTxSlice) < 2 {
+			errChan <- errors.New(fmt.Sprintf("IotAggTx %x aggregates less than two batches.", txHash[0:8]))
+			return
+		}
+		iotBatchTxs, err := fetchIotBatchTxs(iotAggTx.AggregatedTxSlice, initialSetup)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		for _, iotBatchTx := range iotBatchTxs {
+			if iotBatchTx.From != iotAggTx.From {
+				errChan <- errors.New(fmt.Sprintf("IotAggTx %x aggregates a batch of another device.", txHash[0:8]))
+				return
+			}
+		}
+
+		iotAggTxSlice[cnt] = iotAggTx
+		aggregatedIotBatchTxSlice[cnt] = iotBatchTxs
+	}
+
+	errChan <- nil
+}
+
+func fetchDelegateTxData(block *protocol.Block, delegateTxSlice []*protocol.DelegateTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.DelegateTxData, p2p.DELEGATETX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("DelegateTx could not be read: %v", err))
+		return
+	}
+
+	for cnt, txHash := range block.DelegateTxData {
+		var tx protocol.Transaction
+		var delegateTx *protocol.DelegateTx
+
+		closedTx := storage.ReadClosedTx(txHash)
+		if closedTx != nil {
+			if initialSetup {
+				delegateTx = closedTx.(*protocol.DelegateTx)
+				delegateTxSlice[cnt] = delegateTx
+				continue
+			} else {
+				//Reject blocks that have txs which have already been validated.
+				errChan <- errors.New("Block validation had delegateTx that was already in a previous block.")
+				return
+			}
+		}
+
+		//Tx is either in open storage or was fetched from the network.
+		if tx = storage.ReadOpenTx(txHash); tx == nil {
+			tx = fetched[txHash]
+		}
+		delegateTx, ok := tx.(*protocol.DelegateTx)
+		if !ok {
+			errChan <- errors.New("DelegateTx could not be read.")
+			return
 		}
-	}
 
-	//Account has bool already set to the desired value.
-	if b.StateCopy[tx.Account].IsStaking == tx.IsStaking {
-		return errors.New("Account has bool already set to the desired value.")
+		delegateTxSlice[cnt] = delegateTx
 	}
 
-	//Update state copy.
-	accSender := b.StateCopy[tx.Account]
-	accSender.IsStaking = tx.IsStaking
-	accSender.CommitmentKey = tx.CommitmentKey
-
-	//No further checks needed, static checks were already done with verify().
-	b.StakeTxData = append(b.StakeTxData, tx.Hash())
-	logger.Printf("Added tx (%x) to the StakeTxData slice: %v", tx.Hash(), *tx)
-	return nil
+	errChan <- nil
 }
-func fetchIotTxData(block *protocol.Block, iotTxSlice []*protocol.IotTx, initialSetup bool, errChan chan error) {
-	for cnt, txHash := range block.IoTTxData {
+
+func fetchEvidenceTxData(block *protocol.Block, evidenceTxSlice []*protocol.EvidenceTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.EvidenceTxData, p2p.EVIDENCETX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("EvidenceTx could not be read: %v", err))
+		return
+	}
+
+	for cnt, txHash := range block.EvidenceTxData {
 		var tx protocol.Transaction
-		var IoTTx *protocol.IotTx
+		var evidenceTx *protocol.EvidenceTx
 
 		closedTx := storage.ReadClosedTx(txHash)
 		if closedTx != nil {
 			if initialSetup {
-				IoTTx = closedTx.(*protocol.IotTx)
-				iotTxSlice[cnt] = IoTTx
+				evidenceTx = closedTx.(*protocol.EvidenceTx)
+				evidenceTxSlice[cnt] = evidenceTx
 				continue
 			} else {
 				//Reject blocks that have txs which have already been validated.
-				errChan <- errors.New("Block validation had accTx that was already in a previous block.")
+				errChan <- errors.New("Block validation had evidenceTx that was already in a previous block.")
 				return
 			}
 		}
 
-		//TODO Optimize code (duplicated)
-		//Tx is either in open storage or needs to be fetched from the network.
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			IoTTx = tx.(*protocol.IotTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.IOTTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("AccTx could not be read: %v", err))
+		//Tx is either in open storage or was fetched from the network.
+		if tx = storage.ReadOpenTx(txHash); tx == nil {
+			tx = fetched[txHash]
+		}
+		evidenceTx, ok := tx.(*protocol.EvidenceTx)
+		if !ok {
+			errChan <- errors.New("EvidenceTx could not be read.")
+			return
+		}
+
+		evidenceTxSlice[cnt] = evidenceTx
+	}
+
+	errChan <- nil
+}
+
+func fetchGovernanceTxData(block *protocol.Block, governanceTxSlice []*protocol.GovernanceTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.GovernanceTxData, p2p.GOVERNANCETX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("GovernanceTx could not be read: %v", err))
+		return
+	}
+
+	for cnt, txHash := range block.GovernanceTxData {
+		var tx protocol.Transaction
+		var governanceTx *protocol.GovernanceTx
+
+		closedTx := storage.ReadClosedTx(txHash)
+		if closedTx != nil {
+			if initialSetup {
+				governanceTx = closedTx.(*protocol.GovernanceTx)
+				governanceTxSlice[cnt] = governanceTx
+				continue
+			} else {
+				//Reject blocks that have txs which have already been validated.
+				errChan <- errors.New("Block validation had governanceTx that was already in a previous block.")
 				return
 			}
+		}
 
-			//Blocking Wait
-			select {
-			case IoTTx = <-p2p.IoTTxChan:
-				//Limit the waiting time for TXFETCH_TIMEOUT seconds.
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("IoTTx fetch timed out.")
-			}
-			//This check is important. A malicious miner might have sent us a tx whose hash is a different one
-			//from what we requested.
-			if IoTTx.Hash() != txHash {
-				errChan <- errors.New("Received IoTHash did not correspond to our request.")
-			}
+		//Tx is either in open storage or was fetched from the network.
+		if tx = storage.ReadOpenTx(txHash); tx == nil {
+			tx = fetched[txHash]
+		}
+		governanceTx, ok := tx.(*protocol.GovernanceTx)
+		if !ok {
+			errChan <- errors.New("GovernanceTx could not be read.")
+			return
 		}
 
-		iotTxSlice[cnt] = IoTTx
+		governanceTxSlice[cnt] = governanceTx
 	}
 
 	errChan <- nil
 }
 
-//We use slices (not maps) because order is now important.
 func fetchAccTxData(block *protocol.Block, accTxSlice []*protocol.AccTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.AccTxData, p2p.ACCTX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("AccTx could not be read: %v", err))
+		return
+	}
+
 	for cnt, txHash := range block.AccTxData {
 		var tx protocol.Transaction
 		var accTx *protocol.AccTx
@@ -658,30 +1233,14 @@ func fetchAccTxData(block *protocol.Block, accTxSlice []*protocol.AccTx, initial
 			}
 		}
 
-		//TODO Optimize code (duplicated)
-		//Tx is either in open storage or needs to be fetched from the network.
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			accTx = tx.(*protocol.AccTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.ACCTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("AccTx could not be read: %v", err))
-				return
-			}
-
-			//Blocking Wait
-			select {
-			case accTx = <-p2p.AccTxChan:
-				//Limit the waiting time for TXFETCH_TIMEOUT seconds.
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("AccTx fetch timed out.")
-			}
-			//This check is important. A malicious miner might have sent us a tx whose hash is a different one
-			//from what we requested.
-			if accTx.Hash() != txHash {
-				errChan <- errors.New("Received AcctxHash did not correspond to our request.")
-			}
+		//Tx is either in open storage or was fetched from the network.
+		if tx = storage.ReadOpenTx(txHash); tx == nil {
+			tx = fetched[txHash]
+		}
+		accTx, ok := tx.(*protocol.AccTx)
+		if !ok {
+			errChan <- errors.New("AccTx could not be read.")
+			return
 		}
 
 		accTxSlice[cnt] = accTx
@@ -691,6 +1250,12 @@ func fetchAccTxData(block *protocol.Block, accTxSlice []*protocol.AccTx, initial
 }
 
 func fetchFundsTxData(block *protocol.Block, fundsTxSlice []*protocol.FundsTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.FundsTxData, p2p.FUNDSTX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("FundsTx could not be read: %v", err))
+		return
+	}
+
 	for cnt, txHash := range block.FundsTxData {
 		var tx protocol.Transaction
 		var fundsTx *protocol.FundsTx
@@ -716,25 +1281,15 @@ func fetchFundsTxData(block *protocol.Block, fundsTxSlice []*protocol.FundsTx, i
 			fundsTx = tx.(*protocol.FundsTx)
 		} else if  txINVALID != nil && verify(txINVALID) {
 			fundsTx = txINVALID.(*protocol.FundsTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.FUNDSTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("FundsTx could not be read: %v", err))
-				return
-			}
-			select {
-			case fundsTx = <-p2p.FundsTxChan:
-				storage.WriteOpenTx(fundsTx)
-				if initialSetup {
-					storage.WriteBootstrapTxReceived(fundsTx)
-				}
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("FundsTx fetch timed out")
-				return
-			}
-			if fundsTx.Hash() != txHash {
-				errChan <- errors.New("Received FundstxHash did not correspond to our request.")
+		} else if fetchedTx, ok := fetched[txHash].(*protocol.FundsTx); ok {
+			fundsTx = fetchedTx
+			storage.WriteOpenTx(fundsTx)
+			if initialSetup {
+				storage.WriteBootstrapTxReceived(fundsTx)
 			}
+		} else {
+			errChan <- errors.New("FundsTx could not be read.")
+			return
 		}
 
 		fundsTxSlice[cnt] = fundsTx
@@ -744,6 +1299,12 @@ func fetchFundsTxData(block *protocol.Block, fundsTxSlice []*protocol.FundsTx, i
 }
 
 func fetchConfigTxData(block *protocol.Block, configTxSlice []*protocol.ConfigTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.ConfigTxData, p2p.CONFIGTX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("ConfigTx could not be read: %v", err))
+		return
+	}
+
 	for cnt, txHash := range block.ConfigTxData {
 		var tx protocol.Transaction
 		var configTx *protocol.ConfigTx
@@ -760,26 +1321,18 @@ func fetchConfigTxData(block *protocol.Block, configTxSlice []*protocol.ConfigTx
 			}
 		}
 
-		//TODO Optimize code (duplicated)
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			configTx = tx.(*protocol.ConfigTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.CONFIGTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("ConfigTx could not be read: %v", err))
-				return
-			}
+		if tx = storage.ReadOpenTx(txHash); tx == nil {
+			tx = fetched[txHash]
+		}
+		configTx, ok := tx.(*protocol.ConfigTx)
+		if !ok {
+			errChan <- errors.New("ConfigTx could not be read.")
+			return
+		}
 
-			select {
-			case configTx = <-p2p.ConfigTxChan:
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("ConfigTx fetch timed out.")
-				return
-			}
-			if configTx.Hash() != txHash {
-				errChan <- errors.New("Received ConfigtxHash did not correspond to our request.")
-			}
+		if err := checkConfigTx(configTx, block.Height); err != nil {
+			errChan <- err
+			return
 		}
 
 		configTxSlice[cnt] = configTx
@@ -789,6 +1342,12 @@ func fetchConfigTxData(block *protocol.Block, configTxSlice []*protocol.ConfigTx
 }
 
 func fetchStakeTxData(block *protocol.Block, stakeTxSlice []*protocol.StakeTx, initialSetup bool, errChan chan error) {
+	fetched, err := fetchMissingTxs(block.StakeTxData, p2p.STAKETX_REQ)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("StakeTx could not be read: %v", err))
+		return
+	}
+
 	for cnt, txHash := range block.StakeTxData {
 		var tx protocol.Transaction
 		var stakeTx *protocol.StakeTx
@@ -805,25 +1364,13 @@ func fetchStakeTxData(block *protocol.Block, stakeTxSlice []*protocol.StakeTx, i
 			}
 		}
 
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			stakeTx = tx.(*protocol.StakeTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.STAKETX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("StakeTx could not be read: %v", err))
-				return
-			}
-
-			select {
-			case stakeTx = <-p2p.StakeTxChan:
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("StakeTx fetch timed out.")
-				return
-			}
-			if stakeTx.Hash() != txHash {
-				errChan <- errors.New("Received StaketxHash did not correspond to our request.")
-			}
+		if tx = storage.ReadOpenTx(txHash); tx == nil {
+			tx = fetched[txHash]
+		}
+		stakeTx, ok := tx.(*protocol.StakeTx)
+		if !ok {
+			errChan <- errors.New("StakeTx could not be read.")
+			return
 		}
 
 		stakeTxSlice[cnt] = stakeTx
@@ -836,6 +1383,13 @@ func fetchAggTxData(block *protocol.Block, aggTxSlice []*protocol.AggTx, aggrega
 	errAggFundsTxFetchChan := make(chan error, 1)
 	var errAggFundsTxFetch error
 
+	fetched, err := fetchMissingTxs(block.AggTxData, p2p.AGGTX_REQ)
+	if err != nil {
+		logger.Printf("Fetching AggTxs failed: %v... from Block: %v", err, block)
+		errChan <- errors.New(fmt.Sprintf("AggTx could not be read: %v", err))
+		return
+	}
+
 	for cnt, txHash := range block.AggTxData {
 		var tx protocol.Transaction
 		var aggTx *protocol.AggTx
@@ -876,47 +1430,27 @@ func fetchAggTxData(block *protocol.Block, aggTxSlice []*protocol.AggTx, aggrega
 			aggTx = tx.(*protocol.AggTx)
 		//} else if  txINVALID != nil && verify(txINVALID) {
 		//	aggTx = txINVALID.(*protocol.AggTx)
-		} else {
-			cnt := 0
-			here:
-			cnt +=1
-			err := p2p.TxReq(txHash, p2p.AGGTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("AggTx could not be read: %v", err))
-				return
+		} else if fetchedTx, ok := fetched[txHash].(*protocol.AggTx); ok {
+			aggTx = fetchedTx
+			storage.WriteOpenTx(aggTx)
+			if initialSetup {
+				storage.WriteBootstrapTxReceived(aggTx)
 			}
+			for _, trx := range aggTx.AggregatedTxSlice {
+				aggregatedFundsTxSliceHashes = append(aggregatedFundsTxSliceHashes, trx)
+			}
+			aggregatedFundsTxSlice = make([]*protocol.FundsTx, len(aggregatedFundsTxSliceHashes))
 
-			select {
-			case aggTx = <-p2p.AggTxChan:
-				storage.WriteOpenTx(aggTx)
-				if initialSetup {
-					storage.WriteBootstrapTxReceived(aggTx)
-				}
-				for _, trx := range aggTx.AggregatedTxSlice {
-					aggregatedFundsTxSliceHashes = append(aggregatedFundsTxSliceHashes, trx)
-				}
-				aggregatedFundsTxSlice = make([]*protocol.FundsTx, len(aggregatedFundsTxSliceHashes))
-
-				go fetchAggregatedFundsTxData(aggregatedFundsTxSliceHashes, aggregatedFundsTxSlice, initialSetup, errAggFundsTxFetchChan)
-
-				errAggFundsTxFetch = <-errAggFundsTxFetchChan
-
-				if errAggFundsTxFetch != nil {
-					errChan <- errAggFundsTxFetch
-				}
+			go fetchAggregatedFundsTxData(aggregatedFundsTxSliceHashes, aggregatedFundsTxSlice, initialSetup, errAggFundsTxFetchChan)
 
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				logger.Printf("Fetching (%x) timed out... from Block: %v", txHash, block)
-				errChan <- errors.New("AggTx fetch timed out")
-				return
-			}
+			errAggFundsTxFetch = <-errAggFundsTxFetchChan
 
-			//three tries to fetch correct AggTx
-			if aggTx.Hash() != txHash && cnt < 2 {
-				goto here
-				errChan <- errors.New("Received AggTxHash did not correspond to our request.")
+			if errAggFundsTxFetch != nil {
+				errChan <- errAggFundsTxFetch
 			}
-
+		} else {
+			errChan <- errors.New("AggTx could not be read.")
+			return
 		}
 
 		aggTxSlice[cnt] = aggTx
@@ -927,6 +1461,12 @@ func fetchAggTxData(block *protocol.Block, aggTxSlice []*protocol.AggTx, aggrega
 
 
 func fetchAggregatedFundsTxData(aggregatedFundsTxHashesSlice [][32]byte, aggregatedFundsTxSlice []*protocol.FundsTx, initialSetup bool, errAggFundsTxFetchChan chan error) {
+	fetched, err := fetchMissingTxs(aggregatedFundsTxHashesSlice, p2p.FUNDSTX_REQ)
+	if err != nil {
+		errAggFundsTxFetchChan <- errors.New(fmt.Sprintf("FundsTx could not be read: %v", err))
+		return
+	}
+
 	for cnt, txHash := range aggregatedFundsTxHashesSlice {
 		var tx protocol.Transaction
 		var fundsTx *protocol.FundsTx
@@ -944,7 +1484,6 @@ func fetchAggregatedFundsTxData(aggregatedFundsTxHashesSlice [][32]byte, aggrega
 			}
 		}
 
-		//TODO Optimize code (duplicated)
 		//We check if the Transaction is in the invalidOpenTX stash. When it is in there, and it is valid now, we save
 		//it into the fundsTX and continue like usual. This additional stash does lower the amount of network requests.
 		tx = storage.ReadOpenTx(txHash)
@@ -953,26 +1492,15 @@ func fetchAggregatedFundsTxData(aggregatedFundsTxHashesSlice [][32]byte, aggrega
 			fundsTx = tx.(*protocol.FundsTx)
 		} else if  txINVALID != nil && verify(txINVALID) {
 			fundsTx = txINVALID.(*protocol.FundsTx)
-		} else {
-			err := p2p.TxReq(txHash, p2p.FUNDSTX_REQ)
-			if err != nil {
-				errAggFundsTxFetchChan <- errors.New(fmt.Sprintf("FundsTx could not be read: %v", err))
-				return
-			}
-			select {
-			case fundsTx = <-p2p.FundsTxChan:
-				storage.WriteOpenTx(fundsTx)
-				if initialSetup {
-					storage.WriteBootstrapTxReceived(fundsTx)
-				}
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errAggFundsTxFetchChan <- errors.New("FundsTx fetch timed out")
-				return
-			}
-
-			if fundsTx.Hash() != txHash {
-				errAggFundsTxFetchChan <- errors.New("Received AggregatedFundsTxHash did not correspond to our request.")
+		} else if fetchedTx, ok := fetched[txHash].(*protocol.FundsTx); ok {
+			fundsTx = fetchedTx
+			storage.WriteOpenTx(fundsTx)
+			if initialSetup {
+				storage.WriteBootstrapTxReceived(fundsTx)
 			}
+		} else {
+			errAggFundsTxFetchChan <- errors.New("FundsTx could not be read.")
+			return
 		}
 
 		aggregatedFundsTxSlice[cnt] = fundsTx
@@ -1022,7 +1550,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 	if len(blocksToRollback) == 0 {
 		for _, block := range blocksToValidate {
 			//Fetching payload data from the txs (if necessary, ask other miners).
-			accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, err := preValidate(block, initialSetup)
+			accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, contractTxs, iotBatchTxs, delegateTxs, evidenceTxs, governanceTxs, err := preValidate(block, initialSetup)
 
 			//Check if the validator that added the block has previously voted on different competing chains (find slashing proof).
 			//The proof will be stored in the global slashing dictionary.
@@ -1034,7 +1562,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 				return err
 			}
 
-			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, block}
+			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, contractTxs, iotBatchTxs, delegateTxs, evidenceTxs, governanceTxs, block}
 			if err := validateState(blockDataMap[block.Hash]); err != nil {
 				return err
 			}
@@ -1053,7 +1581,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 		}
 		for _, block := range blocksToValidate {
 			//Fetching payload data from the txs (if necessary, ask other miners).
-			accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, err := preValidate(block, initialSetup)
+			accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, contractTxs, iotBatchTxs, delegateTxs, evidenceTxs, governanceTxs, err := preValidate(block, initialSetup)
 
 			//Check if the validator that added the block has previously voted on different competing chains (find slashing proof).
 			//The proof will be stored in the global slashing dictionary.
@@ -1065,7 +1593,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 				return err
 			}
 
-			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs,iotTxs, block}
+			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, contractTxs, iotBatchTxs, delegateTxs, evidenceTxs, governanceTxs, block}
 			if err := validateState(blockDataMap[block.Hash]); err != nil {
 				return err
 			}
@@ -1080,64 +1608,109 @@ func validate(b *protocol.Block, initialSetup bool) error {
 }
 
 //Doesn't involve any state changes.
-func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsTx, configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, aggTxSlice []*protocol.AggTx, iotTxSlice []*protocol.IotTx, err error) {
+func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsTx, configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, aggTxSlice []*protocol.AggTx, iotTxSlice []*protocol.IotTx, contractTxSlice []*protocol.ContractTx, iotBatchTxSlice []*protocol.IotBatchTx, delegateTxSlice []*protocol.DelegateTx, evidenceTxSlice []*protocol.EvidenceTx, governanceTxSlice []*protocol.GovernanceTx, err error) {
 	//This dynamic check is only done if we're up-to-date with syncing, otherwise timestamp is not checked.
 	//Other miners (which are up-to-date) made sure that this is correct.
 	if !initialSetup && uptodate {
 		if err := timestampCheck(block.Timestamp); err != nil {
-			return nil, nil, nil, nil, nil, nil, err
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
 		}
 	}
 
+	//The block is checked against the system parameters in force at its height.
+	parameters := parametersAt(block.Height)
+
 	//Check block size.
-	if block.GetSize() > activeParameters.Block_size {
-		return nil, nil, nil, nil, nil, nil, errors.New("Block size too large.")
+	if block.GetSize() > parameters.Block_size {
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Block size too large.")
 	}
 
 	//Duplicates are not allowed, use tx hash hashmap to easily check for duplicates.
 	duplicates := make(map[[32]byte]bool)
 	for _, txHash := range block.AccTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil,  nil,nil, errors.New("Duplicate Account Transaction Hash detected.")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate Account Transaction Hash detected.")
 		}
 		duplicates[txHash] = true
 	}
 	for _, txHash := range block.FundsTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, nil, nil, errors.New("Duplicate Funds Transaction Hash detected.")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate Funds Transaction Hash detected.")
 		}
 		duplicates[txHash] = true
 	}
 	for _, txHash := range block.ConfigTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, nil, nil, errors.New("Duplicate Config Transaction Hash detected.")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate Config Transaction Hash detected.")
 		}
 		duplicates[txHash] = true
 	}
 	for _, txHash := range block.StakeTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, nil, nil, errors.New("Duplicate Stake Transaction Hash detected.")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate Stake Transaction Hash detected.")
 		}
 		duplicates[txHash] = true
 	}
 
 	for _, txHash := range block.AggTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, nil, nil, errors.New("Duplicate Aggregation Transaction Hash detected.")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate Aggregation Transaction Hash detected.")
 		}
 		duplicates[txHash] = true
 	}
 
 	for _, txHash := range block.IoTTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, nil, nil, errors.New("Duplicate IoT Transaction Hash detected.")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate IoT Transaction Hash detected.")
+		}
+		duplicates[txHash] = true
+	}
+
+	for _, txHash := range block.ContractTxData {
+		if _, exists := duplicates[txHash]; exists {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate Contract Transaction Hash detected.")
+		}
+		duplicates[txHash] = true
+	}
+
+	for _, txHash := range block.IoTBatchTxData {
+		if _, exists := duplicates[txHash]; exists {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate IoT Batch Transaction Hash detected.")
+		}
+		duplicates[txHash] = true
+	}
+
+	for _, txHash := range block.IoTAggTxData {
+		if _, exists := duplicates[txHash]; exists {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate IoT Aggregation Transaction Hash detected.")
+		}
+		duplicates[txHash] = true
+	}
+
+	for _, txHash := range block.DelegateTxData {
+		if _, exists := duplicates[txHash]; exists {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate Delegation Transaction Hash detected.")
+		}
+		duplicates[txHash] = true
+	}
+
+	for _, txHash := range block.EvidenceTxData {
+		if _, exists := duplicates[txHash]; exists {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate Evidence Transaction Hash detected.")
+		}
+		duplicates[txHash] = true
+	}
+
+	for _, txHash := range block.GovernanceTxData {
+		if _, exists := duplicates[txHash]; exists {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate Governance Transaction Hash detected.")
 		}
 		duplicates[txHash] = true
 	}
 
 
 	//We fetch tx data for each type in parallel -> performance boost.
-	nrOfChannels := 6
+	nrOfChannels := 12
 	errChan := make(chan error, nrOfChannels)
 
 	//We need to allocate slice space for the underlying array when we pass them as reference.
@@ -1147,6 +1720,13 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 	stakeTxSlice = make([]*protocol.StakeTx, block.NrStakeTx)
 	aggTxSlice = make([]*protocol.AggTx, block.NrAggTx)
 	iotTxSlice = make([]*protocol.IotTx, block.NrIoTTx)
+	contractTxSlice = make([]*protocol.ContractTx, block.NrContractTx)
+	iotBatchTxSlice = make([]*protocol.IotBatchTx, block.NrIoTBatchTx)
+	delegateTxSlice = make([]*protocol.DelegateTx, block.NrDelegateTx)
+	evidenceTxSlice = make([]*protocol.EvidenceTx, block.NrEvidenceTx)
+	governanceTxSlice = make([]*protocol.GovernanceTx, block.NrGovernanceTx)
+	iotAggTxSlice := make([]*protocol.IotAggTx, block.NrIoTAggTx)
+	aggregatedIotBatchTxSlice := make([][]*protocol.IotBatchTx, block.NrIoTAggTx)
 
 	var aggregatedFundsTxSlice []*protocol.FundsTx
 
@@ -1156,13 +1736,19 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 	go fetchStakeTxData(block, stakeTxSlice, initialSetup, errChan)
 	go fetchAggTxData(block, aggTxSlice, aggregatedFundsTxSlice, initialSetup, errChan)
 	go fetchIotTxData(block, iotTxSlice, initialSetup, errChan)
+	go fetchContractTxData(block, contractTxSlice, initialSetup, errChan)
+	go fetchIotBatchTxData(block, iotBatchTxSlice, initialSetup, errChan)
+	go fetchDelegateTxData(block, delegateTxSlice, initialSetup, errChan)
+	go fetchEvidenceTxData(block, evidenceTxSlice, initialSetup, errChan)
+	go fetchGovernanceTxData(block, governanceTxSlice, initialSetup, errChan)
+	go fetchIotAggTxData(block, iotAggTxSlice, aggregatedIotBatchTxSlice, initialSetup, errChan)
 
 
 	//Wait for all goroutines to finish.
 	for cnt := 0; cnt < nrOfChannels; cnt++ {
 		err = <-errChan
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, err
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
 		}
 	}
 
@@ -1170,15 +1756,38 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 		fundsTxSlice = append(fundsTxSlice, aggregatedFundsTxSlice...)
 	}
 
+	//Aggregated batches are validated like the batches listed in the block, but may only be part of one aggregation.
+	for _, iotBatchTxs := range aggregatedIotBatchTxSlice {
+		for _, iotBatchTx := range iotBatchTxs {
+			if _, exists := duplicates[iotBatchTx.Hash()]; exists {
+				return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Duplicate IoT Batch Transaction Hash detected.")
+			}
+			duplicates[iotBatchTx.Hash()] = true
+		}
+		iotBatchTxSlice = append(iotBatchTxSlice, iotBatchTxs...)
+	}
+
+	//The encoded batches count against the block size and their readings can't be taken after the block.
+	var sizeIoTData uint64
+	for _, iotBatchTx := range iotBatchTxSlice {
+		if err = checkIotBatchTimestamps(iotBatchTx, block.Timestamp); err != nil {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
+		}
+		sizeIoTData += iotBatchTx.Size()
+	}
+	if sizeIoTData != block.SizeIoTData {
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("IoT data size is incorrect.")
+	}
+
 	//Check state contains beneficiary.
 	acc, err := storage.GetAccount(block.Beneficiary)
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, err
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
 	}
 
 	//Check if node is part of the validator set.
 	if !acc.IsStaking {
-		return nil, nil, nil, nil, nil, nil, errors.New("Validator is not part of the validator set.")
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Validator is not part of the validator set.")
 	}
 
 	//First, initialize an RSA Public Key instance with the modulus of the proposer of the block (acc)
@@ -1187,110 +1796,222 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 	//TODO: @ilecipi
 	commitmentPubKey, err := crypto.CreateRSAPubKeyFromBytes(acc.CommitmentKey)
 	if err != nil {
-		return nil, nil, nil, nil,nil, nil, errors.New("Invalid commitment key in account.")
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Invalid commitment key in account.")
 	}
 
 	err = crypto.VerifyMessageWithRSAKey(commitmentPubKey, fmt.Sprint(block.Height), block.CommitmentProof)
 	if err != nil {
-		return nil, nil, nil, nil, nil,nil, errors.New("The submitted commitment proof can not be verified.")
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("The submitted commitment proof can not be verified.")
+	}
+
+	//The signed header is what an evidence against the validator consists of.
+	if err = verifySignedHeader(block.SignedHeader(), acc); err != nil {
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
 	}
 	//Invalid if PoS calculation is not correct.
-	prevProofs := GetLatestProofs(activeParameters.num_included_prev_proofs, block)
+	prevProofs := GetLatestProofs(parameters.num_included_prev_proofs, block)
 
 	//PoS validation
-	if !validateProofOfStake(getDifficulty(), prevProofs, block.Height, acc.Balance, block.CommitmentProof, block.Timestamp) {
-		return nil, nil, nil, nil, nil,nil, errors.New("The nonce is incorrect.")
+	if !validateProofOfStake(getTarget(), prevProofs, block.Height, acc.EffectiveStake(), block.CommitmentProof, block.Timestamp) {
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("The nonce is incorrect.")
 	}
 
-	//Invalid if PoS is too far in the future.
-	now := time.Now()
-	if block.Timestamp > now.Unix()+int64(activeParameters.Accepted_time_diff) {
-		return nil, nil, nil, nil, nil, nil,errors.New("The timestamp is too far in the future. " + string(block.Timestamp) + " vs " + string(now.Unix()))
+	//Invalid if PoS is too far in the future of the network time.
+	systemTime := p2pNode.ReadSystemTime()
+	if block.Timestamp > systemTime+int64(parameters.Accepted_time_diff) {
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("The timestamp is too far in the future. " + strconv.FormatInt(block.Timestamp, 10) + " vs " + strconv.FormatInt(systemTime, 10))
 	}
 
 	//Check for minimum waiting time.
-	if block.Height-acc.StakingBlockHeight < uint32(activeParameters.Waiting_minimum) {
-		return nil, nil, nil, nil, nil,nil, errors.New("The miner must wait a minimum amount of blocks before start validating. Block Height:" + fmt.Sprint(block.Height) + " - Height when started validating " + string(acc.StakingBlockHeight) + " MinWaitingTime: " + string(activeParameters.Waiting_minimum))
+	if block.Height-acc.StakingBlockHeight < uint32(parameters.Waiting_minimum) {
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("The miner must wait a minimum amount of blocks before start validating. Block Height:" + fmt.Sprint(block.Height) + " - Height when started validating " + string(acc.StakingBlockHeight) + " MinWaitingTime: " + string(parameters.Waiting_minimum))
 	}
 
 	//Check if block contains a proof for two conflicting block hashes, else no proof provided.
 	if block.SlashedAddress != [32]byte{} {
 		if _, err = slashingCheck(block.SlashedAddress, block.ConflictingBlockHash1, block.ConflictingBlockHash2, block.ConflictingBlockHashWithoutTx1, block.ConflictingBlockHashWithoutTx2); err != nil {
-			return nil, nil, nil, nil, nil,nil, err
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, err
 		}
 	}
 
 	//Merkle Tree validation
 	if block.Aggregated == false && protocol.BuildMerkleTree(block).MerkleRoot() != block.MerkleRoot {
-		return nil, nil, nil, nil, nil,nil, errors.New("Merkle Root is incorrect.")
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("Merkle Root is incorrect.")
 	}
 
-	return accTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, aggTxSlice, iotTxSlice, err
+	return accTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, aggTxSlice, iotTxSlice, contractTxSlice, iotBatchTxSlice, delegateTxSlice, evidenceTxSlice, governanceTxSlice, err
 }
 
 //Dynamic state check.
 func validateState(data blockData) error {
+	//Every consensus related account change of the block is recorded, such that it can be rolled back exactly.
+	undoLog = new(protocol.UndoLog)
+	//The same holds for the coins created and destroyed by the block.
+	supplyChange = new(protocol.Supply)
+
 	//The sequence of validation matters. If we start with accs, then fund/stake transactions can be done in the same block
 	//even though the accounts did not exist before the block validation.
 	if err := accStateChange(data.accTxSlice); err != nil {
 		return err
 	}
 
+	//Contract lifecycle changes come before fundsTxs, the same order in which they are added to a block.
+	if err := contractStateChange(data.contractTxSlice, data.block.Height); err != nil {
+		accStateChangeRollback(data.accTxSlice)
+		return err
+	}
+
 	if err := fundsStateChange(data.fundsTxSlice); err != nil {
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
 		accStateChangeRollback(data.accTxSlice)
 		return err
 	}
 
 	if err := aggTxStateChange(data.aggTxSlice); err != nil {
 		fundsStateChangeRollback(data.fundsTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
 		accStateChangeRollback(data.accTxSlice)
 		return err
 	}
 
 	if err := stakeStateChange(data.stakeTxSlice, data.block.Height); err != nil {
 		fundsStateChangeRollback(data.fundsTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
 		accStateChangeRollback(data.accTxSlice)
 		aggregatedSenderStateRollback(data.aggTxSlice)
 		return err
 	}
 	if err := iotStateChange(data.iotTxSlice); err != nil {
+		stakeStateChangeRollback(data.stakeTxSlice)
+		fundsStateChangeRollback(data.fundsTxSlice)
+		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
+		accStateChangeRollback(data.accTxSlice)
+		return err
+	}
+
+	if err := iotBatchStateChange(data.iotBatchTxSlice); err != nil {
+		iotStateChangeRollback(data.iotTxSlice)
+		stakeStateChangeRollback(data.stakeTxSlice)
+		fundsStateChangeRollback(data.fundsTxSlice)
+		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
+		accStateChangeRollback(data.accTxSlice)
+		return err
+	}
+
+	if err := delegateStateChange(data.delegateTxSlice); err != nil {
+		iotBatchStateChangeRollback(data.iotBatchTxSlice)
+		iotStateChangeRollback(data.iotTxSlice)
+		stakeStateChangeRollback(data.stakeTxSlice)
+		fundsStateChangeRollback(data.fundsTxSlice)
+		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
+		accStateChangeRollback(data.accTxSlice)
+		return err
+	}
+
+	if err := evidenceStateChange(data.evidenceTxSlice); err != nil {
+		delegateStateChangeRollback(data.delegateTxSlice)
+		iotBatchStateChangeRollback(data.iotBatchTxSlice)
+		iotStateChangeRollback(data.iotTxSlice)
+		stakeStateChangeRollback(data.stakeTxSlice)
+		fundsStateChangeRollback(data.fundsTxSlice)
+		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
+		accStateChangeRollback(data.accTxSlice)
+		return err
+	}
+
+	if err := governanceStateChange(data.governanceTxSlice, data.block.Height); err != nil {
+		evidenceStateChangeRollback(data.evidenceTxSlice)
+		delegateStateChangeRollback(data.delegateTxSlice)
+		iotBatchStateChangeRollback(data.iotBatchTxSlice)
+		iotStateChangeRollback(data.iotTxSlice)
+		stakeStateChangeRollback(data.stakeTxSlice)
+		fundsStateChangeRollback(data.fundsTxSlice)
+		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
+		accStateChangeRollback(data.accTxSlice)
 		return err
 	}
 
-	if err := collectTxFees(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.aggTxSlice, data.iotTxSlice, data.block.Beneficiary); err != nil {
+	if err := collectTxFees(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.aggTxSlice, data.iotTxSlice, data.contractTxSlice, data.iotBatchTxSlice, data.delegateTxSlice, data.evidenceTxSlice, data.governanceTxSlice, data.block.Beneficiary); err != nil {
+		governanceStateChangeRollback(data.governanceTxSlice)
+		evidenceStateChangeRollback(data.evidenceTxSlice)
+		delegateStateChangeRollback(data.delegateTxSlice)
+		iotBatchStateChangeRollback(data.iotBatchTxSlice)
+		iotStateChangeRollback(data.iotTxSlice)
 		stakeStateChangeRollback(data.stakeTxSlice)
 		fundsStateChangeRollback(data.fundsTxSlice)
 		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
 		accStateChangeRollback(data.accTxSlice)
 		return err
 	}
 
-	if err := collectBlockReward(activeParameters.Block_reward, data.block.Beneficiary); err != nil {
-		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
+	if err := collectBlockReward(blockReward(data.block.Height), data.block.Beneficiary); err != nil {
+		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.iotTxSlice, data.contractTxSlice, data.iotBatchTxSlice, data.delegateTxSlice, data.evidenceTxSlice, data.governanceTxSlice, data.block.Beneficiary)
+		governanceStateChangeRollback(data.governanceTxSlice)
+		evidenceStateChangeRollback(data.evidenceTxSlice)
+		delegateStateChangeRollback(data.delegateTxSlice)
+		iotBatchStateChangeRollback(data.iotBatchTxSlice)
+		iotStateChangeRollback(data.iotTxSlice)
 		stakeStateChangeRollback(data.stakeTxSlice)
 		fundsStateChangeRollback(data.fundsTxSlice)
 		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
 		accStateChangeRollback(data.accTxSlice)
 		return err
 	}
 
 	if err := collectSlashReward(activeParameters.Slash_reward, data.block); err != nil {
-		collectBlockRewardRollback(activeParameters.Block_reward, data.block.Beneficiary)
-		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
+		collectBlockRewardRollback(supplyChange.Minted, data.block.Beneficiary)
+		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.iotTxSlice, data.contractTxSlice, data.iotBatchTxSlice, data.delegateTxSlice, data.evidenceTxSlice, data.governanceTxSlice, data.block.Beneficiary)
+		governanceStateChangeRollback(data.governanceTxSlice)
+		evidenceStateChangeRollback(data.evidenceTxSlice)
+		delegateStateChangeRollback(data.delegateTxSlice)
+		iotBatchStateChangeRollback(data.iotBatchTxSlice)
+		iotStateChangeRollback(data.iotTxSlice)
 		stakeStateChangeRollback(data.stakeTxSlice)
 		fundsStateChangeRollback(data.fundsTxSlice)
 		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
+		accStateChangeRollback(data.accTxSlice)
+		return err
+	}
+
+	if err := distributeDelegationRewards(data); err != nil {
+		collectSlashRewardRollback(activeParameters.Slash_reward, data.block)
+		collectBlockRewardRollback(supplyChange.Minted, data.block.Beneficiary)
+		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.iotTxSlice, data.contractTxSlice, data.iotBatchTxSlice, data.delegateTxSlice, data.evidenceTxSlice, data.governanceTxSlice, data.block.Beneficiary)
+		governanceStateChangeRollback(data.governanceTxSlice)
+		evidenceStateChangeRollback(data.evidenceTxSlice)
+		delegateStateChangeRollback(data.delegateTxSlice)
+		iotBatchStateChangeRollback(data.iotBatchTxSlice)
+		iotStateChangeRollback(data.iotTxSlice)
+		stakeStateChangeRollback(data.stakeTxSlice)
+		fundsStateChangeRollback(data.fundsTxSlice)
+		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
 		accStateChangeRollback(data.accTxSlice)
 		return err
 	}
 
 	if err := updateStakingHeight(data.block); err != nil {
+		distributeDelegationRewardsRollback(data.block)
 		collectSlashRewardRollback(activeParameters.Slash_reward, data.block)
-		collectBlockRewardRollback(activeParameters.Block_reward, data.block.Beneficiary)
-		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
+		collectBlockRewardRollback(supplyChange.Minted, data.block.Beneficiary)
+		collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.iotTxSlice, data.contractTxSlice, data.iotBatchTxSlice, data.delegateTxSlice, data.evidenceTxSlice, data.governanceTxSlice, data.block.Beneficiary)
+		governanceStateChangeRollback(data.governanceTxSlice)
+		evidenceStateChangeRollback(data.evidenceTxSlice)
+		delegateStateChangeRollback(data.delegateTxSlice)
+		iotBatchStateChangeRollback(data.iotBatchTxSlice)
+		iotStateChangeRollback(data.iotTxSlice)
 		stakeStateChangeRollback(data.stakeTxSlice)
 		fundsStateChangeRollback(data.fundsTxSlice)
 		aggregatedSenderStateRollback(data.aggTxSlice)
+		contractStateChangeRollback(data.contractTxSlice, data.block.Height)
 		accStateChangeRollback(data.accTxSlice)
 		return err
 	}
@@ -1299,10 +2020,18 @@ func validateState(data blockData) error {
 }
 
 func postValidate(data blockData, initialSetup bool) {
+	//The base fee of the next block depends on the size of this block relative to the block size in force for it.
+	commitBaseFee(data.block, activeParameters.Block_size)
+
 	//The new system parameters get active if the block was successfully validated
 	//This is done after state validation (in contrast to accTx/fundsTx).
 	//Conversely, if blocks are rolled back, the system parameters are changed first.
-	configStateChange(data.configTxSlice, data.block.Hash)
+	configStateChange(data.configTxSlice, data.block.Hash, data.block.Height)
+	storage.WriteUndoLog(data.block.Hash, undoLog)
+	undoLog = new(protocol.UndoLog)
+	commitSupplyChange(data.block)
+	commitValidatorSet(data)
+	pruneContractSnapshots(data.block.Height)
 	//Collects meta information about the block (and handled difficulty adaption).
 	collectStatistics(data.block)
 
@@ -1343,6 +2072,41 @@ func postValidate(data blockData, initialSetup bool) {
 			storage.DeleteOpenTx(tx)
 		}
 		for _, tx := range data.iotTxSlice {
+			storage.WriteClosedTx(tx)
+			storage.WriteIotIndex(tx, data.block)
+			storage.DeleteOpenTx(tx)
+		}
+
+		for _, tx := range data.contractTxSlice {
+			storage.WriteClosedTx(tx)
+			storage.DeleteOpenTx(tx)
+		}
+
+		for _, tx := range data.iotBatchTxSlice {
+			storage.WriteClosedTx(tx)
+			storage.WriteIotBatchIndex(tx, data.block)
+			storage.DeleteOpenTx(tx)
+		}
+
+		//The aggregated batches are part of the iotBatchTxSlice, only the IotAggTxs are left.
+		for _, txHash := range data.block.IoTAggTxData {
+			if tx := storage.ReadOpenTx(txHash); tx != nil {
+				storage.WriteClosedTx(tx)
+				storage.DeleteOpenTx(tx)
+			}
+		}
+
+		for _, tx := range data.delegateTxSlice {
+			storage.WriteClosedTx(tx)
+			storage.DeleteOpenTx(tx)
+		}
+
+		for _, tx := range data.evidenceTxSlice {
+			storage.WriteClosedTx(tx)
+			storage.DeleteOpenTx(tx)
+		}
+
+		for _, tx := range data.governanceTxSlice {
 			storage.WriteClosedTx(tx)
 			storage.DeleteOpenTx(tx)
 		}
@@ -1375,7 +2139,7 @@ func postValidate(data blockData, initialSetup bool) {
 
 //Only blocks with timestamp not diverging from system time (past or future) more than one hour are accepted.
 func timestampCheck(timestamp int64) error {
-	systemTime := p2p.ReadSystemTime()
+	systemTime := p2pNode.ReadSystemTime()
 
 	if timestamp > systemTime {
 		if timestamp-systemTime > int64(time.Hour.Seconds()) {
@@ -1423,11 +2187,11 @@ func slashingCheck(slashedAddress, conflictingBlockHash1, conflictingBlockHash2,
 		conflictingBlock1 = storage.ReadOpenBlock(conflictingBlockHash1)
 		if conflictingBlock1 == nil {
 			//Fetch the block we apparently missed from the network.
-			p2p.BlockReq(conflictingBlockHash1, conflictingBlockHashWithoutTx1)
+			p2pNode.BlockReq(conflictingBlockHash1, conflictingBlockHashWithoutTx1)
 
 			//Blocking wait
 			select {
-			case encodedBlock := <-p2p.BlockReqChan:
+			case encodedBlock := <-p2pNode.BlockReqChan:
 				conflictingBlock1 = conflictingBlock1.Decode(encodedBlock)
 				//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
 			case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
@@ -1447,11 +2211,11 @@ func slashingCheck(slashedAddress, conflictingBlockHash1, conflictingBlockHash2,
 		conflictingBlock2 = storage.ReadOpenBlock(conflictingBlockHash2)
 		if conflictingBlock2 == nil {
 			//Fetch the block we apparently missed from the network.
-			p2p.BlockReq(conflictingBlockHash2, conflictingBlockHashWithoutTx2)
+			p2pNode.BlockReq(conflictingBlockHash2, conflictingBlockHashWithoutTx2)
 
 			//Blocking wait
 			select {
-			case encodedBlock := <-p2p.BlockReqChan:
+			case encodedBlock := <-p2pNode.BlockReqChan:
 				conflictingBlock2 = conflictingBlock2.Decode(encodedBlock)
 				//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
 			case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
@@ -18,7 +18,7 @@ import (
 func TestBlock(t *testing.T) {
 	cleanAndPrepare()
 
-	b := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	hashFundsSlice, hashAccSlice, hashConfigSlice, hashStakeSlice := createBlockWithTxs(b)
 	err := finalizeBlock(b)
 	if err != nil {
@@ -61,7 +61,7 @@ func TestBlock(t *testing.T) {
 func TestBlockTxDuplicates(t *testing.T) {
 
 	cleanAndPrepare()
-	b := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	createBlockWithTxs(b)
 
 	if err := finalizeBlock(b); err != nil {
@@ -100,28 +100,28 @@ func TestBlockTxDuplicates(t *testing.T) {
 func TestMultipleBlocks(t *testing.T) {
 	cleanAndPrepare()
 
-	b := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	createBlockWithTxs(b)
 	finalizeBlock(b)
 	if err := validate(b, false); err != nil {
 		t.Errorf("Block validation for (%v) failed: %v\n", b, err)
 	}
 
-	b2 := newBlock(b.Hash, [crypto.COMM_KEY_LENGTH]byte{}, 2)
+	b2 := newBlock(b.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 2)
 	createBlockWithTxs(b2)
 	finalizeBlock(b2)
 	if err := validate(b2, false); err != nil {
 		t.Errorf("Block validation failed: %v\n", err)
 	}
 
-	b3 := newBlock(b2.Hash, [crypto.COMM_KEY_LENGTH]byte{}, 3)
+	b3 := newBlock(b2.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 3)
 	createBlockWithTxs(b3)
 	finalizeBlock(b3)
 	if err := validate(b3, false); err != nil {
 		t.Errorf("Block validation failed: %v\n", err)
 	}
 
-	b4 := newBlock(b3.Hash, [crypto.COMM_KEY_LENGTH]byte{}, 4)
+	b4 := newBlock(b3.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 4)
 	createBlockWithTxs(b4)
 	finalizeBlock(b4)
 	if err := validate(b4, false); err != nil {
@@ -167,7 +167,7 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 	for cnt := int(accA.TxCnt); cnt < loopMax; cnt++ {
 		accAHash := protocol.SerializeHashContent(accA.Address)
 		accBHash := protocol.SerializeHashContent(accB.Address)
-		tx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%100+1, randVar.Uint64()%100+1, uint32(cnt), accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+		tx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%100+1, randVar.Uint64()%100+1, uint32(cnt), accAHash, accBHash, PrivKeyAccA, nil)
 		if err := addTx(b, tx); err == nil {
 			//Might  be that we generated a block that was already generated before
 			if storage.ReadOpenTx(tx.Hash()) != nil || storage.ReadClosedTx(tx.Hash()) != nil {
@@ -180,7 +180,7 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 		}
 	}
 
-	nullAddress := [64]byte{}
+	nullAddress := [32]byte{}
 	loopMax = int(randVar.Uint32()%testSize) + 1
 	for cnt := 0; cnt < loopMax; cnt++ {
 		tx, _, _ := protocol.ConstrAccTx(0, randVar.Uint64()%100+1, nullAddress, PrivKeyRoot, nil, nil)
@@ -198,7 +198,7 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 	//NrConfigTx is saved in a uint8, so testsize shouldn't be larger than 255
 	loopMax = int(randVar.Uint32()%testSize) + 1
 	for cnt := 0; cnt < loopMax; cnt++ {
-		tx, err := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), uint8(randVar.Uint32()%10+1), randVar.Uint64()%2342873423, randVar.Uint64()%1000+1, uint8(cnt), PrivKeyRoot)
+		tx, err := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), uint8(randVar.Uint32()%10+1), randVar.Uint64()%2342873423, randVar.Uint64()%1000+1, uint8(cnt), 0, PrivKeyRoot)
 		if err != nil {
 			fmt.Print(err)
 		}
@@ -3,6 +3,7 @@ package miner
 import (
 	"crypto/rsa"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 	"golang.org/x/crypto/ed25519"
@@ -30,10 +31,11 @@ var (
 )
 
 //Miner entry point
-func Init(validatorWallet, multisigWallet ed25519.PublicKey , rootWallet ed25519.PrivateKey, validatorCommitment, rootCommitment *rsa.PrivateKey) {
+func Init(node *p2p.Node, validatorWallet, multisigWallet ed25519.PublicKey , rootWallet ed25519.PrivateKey, validatorCommitment, rootCommitment *rsa.PrivateKey) {
 	var err error
 
 
+	p2pNode = node
 	validatorAccAddress = crypto.GetAddressFromPubKeyED(validatorWallet)
 	multisigPubKey = multisigWallet
 	commPrivKey = validatorCommitment
@@ -45,6 +47,7 @@ func Init(validatorWallet, multisigWallet ed25519.PublicKey , rootWallet ed25519
 
 	parameterSlice = append(parameterSlice, NewDefaultParameters())
 	activeParameters = &parameterSlice[0]
+	p2pNode.SetAcceptedTimeDiff(int64(activeParameters.Accepted_time_diff))
 
 	//Initialize root key.
 	initRootKey(ed25519.PublicKey(rootWallet[32:]))
@@ -53,7 +56,7 @@ func Init(validatorWallet, multisigWallet ed25519.PublicKey , rootWallet ed25519
 	}
 
 	currentTargetTime = new(timerange)
-	target = append(target, 15)
+	target = append(target, targetFromBits(15))
 
 	initialBlock, err := initState()
 	if err != nil {
@@ -123,7 +126,11 @@ func initRootKey(rootKey ed25519.PublicKey) error {
 	copy(commPubKey[:], rootCommPrivKey.N.Bytes())
 
 	rootAcc := protocol.NewAccount(address, [32]byte{}, activeParameters.Staking_minimum, true, commPubKey, nil, nil)
+	//The root account is a validator from the start, its stake is locked like the stake of every other validator
+	rootAcc.StakedAmount = activeParameters.Staking_minimum
 	storage.State[addressHash] = &rootAcc
+	//The genesis balance and stake of the root account are the initial supply
+	supply.Issued += rootAcc.Balance + rootAcc.StakedAmount
 	storage.RootKeys[addressHash] = &rootAcc
 
 	return nil
@@ -5,6 +5,7 @@ import (
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 	"math"
+	"math/big"
 	"time"
 )
 
@@ -12,7 +13,7 @@ var (
 	lastBlock         *protocol.Block
 	globalBlockCount  = int64(-1)
 	localBlockCount   = int64(-1)
-	target            []uint8    //Stores the history of target values
+	target            []*big.Int //Stores the history of target values
 	currentTargetTime *timerange //Corresponds to the active timerange
 )
 
@@ -32,6 +33,8 @@ type Parameters struct {
 	Accepted_time_diff      	uint64 //Number of seconds that a block can be received in the future.
 	Slashing_window_size    	uint64 //Number of blocks that a validator cannot vote on two competing chains.
 	Slash_reward            	uint64 //Reward for providing the correct slashing proof.
+	Unbonding_period        	uint64 //Number of blocks the stake stays locked after leaving the validator set.
+	Target_adjustment_height	uint64 //Height from which difficulty adjustments change the target proportionally.
 	num_included_prev_proofs	int
 }
 
@@ -48,6 +51,8 @@ func NewDefaultParameters() Parameters {
 		ACCEPTED_TIME_DIFF,
 		SLASHING_WINDOW_SIZE,
 		SLASH_REWARD,
+		UNBONDING_PERIOD,
+		TARGET_ADJUSTMENT_HEIGHT,
 		NUM_INCL_PREV_PROOFS,
 	}
 
@@ -78,7 +83,7 @@ func collectStatistics(b *protocol.Block) {
 		if currentTargetTime.first == 0 {
 			target = append(target, target[len(target)-1])
 		} else {
-			target = append(target, calculateNewDifficulty(currentTargetTime))
+			target = append(target, calculateNewDifficulty(currentTargetTime, b.Height))
 			logger.Printf("TARGET_CHECK: Target changed, new target: %v", target)
 		}
 
@@ -110,22 +115,56 @@ func collectStatisticsRollback(b *protocol.Block) {
 	lastBlock = storage.ReadClosedBlock(b.PrevHash)
 }
 
-func calculateNewDifficulty(t *timerange) uint8 {
+//Target of the next difficulty interval. Adjustments at blocks from Target_adjustment_height of the chain on are
+//proportional to how much the interval took longer or shorter than it should have, earlier ones changed the target in
+//whole bits.
+func calculateNewDifficulty(t *timerange, height uint32) *big.Int {
 	//Time difference between the first and last block in the measured range.
 	diff_now := t.last - t.first
 
 	//This is how long it should have taken.
 	diff_wanted := activeParameters.Block_interval * (activeParameters.Diff_interval)
 
-	diff_ratio := float64(diff_wanted) / float64(diff_now)
-
-	//If the last is earlier time than first, we get a negative number, can't take the log from that.
+	//If the last is earlier time than first, we give the current target back.
 	//This precipitates that reasonable parameter should be chosen for block-/diff interval
-	//such that this case does not happen. In case it still does, we give the current difficulty back.
-	if diff_ratio < 0 {
-		return getDifficulty()
+	//such that this case does not happen.
+	if diff_now < 0 {
+		return getTarget()
+	}
+
+	if uint64(height) < parametersAt(height).Target_adjustment_height {
+		return calculateNewDifficultyBits(float64(diff_wanted) / float64(diff_now))
+	}
+	if diff_wanted == 0 {
+		return getTarget()
 	}
 
+	//Sanity check! Make it at most MAX_TARGET_ADJUSTMENT times as hard or easy, Bitcoin has a similar check.
+	diff_min := int64(diff_wanted / MAX_TARGET_ADJUSTMENT)
+	diff_max := int64(diff_wanted * MAX_TARGET_ADJUSTMENT)
+	if diff_now < diff_min {
+		diff_now = diff_min
+	} else if diff_now > diff_max {
+		diff_now = diff_max
+	}
+
+	//The target is the threshold, a higher target makes it easier to find a valid PoS.
+	newTarget := new(big.Int).Mul(getTarget(), big.NewInt(diff_now))
+	newTarget.Div(newTarget, new(big.Int).SetUint64(diff_wanted))
+
+	if newTarget.Sign() == 0 {
+		return big.NewInt(1)
+	}
+	if maxTarget := targetFromBits(0); newTarget.Cmp(maxTarget) > 0 {
+		return maxTarget
+	}
+
+	return newTarget
+}
+
+//The adjustment of blocks before Target_adjustment_height, the difficulty is the number of leading zero bits the
+//stake-scaled hash needs to have. diff_ratio is how much faster than wanted the interval was.
+func calculateNewDifficultyBits(diff_ratio float64) *big.Int {
 	//Take the log2 from the diff_ratio, because adding a zero makes it twice as hard, adding two zeros four times as
 	//hard etc.
 	target_change := math.Log2(diff_ratio)
@@ -144,14 +183,22 @@ func calculateNewDifficulty(t *timerange) uint8 {
 		target_change = -3
 	}
 
-	//Rounding down (for positive values) and runding up (for negative values).
-	target_change_rounded := uint8(target_change)
+	//Rounding down (for positive values) and runding up (for negative values). Every additional zero bit halves the
+	//target.
+	target_change_rounded := int(target_change)
+	if target_change_rounded > 0 {
+		return new(big.Int).Rsh(getTarget(), uint(target_change_rounded))
+	}
+
+	return new(big.Int).Lsh(getTarget(), uint(-target_change_rounded))
+}
 
-	//Return the new target based on the calculation and the current target.
-	return target_change_rounded + target[len(target)-1]
+//A stake-scaled hash with diff leading zero bits is below this target.
+func targetFromBits(diff uint8) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), 256-uint(diff))
 }
 
-func getDifficulty() uint8 {
+func getTarget() *big.Int {
 	return target[len(target)-1]
 }
 
@@ -169,6 +216,8 @@ func (param Parameters) String() string {
 			"Acceptanced time difference: %v\n"+
 			"Slashing window size: %v\n"+
 			"Slash reward: %v\n"+
+			"Unbonding period: %v\n"+
+			"Target adjustment height: %v\n"+
 			"Num of previous proofs included in PoS: %v\n",
 		param.BlockHash[0:8],
 		param.Block_size,
@@ -181,6 +230,8 @@ func (param Parameters) String() string {
 		param.Accepted_time_diff,
 		param.Slashing_window_size,
 		param.Slash_reward,
+		param.Unbonding_period,
+		param.Target_adjustment_height,
 		param.num_included_prev_proofs,
 	)
 }
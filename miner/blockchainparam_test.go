@@ -3,6 +3,8 @@ package miner
 import (
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"math"
+	"math/big"
 	"testing"
 )
 
@@ -19,7 +21,7 @@ func TestTargetHistory(t *testing.T) {
 	var tmpBlock *protocol.Block
 	tmpBlock = new(protocol.Block)
 	for cnt := 0; cnt < 10; cnt++ {
-		tmpBlock = newBlock(tmpBlock.Hash, [crypto.COMM_KEY_LENGTH]byte{}, tmpBlock.Height+1)
+		tmpBlock = newBlock(tmpBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, tmpBlock.Height+1)
 		finalizeBlock(tmpBlock)
 		validate(tmpBlock, false)
 		blocks = append(blocks, tmpBlock)
@@ -53,7 +55,7 @@ func TestTargetHistory(t *testing.T) {
 	targetSize = len(target)
 	targetTimesSize = len(targetTimes)
 
-	tmpBlock = newBlock(blocks[len(blocks)-1].Hash, [crypto.COMM_KEY_LENGTH]byte{}, blocks[len(blocks)-1].Height+1)
+	tmpBlock = newBlock(blocks[len(blocks)-1].Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, blocks[len(blocks)-1].Height+1)
 	finalizeBlock(tmpBlock)
 	validate(tmpBlock, false)
 
@@ -72,11 +74,11 @@ func TestTimestamps(t *testing.T) {
 
 	prevHash := [32]byte{}
 	for cnt := 0; cnt < 0; cnt++ {
-		b := newBlock(prevHash, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+		b := newBlock(prevHash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 
 		if cnt == 8 {
-			tx, err := protocol.ConstrConfigTx(0, protocol.DIFF_INTERVAL_ID, 20, 2, 0, PrivKeyRoot)
-			tx2, err2 := protocol.ConstrConfigTx(0, protocol.BLOCK_INTERVAL_ID, 60, 2, 0, PrivKeyRoot)
+			tx, err := protocol.ConstrConfigTx(0, protocol.DIFF_INTERVAL_ID, 20, 2, 0, 0, PrivKeyRoot)
+			tx2, err2 := protocol.ConstrConfigTx(0, protocol.BLOCK_INTERVAL_ID, 60, 2, 0, 0, PrivKeyRoot)
 			if err != nil || err2 != nil {
 				t.Errorf("Creating config txs failed: %v, %v\n", err, err2)
 			}
@@ -108,42 +110,144 @@ func TestCalculateNewDifficulty(t *testing.T) {
 	cleanAndPrepare()
 
 	//set new system parameters
-	target[len(target)-1] = 10
+	target[len(target)-1] = targetFromBits(10)
 	activeParameters.Block_interval = 10
 	activeParameters.Diff_interval = 10
-	time := timerange{0, 100}
 
-	if calculateNewDifficulty(&time) != 10 {
-		t.Errorf("Difficulty should: %v, difficulty is: %v\n", 10, calculateNewDifficulty(&time))
+	for _, test := range []struct {
+		time        timerange
+		numerator   int64
+		denominator int64
+	}{
+		//should: 100, is: 100, target stays
+		{timerange{0, 100}, 1, 1},
+		//test for illegal values
+		{timerange{100, 99}, 1, 1},
+		//should: 100, is: 900, at most 4 times as easy
+		{timerange{100, 1000}, 4, 1},
+		//should: 100, is: 200, twice as easy
+		{timerange{100, 300}, 2, 1},
+		//should: 100, is: 80, a bit harder
+		{timerange{100, 180}, 4, 5},
+		//should: 100, is: 50, twice as hard
+		{timerange{100, 150}, 1, 2},
+		//should: 100, is: 1, at most 4 times as hard
+		{timerange{1000, 1001}, 1, 4},
+	} {
+		expected := new(big.Int).Mul(getTarget(), big.NewInt(test.numerator))
+		expected.Div(expected, big.NewInt(test.denominator))
+		if newTarget := calculateNewDifficulty(&test.time, 1); newTarget.Cmp(expected) != 0 {
+			t.Errorf("Target for %v should: %x, target is: %x\n", test.time, expected, newTarget)
+		}
 	}
 
-	//test for illegal values
-	time = timerange{100, 99}
-	if calculateNewDifficulty(&time) != 10 {
-		t.Errorf("Difficult should: %v, difficulty is: %v\n", 10, calculateNewDifficulty(&time))
+	//Before the proportional adjustment the target changed in whole bits, at most 3 of them
+	for _, test := range []struct {
+		ratio float64
+		bits  uint8
+	}{
+		{100.0 / 900.0, 7},
+		{100.0 / 500.0, 8},
+		{100.0 / 1.0, 13},
+		{100.0 / 50.0, 11},
+		{100.0 / 80.0, 10},
+	} {
+		if newTarget := calculateNewDifficultyBits(test.ratio); newTarget.Cmp(targetFromBits(test.bits)) != 0 {
+			t.Errorf("Difficulty for ratio %v should: %v bits, target is: %x\n", test.ratio, test.bits, newTarget)
+		}
 	}
+}
 
-	//should: 100, is: 900, target should be -3
-	time = timerange{100, 1000}
-	if calculateNewDifficulty(&time) != getDifficulty()-3 {
-		t.Errorf("Difficulty should: %v, difficulty is: %v\n", 7, calculateNewDifficulty(&time))
+//Tests whether the block interval converges to the wanted one, which whole bits can only approximate up to a factor 2
+func TestTargetConvergence(t *testing.T) {
+	cleanAndPrepare()
+
+	activeParameters.Block_interval = 10
+	activeParameters.Diff_interval = 10
+	target[len(target)-1] = targetFromBits(15)
+
+	//A validator tries one timestamp per second, the stake-scaled hash is below the target with probability
+	//target*stake/2^256. The blocks are found after the expected interval.
+	stake := big.NewInt(1000)
+	interval := func() float64 {
+		chance := new(big.Float).SetInt(new(big.Int).Mul(getTarget(), stake))
+		expected, _ := new(big.Float).Quo(new(big.Float).SetInt(targetFromBits(0)), chance).Float64()
+		return expected
 	}
 
-	//should: 100, is: 500, log2(0.2) = -2.3 -> target -= 2
-	time = timerange{100, 600}
-	if calculateNewDifficulty(&time) != getDifficulty()-2 {
-		t.Errorf("Difficulty should: %v, difficulty is: %v\n", 8, calculateNewDifficulty(&time))
+	timestamp := int64(1000)
+	for height := uint32(1); height <= 300; height++ {
+		timestamp += int64(math.Round(interval()))
+		collectStatistics(&protocol.Block{Height: height, Timestamp: timestamp})
 	}
 
-	//should: 100, is: 1, log2(100) > 3 -> target_change = 3
-	time = timerange{1000, 1001}
-	if calculateNewDifficulty(&time) != getDifficulty()+3 {
-		t.Errorf("Difficulty should: %v, difficulty is: %v\n", 13, calculateNewDifficulty(&time))
+	if expected := interval(); math.Abs(expected-float64(activeParameters.Block_interval)) > 1 {
+		t.Errorf("Block interval did not converge: %v vs. %v\n", expected, activeParameters.Block_interval)
 	}
+}
+
+//A chain which adjusted the target in whole bits before its Target_adjustment_height is replayed with the same targets,
+//the proportional rule would have computed other ones for the blocks of the whole-bit era
+func TestTargetRuleReplay(t *testing.T) {
+	const switchHeight = 150
+
+	//Blocks are found after the expected interval of a validator trying one timestamp per second
+	replay := func(adjustmentHeight uint64, timestamps []int64) (targets []*big.Int, replayed []int64) {
+		cleanAndPrepare()
+		activeParameters.Block_interval = 10
+		activeParameters.Diff_interval = 10
+		//The target at index i is computed at the end of the i-th difficulty interval
+		target = []*big.Int{targetFromBits(15)}
+		changeParameter(activeParameters, protocol.TARGET_ADJUSTMENT_HEIGHT_ID, adjustmentHeight)
+
+		stake := big.NewInt(1000)
+		timestamp := int64(1000)
+		for height := uint32(1); height <= 300; height++ {
+			if timestamps != nil {
+				timestamp = timestamps[height-1]
+			} else {
+				chance := new(big.Float).SetInt(new(big.Int).Mul(getTarget(), stake))
+				expected, _ := new(big.Float).Quo(new(big.Float).SetInt(targetFromBits(0)), chance).Float64()
+				timestamp += int64(math.Round(expected))
+			}
+			replayed = append(replayed, timestamp)
+			collectStatistics(&protocol.Block{Height: height, Timestamp: timestamp})
+		}
 
-	//should: 100, is: 50, log2(2) = 1
-	time = timerange{100, 150}
-	if calculateNewDifficulty(&time) != getDifficulty()+1 {
-		t.Errorf("Difficulty should: %v, difficulty is: %v\n", 11, calculateNewDifficulty(&time))
+		return append(targets, target...), replayed
+	}
+
+	targets, timestamps := replay(switchHeight, nil)
+
+	isWholeBit := func(t *big.Int) bool { return new(big.Int).And(t, new(big.Int).Sub(t, big.NewInt(1))).Sign() == 0 }
+	proportional := false
+	for i, tmpTarget := range targets {
+		if uint64(i)*activeParameters.Diff_interval < switchHeight && !isWholeBit(tmpTarget) {
+			t.Errorf("Target %v of the whole-bit era is %x\n", i, tmpTarget)
+		}
+		if uint64(i)*activeParameters.Diff_interval >= switchHeight && !isWholeBit(tmpTarget) {
+			proportional = true
+		}
+	}
+	if !proportional {
+		t.Error("Targets after the switch height were not adjusted proportionally.\n")
+	}
+
+	//A node syncing the chain computes the same targets
+	replayedTargets, _ := replay(switchHeight, timestamps)
+	for i := range targets {
+		if targets[i].Cmp(replayedTargets[i]) != 0 {
+			t.Errorf("Replayed target %v differs: %x vs. %x\n", i, replayedTargets[i], targets[i])
+		}
+	}
+
+	//Without the switch height of the chain, the blocks of the whole-bit era would be checked against other targets
+	proportionalTargets, _ := replay(0, timestamps)
+	differs := false
+	for i := range targets {
+		differs = differs || targets[i].Cmp(proportionalTargets[i]) != 0
+	}
+	if !differs {
+		t.Error("Whole-bit and proportional adjustments computed the same targets.\n")
 	}
 }
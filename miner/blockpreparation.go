@@ -4,6 +4,7 @@ import (
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 	"sort"
+	"time"
 )
 
 //The code here is needed if a new block is built. All open (not yet validated) transactions are first fetched
@@ -23,6 +24,9 @@ func prepareBlock(block *protocol.Block) {
 	tmpCopy = opentxs
 	sort.Sort(tmpCopy)
 
+	//The block is filled up to the block size in force at its height.
+	parameters := parametersAt(block.Height)
+
 	//Counter for all transactions which will not be aggregated. (Stake-, config-, acctx)
 	nonAggregatableTxCounter := 0
 	blockSize := block.GetSize()+block.GetBloomFilterSize()
@@ -33,9 +37,19 @@ func prepareBlock(block *protocol.Block) {
 	for i, tx := range opentxs {
 		//Switch because with an if statement every transaction would need a getter-method for its type.
 		//Therefore, switch is more code-efficient.
-		if int(block.GetSize()+10)+(i*int(len(tx.Hash()))) > int(activeParameters.Block_size){
+		if int(block.GetSize()+10)+(i*int(len(tx.Hash()))) > int(parameters.Block_size){
 			break
 		}
+		//Txs paying less than the base fee stay in the mempool until the base fee is low enough.
+		if tx.TxFee() < baseFee {
+			continue
+		}
+		//The readings of IoT batches count against the block size, batches with readings from the future wait.
+		if iotBatchTx, ok := tx.(*protocol.IotBatchTx); ok {
+			if block.GetSize()+iotBatchTx.Size() > parameters.Block_size || checkIotBatchTimestamps(iotBatchTx, time.Now().Unix()) != nil {
+				continue
+			}
+		}
 		switch tx.(type) {
 		case *protocol.FundsTx, *protocol.AggTx:
 			storage.DifferentSenders[tx.Sender()] = storage.DifferentSenders[tx.Sender()]+1
@@ -47,7 +61,7 @@ func prepareBlock(block *protocol.Block) {
 		//Check if block will become to big when adding the next transaction.
 		if int(blockSize)+
 			(len(storage.DifferentSenders)*int(len(tx.Hash()))) +
-			(int(nonAggregatableTxCounter)*int(len(tx.Hash()))) > int(activeParameters.Block_size){
+			(int(nonAggregatableTxCounter)*int(len(tx.Hash()))) > int(parameters.Block_size){
 			break
 		}
 		err := addTx(block, tx)
@@ -67,6 +81,8 @@ func prepareBlock(block *protocol.Block) {
 		splitSortedAggregatableTransactions(block)
 	}
 
+	aggregateIotBatchTxs(block)
+
 	//Set measurement values back to zero / nil.
 	storage.DifferentSenders = nil
 	storage.DifferentReceivers = nil
@@ -86,6 +102,13 @@ func (f openTxs) Swap(i, j int) {
 func (f openTxs) Less(i, j int) bool {
 	//Comparison only makes sense if both tx are fundsTxs.
 	//Why can we only do that with switch, and not e.g., if tx.(type) == ..?
+	//IoT batches of a device are aggregated, therefore they are sorted according to their txCnt as well.
+	if batchI, ok := f[i].(*protocol.IotBatchTx); ok {
+		if batchJ, ok := f[j].(*protocol.IotBatchTx); ok {
+			return batchI.TxCnt < batchJ.TxCnt
+		}
+	}
+
 	switch f[i].(type) {
 	case *protocol.AccTx:
 		//We only want to sort a subset of all transactions, namely all fundsTxs.
@@ -100,6 +123,18 @@ func (f openTxs) Less(i, j int) bool {
 		return true
 	case *protocol.IotTx:
 		return true
+	case *protocol.ContractTx:
+		return true
+	case *protocol.IotBatchTx:
+		return true
+	case *protocol.IotAggTx:
+		return true
+	case *protocol.DelegateTx:
+		return true
+	case *protocol.EvidenceTx:
+		return true
+	case *protocol.GovernanceTx:
+		return true
 	}
 
 	switch f[j].(type) {
@@ -113,6 +148,18 @@ func (f openTxs) Less(i, j int) bool {
 		return false
 	case *protocol.IotTx:
 		return false
+	case *protocol.ContractTx:
+		return false
+	case *protocol.IotBatchTx:
+		return false
+	case *protocol.IotAggTx:
+		return false
+	case *protocol.DelegateTx:
+		return false
+	case *protocol.EvidenceTx:
+		return false
+	case *protocol.GovernanceTx:
+		return false
 	}
 
 	return f[i].(*protocol.FundsTx).TxCnt < f[j].(*protocol.FundsTx).TxCnt
@@ -2,6 +2,7 @@ package miner
 
 import (
 	"errors"
+	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 )
@@ -9,12 +10,36 @@ import (
 //Already validated block but not part of the current longest chain.
 //No need for an additional state mutex, because this function is called while the blockValidation mutex is actively held.
 func rollback(b *protocol.Block) error {
-	accTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, aggTxSlice, iotTxSlice, err := preValidateRollback(b)
+	accTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, aggTxSlice, iotTxSlice, contractTxSlice, iotBatchTxSlice, delegateTxSlice, evidenceTxSlice, governanceTxSlice, err := preValidateRollback(b)
 	if err != nil {
 		return err
 	}
 
-	data := blockData{accTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, aggTxSlice, iotTxSlice, b}
+	data := blockData{accTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, aggTxSlice, iotTxSlice, contractTxSlice, iotBatchTxSlice, delegateTxSlice, evidenceTxSlice, governanceTxSlice, b}
+
+	//All records stored with the block are checked before anything is reverted, such that a missing one leaves the
+	//state after the block untouched.
+	blockUndoLog := storage.ReadUndoLog(b.Hash)
+	if blockUndoLog == nil {
+		return errors.New(fmt.Sprintf("CRITICAL: The undo log of block %x does not exist.", b.Hash[0:8]))
+	}
+	if storage.ReadSupply(b.Hash) == nil {
+		return errors.New(fmt.Sprintf("CRITICAL: The supply after block %x does not exist.", b.Hash[0:8]))
+	}
+	if _, found := storage.ReadBaseFee(b.Hash); !found {
+		return errors.New(fmt.Sprintf("CRITICAL: The base fee of block %x does not exist.", b.Hash[0:8]))
+	}
+	if storage.ReadValidatorSet(b.Height, b.Hash) == nil {
+		return errors.New(fmt.Sprintf("CRITICAL: The validator set after block %x does not exist.", b.Hash[0:8]))
+	}
+
+	//The consensus related account changes of the block are replayed from its undo log.
+	undoLog = blockUndoLog
+	//The block reward paid by the block is known from the supply before and after it.
+	supplyChange = revertSupplyChange(b)
+	//The fees are rolled back with the base fee the block was validated with.
+	revertBaseFee(b)
+	revertValidatorSet(b)
 
 	//Going back to pre-block system parameters before the state is rolled back.
 	configStateChangeRollback(data.configTxSlice, b.Hash)
@@ -22,20 +47,25 @@ func rollback(b *protocol.Block) error {
 	//TODO Does not throw error but crashes
 	validateStateRollback(data)
 
+	if len(undoLog.Entries) != 0 {
+		logger.Fatalf("CRITICAL: %v entries of the undo log of block %x were not rolled back.", len(undoLog.Entries), b.Hash[0:8])
+	}
+	storage.DeleteUndoLog(b.Hash)
+
 	postValidateRollback(data)
 
 	return nil
 }
 
 func preValidateRollback(b *protocol.Block) (accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsTx,
-	configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, aggTxSlice []*protocol.AggTx,iotTxSlice []*protocol.IotTx, err error) {
+	configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, aggTxSlice []*protocol.AggTx,iotTxSlice []*protocol.IotTx, contractTxSlice []*protocol.ContractTx, iotBatchTxSlice []*protocol.IotBatchTx, delegateTxSlice []*protocol.DelegateTx, evidenceTxSlice []*protocol.EvidenceTx, governanceTxSlice []*protocol.GovernanceTx, err error) {
 	//Fetch all transactions from closed storage.
 	for _, hash := range b.AccTxData {
 		var accTx *protocol.AccTx
 		tx := storage.ReadClosedTx(hash)
 		if tx == nil {
 			//This should never happen, because all validated transactions are in closed storage.
-			return nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated accTx was not in the confirmed tx storage")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated accTx was not in the confirmed tx storage")
 		} else {
 			accTx = tx.(*protocol.AccTx)
 		}
@@ -46,7 +76,7 @@ func preValidateRollback(b *protocol.Block) (accTxSlice []*protocol.AccTx, funds
 		var fundsTx *protocol.FundsTx
 		tx := storage.ReadClosedTx(hash)
 		if tx == nil {
-			return nil, nil, nil, nil,nil, nil,errors.New("CRITICAL: Validated fundsTx was not in the confirmed tx storage")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated fundsTx was not in the confirmed tx storage")
 		} else {
 			fundsTx = tx.(*protocol.FundsTx)
 		}
@@ -57,7 +87,7 @@ func preValidateRollback(b *protocol.Block) (accTxSlice []*protocol.AccTx, funds
 		var configTx *protocol.ConfigTx
 		tx := storage.ReadClosedTx(hash)
 		if tx == nil {
-			return nil, nil, nil, nil, nil,nil, errors.New("CRITICAL: Validated configTx was not in the confirmed tx storage")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated configTx was not in the confirmed tx storage")
 		} else {
 			configTx = tx.(*protocol.ConfigTx)
 		}
@@ -68,7 +98,7 @@ func preValidateRollback(b *protocol.Block) (accTxSlice []*protocol.AccTx, funds
 		var stakeTx *protocol.StakeTx
 		tx := storage.ReadClosedTx(hash)
 		if tx == nil {
-			return nil, nil, nil, nil, nil,nil, errors.New("CRITICAL: Validated stakeTx was not in the confirmed tx storage")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated stakeTx was not in the confirmed tx storage")
 		} else {
 			stakeTx = tx.(*protocol.StakeTx)
 		}
@@ -79,7 +109,7 @@ func preValidateRollback(b *protocol.Block) (accTxSlice []*protocol.AccTx, funds
 		var IoTTx *protocol.IotTx
 		tx := storage.ReadClosedTx(hash)
 		if tx == nil {
-			return nil, nil, nil, nil, nil, nil,errors.New("CRITICAL: Aggregated Transaction was not in the confirmed tx storage")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Aggregated Transaction was not in the confirmed tx storage")
 		} else {
 			IoTTx = tx.(*protocol.IotTx)
 		}
@@ -90,23 +120,101 @@ func preValidateRollback(b *protocol.Block) (accTxSlice []*protocol.AccTx, funds
 		var aggTx *protocol.AggTx
 		tx := storage.ReadClosedTx(hash)
 		if tx == nil {
-			return nil, nil, nil, nil, nil, nil,errors.New("CRITICAL: Aggregated Transaction was not in the confirmed tx storage")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Aggregated Transaction was not in the confirmed tx storage")
 		} else {
 			aggTx = tx.(*protocol.AggTx)
 		}
 		aggTxSlice = append(aggTxSlice, aggTx)
 	}
 
-	return accTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, aggTxSlice, iotTxSlice,nil
+	for _, hash := range b.ContractTxData {
+		var contractTx *protocol.ContractTx
+		tx := storage.ReadClosedTx(hash)
+		if tx == nil {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated contractTx was not in the confirmed tx storage")
+		} else {
+			contractTx = tx.(*protocol.ContractTx)
+		}
+		contractTxSlice = append(contractTxSlice, contractTx)
+	}
+
+	for _, hash := range b.IoTBatchTxData {
+		var iotBatchTx *protocol.IotBatchTx
+		tx := storage.ReadClosedTx(hash)
+		if tx == nil {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated iotBatchTx was not in the confirmed tx storage")
+		} else {
+			iotBatchTx = tx.(*protocol.IotBatchTx)
+		}
+		iotBatchTxSlice = append(iotBatchTxSlice, iotBatchTx)
+	}
+
+	//The aggregated batches are rolled back like the batches listed in the block.
+	for _, hash := range b.IoTAggTxData {
+		iotAggTx, ok := storage.ReadClosedTx(hash).(*protocol.IotAggTx)
+		if !ok {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated iotAggTx was not in the confirmed tx storage")
+		}
+		for _, batchHash := range iotAggTx.AggregatedTxSlice {
+			iotBatchTx, ok := storage.ReadClosedTx(batchHash).(*protocol.IotBatchTx)
+			if !ok {
+				return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Aggregated iotBatchTx was not in the confirmed tx storage")
+			}
+			iotBatchTxSlice = append(iotBatchTxSlice, iotBatchTx)
+		}
+	}
+
+	for _, hash := range b.DelegateTxData {
+		var delegateTx *protocol.DelegateTx
+		tx := storage.ReadClosedTx(hash)
+		if tx == nil {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated delegateTx was not in the confirmed tx storage")
+		} else {
+			delegateTx = tx.(*protocol.DelegateTx)
+		}
+		delegateTxSlice = append(delegateTxSlice, delegateTx)
+	}
+
+	for _, hash := range b.EvidenceTxData {
+		var evidenceTx *protocol.EvidenceTx
+		tx := storage.ReadClosedTx(hash)
+		if tx == nil {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated evidenceTx was not in the confirmed tx storage")
+		} else {
+			evidenceTx = tx.(*protocol.EvidenceTx)
+		}
+		evidenceTxSlice = append(evidenceTxSlice, evidenceTx)
+	}
+
+	for _, hash := range b.GovernanceTxData {
+		var governanceTx *protocol.GovernanceTx
+		tx := storage.ReadClosedTx(hash)
+		if tx == nil {
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, errors.New("CRITICAL: Validated governanceTx was not in the confirmed tx storage")
+		} else {
+			governanceTx = tx.(*protocol.GovernanceTx)
+		}
+		governanceTxSlice = append(governanceTxSlice, governanceTx)
+	}
+
+	return accTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, aggTxSlice, iotTxSlice, contractTxSlice, iotBatchTxSlice, delegateTxSlice, evidenceTxSlice, governanceTxSlice, nil
 }
 
 func validateStateRollback(data blockData) {
+	updateStakingHeightRollback(data.block)
+	distributeDelegationRewardsRollback(data.block)
 	collectSlashRewardRollback(activeParameters.Slash_reward, data.block)
-	collectBlockRewardRollback(activeParameters.Block_reward, data.block.Beneficiary)
-	collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.block.Beneficiary)
+	collectBlockRewardRollback(supplyChange.Minted, data.block.Beneficiary)
+	collectTxFeesRollback(data.accTxSlice, data.fundsTxSlice, data.configTxSlice, data.stakeTxSlice, data.iotTxSlice, data.contractTxSlice, data.iotBatchTxSlice, data.delegateTxSlice, data.evidenceTxSlice, data.governanceTxSlice, data.block.Beneficiary)
+	governanceStateChangeRollback(data.governanceTxSlice)
+	evidenceStateChangeRollback(data.evidenceTxSlice)
+	delegateStateChangeRollback(data.delegateTxSlice)
+	iotBatchStateChangeRollback(data.iotBatchTxSlice)
+	iotStateChangeRollback(data.iotTxSlice)
 	stakeStateChangeRollback(data.stakeTxSlice)
 	fundsStateChangeRollback(data.fundsTxSlice)
 	aggregatedSenderStateRollback(data.aggTxSlice)
+	contractStateChangeRollback(data.contractTxSlice, data.block.Height)
 	accStateChangeRollback(data.accTxSlice)
 }
 
@@ -132,6 +240,45 @@ func postValidateRollback(data blockData) {
 		storage.DeleteClosedTx(tx)
 	}
 
+	for _, tx := range data.contractTxSlice {
+		storage.WriteOpenTx(tx)
+		storage.DeleteClosedTx(tx)
+	}
+
+	for _, tx := range data.iotTxSlice {
+		storage.WriteOpenTx(tx)
+		storage.DeleteClosedTx(tx)
+		storage.DeleteIotIndex(tx, data.block)
+	}
+
+	for _, tx := range data.iotBatchTxSlice {
+		storage.WriteOpenTx(tx)
+		storage.DeleteClosedTx(tx)
+		storage.DeleteIotBatchIndex(tx, data.block)
+	}
+
+	//IotAggTxs are not reopened, the batches are aggregated again by the next block.
+	for _, txHash := range data.block.IoTAggTxData {
+		if tx := storage.ReadClosedTx(txHash); tx != nil {
+			storage.DeleteClosedTx(tx)
+		}
+	}
+
+	for _, tx := range data.delegateTxSlice {
+		storage.WriteOpenTx(tx)
+		storage.DeleteClosedTx(tx)
+	}
+
+	for _, tx := range data.evidenceTxSlice {
+		storage.WriteOpenTx(tx)
+		storage.DeleteClosedTx(tx)
+	}
+
+	for _, tx := range data.governanceTxSlice {
+		storage.WriteOpenTx(tx)
+		storage.DeleteClosedTx(tx)
+	}
+
 	for _, tx := range data.aggTxSlice {
 
 		//Reopen FundsTx per aggTx
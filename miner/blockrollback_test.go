@@ -13,12 +13,12 @@ import (
 func TestValidateBlockRollback(t *testing.T) {
 	cleanAndPrepare()
 
-	b := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 
 	//Make state snapshot
-	accsBefore := make(map[[64]byte]protocol.Account)
-	accsBefore2 := make(map[[64]byte]protocol.Account)
-	accsAfter := make(map[[64]byte]protocol.Account)
+	accsBefore := make(map[[32]byte]protocol.Account)
+	accsBefore2 := make(map[[32]byte]protocol.Account)
+	accsAfter := make(map[[32]byte]protocol.Account)
 
 	for _, acc := range storage.State {
 		accsBefore[acc.Address] = *acc
@@ -62,17 +62,17 @@ func TestMultipleBlocksRollback(t *testing.T) {
 	cleanAndPrepare()
 
 	//State snapshot
-	stateb := make(map[[64]byte]protocol.Account)
-	stateb2 := make(map[[64]byte]protocol.Account)
-	stateb3 := make(map[[64]byte]protocol.Account)
-	tmpState := make(map[[64]byte]protocol.Account)
+	stateb := make(map[[32]byte]protocol.Account)
+	stateb2 := make(map[[32]byte]protocol.Account)
+	stateb3 := make(map[[32]byte]protocol.Account)
+	tmpState := make(map[[32]byte]protocol.Account)
 
 	//system parameters
 	var paramb []Parameters
 	var paramb2 []Parameters
 	var paramb3 []Parameters
 
-	b := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	createBlockWithTxs(b)
 	finalizeBlock(b)
 	if err := validate(b, false); err != nil {
@@ -86,7 +86,7 @@ func TestMultipleBlocksRollback(t *testing.T) {
 	paramb = make([]Parameters, len(parameterSlice))
 	copy(paramb, parameterSlice)
 
-	b2 := newBlock(b.Hash, [crypto.COMM_KEY_LENGTH]byte{}, 2)
+	b2 := newBlock(b.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 2)
 	createBlockWithTxs(b2)
 	finalizeBlock(b2)
 	if err := validate(b2, false); err != nil {
@@ -100,7 +100,7 @@ func TestMultipleBlocksRollback(t *testing.T) {
 	paramb2 = make([]Parameters, len(parameterSlice))
 	copy(paramb2, parameterSlice)
 
-	b3 := newBlock(b2.Hash, [crypto.COMM_KEY_LENGTH]byte{}, 3)
+	b3 := newBlock(b2.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 3)
 	createBlockWithTxs(b3)
 	finalizeBlock(b3)
 	if err := validate(b3, false); err != nil {
@@ -114,7 +114,7 @@ func TestMultipleBlocksRollback(t *testing.T) {
 	paramb3 = make([]Parameters, len(parameterSlice))
 	copy(paramb3, parameterSlice)
 
-	b4 := newBlock(b3.Hash, [crypto.COMM_KEY_LENGTH]byte{}, 4)
+	b4 := newBlock(b3.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 4)
 	createBlockWithTxs(b4)
 	finalizeBlock(b4)
 	if err := validate(b4, false); err != nil {
@@ -185,8 +185,8 @@ func TestMultipleBlocksRollback(t *testing.T) {
 // resetStakingBlockHeight sets the StackingBlockHeight of all accounts to 0.
 // This is needed so that the other fields can get tested.
 // TODO Remove this function if rollback of StakingBlockHeight gets implemented.
-func resetStakingBlockHeight(accounts map[[64]byte]protocol.Account) map[[64]byte]protocol.Account {
-	accountsNoStakingBlockHeight := make(map[[64]byte]protocol.Account)
+func resetStakingBlockHeight(accounts map[[32]byte]protocol.Account) map[[32]byte]protocol.Account {
+	accountsNoStakingBlockHeight := make(map[[32]byte]protocol.Account)
 
 	for hash, acc := range accounts {
 		acc.StakingBlockHeight = 0
@@ -195,3 +195,29 @@ func resetStakingBlockHeight(accounts map[[64]byte]protocol.Account) map[[64]byt
 
 	return accountsNoStakingBlockHeight
 }
+
+//A block with a missing record is not rolled back at all, the records which exist are not reverted either
+func TestRollbackWithMissingRecord(t *testing.T) {
+	cleanAndPrepare()
+
+	b := newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	createBlockWithTxs(b)
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Could not finalize block: %v\n", err)
+	}
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Could not validate block: %v\n", err)
+	}
+
+	supplyAfter, baseFeeAfter, validatorsAfter := supply, baseFee, validatorSet
+	storage.DeleteValidatorSet(b.Height, b.Hash)
+	if err := rollback(b); err == nil {
+		t.Fatal("Block without a stored validator set rolled back.")
+	}
+	if supply != supplyAfter || baseFee != baseFeeAfter || validatorSet != validatorsAfter {
+		t.Errorf("Globals reverted by a failed rollback: %v, %v, %v\n", supply, baseFee, validatorSet)
+	}
+	if _, found := storage.ReadBaseFee(b.Hash); !found || storage.ReadSupply(b.Hash) == nil || storage.ReadUndoLog(b.Hash) == nil {
+		t.Error("Records of the block deleted by a failed rollback.")
+	}
+}
@@ -8,6 +8,10 @@ const (
 	TXFETCH_TIMEOUT    = 5  //Sec
 	BLOCKFETCH_TIMEOUT = 40 //Sec
 
+	//Competing chains which would roll back more blocks are rejected. Records only needed to roll back a block are
+	//deleted once the block is this deep in the chain.
+	ROLLBACK_DEPTH = 100 //Blocks
+
 	//Some prominent programming languages (e.g., Java) have not unsigned integer types
 	//Neglecting MSB simplifies compatibility
 	MAX_MONEY = 9223372036854775807 //(2^63)-1
@@ -24,6 +28,34 @@ const (
 	ACCEPTED_TIME_DIFF   	= 60      //Sec
 	SLASHING_WINDOW_SIZE 	= 100     //Blocks
 	SLASH_REWARD         	= 2       //Coins
+	UNBONDING_PERIOD     	= 100     //Blocks
 	NUM_INCL_PREV_PROOFS 	= 5       //Number of previous proofs included in the PoS condition
 	NO_AGGREGATION_LENGTH	= 3		  //Number of blocks after the newest block which are not aggregated.
+
+	//Block reward schedule defined at genesis, see monetarypolicy.go. The flat schedule pays the Block_reward
+	//parameter, the others compute the reward from the height and never pay less than the tail reward.
+	REWARD_SCHEDULE         = REWARD_FLAT
+	INITIAL_BLOCK_REWARD    = 0         //Coins
+	REWARD_HALVING_INTERVAL = 210000    //Blocks
+	REWARD_DECAY_PERIOD     = 1000000   //Blocks
+	TAIL_BLOCK_REWARD       = 0         //Coins
+	MAX_SUPPLY              = MAX_MONEY //Coins, block rewards stop once the supply reaches it
+
+	//The base fee is burned with every tx fee, only the tip goes to the validator, see basefee.go. It changes by at
+	//most 1/BASE_FEE_CHANGE_DENOMINATOR per block, depending on how far the size of the previous block was from
+	//BASE_FEE_TARGET_PERCENT of the block size.
+	INITIAL_BASE_FEE            = 1  //Coins
+	BASE_FEE_TARGET_PERCENT     = 50 //Percent of the block size
+	BASE_FEE_CHANGE_DENOMINATOR = 8
+
+	//Difficulty adjustments at blocks from this height on change the PoS target proportionally, by at most a factor
+	//of MAX_TARGET_ADJUSTMENT. It is the initial Target_adjustment_height of the chain, chains started with whole-bit
+	//adjustments keep them until a ConfigTx (TARGET_ADJUSTMENT_HEIGHT_ID) schedules the switch.
+	TARGET_ADJUSTMENT_HEIGHT = 0 //Blocks
+	MAX_TARGET_ADJUSTMENT    = 4
+
+	//A proposal is accepted once validators with more than GOVERNANCE_QUORUM_NUMERATOR/GOVERNANCE_QUORUM_DENOMINATOR
+	//of the locked stake voted for it.
+	GOVERNANCE_QUORUM_NUMERATOR   = 2
+	GOVERNANCE_QUORUM_DENOMINATOR = 3
 )
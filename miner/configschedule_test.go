@@ -0,0 +1,74 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//A ConfigTx with an activation height is queued and in force from that height on, also for validation and
+//preparation of blocks at that height
+func TestConfigTxActivationHeight(t *testing.T) {
+	cleanAndPrepare()
+
+	feeMinimum := activeParameters.Fee_minimum
+	blockSize := activeParameters.Block_size
+
+	scheduled, _ := protocol.ConstrConfigTx(0, protocol.FEE_MINIMUM_ID, feeMinimum+1, 1, 0, 5, PrivKeyRoot)
+	immediate, _ := protocol.ConstrConfigTx(0, protocol.BLOCK_SIZE_ID, blockSize+1, 1, 1, 0, PrivKeyRoot)
+
+	//Changes can't be scheduled for the including block or earlier ones
+	b := newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 5)
+	if err := addConfigTx(b, scheduled); err == nil {
+		t.Error("ConfigTx activated at the height of the including block accepted.")
+	}
+	b = newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	if err := addConfigTx(b, scheduled); err != nil {
+		t.Errorf("ConfigTx with an activation height rejected: %v\n", err)
+	}
+
+	//The ConfigTx without activation height is in force from the next block on, the other one is queued
+	configStateChange([]*protocol.ConfigTx{scheduled, immediate}, [32]byte{0x01}, 1)
+	if activeParameters.Fee_minimum != feeMinimum || activeParameters.Block_size != blockSize+1 || len(parameterSlice) != 2 {
+		t.Errorf("ConfigTxs not applied: %v\n", *activeParameters)
+	}
+	lastBlock = newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	if parametersAt(4).Fee_minimum != feeMinimum || parametersAt(5).Fee_minimum != feeMinimum+1 || activeParameters.Fee_minimum != feeMinimum {
+		t.Errorf("Parameters of future heights not computed: %v vs. %v\n", *parametersAt(4), *parametersAt(5))
+	}
+
+	storage.DeleteAllLastClosedBlock()
+	storage.WriteLastClosedBlock(lastBlock)
+	schedule := storage.ReadParameterSchedule()
+	if schedule.Height != 1 || len(schedule.Changes) != 1 || schedule.Changes[0].Source != scheduled.Hash() || schedule.Changes[0].ActivationHeight != 5 {
+		t.Fatalf("Pending change not listed: %v\n", schedule)
+	}
+
+	//The change is put in force with the block before its activation height and not listed as pending anymore
+	configStateChange(nil, [32]byte{0x04}, 4)
+	if activeParameters.Fee_minimum != feeMinimum+1 || activeParameters.Block_size != blockSize+1 || len(parameterSlice) != 3 {
+		t.Errorf("Scheduled change not activated: %v\n", *activeParameters)
+	}
+	lastBlock = newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 4)
+	storage.DeleteAllLastClosedBlock()
+	storage.WriteLastClosedBlock(lastBlock)
+	if schedule := storage.ReadParameterSchedule(); len(schedule.Changes) != 0 {
+		t.Errorf("Change in force listed as pending: %v\n", schedule)
+	}
+
+	//Rolling back the activating block puts the change back into the schedule, rolling back the including block
+	//drops it
+	configStateChangeRollback(nil, [32]byte{0x04})
+	if activeParameters.Fee_minimum != feeMinimum || len(parameterSlice) != 2 {
+		t.Errorf("Activation not rolled back: %v\n", *activeParameters)
+	}
+	configStateChangeRollback([]*protocol.ConfigTx{scheduled, immediate}, [32]byte{0x01})
+	if activeParameters.Block_size != blockSize || len(parameterSlice) != 1 {
+		t.Errorf("ConfigTx not rolled back: %v\n", *activeParameters)
+	}
+	if changes := storage.ReadAllParameterChanges(); len(changes) != 0 {
+		t.Errorf("Queued change not dropped after rollback: %v\n", changes)
+	}
+}
@@ -0,0 +1,214 @@
+package miner
+
+import (
+	"crypto/rand"
+	"reflect"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/ed25519"
+)
+
+//Upgrades the contract, variables present in both layouts keep their value
+func TestContractUpgradeAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	issuerPrivKey, _ := createContractIssuer(1000)
+	contractHash := createContractAccount(issuerPrivKey, []byte{35, 50}, []protocol.ByteArray{[]byte{0, 7}})
+	contractAcc, _ := storage.GetAccount(contractHash)
+
+	newContract := []byte{35, 29, 0, 4, 27, 0, 50}
+	tx, _ := protocol.ConstrContractTx(protocol.CONTRACT_UPGRADE, 1, 0, contractHash, [32]byte{}, newContract, []protocol.ByteArray{[]byte{0, 0}, []byte{0, 1}}, issuerPrivKey)
+
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	if err := addTx(b, tx); err != nil {
+		t.Errorf("Block rejected a valid contract upgrade: %v\n", err)
+	}
+
+	if err := contractStateChange([]*protocol.ContractTx{tx}, 1); err != nil {
+		t.Errorf("Contract upgrade failed: %v\n", err)
+	}
+
+	expected := []protocol.ByteArray{[]byte{0, 7}, []byte{0, 1}}
+	if !reflect.DeepEqual(contractAcc.Contract, newContract) || !reflect.DeepEqual(contractAcc.ContractVariables, expected) {
+		t.Errorf("Contract not upgraded, code: %v, variables: %v (expected %v)\n", contractAcc.Contract, contractAcc.ContractVariables, expected)
+	}
+	if contractAcc.TxCnt != 1 {
+		t.Errorf("Contract txCnt not increased: %v\n", contractAcc.TxCnt)
+	}
+
+	contractStateChangeRollback([]*protocol.ContractTx{tx}, 1)
+	if !reflect.DeepEqual(contractAcc.Contract, []byte{35, 50}) || !reflect.DeepEqual(contractAcc.ContractVariables, []protocol.ByteArray{[]byte{0, 7}}) || contractAcc.TxCnt != 0 {
+		t.Errorf("Contract upgrade rollback failed: %v\n", contractAcc)
+	}
+	if storage.ReadContractSnapshot(1, tx.Hash()) != nil {
+		t.Error("Contract snapshot not deleted after rollback.")
+	}
+}
+
+//A frozen contract does not accept fundsTxs anymore
+func TestContractFreezeAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	issuerPrivKey, _ := createContractIssuer(1000)
+	contractHash := createContractAccount(issuerPrivKey, []byte{35, 50}, nil)
+	contractAcc, _ := storage.GetAccount(contractHash)
+
+	tx, _ := protocol.ConstrContractTx(protocol.CONTRACT_FREEZE, 1, 0, contractHash, [32]byte{}, nil, nil, issuerPrivKey)
+	if err := contractStateChange([]*protocol.ContractTx{tx}, 1); err != nil {
+		t.Errorf("Contract freeze failed: %v\n", err)
+	}
+	if !contractAcc.IsFrozen {
+		t.Error("Contract not frozen.")
+	}
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	balanceA := accA.Balance
+	fundsTx := &protocol.FundsTx{Header: 0x01, Amount: 10, Fee: 1, TxCnt: accA.TxCnt, From: accAHash, To: contractHash}
+	if err := fundsStateChange([]*protocol.FundsTx{fundsTx}); err == nil {
+		t.Error("Frozen contract accepted a fundsTx.")
+	}
+	if accA.Balance != balanceA {
+		t.Errorf("Rejected fundsTx changed the sender balance: %v vs. %v\n", accA.Balance, balanceA)
+	}
+
+	//Freezing twice is not possible
+	tx2, _ := protocol.ConstrContractTx(protocol.CONTRACT_FREEZE, 1, 1, contractHash, [32]byte{}, nil, nil, issuerPrivKey)
+	if err := contractStateChange([]*protocol.ContractTx{tx2}, 1); err == nil {
+		t.Error("Already frozen contract was frozen again.")
+	}
+
+	contractStateChangeRollback([]*protocol.ContractTx{tx}, 1)
+	if contractAcc.IsFrozen || contractAcc.TxCnt != 0 {
+		t.Errorf("Contract freeze rollback failed: %v\n", contractAcc)
+	}
+	if err := fundsStateChange([]*protocol.FundsTx{fundsTx}); err != nil {
+		t.Errorf("Unfrozen contract rejected a fundsTx: %v\n", err)
+	}
+}
+
+//The remaining balance of a self-destructed contract goes to the beneficiary
+func TestContractSelfDestructAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	issuerPrivKey, _ := createContractIssuer(1000)
+	contractHash := createContractAccount(issuerPrivKey, []byte{35, 50}, []protocol.ByteArray{[]byte{0, 7}})
+	contractAcc, _ := storage.GetAccount(contractHash)
+	contractAcc.Balance = 500
+
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	balanceB := accB.Balance
+
+	tx, _ := protocol.ConstrContractTx(protocol.CONTRACT_SELFDESTRUCT, 1, 0, contractHash, accBHash, nil, nil, issuerPrivKey)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	if err := addTx(b, tx); err != nil {
+		t.Errorf("Block rejected a valid contract self-destruct: %v\n", err)
+	}
+
+	if err := contractStateChange([]*protocol.ContractTx{tx}, 1); err != nil {
+		t.Errorf("Contract self-destruct failed: %v\n", err)
+	}
+	if accB.Balance != balanceB+500 || contractAcc.Balance != 0 || contractAcc.Contract != nil || !contractAcc.IsFrozen {
+		t.Errorf("Contract self-destruct not applied: %v\n", contractAcc)
+	}
+
+	contractStateChangeRollback([]*protocol.ContractTx{tx}, 1)
+	if accB.Balance != balanceB || contractAcc.Balance != 500 || !reflect.DeepEqual(contractAcc.Contract, []byte{35, 50}) || contractAcc.IsFrozen {
+		t.Errorf("Contract self-destruct rollback failed: %v\n", contractAcc)
+	}
+}
+
+//Only the issuer may change the contract and a contractTx cannot be replayed
+func TestContractTxIssuerAndReplay(t *testing.T) {
+	cleanAndPrepare()
+
+	issuerPrivKey, issuerHash := createContractIssuer(1000)
+	contractHash := createContractAccount(issuerPrivKey, []byte{35, 50}, nil)
+	issuerAcc, _ := storage.GetAccount(issuerHash)
+	minerHash := protocol.SerializeHashContent(validatorAcc.Address)
+
+	otherPrivKey, _ := createContractIssuer(1000)
+	foreignTx, _ := protocol.ConstrContractTx(protocol.CONTRACT_FREEZE, 1, 0, contractHash, [32]byte{}, nil, nil, otherPrivKey)
+	if verify(foreignTx) {
+		t.Error("ContractTx not signed by the issuer was verified.")
+	}
+
+	tx, _ := protocol.ConstrContractTx(protocol.CONTRACT_FREEZE, 5, 0, contractHash, [32]byte{}, nil, nil, issuerPrivKey)
+	if !verify(tx) {
+		t.Error("ContractTx signed by the issuer could not be verified.")
+	}
+	if err := contractStateChange([]*protocol.ContractTx{tx}, 1); err != nil {
+		t.Errorf("Contract freeze failed: %v\n", err)
+	}
+
+	balanceIssuer, balanceMiner := issuerAcc.Balance, validatorAcc.Balance
+	collectTxFees(nil, nil, nil, nil, nil, nil, []*protocol.ContractTx{tx}, nil, nil, nil, nil, minerHash)
+	if issuerAcc.Balance != balanceIssuer-5 || validatorAcc.Balance != balanceMiner+txTip(5) {
+		t.Errorf("ContractTx fee not collected: issuer %v, miner %v\n", issuerAcc.Balance, validatorAcc.Balance)
+	}
+	collectTxFeesRollback(nil, nil, nil, nil, nil, []*protocol.ContractTx{tx}, nil, nil, nil, nil, minerHash)
+	if issuerAcc.Balance != balanceIssuer || validatorAcc.Balance != balanceMiner {
+		t.Error("ContractTx fee rollback failed.")
+	}
+
+	//Same txCnt again, i.e., a replayed unfreeze with an old counter
+	replayTx, _ := protocol.ConstrContractTx(protocol.CONTRACT_UPGRADE, 1, 0, contractHash, [32]byte{}, []byte{50}, nil, issuerPrivKey)
+	if err := contractStateChange([]*protocol.ContractTx{replayTx}, 1); err == nil {
+		t.Error("ContractTx with outdated txCnt was accepted.")
+	}
+}
+
+//Creates a non-root account which is used to issue contracts, returns its key and the hash of its address
+func createContractIssuer(balance uint64) (ed25519.PrivateKey, [32]byte) {
+	pubKey, privKey, _ := ed25519.GenerateKey(rand.Reader)
+	var address [32]byte
+	copy(address[:], pubKey)
+
+	acc := protocol.NewAccount(address, [32]byte{}, balance, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	accHash := acc.Hash()
+	storage.State[accHash] = &acc
+
+	return privKey, accHash
+}
+
+func createContractAccount(issuerPrivKey ed25519.PrivateKey, contract []byte, contractVariables []protocol.ByteArray) [32]byte {
+	pubKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	var address, issuer [32]byte
+	copy(address[:], pubKey)
+	copy(issuer[:], issuerPrivKey[32:])
+
+	acc := protocol.NewAccount(address, protocol.SerializeHashContent(issuer), 0, false, [crypto.COMM_KEY_LENGTH]byte{}, contract, contractVariables)
+	accHash := acc.Hash()
+	storage.State[accHash] = &acc
+
+	return accHash
+}
+
+//Snapshots are kept as long as the block containing the ContractTx can be rolled back
+func TestContractSnapshotPruning(t *testing.T) {
+	cleanAndPrepare()
+
+	issuerPrivKey, _ := createContractIssuer(1000)
+	contractHash1 := createContractAccount(issuerPrivKey, []byte{35, 50}, nil)
+	contractHash2 := createContractAccount(issuerPrivKey, []byte{35, 50}, nil)
+
+	tx1, _ := protocol.ConstrContractTx(protocol.CONTRACT_FREEZE, 1, 0, contractHash1, [32]byte{}, nil, nil, issuerPrivKey)
+	if err := contractStateChange([]*protocol.ContractTx{tx1}, 1); err != nil {
+		t.Errorf("Contract freeze failed: %v\n", err)
+	}
+	tx2, _ := protocol.ConstrContractTx(protocol.CONTRACT_FREEZE, 1, 0, contractHash2, [32]byte{}, nil, nil, issuerPrivKey)
+	if err := contractStateChange([]*protocol.ContractTx{tx2}, 2); err != nil {
+		t.Errorf("Contract freeze failed: %v\n", err)
+	}
+
+	//The block at height 2 is the deepest one which can still be rolled back
+	pruneContractSnapshots(ROLLBACK_DEPTH + 1)
+	if storage.ReadContractSnapshot(1, tx1.Hash()) != nil {
+		t.Error("Snapshot of a block deeper than the rollback depth still stored.")
+	}
+	if storage.ReadContractSnapshot(2, tx2.Hash()) == nil {
+		t.Error("Snapshot needed for a rollback deleted.")
+	}
+}
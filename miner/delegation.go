@@ -0,0 +1,154 @@
+package miner
+
+import (
+	"errors"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"math/big"
+)
+
+//Pays the delegators of the validator of a block their share of the block reward and the tx fees. The delegators get
+//the part of the reward their delegations make up of the effective stake of the validator, minus the commission of
+//the validator. The payouts are stored, such that they can be rolled back even if the delegations changed since.
+func distributeDelegationRewards(data blockData) error {
+	validatorAcc, err := storage.GetAccount(data.block.Beneficiary)
+	if err != nil {
+		return err
+	}
+
+	delegated := validatorAcc.DelegatedStake()
+	if delegated == 0 {
+		return nil
+	}
+
+	//The rewards of this block were already credited to the validator, they don't count towards its own stake
+	reward := supplyChange.Minted + blockTxFees(data)
+	earned := reward
+	if hasSlashingProof(data.block) {
+		earned += activeParameters.Slash_reward
+	}
+	selfStake := validatorAcc.StakedAmount
+	if validatorAcc.Balance > earned {
+		selfStake += validatorAcc.Balance - earned
+	}
+
+	delegatorsReward := mulDiv(mulDiv(reward, delegated, selfStake+delegated), uint64(protocol.MAX_COMMISSION-validatorAcc.Commission), protocol.MAX_COMMISSION)
+
+	//Every delegator gets its share of the delegators' reward, what is lost by rounding stays with the validator
+	payouts := &protocol.DelegationPayouts{Validator: data.block.Beneficiary, Payouts: make(map[[32]byte]uint64)}
+	var total uint64
+	for delegator, amount := range validatorAcc.Delegations {
+		payout := mulDiv(delegatorsReward, amount, delegated)
+		if payout == 0 {
+			continue
+		}
+
+		delegatorAcc, err := storage.GetAccount(delegator)
+		if err != nil {
+			return err
+		}
+		if delegatorAcc.Balance+payout > MAX_MONEY {
+			return errors.New("Delegation reward would lead to balance overflow at the delegator account.")
+		}
+
+		payouts.Payouts[delegator] = payout
+		total += payout
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	if err := storage.WriteDelegationPayouts(data.block.Hash, payouts); err != nil {
+		return err
+	}
+
+	//We're manipulating pointer, no need to write back
+	validatorAcc.Balance -= total
+	for delegator, payout := range payouts.Payouts {
+		delegatorAcc, _ := storage.GetAccount(delegator)
+		delegatorAcc.Balance += payout
+	}
+
+	return nil
+}
+
+func distributeDelegationRewardsRollback(block *protocol.Block) {
+	payouts := storage.ReadDelegationPayouts(block.Hash)
+	if payouts == nil {
+		return
+	}
+
+	validatorAcc, _ := storage.GetAccount(payouts.Validator)
+	for delegator, payout := range payouts.Payouts {
+		delegatorAcc, _ := storage.GetAccount(delegator)
+		delegatorAcc.Balance -= payout
+		validatorAcc.Balance += payout
+	}
+
+	storage.DeleteDelegationPayouts(block.Hash)
+}
+
+//Sum of the tips the validator collected with the block, the same as in collectTxFees. The base fees are burned.
+func blockTxFees(data blockData) (fees uint64) {
+	for _, tx := range data.accTxSlice {
+		fees += txTip(tx.Fee)
+	}
+	for _, tx := range data.fundsTxSlice {
+		fees += txTip(tx.Fee)
+	}
+	for _, tx := range data.aggTxSlice {
+		for _, txHash := range tx.AggregatedTxSlice {
+			trx := storage.ReadOpenTx(txHash)
+			if trx == nil {
+				trx = storage.ReadBootstrapReceivedTransactions(txHash)
+			}
+			if trx == nil {
+				trx = storage.ReadClosedTx(txHash)
+			}
+			if trx != nil {
+				fees += txTip(trx.TxFee())
+			}
+		}
+	}
+	for _, tx := range data.configTxSlice {
+		fees += txTip(tx.Fee)
+	}
+	for _, tx := range data.stakeTxSlice {
+		fees += txTip(tx.Fee)
+	}
+	for _, tx := range data.iotTxSlice {
+		fees += txTip(tx.Fee)
+	}
+	for _, tx := range data.contractTxSlice {
+		fees += txTip(tx.Fee)
+	}
+	for _, tx := range data.iotBatchTxSlice {
+		fees += txTip(tx.Fee)
+	}
+	for _, tx := range data.delegateTxSlice {
+		fees += txTip(tx.Fee)
+	}
+	for _, tx := range data.evidenceTxSlice {
+		fees += txTip(tx.Fee)
+	}
+	for _, tx := range data.governanceTxSlice {
+		fees += txTip(tx.Fee)
+	}
+
+	return fees
+}
+
+func hasSlashingProof(block *protocol.Block) bool {
+	return block.SlashedAddress != [32]byte{} || block.ConflictingBlockHash1 != [32]byte{} || block.ConflictingBlockHash2 != [32]byte{} || block.ConflictingBlockHashWithoutTx1 != [32]byte{} || block.ConflictingBlockHashWithoutTx2 != [32]byte{}
+}
+
+//Calculates a*b/c without overflowing, the result is rounded down.
+func mulDiv(a, b, c uint64) uint64 {
+	if c == 0 {
+		return 0
+	}
+
+	result := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	return result.Div(result, new(big.Int).SetUint64(c)).Uint64()
+}
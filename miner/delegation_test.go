@@ -0,0 +1,154 @@
+package miner
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Delegated coins move from the balance of the delegator to the stake of the validator and back
+func TestDelegationAndRollback(t *testing.T) {
+	cleanAndPrepare()
+	validatorAcc.Delegations = nil
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	validatorHash := protocol.SerializeHashContent(validatorAcc.Address)
+	balanceA := accA.Balance
+	stake := validatorAcc.EffectiveStake()
+
+	tx, _ := protocol.ConstrDelegateTx(protocol.DELEGATE, 1000, 1, 0, accAHash, validatorHash, PrivKeyAccA)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	if err := addTx(b, tx); err != nil {
+		t.Errorf("Block rejected a valid delegation: %v\n", err)
+	}
+	if validatorAcc.Delegations != nil || b.StateCopy[validatorHash].Delegations[accAHash] != 1000 {
+		t.Error("Delegation not applied to the state copy only.")
+	}
+
+	if err := delegateStateChange([]*protocol.DelegateTx{tx}); err != nil {
+		t.Errorf("Delegation failed: %v\n", err)
+	}
+	if accA.Balance != balanceA-1001 || accA.TxCnt != 1 || validatorAcc.Delegations[accAHash] != 1000 {
+		t.Errorf("Delegation not applied: %v\n", validatorAcc)
+	}
+	if validatorAcc.EffectiveStake() != stake+1000 {
+		t.Errorf("Delegated coins do not count towards the stake: %v vs. %v\n", validatorAcc.EffectiveStake(), stake+1000)
+	}
+
+	//Only delegated coins can be undelegated
+	tooMuch, _ := protocol.ConstrDelegateTx(protocol.UNDELEGATE, 1001, 1, 1, accAHash, validatorHash, PrivKeyAccA)
+	if err := delegateStateChange([]*protocol.DelegateTx{tooMuch}); err == nil {
+		t.Error("More coins undelegated than delegated.")
+	}
+
+	undelegate, _ := protocol.ConstrDelegateTx(protocol.UNDELEGATE, 1000, 1, 1, accAHash, validatorHash, PrivKeyAccA)
+	if err := delegateStateChange([]*protocol.DelegateTx{undelegate}); err != nil {
+		t.Errorf("Undelegation failed: %v\n", err)
+	}
+	if accA.Balance != balanceA-2 || len(validatorAcc.Delegations) != 0 {
+		t.Errorf("Undelegation not applied: %v\n", validatorAcc)
+	}
+
+	delegateStateChangeRollback([]*protocol.DelegateTx{tx, undelegate})
+	if accA.Balance != balanceA || accA.TxCnt != 0 || len(validatorAcc.Delegations) != 0 {
+		t.Errorf("Delegation rollback failed: %v\n", validatorAcc)
+	}
+
+	//Validators which are not staking can't be delegated to
+	validatorAcc.IsStaking = false
+	if err := delegateStateChange([]*protocol.DelegateTx{tx}); err == nil {
+		t.Error("Coins delegated to an account which is not staking.")
+	}
+}
+
+//The amounts and fees of all delegations of a delegator in a block together need to be covered by its balance
+func TestDelegationCumulativeFees(t *testing.T) {
+	cleanAndPrepare()
+	validatorAcc.Delegations = nil
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	validatorHash := protocol.SerializeHashContent(validatorAcc.Address)
+	accA.Balance = 100
+
+	tx1, _ := protocol.ConstrDelegateTx(protocol.DELEGATE, 50, 1, 0, accAHash, validatorHash, PrivKeyAccA)
+	tx2, _ := protocol.ConstrDelegateTx(protocol.DELEGATE, 49, 1, 1, accAHash, validatorHash, PrivKeyAccA)
+	if err := delegateStateChange([]*protocol.DelegateTx{tx1, tx2}); err == nil {
+		t.Error("Delegations exceeding the balance of the delegator were accepted.")
+	}
+	if accA.Balance != 100 || accA.TxCnt != 0 || len(validatorAcc.Delegations) != 0 {
+		t.Errorf("Rejected delegations changed the state: %v, %v\n", accA, validatorAcc.Delegations)
+	}
+}
+
+//Amounts close to 2^64 must not wrap around the balance and stake checks
+func TestDelegationOverflow(t *testing.T) {
+	cleanAndPrepare()
+	validatorAcc.Delegations = nil
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	validatorHash := protocol.SerializeHashContent(validatorAcc.Address)
+	accA.Balance = 10
+	validatorAcc.Balance = 10
+
+	balanceA := accA.Balance
+	tx, _ := protocol.ConstrDelegateTx(protocol.DELEGATE, math.MaxUint64-4, 5, 0, accAHash, validatorHash, PrivKeyAccA)
+	if err := delegateStateChange([]*protocol.DelegateTx{tx}); err == nil {
+		t.Error("Delegation with a wrapping amount accepted.")
+	}
+	if accA.Balance != balanceA || len(validatorAcc.Delegations) != 0 {
+		t.Errorf("Coins minted by a wrapping delegation: %v, %v\n", accA.Balance, validatorAcc.Delegations)
+	}
+
+	//The stake of the validator can't exceed the maximum either
+	accA.Balance = MAX_MONEY
+	validatorAcc.Balance = MAX_MONEY - 10
+	tx, _ = protocol.ConstrDelegateTx(protocol.DELEGATE, 11, 5, 0, accAHash, validatorHash, PrivKeyAccA)
+	if err := delegateStateChange([]*protocol.DelegateTx{tx}); err == nil {
+		t.Error("Delegation overflowing the stake of the validator accepted.")
+	}
+}
+
+//Delegators get their share of the block reward, minus the commission of the validator
+func TestDelegationRewardsAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	balanceA, balanceB := accA.Balance, accB.Balance
+
+	activeParameters.Block_reward = 100
+	validatorAcc.Commission = 25
+	validatorAcc.Delegations = map[[32]byte]uint64{accAHash: 1000, accBHash: 3000}
+	//Own stake of 1000, the block reward was already collected
+	validatorAcc.Balance = 1000 + activeParameters.Block_reward
+	supplyChange.Minted = activeParameters.Block_reward
+
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b.Beneficiary = protocol.SerializeHashContent(validatorAcc.Address)
+	b.Hash = [32]byte{0x01}
+	data := blockData{block: b}
+
+	//The delegators make up 80% of the stake, the validator keeps 25% of their 80 coins
+	if err := distributeDelegationRewards(data); err != nil {
+		t.Fatalf("Distributing the rewards failed: %v\n", err)
+	}
+	if accA.Balance != balanceA+15 || accB.Balance != balanceB+45 || validatorAcc.Balance != 1040 {
+		t.Errorf("Unexpected rewards: %v, %v, %v\n", accA.Balance-balanceA, accB.Balance-balanceB, validatorAcc.Balance)
+	}
+	if storage.ReadDelegationPayouts(b.Hash) == nil {
+		t.Error("Payouts not stored.")
+	}
+
+	//The payouts are rolled back even if the delegations changed in the meantime
+	validatorAcc.Delegations = nil
+	distributeDelegationRewardsRollback(b)
+	if accA.Balance != balanceA || accB.Balance != balanceB || validatorAcc.Balance != 1100 {
+		t.Errorf("Rewards rollback failed: %v, %v, %v\n", accA.Balance, accB.Balance, validatorAcc.Balance)
+	}
+	if storage.ReadDelegationPayouts(b.Hash) != nil {
+		t.Error("Payouts not deleted after rollback.")
+	}
+}
@@ -0,0 +1,141 @@
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Checks that the header was signed by the validator acc with its commitment key and that the commitment proof
+//belongs to the height of the header.
+func verifySignedHeader(header protocol.SignedHeader, acc *protocol.Account) error {
+	commitmentPubKey, err := crypto.CreateRSAPubKeyFromBytes(acc.CommitmentKey)
+	if err != nil {
+		return errors.New("Invalid commitment key in account.")
+	}
+
+	if err := crypto.VerifyMessageWithRSAKey(commitmentPubKey, fmt.Sprint(header.Height), header.CommitmentProof); err != nil {
+		return errors.New(fmt.Sprintf("The commitment proof of block %x can not be verified.", header.Hash[0:8]))
+	}
+
+	if err := crypto.VerifyMessageWithRSAKey(commitmentPubKey, header.SigningMessage(), header.Signature); err != nil {
+		return errors.New(fmt.Sprintf("The signature of block %x can not be verified.", header.Hash[0:8]))
+	}
+
+	return nil
+}
+
+//Checks that both headers were signed by the offender and that they prove the offence. Conflicting chains can only be
+//proven if this node knows the chain of the higher block down to the height of the lower one.
+func verifyEvidence(tx *protocol.EvidenceTx, offenderAcc *protocol.Account) error {
+	header1, header2 := tx.Header1, tx.Header2
+	if header1.Hash == header2.Hash {
+		return errors.New("Conflicting block hashes are the same.")
+	}
+
+	for _, header := range []protocol.SignedHeader{header1, header2} {
+		if header.Beneficiary != tx.Offender {
+			return errors.New(fmt.Sprintf("Block %x was not proposed by the offender.", header.Hash[0:8]))
+		}
+		if err := verifySignedHeader(header, offenderAcc); err != nil {
+			return err
+		}
+	}
+
+	switch tx.Header {
+	case protocol.EVIDENCE_DOUBLE_PROPOSAL:
+		if header1.Height != header2.Height {
+			return errors.New("Blocks of a double proposal must have the same height.")
+		}
+	case protocol.EVIDENCE_CONFLICTING_CHAINS:
+		if header1.Height == header2.Height {
+			return errors.New("Blocks at the same height are a double proposal.")
+		}
+		if header1.Height > header2.Height {
+			header1, header2 = header2, header1
+		}
+		if uint64(header2.Height-header1.Height) >= activeParameters.Slashing_window_size {
+			return errors.New("Blocks are not within the slashing window.")
+		}
+
+		isAncestor, err := isAncestorBlock(header1, header2)
+		if err != nil {
+			return err
+		}
+		if isAncestor {
+			return errors.New("Blocks are on the same chain.")
+		}
+	default:
+		return errors.New(fmt.Sprintf("Invalid offence: %v", tx.Header))
+	}
+
+	return nil
+}
+
+//Walks back the chain of the higher block to the height of the lower one.
+func isAncestorBlock(lower, higher protocol.SignedHeader) (bool, error) {
+	hash, hashWithoutTx := higher.PrevHash, higher.PrevHashWithoutTx
+	for height := higher.Height - 1; height > lower.Height; height-- {
+		block := storage.ReadClosedBlock(hash)
+		if block == nil {
+			block = storage.ReadClosedBlockWithoutTx(hashWithoutTx)
+		}
+		if block == nil {
+			block = storage.ReadOpenBlock(hash)
+		}
+		if block == nil {
+			return false, errors.New(fmt.Sprintf("Block %x is unknown, the chains can't be compared.", hash[0:8]))
+		}
+
+		hash, hashWithoutTx = block.PrevHash, block.PrevHashWithoutTx
+	}
+
+	return hash == lower.Hash, nil
+}
+
+func checkEvidenceTx(tx *protocol.EvidenceTx, acc *protocol.Account, offenderAcc *protocol.Account) error {
+	//Transaction count need to match the state, preventing replay attacks.
+	if tx.TxCnt != acc.TxCnt {
+		return errors.New(fmt.Sprintf("Sender txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt).", tx.TxCnt, acc.TxCnt))
+	}
+
+	if tx.From == tx.Offender {
+		return errors.New("Validators can't report themselves.")
+	}
+
+	if tx.Fee > acc.Balance {
+		return errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Amount = %v, Fee = %v.", acc.Balance, 0, tx.Fee))
+	}
+
+	if acc.Balance+activeParameters.Slash_reward > MAX_MONEY {
+		return errors.New("Slash reward would lead to balance overflow at the sender account.")
+	}
+
+	//The same offence is only punished once
+	if _, found := storage.ReadEvidence(tx.EvidenceID()); found {
+		return errors.New("Offence was already punished.")
+	}
+
+	//Validators which were already slashed have nothing left to loose
+	if offenderAcc.StakedAmount == 0 && offenderAcc.UnbondingAmount == 0 {
+		return errors.New("Offender has no stake to slash.")
+	}
+
+	return verifyEvidence(tx, offenderAcc)
+}
+
+//The sender is rewarded with the slashing reward and pays the fee right away, the offender looses its locked stake,
+//also if it is still unbonding, and is removed from the validator set. The supply is accounted for by the caller, the
+//block preparation applies evidences to its state copy only.
+func applyEvidenceTx(tx *protocol.EvidenceTx, acc *protocol.Account, offenderAcc *protocol.Account) {
+	acc.Balance += activeParameters.Slash_reward
+	acc.Balance -= tx.Fee
+	acc.TxCnt += 1
+
+	offenderAcc.StakedAmount = 0
+	offenderAcc.UnbondingAmount = 0
+	offenderAcc.UnbondingHeight = 0
+	offenderAcc.IsStaking = false
+}
@@ -0,0 +1,177 @@
+package miner
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func signedHeader(height uint32, hash, prevHash [32]byte, beneficiary [32]byte, commKey *rsa.PrivateKey) protocol.SignedHeader {
+	header := protocol.SignedHeader{Height: height, Hash: hash, PrevHash: prevHash, Beneficiary: beneficiary}
+	header.CommitmentProof, _ = crypto.SignMessageWithRSAKey(commKey, fmt.Sprint(height))
+	header.Signature, _ = crypto.SignMessageWithRSAKey(commKey, header.SigningMessage())
+	return header
+}
+
+//Two blocks at the same height cost the offender its stake, also if it is unbonding already
+func TestEvidenceDoubleProposalAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	validatorHash := protocol.SerializeHashContent(validatorAcc.Address)
+	validatorAcc.StakedAmount = activeParameters.Staking_minimum
+	balanceA := accA.Balance
+
+	header1 := signedHeader(5, [32]byte{0x01}, [32]byte{0x10}, validatorHash, commPrivKey)
+	header2 := signedHeader(5, [32]byte{0x02}, [32]byte{0x10}, validatorHash, commPrivKey)
+
+	//Headers which were not signed by the offender don't prove anything
+	forged := signedHeader(5, [32]byte{0x03}, [32]byte{0x10}, validatorHash, CommPrivKeyAccB)
+	tx, _ := protocol.ConstrEvidenceTx(protocol.EVIDENCE_DOUBLE_PROPOSAL, 1, 0, accAHash, header1, forged, PrivKeyAccA)
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx}); err == nil {
+		t.Error("Evidence with a forged header accepted.")
+	}
+
+	tx, _ = protocol.ConstrEvidenceTx(protocol.EVIDENCE_CONFLICTING_CHAINS, 1, 0, accAHash, header1, header2, PrivKeyAccA)
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx}); err == nil {
+		t.Error("Blocks at the same height accepted as conflicting chains.")
+	}
+
+	tx, _ = protocol.ConstrEvidenceTx(protocol.EVIDENCE_DOUBLE_PROPOSAL, 1, 0, accAHash, header1, header2, PrivKeyAccA)
+	if !verify(tx) {
+		t.Error("Valid evidence could not be verified.")
+	}
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx}); err != nil {
+		t.Fatalf("Evidence rejected: %v\n", err)
+	}
+	if validatorAcc.IsStaking || validatorAcc.StakedAmount != 0 || accA.Balance != balanceA+activeParameters.Slash_reward-tx.Fee || accA.TxCnt != 1 {
+		t.Errorf("Evidence not applied: %v\n", validatorAcc)
+	}
+
+	//The same offence can't be punished twice, not even after the offender staked again
+	validatorAcc.StakedAmount = activeParameters.Staking_minimum
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	again, _ := protocol.ConstrEvidenceTx(protocol.EVIDENCE_DOUBLE_PROPOSAL, 1, 0, accBHash, header2, header1, PrivKeyAccB)
+	if err := evidenceStateChange([]*protocol.EvidenceTx{again}); err == nil {
+		t.Error("Offence punished twice.")
+	}
+	validatorAcc.StakedAmount = 0
+
+	evidenceStateChangeRollback([]*protocol.EvidenceTx{tx})
+	if !validatorAcc.IsStaking || validatorAcc.StakedAmount != activeParameters.Staking_minimum || accA.Balance != balanceA || accA.TxCnt != 0 {
+		t.Errorf("Evidence rollback failed: %v\n", validatorAcc)
+	}
+	if _, found := storage.ReadEvidence(tx.EvidenceID()); found {
+		t.Error("Evidence not deleted after rollback.")
+	}
+
+	//Stake which is unbonding can still be slashed
+	validatorAcc.IsStaking = false
+	validatorAcc.StakedAmount = 0
+	validatorAcc.UnbondingAmount = activeParameters.Staking_minimum
+	validatorAcc.UnbondingHeight = 100
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx}); err != nil {
+		t.Fatalf("Evidence against an unbonding validator rejected: %v\n", err)
+	}
+	if validatorAcc.IsUnbonding() || validatorAcc.UnbondingAmount != 0 {
+		t.Errorf("Unbonding stake not slashed: %v\n", validatorAcc)
+	}
+}
+
+//Blocks within the slashing window are only conflicting if the lower one is not an ancestor of the higher one
+func TestEvidenceConflictingChains(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	validatorHash := protocol.SerializeHashContent(validatorAcc.Address)
+	validatorAcc.StakedAmount = activeParameters.Staking_minimum
+
+	//Chain of this node: 0x10 (1) <- 0x20 (2)
+	for _, b := range []*protocol.Block{{Hash: [32]byte{0x10}, Height: 1}, {Hash: [32]byte{0x20}, PrevHash: [32]byte{0x10}, Height: 2}} {
+		storage.WriteClosedBlock(b)
+	}
+
+	onChain := signedHeader(1, [32]byte{0x10}, [32]byte{}, validatorHash, commPrivKey)
+	offChain := signedHeader(1, [32]byte{0x11}, [32]byte{}, validatorHash, commPrivKey)
+	higher := signedHeader(3, [32]byte{0x30}, [32]byte{0x20}, validatorHash, commPrivKey)
+
+	tx, _ := protocol.ConstrEvidenceTx(protocol.EVIDENCE_CONFLICTING_CHAINS, 1, 0, accAHash, onChain, higher, PrivKeyAccA)
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx}); err == nil {
+		t.Error("Blocks on the same chain accepted as evidence.")
+	}
+
+	//A chain this node doesn't know can't be compared
+	unknown := signedHeader(3, [32]byte{0x31}, [32]byte{0x21}, validatorHash, commPrivKey)
+	tx, _ = protocol.ConstrEvidenceTx(protocol.EVIDENCE_CONFLICTING_CHAINS, 1, 0, accAHash, offChain, unknown, PrivKeyAccA)
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx}); err == nil {
+		t.Error("Evidence accepted without knowing the chain.")
+	}
+
+	tx, _ = protocol.ConstrEvidenceTx(protocol.EVIDENCE_CONFLICTING_CHAINS, 1, 0, accAHash, higher, offChain, PrivKeyAccA)
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx}); err != nil {
+		t.Fatalf("Evidence of conflicting chains rejected: %v\n", err)
+	}
+	if validatorAcc.IsStaking || validatorAcc.StakedAmount != 0 {
+		t.Errorf("Evidence not applied: %v\n", validatorAcc)
+	}
+	evidenceStateChangeRollback([]*protocol.EvidenceTx{tx})
+
+	//A block takes evidences against several validators, but only one per offender. The supply only changes when the
+	//block is validated.
+	supplyChange = new(protocol.Supply)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 4)
+	if err := addTx(b, tx); err != nil {
+		t.Errorf("Block rejected a valid evidence: %v\n", err)
+	}
+	double, _ := protocol.ConstrEvidenceTx(protocol.EVIDENCE_DOUBLE_PROPOSAL, 1, 1, accAHash, offChain, onChain, PrivKeyAccA)
+	if err := addTx(b, double); err == nil {
+		t.Error("Block accepted two evidences against the same offender.")
+	}
+	if len(b.EvidenceTxData) != 1 || !validatorAcc.IsStaking || *supplyChange != (protocol.Supply{}) {
+		t.Error("Evidence not applied to the state copy only.")
+	}
+}
+
+//The fees of all evidences of a sender in a block together need to be covered by its balance
+func TestEvidenceCumulativeFees(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	validatorHash := protocol.SerializeHashContent(validatorAcc.Address)
+	validatorAcc.StakedAmount = activeParameters.Staking_minimum
+	accB.StakedAmount = activeParameters.Staking_minimum
+
+	//Without a slash reward the fees can only be paid from the balance
+	activeParameters.Slash_reward = 0
+	accA.Balance = 1
+
+	tx1, _ := protocol.ConstrEvidenceTx(protocol.EVIDENCE_DOUBLE_PROPOSAL, 1, 0, accAHash,
+		signedHeader(5, [32]byte{0x01}, [32]byte{0x10}, validatorHash, commPrivKey),
+		signedHeader(5, [32]byte{0x02}, [32]byte{0x10}, validatorHash, commPrivKey), PrivKeyAccA)
+	tx2, _ := protocol.ConstrEvidenceTx(protocol.EVIDENCE_DOUBLE_PROPOSAL, 1, 1, accAHash,
+		signedHeader(6, [32]byte{0x03}, [32]byte{0x11}, accBHash, CommPrivKeyAccB),
+		signedHeader(6, [32]byte{0x04}, [32]byte{0x11}, accBHash, CommPrivKeyAccB), PrivKeyAccA)
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx1, tx2}); err == nil {
+		t.Error("Evidences with fees exceeding the balance of the sender were accepted.")
+	}
+	if accA.Balance != 1 || accA.TxCnt != 0 || validatorAcc.StakedAmount == 0 || accB.StakedAmount == 0 {
+		t.Errorf("Rejected evidences changed the state: Balance = %v, TxCnt = %v\n", accA.Balance, accA.TxCnt)
+	}
+
+	//Each evidence on its own is fine, the supply change is reset for every block
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx2}); err == nil {
+		t.Error("Evidence with a wrong txCnt accepted.")
+	}
+	supplyChange = new(protocol.Supply)
+	if err := evidenceStateChange([]*protocol.EvidenceTx{tx1}); err != nil {
+		t.Fatalf("Evidence rejected: %v\n", err)
+	}
+	if accA.Balance != 0 || supplyChange.Burned != activeParameters.Staking_minimum {
+		t.Errorf("Evidence fee or burned stake not accounted for: Balance = %v, Burned = %v\n", accA.Balance, supplyChange.Burned)
+	}
+}
@@ -0,0 +1,117 @@
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Locked stake of the validator set, the quorum of a proposal is measured against it.
+func totalStake() (total uint64) {
+	for _, acc := range storage.State {
+		if acc.IsStaking {
+			total += acc.StakedAmount
+		}
+	}
+
+	return total
+}
+
+func hasQuorum(tally uint64) bool {
+	return tally > mulDiv(totalStake(), GOVERNANCE_QUORUM_NUMERATOR, GOVERNANCE_QUORUM_DENOMINATOR)
+}
+
+//Once validators locked stake, parameters are only changed by their proposals and votes. Before, there is no one to
+//vote and the root key configures the chain with ConfigTxs.
+func governanceActive() bool {
+	return totalStake() > 0
+}
+
+//Votes are counted with the current locked stake of the voters. Validators which left the validator set or were
+//slashed since they voted don't count anymore, such that stake unbonded and locked again in another account is not
+//counted twice.
+func proposalTally(proposal *protocol.Proposal) (tally uint64) {
+	for _, vote := range proposal.Votes {
+		if acc := storage.State[vote.Voter]; acc != nil && acc.IsStaking {
+			tally += acc.StakedAmount
+		}
+	}
+
+	return tally
+}
+
+//Checks a proposal or vote of acc in the block with the given height.
+func checkGovernanceTx(tx *protocol.GovernanceTx, acc *protocol.Account, height uint32) error {
+	//Transaction count need to match the state, preventing replay attacks.
+	if tx.TxCnt != acc.TxCnt {
+		return errors.New(fmt.Sprintf("Sender txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt).", tx.TxCnt, acc.TxCnt))
+	}
+
+	if tx.Fee > acc.Balance {
+		return errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Amount = %v, Fee = %v.", acc.Balance, 0, tx.Fee))
+	}
+
+	//Votes are weighted by the locked stake, accounts without one have no say
+	if !acc.IsStaking || acc.StakedAmount == 0 {
+		return errors.New("Only validators can propose and vote.")
+	}
+
+	switch tx.Header {
+	case protocol.GOVERNANCE_PROPOSAL:
+		if !parameterBoundsChecking(tx.ParameterId, tx.Value) {
+			return errors.New(fmt.Sprintf("Invalid parameter or value out of bounds: %v = %v", tx.ParameterId, tx.Value))
+		}
+		if tx.ActivationHeight <= height {
+			return errors.New(fmt.Sprintf("Activation height %v is not after block height %v.", tx.ActivationHeight, height))
+		}
+	case protocol.GOVERNANCE_VOTE:
+		proposal := storage.ReadProposal(tx.ProposalHash)
+		if proposal == nil {
+			return errors.New(fmt.Sprintf("Proposal %x does not exist.", tx.ProposalHash[0:8]))
+		}
+		if proposal.IsAccepted() {
+			return errors.New("Proposal was already accepted.")
+		}
+		if proposal.ActivationHeight <= height {
+			return errors.New("Voting period of the proposal is over.")
+		}
+		if proposal.HasVoted(tx.From) {
+			return errors.New("Validator already voted for the proposal.")
+		}
+	default:
+		return errors.New(fmt.Sprintf("Invalid governance operation: %v", tx.Header))
+	}
+
+	return nil
+}
+
+//Counts the stake of acc for the proposal of tx, a proposal is created first. Returns the changed proposal and its
+//hash, the proposal is not written back. The fee is taken right away, such that the next tx of the same validator is
+//checked against what is left.
+func applyGovernanceTx(tx *protocol.GovernanceTx, acc *protocol.Account) (proposalHash [32]byte, proposal *protocol.Proposal) {
+	acc.TxCnt += 1
+	acc.Balance -= tx.Fee
+
+	if tx.Header == protocol.GOVERNANCE_PROPOSAL {
+		proposalHash = tx.Hash()
+		proposal = &protocol.Proposal{
+			Proposer:         tx.From,
+			ParameterId:      tx.ParameterId,
+			Value:            tx.Value,
+			ActivationHeight: tx.ActivationHeight,
+		}
+	} else {
+		proposalHash = tx.ProposalHash
+		proposal = storage.ReadProposal(tx.ProposalHash)
+	}
+
+	proposal.Votes = append(proposal.Votes, protocol.ProposalVote{Voter: tx.From, Stake: acc.StakedAmount})
+	proposal.Tally = proposalTally(proposal)
+	if !proposal.IsAccepted() && hasQuorum(proposal.Tally) {
+		proposal.AcceptedBy = tx.Hash()
+		logger.Printf("Proposal %x accepted, parameter %v changes to %v at height %v.", proposalHash[0:8], proposal.ParameterId, proposal.Value, proposal.ActivationHeight)
+	}
+
+	return proposalHash, proposal
+}
@@ -0,0 +1,204 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//A proposal is accepted once more than two thirds of the locked stake voted for it and is in force from its height on
+func TestGovernanceProposalVoteAndActivation(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	rootHash := protocol.SerializeHashContent(rootAcc.Address)
+
+	//400 coins are locked, the quorum is reached with more than 266
+	accA.IsStaking, accA.StakedAmount = true, 200
+	accB.IsStaking, accB.StakedAmount = true, 100
+	validatorAcc.StakedAmount = 100
+	feeMinimum := activeParameters.Fee_minimum
+
+	//Accounts without locked stake have no say
+	rejected, _ := protocol.ConstrGovernanceProposal(1, 0, rootHash, protocol.FEE_MINIMUM_ID, feeMinimum+1, 5, PrivKeyRoot)
+	if err := governanceStateChange([]*protocol.GovernanceTx{rejected}, 1); err == nil {
+		t.Error("Proposal of an account without stake accepted.")
+	}
+
+	//Proposals need to be within the parameter bounds and activated in the future
+	rejected, _ = protocol.ConstrGovernanceProposal(1, 0, accAHash, protocol.FEE_MINIMUM_ID, feeMinimum+1, 1, PrivKeyAccA)
+	if err := governanceStateChange([]*protocol.GovernanceTx{rejected}, 1); err == nil {
+		t.Error("Proposal activated in the past accepted.")
+	}
+	rejected, _ = protocol.ConstrGovernanceProposal(1, 0, accAHash, protocol.BLOCK_SIZE_ID, 0, 5, PrivKeyAccA)
+	if err := governanceStateChange([]*protocol.GovernanceTx{rejected}, 1); err == nil {
+		t.Error("Proposal out of bounds accepted.")
+	}
+
+	proposal, _ := protocol.ConstrGovernanceProposal(1, 0, accAHash, protocol.FEE_MINIMUM_ID, feeMinimum+1, 5, PrivKeyAccA)
+	if !verify(proposal) {
+		t.Error("Valid proposal could not be verified.")
+	}
+	vote, _ := protocol.ConstrGovernanceVote(1, 0, accBHash, proposal.Hash(), PrivKeyAccB)
+	if !verify(vote) {
+		t.Error("Valid vote could not be verified.")
+	}
+
+	//The vote of the proposer does not reach the quorum on its own
+	if err := governanceStateChange([]*protocol.GovernanceTx{proposal}, 1); err != nil {
+		t.Fatalf("Proposal rejected: %v\n", err)
+	}
+	if p := storage.ReadProposal(proposal.Hash()); p == nil || p.Tally != 200 || p.IsAccepted() || accA.TxCnt != 1 {
+		t.Fatalf("Proposal not stored: %v\n", p)
+	}
+
+	//A vote after the activation height is too late, a second vote of the proposer is not counted
+	if err := governanceStateChange([]*protocol.GovernanceTx{vote}, 5); err == nil {
+		t.Error("Vote after the activation height accepted.")
+	}
+	double, _ := protocol.ConstrGovernanceVote(1, 1, accAHash, proposal.Hash(), PrivKeyAccA)
+	if err := governanceStateChange([]*protocol.GovernanceTx{double}, 2); err == nil {
+		t.Error("Second vote of a validator accepted.")
+	}
+
+	if err := governanceStateChange([]*protocol.GovernanceTx{vote}, 2); err != nil {
+		t.Fatalf("Vote rejected: %v\n", err)
+	}
+	if p := storage.ReadProposal(proposal.Hash()); p == nil || p.Tally != 300 || p.AcceptedBy != vote.Hash() || accB.TxCnt != 1 {
+		t.Fatalf("Proposal not accepted: %v\n", p)
+	}
+
+	//The accepted proposal does not change anything before its activation height, it is in force from there on
+	configStateChange(nil, [32]byte{0x03}, 3)
+	if activeParameters.Fee_minimum != feeMinimum || len(parameterSlice) != 1 {
+		t.Errorf("Proposal activated too early: %v\n", *activeParameters)
+	}
+	configStateChange(nil, [32]byte{0x04}, 4)
+	if activeParameters.Fee_minimum != feeMinimum+1 || len(parameterSlice) != 2 {
+		t.Errorf("Proposal not activated: %v\n", *activeParameters)
+	}
+
+	configStateChangeRollback(nil, [32]byte{0x04})
+	if activeParameters.Fee_minimum != feeMinimum || len(parameterSlice) != 1 {
+		t.Errorf("Activation not rolled back: %v\n", *activeParameters)
+	}
+
+	//Rolling back the vote revokes the acceptance, rolling back the proposal deletes it
+	governanceStateChangeRollback([]*protocol.GovernanceTx{vote})
+	if p := storage.ReadProposal(proposal.Hash()); p == nil || p.Tally != 200 || p.IsAccepted() || p.HasVoted(accBHash) || accB.TxCnt != 0 {
+		t.Errorf("Vote not rolled back: %v\n", p)
+	}
+	governanceStateChangeRollback([]*protocol.GovernanceTx{proposal})
+	if storage.ReadProposal(proposal.Hash()) != nil || accA.TxCnt != 0 {
+		t.Error("Proposal not deleted after rollback.")
+	}
+}
+
+//Once stake is locked the root key can't change parameters anymore
+func TestGovernanceReplacesConfigTx(t *testing.T) {
+	cleanAndPrepare()
+
+	tx, _ := protocol.ConstrConfigTx(0, protocol.FEE_MINIMUM_ID, activeParameters.Fee_minimum+1, 1, 0, 0, PrivKeyRoot)
+	if err := checkConfigTx(tx, 1); err != nil {
+		t.Errorf("ConfigTx rejected before any stake was locked: %v\n", err)
+	}
+
+	validatorAcc.StakedAmount = activeParameters.Staking_minimum
+	if err := checkConfigTx(tx, 1); err == nil {
+		t.Error("ConfigTx accepted while governance is active.")
+	}
+	scheduled, _ := protocol.ConstrConfigTx(0, protocol.FEE_MINIMUM_ID, activeParameters.Fee_minimum+1, 1, 0, 5, PrivKeyRoot)
+	if err := checkConfigTx(scheduled, 1); err == nil {
+		t.Error("Scheduled ConfigTx accepted while governance is active.")
+	}
+}
+
+//Votes count with the current stake of the voters, slashed or unbonded voters lose their weight
+func TestGovernanceRetally(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	//400 coins are locked, the quorum is reached with more than 266
+	accA.IsStaking, accA.StakedAmount = true, 200
+	accB.IsStaking, accB.StakedAmount = true, 100
+	validatorAcc.StakedAmount = 100
+	feeMinimum := activeParameters.Fee_minimum
+
+	proposal, _ := protocol.ConstrGovernanceProposal(1, 0, accAHash, protocol.FEE_MINIMUM_ID, feeMinimum+1, 5, PrivKeyAccA)
+	if err := governanceStateChange([]*protocol.GovernanceTx{proposal}, 1); err != nil {
+		t.Fatalf("Proposal rejected: %v\n", err)
+	}
+
+	//accA unbonds and locks its stake again in accB, which votes with it: the stake is only counted once
+	accA.IsStaking, accA.StakedAmount = false, 0
+	accB.StakedAmount = 300
+	vote, _ := protocol.ConstrGovernanceVote(1, 0, accBHash, proposal.Hash(), PrivKeyAccB)
+	if err := governanceStateChange([]*protocol.GovernanceTx{vote}, 2); err != nil {
+		t.Fatalf("Vote rejected: %v\n", err)
+	}
+	if p := storage.ReadProposal(proposal.Hash()); p == nil || p.Tally != 300 || !p.IsAccepted() {
+		t.Fatalf("Recycled stake counted twice or the vote not counted: %v\n", p)
+	}
+
+	//accB is slashed before the activation height, the proposal loses its quorum and is not activated
+	accB.IsStaking, accB.StakedAmount = false, 0
+	configStateChange(nil, [32]byte{0x04}, 4)
+	if activeParameters.Fee_minimum != feeMinimum || len(parameterSlice) != 1 {
+		t.Errorf("Proposal without quorum activated: %v\n", *activeParameters)
+	}
+
+	//Rolling back the vote counts the remaining votes with the current stake
+	accA.IsStaking, accA.StakedAmount = true, 200
+	governanceStateChangeRollback([]*protocol.GovernanceTx{vote})
+	if p := storage.ReadProposal(proposal.Hash()); p == nil || p.Tally != 200 || p.IsAccepted() {
+		t.Errorf("Vote not rolled back: %v\n", p)
+	}
+}
+
+//The fees of all proposals and votes of a validator in a block together need to be covered by its balance
+func TestGovernanceCumulativeFees(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	accA.IsStaking, accA.StakedAmount = true, 200
+	accB.IsStaking, accB.StakedAmount = true, 100
+	validatorAcc.StakedAmount = 100
+	feeMinimum := activeParameters.Fee_minimum
+
+	proposal1, _ := protocol.ConstrGovernanceProposal(1, 0, accAHash, protocol.FEE_MINIMUM_ID, feeMinimum+1, 5, PrivKeyAccA)
+	proposal2, _ := protocol.ConstrGovernanceProposal(1, 1, accAHash, protocol.FEE_MINIMUM_ID, feeMinimum+2, 5, PrivKeyAccA)
+	if err := governanceStateChange([]*protocol.GovernanceTx{proposal1, proposal2}, 1); err != nil {
+		t.Fatalf("Proposals rejected: %v\n", err)
+	}
+
+	//Each vote on its own is covered by the balance, both together are not
+	accB.Balance = 5
+	vote1, _ := protocol.ConstrGovernanceVote(3, 0, accBHash, proposal1.Hash(), PrivKeyAccB)
+	vote2, _ := protocol.ConstrGovernanceVote(3, 1, accBHash, proposal2.Hash(), PrivKeyAccB)
+	if err := governanceStateChange([]*protocol.GovernanceTx{vote1, vote2}, 2); err == nil {
+		t.Error("Votes with fees exceeding the balance of the validator were accepted.")
+	}
+	if accB.Balance != 5 || accB.TxCnt != 0 {
+		t.Errorf("Rejected votes changed the state: Balance = %v, TxCnt = %v\n", accB.Balance, accB.TxCnt)
+	}
+	if p := storage.ReadProposal(proposal1.Hash()); p == nil || p.HasVoted(accBHash) {
+		t.Errorf("Vote of the rejected votes was counted: %v\n", p)
+	}
+
+	if err := governanceStateChange([]*protocol.GovernanceTx{vote1}, 2); err != nil {
+		t.Fatalf("Vote rejected: %v\n", err)
+	}
+	if accB.Balance != 2 {
+		t.Errorf("Vote fee not taken from the validator: %v vs. 2\n", accB.Balance)
+	}
+	governanceStateChangeRollback([]*protocol.GovernanceTx{vote1})
+	if accB.Balance != 5 || accB.TxCnt != 0 {
+		t.Error("Rollback failed!")
+	}
+}
@@ -0,0 +1,305 @@
+package miner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func TestConstrIotBatchTx(t *testing.T) {
+	cleanAndPrepare()
+
+	devicePrivKey, deviceHash := createContractIssuer(1000)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	tx, err := protocol.ConstrIotBatchTx(0x01, 1, 0, deviceHash, accBHash, createIotBatchReadings(10, 0), devicePrivKey)
+	if err != nil {
+		t.Fatalf("Could not create IoT batch: %v\n", err)
+	}
+	if !verify(tx) {
+		t.Error("Valid IoT batch could not be verified.")
+	}
+
+	//The signature only covers the readings root, changed readings must be detected
+	tx.Readings[3].Data = []byte{42}
+	if verify(tx) {
+		t.Error("IoT batch with tampered reading was verified.")
+	}
+
+	readings := createIotBatchReadings(3, 0)
+	readings[2].Timestamp = readings[0].Timestamp
+	if _, err := protocol.ConstrIotBatchTx(0x01, 1, 0, deviceHash, accBHash, readings, devicePrivKey); err == nil {
+		t.Error("IoT batch with unordered readings was created.")
+	}
+	if _, err := protocol.ConstrIotBatchTx(0x01, 1, 0, deviceHash, accBHash, nil, devicePrivKey); err == nil {
+		t.Error("Empty IoT batch was created.")
+	}
+}
+
+func TestAddIotBatchTxAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	devicePrivKey, deviceHash := createContractIssuer(1000)
+	deviceAcc, _ := storage.GetAccount(deviceHash)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	minerHash := protocol.SerializeHashContent(validatorAcc.Address)
+
+	tx, _ := protocol.ConstrIotBatchTx(0x01, 5, 0, deviceHash, accBHash, createIotBatchReadings(100, 0), devicePrivKey)
+
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	if err := addTx(b, tx); err != nil {
+		t.Fatalf("Block rejected a valid IoT batch: %v\n", err)
+	}
+	if len(b.IoTBatchTxData) != 1 || b.StateCopy[deviceHash].TxCnt != 1 || b.StateCopy[deviceHash].Balance != 995 {
+		t.Errorf("IoT batch not added correctly: %v hashes, state copy %v\n", len(b.IoTBatchTxData), b.StateCopy[deviceHash])
+	}
+	if err := addTx(b, tx); err == nil {
+		t.Error("Block accepted the same IoT batch twice.")
+	}
+
+	balanceMiner := validatorAcc.Balance
+	if err := iotBatchStateChange([]*protocol.IotBatchTx{tx}); err != nil {
+		t.Errorf("IoT batch state change failed: %v\n", err)
+	}
+	if err := collectTxFees(nil, nil, nil, nil, nil, nil, nil, []*protocol.IotBatchTx{tx}, nil, nil, nil, minerHash); err != nil {
+		t.Errorf("Collecting IoT batch fee failed: %v\n", err)
+	}
+	if deviceAcc.TxCnt != 1 || deviceAcc.Balance != 995 || validatorAcc.Balance != balanceMiner+txTip(5) {
+		t.Errorf("IoT batch not applied: device %v, miner balance %v\n", deviceAcc, validatorAcc.Balance)
+	}
+
+	//Replaying the batch is rejected
+	if err := iotBatchStateChange([]*protocol.IotBatchTx{tx}); err == nil {
+		t.Error("Replayed IoT batch was accepted.")
+	}
+
+	collectTxFeesRollback(nil, nil, nil, nil, nil, nil, []*protocol.IotBatchTx{tx}, nil, nil, nil, minerHash)
+	iotBatchStateChangeRollback([]*protocol.IotBatchTx{tx})
+	if deviceAcc.TxCnt != 0 || deviceAcc.Balance != 1000 || validatorAcc.Balance != balanceMiner {
+		t.Errorf("IoT batch rollback failed: device %v, miner balance %v\n", deviceAcc, validatorAcc.Balance)
+	}
+}
+
+//The fees of all batches of a device in a block together need to be covered by its balance
+func TestIotBatchCumulativeFees(t *testing.T) {
+	cleanAndPrepare()
+
+	devicePrivKey, deviceHash := createContractIssuer(100)
+	deviceAcc, _ := storage.GetAccount(deviceHash)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	tx1, _ := protocol.ConstrIotBatchTx(0x01, 60, 0, deviceHash, accBHash, createIotBatchReadings(10, 0), devicePrivKey)
+	tx2, _ := protocol.ConstrIotBatchTx(0x01, 60, 1, deviceHash, accBHash, createIotBatchReadings(10, 10), devicePrivKey)
+	if err := iotBatchStateChange([]*protocol.IotBatchTx{tx1, tx2}); err == nil {
+		t.Error("IoT batches with fees exceeding the balance of the device were accepted.")
+	}
+	if deviceAcc.Balance != 100 || deviceAcc.TxCnt != 0 {
+		t.Errorf("Rejected IoT batches changed the state: %v\n", deviceAcc)
+	}
+}
+
+//Every reading of a batch can be queried and proven on its own
+func TestQueryIotBatchReadings(t *testing.T) {
+	cleanAndPrepare()
+
+	devicePrivKey, deviceHash := createContractIssuer(1000)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	tx, _ := protocol.ConstrIotBatchTx(0x01, 1, 0, deviceHash, accBHash, createIotBatchReadings(7, 100), devicePrivKey)
+	single := &protocol.IotTx{Header: 0x01, TxCnt: 1, From: deviceHash, To: accBHash, Data: []byte{1}, Fee: 1}
+
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b.Timestamp = 103
+	b.IoTTxData = append(b.IoTTxData, single.Hash())
+	b.IoTBatchTxData = append(b.IoTBatchTxData, tx.Hash())
+	b.MerkleRoot = protocol.BuildMerkleTree(b).MerkleRoot()
+	b.Hash = b.HashBlock()
+
+	storage.WriteClosedTx(tx)
+	storage.WriteClosedTx(single)
+	storage.WriteIotBatchIndex(tx, b)
+	storage.WriteIotIndex(single, b)
+	storage.WriteClosedBlock(b)
+
+	result, err := storage.QueryIotData(&protocol.IotQuery{Account: deviceHash, From: 102, To: 105, WithProof: true})
+	if err != nil {
+		t.Fatalf("IoT data query failed: %v\n", err)
+	}
+	if len(result.Readings) != 5 {
+		t.Fatalf("Query returned %v readings, expected 5\n", len(result.Readings))
+	}
+	for i, reading := range result.Readings {
+		if !reading.VerifyProof() {
+			t.Errorf("Merkle proof of reading %v invalid.\n", i)
+		}
+		if reading.BatchTx != nil && (reading.BatchTx.Readings != nil || reading.Reading.Timestamp != reading.Timestamp) {
+			t.Errorf("Batch reading %v not returned on its own.\n", i)
+		}
+	}
+
+	//A reading moved to another position in the batch does not verify
+	for _, reading := range result.Readings {
+		if reading.BatchTx != nil {
+			reading.ReadingIndex++
+			if reading.VerifyProof() {
+				t.Error("Batch reading with wrong index verified against its proof.")
+			}
+			break
+		}
+	}
+
+	storage.DeleteIotBatchIndex(tx, b)
+	result, _ = storage.QueryIotData(&protocol.IotQuery{Account: deviceHash, From: 0, To: 1000})
+	if len(result.Readings) != 1 || result.Readings[0].Tx == nil {
+		t.Errorf("Index contains %v readings after rollback, expected the single IoT tx only\n", len(result.Readings))
+	}
+}
+
+func createIotBatchReadings(n int, start int64) (readings []protocol.IotBatchReading) {
+	for i := 0; i < n; i++ {
+		readings = append(readings, protocol.IotBatchReading{Timestamp: start + int64(i), Data: []byte{byte(i), byte(i >> 8)}})
+	}
+	return readings
+}
+
+//Readings before 1970 are found and come first
+func TestQueryIotNegativeTimestamps(t *testing.T) {
+	cleanAndPrepare()
+
+	devicePrivKey, deviceHash := createContractIssuer(1000)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	tx, _ := protocol.ConstrIotBatchTx(0x01, 1, 0, deviceHash, accBHash, createIotBatchReadings(4, -2), devicePrivKey)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b.IoTBatchTxData = append(b.IoTBatchTxData, tx.Hash())
+	b.MerkleRoot = protocol.BuildMerkleTree(b).MerkleRoot()
+	b.Hash = b.HashBlock()
+
+	storage.WriteClosedTx(tx)
+	storage.WriteIotBatchIndex(tx, b)
+	storage.WriteClosedBlock(b)
+
+	result, _ := storage.QueryIotData(&protocol.IotQuery{Account: deviceHash, From: -10, To: 10})
+	if len(result.Readings) != 4 {
+		t.Fatalf("Query returned %v readings, expected 4\n", len(result.Readings))
+	}
+	for i, reading := range result.Readings {
+		if reading.Timestamp != int64(i)-2 {
+			t.Errorf("Reading %v has timestamp %v, expected %v\n", i, reading.Timestamp, int64(i)-2)
+		}
+	}
+
+	result, _ = storage.QueryIotData(&protocol.IotQuery{Account: deviceHash, From: 0, To: 10})
+	if len(result.Readings) != 2 {
+		t.Errorf("Query from 0 returned %v readings, expected 2\n", len(result.Readings))
+	}
+}
+
+//All batches of a device end up in one IotAggTx, the readings can still be proven on their own
+func TestIotBatchAggregation(t *testing.T) {
+	cleanAndPrepare()
+
+	devicePrivKey, deviceHash := createContractIssuer(1000)
+	deviceAcc, _ := storage.GetAccount(deviceHash)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	var batches []*protocol.IotBatchTx
+	var sizeIoTData uint64
+	for i := 0; i < 3; i++ {
+		tx, _ := protocol.ConstrIotBatchTx(0x01, 5, uint32(i), deviceHash, accBHash, createIotBatchReadings(5, int64(i*10)), devicePrivKey)
+		storage.WriteOpenTx(tx)
+		batches = append(batches, tx)
+		sizeIoTData += tx.Size()
+	}
+
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	prepareBlock(b)
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Block finalization failed: %v\n", err)
+	}
+	if b.NrIoTAggTx != 1 || b.NrIoTBatchTx != 0 {
+		t.Fatalf("Block contains %v IotAggTxs and %v IotBatchTxs, expected 1 and 0\n", b.NrIoTAggTx, b.NrIoTBatchTx)
+	}
+	if b.SizeIoTData != sizeIoTData {
+		t.Errorf("Block counts %v bytes of IoT data, expected %v\n", b.SizeIoTData, sizeIoTData)
+	}
+
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Block with aggregated IoT batches was rejected: %v\n", err)
+	}
+	if deviceAcc.TxCnt != 3 || deviceAcc.Balance != 985 {
+		t.Errorf("Aggregated IoT batches not applied: %v\n", deviceAcc)
+	}
+	if storage.ReadClosedTx(b.IoTAggTxData[0]) == nil || storage.ReadOpenTx(b.IoTAggTxData[0]) != nil {
+		t.Error("IotAggTx was not closed.")
+	}
+
+	result, err := storage.QueryIotData(&protocol.IotQuery{Account: deviceHash, From: 0, To: 100, WithProof: true})
+	if err != nil {
+		t.Fatalf("IoT data query failed: %v\n", err)
+	}
+	if len(result.Readings) != 15 {
+		t.Fatalf("Query returned %v readings, expected 15\n", len(result.Readings))
+	}
+	for i, reading := range result.Readings {
+		if !reading.VerifyProof() {
+			t.Errorf("Merkle proof of aggregated reading %v invalid.\n", i)
+		}
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Rollback failed: %v\n", err)
+	}
+	if deviceAcc.TxCnt != 0 || deviceAcc.Balance != 1000 {
+		t.Errorf("Aggregated IoT batches not rolled back: %v\n", deviceAcc)
+	}
+	if storage.ReadClosedTx(b.IoTAggTxData[0]) != nil {
+		t.Error("IotAggTx still closed after rollback.")
+	}
+	for _, tx := range batches {
+		if storage.ReadOpenTx(tx.Hash()) == nil {
+			t.Errorf("Aggregated IoT batch %x not reopened.\n", tx.Hash())
+		}
+	}
+}
+
+//The IoT data size of a block has to match its batches and readings can't be taken after the block
+func TestIotBatchSizeAndTimestamps(t *testing.T) {
+	cleanAndPrepare()
+
+	devicePrivKey, deviceHash := createContractIssuer(1000)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	future, _ := protocol.ConstrIotBatchTx(0x01, 5, 0, deviceHash, accBHash, createIotBatchReadings(3, time.Now().Unix()+1000), devicePrivKey)
+	storage.WriteOpenTx(future)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	prepareBlock(b)
+	if b.NrIoTBatchTx != 0 || storage.ReadOpenTx(future.Hash()) == nil {
+		t.Error("IoT batch with readings from the future was not kept in the mempool.")
+	}
+
+	if err := addTx(b, future); err != nil {
+		t.Fatalf("Block rejected IoT batch: %v\n", err)
+	}
+	finalizeBlock(b)
+	if err := validate(b, false); err == nil {
+		t.Error("Block with readings after its timestamp was accepted.")
+	}
+
+	tx, _ := protocol.ConstrIotBatchTx(0x01, 5, 0, deviceHash, accBHash, createIotBatchReadings(3, 0), devicePrivKey)
+	b = newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	if err := addTx(b, tx); err != nil {
+		t.Fatalf("Block rejected IoT batch: %v\n", err)
+	}
+	if b.SizeIoTData != uint64(len(tx.Encode())) {
+		t.Errorf("Block counts %v bytes of IoT data, expected %v\n", b.SizeIoTData, len(tx.Encode()))
+	}
+	b.SizeIoTData--
+	finalizeBlock(b)
+	if err := validate(b, false); err == nil {
+		t.Error("Block with wrong IoT data size was accepted.")
+	}
+}
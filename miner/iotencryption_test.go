@@ -0,0 +1,60 @@
+package miner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func TestEncryptedIotTx(t *testing.T) {
+	cleanAndPrepare()
+
+	devicePrivKey, deviceHash := createContractIssuer(1000)
+	receiverPrivKey, receiverHash := createContractIssuer(0)
+	otherPrivKey, _ := createContractIssuer(0)
+	device, receiver := storage.State[deviceHash], storage.State[receiverHash]
+
+	data := []byte("temperature: 21.5")
+	tx, err := protocol.ConstrEncryptedIotTx(0x01, 1, 0, protocol.SerializeHashContentIoT(device.Address), protocol.SerializeHashContentIoT(receiver.Address), receiver.Address, devicePrivKey, data)
+	if err != nil {
+		t.Fatalf("Could not create encrypted IoT tx: %v\n", err)
+	}
+	if !tx.IsEncrypted() || bytes.Contains(tx.Data, data) {
+		t.Error("IoT data not encrypted.")
+	}
+
+	//Devices sign over the IoT address hashes, the miner looks the accounts up by their state hashes
+	tx.From, tx.To = deviceHash, receiverHash
+
+	//Miners verify the signature over the ciphertext without knowing the plaintext
+	if !verify(tx) {
+		t.Error("Valid encrypted IoT tx could not be verified.")
+	}
+
+	decrypted, err := crypto.DecryptIoTData(receiverPrivKey, tx.Data)
+	if err != nil || !bytes.Equal(decrypted, data) {
+		t.Errorf("Receiver could not decrypt IoT data: %v (%v)\n", decrypted, err)
+	}
+	if _, err := crypto.DecryptIoTData(otherPrivKey, tx.Data); err == nil {
+		t.Error("IoT data could be decrypted with another key.")
+	}
+	if _, err := crypto.DecryptIoTData(devicePrivKey, tx.Data); err == nil {
+		t.Error("IoT data could be decrypted by the sender.")
+	}
+
+	tampered := *tx
+	tampered.Data = append([]byte{}, tx.Data...)
+	tampered.Data[len(tampered.Data)-1] ^= 0x01
+	if _, err := crypto.DecryptIoTData(receiverPrivKey, tampered.Data); err == nil {
+		t.Error("Tampered IoT data could be decrypted.")
+	}
+
+	short, _ := protocol.ConstrIotTx(0x01|protocol.IOT_ENCRYPTED, 1, 0, protocol.SerializeHashContentIoT(device.Address), protocol.SerializeHashContentIoT(receiver.Address), devicePrivKey, []byte{1, 2, 3})
+	short.From, short.To = deviceHash, receiverHash
+	if verify(short) {
+		t.Error("Encrypted IoT tx without ciphertext was verified.")
+	}
+}
@@ -0,0 +1,104 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Readings of a device are found by time range, are paged and come with a valid Merkle proof
+func TestQueryIotData(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	var blocks []*protocol.Block
+	var txCnt uint32
+	for height := uint32(1); height <= 3; height++ {
+		b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, height)
+		b.Timestamp = int64(height) * 1000
+		var iots []*protocol.IotTx
+		for i := 0; i < 3; i++ {
+			tx := &protocol.IotTx{Header: 0x01, TxCnt: txCnt, From: accAHash, To: accBHash, Data: []byte{byte(txCnt)}, Fee: 1}
+			iots = append(iots, tx)
+			b.IoTTxData = append(b.IoTTxData, tx.Hash())
+			txCnt++
+		}
+		b.MerkleRoot = protocol.BuildMerkleTree(b).MerkleRoot()
+		b.Hash = b.HashBlock()
+
+		for _, tx := range iots {
+			storage.WriteClosedTx(tx)
+			storage.WriteIotIndex(tx, b)
+		}
+		storage.WriteClosedBlock(b)
+		blocks = append(blocks, b)
+	}
+
+	//Only the readings of the second and third block are in the range
+	query := &protocol.IotQuery{Account: accAHash, From: 1500, To: 3000, Limit: 4, WithProof: true}
+	result, err := storage.QueryIotData(query)
+	if err != nil {
+		t.Fatalf("IoT data query failed: %v\n", err)
+	}
+	if len(result.Readings) != 4 || result.Cursor == nil {
+		t.Fatalf("Wrong number of readings: %v, cursor %x\n", len(result.Readings), result.Cursor)
+	}
+	for i, reading := range result.Readings {
+		if reading.Timestamp < 1500 || reading.Timestamp > 3000 {
+			t.Errorf("Reading %v outside of the time range: %v\n", i, reading.Timestamp)
+		}
+		if i > 0 && reading.Timestamp < result.Readings[i-1].Timestamp {
+			t.Error("Readings are not in chronological order.")
+		}
+		if !reading.VerifyProof() {
+			t.Errorf("Merkle proof of reading %v invalid.\n", i)
+		}
+		block := blocks[reading.Height-1]
+		if reading.BlockHash != block.Hash || reading.MerkleRoot != block.MerkleRoot {
+			t.Errorf("Reading %v points to the wrong block.\n", i)
+		}
+	}
+
+	//Next page, the last one
+	last := result.Readings[3]
+	query.Cursor = result.Cursor
+	result, _ = storage.QueryIotData(query)
+	if len(result.Readings) != 2 || result.Cursor != nil {
+		t.Fatalf("Second page contains %v readings, expected 2 and no cursor\n", len(result.Readings))
+	}
+	if result.Readings[0].Timestamp < last.Timestamp || result.Readings[0].Tx.Hash() == last.Tx.Hash() {
+		t.Error("Second page does not continue after the first one.")
+	}
+
+	//A tampered reading does not verify against the proof
+	result.Readings[0].Tx.Data = []byte{42}
+	if result.Readings[0].VerifyProof() {
+		t.Error("Tampered reading verified against its Merkle proof.")
+	}
+
+	//The receiver index contains the same readings
+	result, _ = storage.QueryIotData(&protocol.IotQuery{Account: accBHash, ByReceiver: true, From: 0, To: 3000})
+	if len(result.Readings) != 9 || result.Readings[0].Proof != nil {
+		t.Errorf("Receiver query returned %v readings, expected 9 without proof\n", len(result.Readings))
+	}
+
+	if _, err := storage.QueryIotData(&protocol.IotQuery{Account: accAHash, From: 10, To: 5}); err == nil {
+		t.Error("IoT query with invalid time range was accepted.")
+	}
+	if _, err := storage.QueryIotData(&protocol.IotQuery{Account: accAHash, From: 0, To: 3000, Cursor: []byte{1}}); err == nil {
+		t.Error("IoT query with invalid cursor was accepted.")
+	}
+
+	//Rolled back txs are removed from the index
+	for _, hash := range blocks[2].IoTTxData {
+		storage.DeleteIotIndex(storage.ReadClosedTx(hash).(*protocol.IotTx), blocks[2])
+	}
+	result, _ = storage.QueryIotData(&protocol.IotQuery{Account: accAHash, From: 0, To: 3000})
+	if len(result.Readings) != 6 {
+		t.Errorf("Index contains %v readings after rollback, expected 6\n", len(result.Readings))
+	}
+}
@@ -0,0 +1,186 @@
+package miner
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestIotTxStateChange(t *testing.T) {
+	cleanAndPrepare()
+
+	randVar := rand.New(rand.NewSource(time.Now().Unix()))
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	var iots []*protocol.IotTx
+	loopMax := int(randVar.Uint32()%100) + 1
+	for i := 0; i < loopMax; i++ {
+		iots = append(iots, &protocol.IotTx{Header: 0x01, TxCnt: uint32(i), From: accAHash, To: accBHash, Data: []byte{byte(i)}, Fee: randVar.Uint64()%100 + 1})
+	}
+
+	if err := iotStateChange(iots); err != nil {
+		t.Errorf("IoT state change failed: %v\n", err)
+	}
+	if accA.TxCnt != uint32(loopMax) {
+		t.Errorf("Sender txCnt not updated: %v vs. %v\n", accA.TxCnt, loopMax)
+	}
+
+	//Replaying an already applied IoT tx is rejected and does not change the state
+	balanceA := accA.Balance
+	if err := iotStateChange([]*protocol.IotTx{iots[0]}); err == nil {
+		t.Error("Replayed IoT tx was accepted.")
+	}
+	if accA.TxCnt != uint32(loopMax) || accA.Balance != balanceA {
+		t.Error("Rejected IoT tx changed the state.")
+	}
+
+	//A failing tx in the middle of the slice reverts the txs applied before
+	tx1 := &protocol.IotTx{Header: 0x01, TxCnt: uint32(loopMax), From: accAHash, To: accBHash, Fee: 1}
+	tx2 := &protocol.IotTx{Header: 0x01, TxCnt: uint32(loopMax + 5), From: accAHash, To: accBHash, Fee: 1}
+	if err := iotStateChange([]*protocol.IotTx{tx1, tx2}); err == nil {
+		t.Error("IoT tx with wrong txCnt was accepted.")
+	}
+	if accA.TxCnt != uint32(loopMax) {
+		t.Errorf("Partially applied IoT txs were not reverted: %v vs. %v\n", accA.TxCnt, loopMax)
+	}
+
+	//Fee larger than the balance
+	tx3 := &protocol.IotTx{Header: 0x01, TxCnt: uint32(loopMax), From: accAHash, To: accBHash, Fee: accA.Balance + 1}
+	if err := iotStateChange([]*protocol.IotTx{tx3}); err == nil {
+		t.Error("IoT tx with insufficient funds was accepted.")
+	}
+}
+
+//The fees of all IoT txs of a sender in a block together need to be covered by its balance
+func TestIotTxCumulativeFees(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	accA.Balance = 100
+	accA.TxCnt = 0
+
+	tx1 := &protocol.IotTx{Header: 0x01, TxCnt: 0, From: accAHash, To: accBHash, Data: []byte{1}, Fee: 60}
+	tx2 := &protocol.IotTx{Header: 0x01, TxCnt: 1, From: accAHash, To: accBHash, Data: []byte{2}, Fee: 60}
+	if err := iotStateChange([]*protocol.IotTx{tx1, tx2}); err == nil {
+		t.Error("IoT txs with fees exceeding the balance of the sender were accepted.")
+	}
+	if accA.Balance != 100 || accA.TxCnt != 0 {
+		t.Errorf("Rejected IoT txs changed the state: Balance = %v, TxCnt = %v\n", accA.Balance, accA.TxCnt)
+	}
+
+	//Both txs on their own are fine
+	if err := iotStateChange([]*protocol.IotTx{tx1}); err != nil {
+		t.Errorf("IoT state change failed: %v\n", err)
+	}
+	if accA.Balance != 40 {
+		t.Errorf("IoT tx fee not taken from the sender: %v vs. 40\n", accA.Balance)
+	}
+	iotStateChangeRollback([]*protocol.IotTx{tx1})
+	if accA.Balance != 100 || accA.TxCnt != 0 {
+		t.Error("Rollback failed!")
+	}
+}
+
+//collectTxFees never lets the balance of a sender wrap around
+func TestCollectTxFeesInsufficientBalance(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	minerAccHash := protocol.SerializeHashContent(validatorAcc.Address)
+
+	accA.Balance = 10
+	minerBal := validatorAcc.Balance
+
+	tx := &protocol.FundsTx{Header: 0x01, Amount: 0, Fee: 11, TxCnt: 0, From: accAHash, To: accBHash}
+	if err := collectTxFees(nil, []*protocol.FundsTx{tx}, nil, nil, nil, nil, nil, nil, nil, nil, nil, minerAccHash); err == nil {
+		t.Error("Fee exceeding the balance of the sender was collected.")
+	}
+	if accA.Balance != 10 || validatorAcc.Balance != minerBal {
+		t.Errorf("Refused fee collection changed the state: %v, %v\n", accA.Balance, validatorAcc.Balance)
+	}
+}
+
+func TestAddIotTx(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+
+	tx := &protocol.IotTx{Header: 0x01, TxCnt: 0, From: accAHash, To: accBHash, Data: []byte{1}, Fee: 5}
+	if err := addIoTTx(b, tx); err != nil {
+		t.Errorf("Block rejected a valid IoT tx: %v\n", err)
+	}
+	if b.StateCopy[accAHash].Balance != accA.Balance-5 || b.StateCopy[accAHash].TxCnt != 1 {
+		t.Errorf("IoT tx fee or txCnt not applied to the state copy: %v\n", b.StateCopy[accAHash])
+	}
+
+	//Same txCnt again
+	replayTx := &protocol.IotTx{Header: 0x01, TxCnt: 0, From: accAHash, To: accBHash, Data: []byte{2}, Fee: 5}
+	if err := addIoTTx(b, replayTx); err == nil {
+		t.Error("Block accepted an IoT tx with an outdated txCnt.")
+	}
+	if len(b.IoTTxData) != 1 {
+		t.Errorf("Block contains %v IoT txs, expected 1\n", len(b.IoTTxData))
+	}
+}
+
+func TestConstrIotTxFee(t *testing.T) {
+	_, privKey, _ := ed25519.GenerateKey(nil)
+	tx, _ := protocol.ConstrIotTx(0x01, 42, 0, [32]byte{1}, [32]byte{2}, privKey, []byte{1})
+	if tx.Fee != 42 {
+		t.Errorf("IoT tx fee not set: %v vs. 42\n", tx.Fee)
+	}
+}
+
+//Rollback tests for IoT txs, analogous to TestFundsStateChangeRollback
+func TestIotStateChangeRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	randVar := rand.New(rand.NewSource(time.Now().Unix()))
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	minerAccHash := protocol.SerializeHashContent(validatorAcc.Address)
+
+	//State snapshot
+	rollBackA := accA.Balance
+	rollBackTxCntA := accA.TxCnt
+	rollBackMiner := validatorAcc.Balance
+
+	var iots []*protocol.IotTx
+	var fee, tip uint64
+	loopMax := int(randVar.Uint32()%100) + 1
+	for i := 0; i < loopMax; i++ {
+		tx := &protocol.IotTx{Header: 0x01, TxCnt: uint32(i), From: accAHash, To: accBHash, Data: []byte{byte(i)}, Fee: randVar.Uint64()%100 + 1}
+		iots = append(iots, tx)
+		fee += tx.Fee
+		tip += txTip(tx.Fee)
+	}
+
+	if err := iotStateChange(iots); err != nil {
+		t.Errorf("IoT state change failed: %v\n", err)
+	}
+	if err := collectTxFees(nil, nil, nil, nil, nil, iots, nil, nil, nil, nil, nil, minerAccHash); err != nil {
+		t.Errorf("Collecting IoT tx fees failed: %v\n", err)
+	}
+	if accA.Balance != rollBackA-fee || validatorAcc.Balance != rollBackMiner+tip {
+		t.Error("IoT tx fees not collected!")
+	}
+
+	collectTxFeesRollback(nil, nil, nil, nil, iots, nil, nil, nil, nil, nil, minerAccHash)
+	iotStateChangeRollback(iots)
+	if accA.Balance != rollBackA || accA.TxCnt != rollBackTxCntA || validatorAcc.Balance != rollBackMiner {
+		t.Error("Rollback failed!")
+	}
+}
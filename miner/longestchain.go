@@ -3,7 +3,6 @@ package miner
 import (
 	"errors"
 	"fmt"
-	"github.com/bazo-blockchain/bazo-miner/p2p"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 	"time"
@@ -32,6 +31,11 @@ func getBlockSequences(newBlock *protocol.Block) (blocksToRollback, blocksToVali
 		tmpBlock = storage.ReadClosedBlock(tmpBlock.PrevHash)
 	}
 
+	//The records needed to roll back blocks deeper in the chain are gone.
+	if len(blocksToRollback) > ROLLBACK_DEPTH {
+		return nil, nil, errors.New(fmt.Sprintf("Block belongs to a chain which splits more than %d blocks back --> NO ROLLBACK", ROLLBACK_DEPTH))
+	}
+
 	//Compare current length with new chain length.
 	if len(blocksToRollback) >= len(newChain) {
 		//Current chain length is longer or equal (our consensus protocol states that in this case we reject the block).
@@ -86,11 +90,11 @@ func getNewChain(newBlock *protocol.Block) (ancestor *protocol.Block, newChain [
 
 		//Fetch the block we apparently missed from the network.
 		//p2p.BlockReq(newBlock.PrevHash, newBlock.PrevHashWithoutTx)
-		p2p.BlockReq(newBlock.PrevHash, newBlock.PrevHashWithoutTx)
+		p2pNode.BlockReq(newBlock.PrevHash, newBlock.PrevHashWithoutTx)
 
 		//Blocking wait
 		select {
-		case encodedBlock := <-p2p.BlockReqChan:
+		case encodedBlock := <-p2pNode.BlockReqChan:
 			newBlock = newBlock.Decode(encodedBlock)
 			storage.WriteToReceivedStash(newBlock)
 		//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
@@ -0,0 +1,115 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+const (
+	//The reward is the Block_reward system parameter, which can be changed by ConfigTxs and proposals
+	REWARD_FLAT = iota
+	//The initial reward is halved every halving interval
+	REWARD_HALVING
+	//The reward decreases linearly from the initial to the tail reward over the decay period
+	REWARD_LINEAR_DECAY
+)
+
+var (
+	rewardSchedule = NewDefaultRewardSchedule()
+	supply         protocol.Supply        //Supply after the last block
+	supplyChange   = new(protocol.Supply) //Coins created and destroyed by the block which is validated or rolled back
+)
+
+//The monetary policy of the chain, it is defined at genesis and can't be changed later on.
+type RewardSchedule struct {
+	Kind            uint8
+	InitialReward   uint64
+	HalvingInterval uint32
+	DecayPeriod     uint32
+	TailReward      uint64
+	MaxSupply       uint64
+}
+
+func NewDefaultRewardSchedule() RewardSchedule {
+	return RewardSchedule{
+		REWARD_SCHEDULE,
+		INITIAL_BLOCK_REWARD,
+		REWARD_HALVING_INTERVAL,
+		REWARD_DECAY_PERIOD,
+		TAIL_BLOCK_REWARD,
+		MAX_SUPPLY,
+	}
+}
+
+//Reward of the block with the given height according to the schedule, before the supply cap is applied.
+func blockReward(height uint32) (reward uint64) {
+	switch rewardSchedule.Kind {
+	case REWARD_HALVING:
+		reward = rewardSchedule.InitialReward
+		if rewardSchedule.HalvingInterval != 0 {
+			halvings := height / rewardSchedule.HalvingInterval
+			if halvings >= 64 {
+				reward = 0
+			} else {
+				reward >>= halvings
+			}
+		}
+	case REWARD_LINEAR_DECAY:
+		if height < rewardSchedule.DecayPeriod && rewardSchedule.InitialReward > rewardSchedule.TailReward {
+			decay := mulDiv(rewardSchedule.InitialReward-rewardSchedule.TailReward, uint64(height), uint64(rewardSchedule.DecayPeriod))
+			reward = rewardSchedule.InitialReward - decay
+		}
+	default:
+		return parametersAt(height).Block_reward
+	}
+
+	if reward < rewardSchedule.TailReward {
+		reward = rewardSchedule.TailReward
+	}
+
+	return reward
+}
+
+//Caps the reward such that the supply including the changes of the current block does not exceed the maximum supply.
+func capBlockReward(reward uint64) uint64 {
+	total := supply.Add(*supplyChange).Total()
+	if total >= rewardSchedule.MaxSupply {
+		return 0
+	}
+	if reward > rewardSchedule.MaxSupply-total {
+		return rewardSchedule.MaxSupply - total
+	}
+
+	return reward
+}
+
+//The coins created and destroyed by the block are added to the supply, which is stored with the block.
+func commitSupplyChange(block *protocol.Block) {
+	supply = supply.Add(*supplyChange)
+	supply.MaxSupply = rewardSchedule.MaxSupply
+	storage.WriteSupply(block.Hash, &supply)
+	supplyChange = new(protocol.Supply)
+}
+
+//Returns the coins created and destroyed by the block, which is the difference of the supply before and after it.
+//The supply before the block becomes the current one again.
+func revertSupplyChange(block *protocol.Block) *protocol.Supply {
+	after := storage.ReadSupply(block.Hash)
+	if after == nil {
+		return nil
+	}
+
+	var before protocol.Supply
+	if prevSupply := storage.ReadSupply(block.PrevHash); prevSupply != nil {
+		before = *prevSupply
+	}
+
+	supply = before
+	storage.DeleteSupply(block.Hash)
+
+	return &protocol.Supply{
+		Minted: after.Minted - before.Minted,
+		Issued: after.Issued - before.Issued,
+		Burned: after.Burned - before.Burned,
+	}
+}
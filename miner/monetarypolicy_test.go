@@ -0,0 +1,107 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func TestBlockRewardSchedule(t *testing.T) {
+	cleanAndPrepare()
+
+	//The flat schedule pays the Block_reward parameter
+	if reward := blockReward(10); reward != activeParameters.Block_reward {
+		t.Errorf("Flat reward %v does not match the parameter %v.\n", reward, activeParameters.Block_reward)
+	}
+
+	rewardSchedule = RewardSchedule{REWARD_HALVING, 100, 10, 0, 0, MAX_SUPPLY}
+	for height, expected := range map[uint32]uint64{0: 100, 9: 100, 10: 50, 25: 25, 60: 1, 70: 0, 10000: 0} {
+		if reward := blockReward(height); reward != expected {
+			t.Errorf("Halving reward at height %v: %v vs. %v\n", height, reward, expected)
+		}
+	}
+
+	//The tail reward is paid once the halvings fall below it
+	rewardSchedule.TailReward = 10
+	if reward := blockReward(40); reward != 10 {
+		t.Errorf("Tail reward not paid: %v\n", reward)
+	}
+
+	rewardSchedule = RewardSchedule{REWARD_LINEAR_DECAY, 100, 0, 10, 20, MAX_SUPPLY}
+	for height, expected := range map[uint32]uint64{0: 100, 5: 60, 9: 28, 10: 20, 1000: 20} {
+		if reward := blockReward(height); reward != expected {
+			t.Errorf("Linear decay reward at height %v: %v vs. %v\n", height, reward, expected)
+		}
+	}
+}
+
+//Block rewards are capped by the maximum supply, the capped reward is the one which is rolled back
+func TestBlockRewardCap(t *testing.T) {
+	cleanAndPrepare()
+
+	minerHash := protocol.SerializeHashContent(validatorAcc.Address)
+	balance := validatorAcc.Balance
+
+	supply = protocol.Supply{Issued: 1000}
+	rewardSchedule = RewardSchedule{REWARD_HALVING, 100, 0, 0, 0, 1150}
+
+	if err := collectBlockReward(blockReward(1), minerHash); err != nil || supplyChange.Minted != 100 {
+		t.Errorf("Block reward below the cap not paid: %v, %v\n", err, supplyChange)
+	}
+	if err := collectBlockReward(blockReward(2), minerHash); err != nil || supplyChange.Minted != 150 {
+		t.Errorf("Block reward not capped at the remaining supply: %v, %v\n", err, supplyChange)
+	}
+	if err := collectBlockReward(blockReward(3), minerHash); err != nil || supplyChange.Minted != 150 {
+		t.Errorf("Block reward paid beyond the maximum supply: %v, %v\n", err, supplyChange)
+	}
+	if validatorAcc.Balance != balance+150 {
+		t.Errorf("Capped block rewards not credited: %v vs. %v\n", validatorAcc.Balance, balance+150)
+	}
+
+	collectBlockRewardRollback(supplyChange.Minted, minerHash)
+	if validatorAcc.Balance != balance {
+		t.Errorf("Capped block rewards not rolled back: %v vs. %v\n", validatorAcc.Balance, balance)
+	}
+}
+
+//Coins issued by the root account are tracked, the supply is stored per block and restored on rollback
+func TestSupplyTracking(t *testing.T) {
+	cleanAndPrepare()
+
+	rootHash := protocol.SerializeHashContent(rootAcc.Address)
+	accAHash := protocol.SerializeHashContent(accA.Address)
+
+	commitSupplyChange(genesisBlock)
+	before := supply
+
+	tx, _ := protocol.ConstrFundsTx(0x01, 1000, 1, rootAcc.TxCnt, rootHash, accAHash, PrivKeyRoot, nil)
+	if err := fundsStateChange([]*protocol.FundsTx{tx}); err != nil {
+		t.Fatalf("Root funds tx failed: %v\n", err)
+	}
+	if supplyChange.Issued != 1001 {
+		t.Errorf("Coins issued by the root account not tracked: %v\n", supplyChange)
+	}
+
+	b := newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b.Hash = [32]byte{0x01}
+	commitSupplyChange(b)
+	if supply.Total() != before.Total()+1001 || *supplyChange != (protocol.Supply{}) {
+		t.Errorf("Supply change not committed: %v vs. %v\n", supply, before)
+	}
+	if stored := storage.ReadSupply(b.Hash); stored == nil || *stored != supply {
+		t.Errorf("Supply not stored with the block: %v\n", stored)
+	}
+
+	change := revertSupplyChange(b)
+	if change == nil || change.Issued != 1001 || change.Minted != 0 || change.Burned != 0 {
+		t.Errorf("Supply change of the block not recovered: %v\n", change)
+	}
+	if supply != before || storage.ReadSupply(b.Hash) != nil {
+		t.Errorf("Supply not restored on rollback: %v vs. %v\n", supply, before)
+	}
+	if revertSupplyChange(b) != nil {
+		t.Error("Supply of a block without stored supply reverted.")
+	}
+}
@@ -11,12 +11,14 @@ import (
 
 var (
 	processBlockMutex = &sync.Mutex{}
+	//Node the miner receives blocks from and sends its blocks and requests to
+	p2pNode *p2p.Node
 )
 
 //Constantly listen to incoming data from the network
 func incomingData() {
 	for {
-		block := <-p2p.BlockIn
+		block := <-p2pNode.BlockIn
 		processBlock(block)
 	}
 }
@@ -30,6 +32,15 @@ func processBlock(payload []byte) {
 	var block *protocol.Block
 	block = block.Decode(payload)
 
+	//Txs of a compact block which were not in the mempool only get there once they are verified
+	for _, tx := range p2pNode.BlockTxs(block.Hash) {
+		if !verify(tx) {
+			p2pNode.ReportInvalidTx(tx.Hash())
+			continue
+		}
+		storage.WriteOpenTx(tx)
+	}
+
 	//Block already confirmed and validated
 	if storage.ReadClosedBlock(block.Hash) != nil {
 		logger.Printf("Received block (%x) has already been validated.\n", block.Hash[0:8])
@@ -46,18 +57,19 @@ func processBlock(payload []byte) {
 		broadcastBlock(block)
 	} else {
 		logger.Printf("Received block (%x) could not be validated: %v\n", block.Hash[0:8], err)
+		p2pNode.ReportInvalidBlock(block.Hash)
 	}
 }
 
 //p2p.BlockOut is a channel whose data get consumed by the p2p package
 func broadcastBlock(block *protocol.Block) {
-	p2p.BlockOut <- block.Encode()
+	p2pNode.BlockOut <- block.Encode()
 
 	//Make a deep copy of the block (since it is a pointer and will be saved to db later).
 	//Otherwise the block's bloom filter is initialized on the original block.
 	var blockCopy = *block
 	blockCopy.InitBloomFilter(append(storage.GetTxPubKeys(&blockCopy)))
-	p2p.BlockHeaderOut <- blockCopy.EncodeHeader()
+	p2pNode.BlockHeaderOut <- blockCopy.EncodeHeader()
 }
 
 func broadcastVerifiedTxs(txs []*protocol.FundsTx) {
@@ -67,5 +79,5 @@ func broadcastVerifiedTxs(txs []*protocol.FundsTx) {
 		verifiedTxs = append(verifiedTxs, tx.Encode()[:])
 	}
 
-	p2p.VerifiedTxsOut <- protocol.Encode(verifiedTxs, protocol.FUNDSTX_SIZE)
+	p2pNode.VerifiedTxsOut <- protocol.Encode(verifiedTxs, protocol.FUNDSTX_SIZE)
 }
\ No newline at end of file
@@ -1,10 +1,10 @@
 package miner
 
 import (
-	"bytes"
 	"encoding/binary"
 	"errors"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"math/big"
 	"time"
 
 	"github.com/bazo-blockchain/bazo-miner/protocol"
@@ -12,8 +12,8 @@ import (
 	"golang.org/x/crypto/sha3"
 )
 
-//Tests whether the first diff bits are zero
-func validateProofOfStake(diff uint8,
+//Tests whether the hash divided by the stake is below the target
+func validateProofOfStake(target *big.Int,
 	prevProofs [][crypto.COMM_KEY_LENGTH]byte,
 	height uint32,
 	balance uint64,
@@ -46,33 +46,17 @@ func validateProofOfStake(diff uint8,
 
 	copy(hashArgs[index:index+8], timestampBuf[:])
 
-	//calculate the hash
-	pos := sha3.Sum256(hashArgs[:])
-
-	data := binary.BigEndian.Uint64(pos[:])
-	data = data / balance
-	var buf bytes.Buffer
-	binary.Write(&buf, binary.BigEndian, data)
-
-	copy(pos[0:32], buf.Bytes())
-
-	var byteNr uint8
-	//Bytes check
-	for byteNr = 0; byteNr < (uint8)(diff/8); byteNr++ {
-		if pos[byteNr] != 0 {
-			return false
-		}
-	}
-	//Bits check
-	if diff%8 != 0 && pos[byteNr] >= 1<<(8-diff%8) {
+	//Without stake there is no chance to validate
+	if balance == 0 {
 		return false
 	}
-	return true
+
+	return stakeScaledHash(hashArgs, balance).Cmp(target) < 0
 }
 
-//diff and partialHash is needed to calculate a valid PoS, prevHash is needed to check whether we should stop
+//target and partialHash is needed to calculate a valid PoS, prevHash is needed to check whether we should stop
 //PoS calculation because another block has been validated meanwhile
-func proofOfStake(diff uint8,
+func proofOfStake(target *big.Int,
 	prevHash [32]byte,
 	prevProofs [][crypto.COMM_KEY_LENGTH]byte,
 	height uint32,
@@ -80,10 +64,6 @@ func proofOfStake(diff uint8,
 	commitmentProof [crypto.COMM_KEY_LENGTH]byte) (int64, error) {
 
 	var (
-		pos    [32]byte
-		byteNr uint8
-		abort  bool
-
 		timestampBuf [8]byte
 		heightBuf    [4]byte
 
@@ -122,49 +102,34 @@ func proofOfStake(diff uint8,
 			return -2, errors.New("Abort mining, another block has been successfully validated in the meantime")
 		}
 
-		abort = false
-
 		//add the number of seconds that have passed since the Unix epoch (00:00:00 UTC, 1 January 1970)
 		timestamp = time.Now().Unix()
 		binary.BigEndian.PutUint64(timestampBuf[:], uint64(timestamp))
 		copy(hashArgs[timestampBufIndexStart:timestampBufIndexEnd], timestampBuf[:]) //8 bytes
 
-		//calculate the hash
-		pos = sha3.Sum256(hashArgs[:])
-
 		//divide the hash by the balance (should not happen but possible in a testing environment)
-		data := binary.BigEndian.Uint64(pos[:])
 		if balance == 0 {
 			return -1, errors.New("Zero division: Account owns 0 coins.")
 		}
-		data = data / balance
-		var buf bytes.Buffer
-		binary.Write(&buf, binary.BigEndian, data)
-
-		copy(pos[0:32], buf.Bytes())
-
-		//Byte check
-		for byteNr = 0; byteNr < (uint8)(diff/8); byteNr++ {
-			if pos[byteNr] != 0 {
-				//continue begins the next iteration of the innermost
-				abort = true
-				break
-			}
-		}
 
-		if abort {
-			continue
-		}
-		//Bit check
-		if diff%8 != 0 && pos[byteNr] >= 1<<(8-diff%8) {
-			continue
+		if stakeScaledHash(hashArgs, balance).Cmp(target) < 0 {
+			break
 		}
-		break
 	}
 
 	return timestamp, nil
 }
 
+//The hash of the PoS arguments divided by the stake, the more stake the more likely it is below the target. Dividing
+//the whole hash leaves the leading bits the same as dividing its first 8 bytes did, which blocks checked against
+//a number of leading zero bits relied on.
+func stakeScaledHash(hashArgs []byte, balance uint64) *big.Int {
+	pos := sha3.Sum256(hashArgs)
+
+	scaled := new(big.Int).SetBytes(pos[:])
+	return scaled.Div(scaled, new(big.Int).SetUint64(balance))
+}
+
 func GetLatestProofs(n int, block *protocol.Block) (prevProofs [][crypto.COMM_KEY_LENGTH]byte) {
 
 	for block.Height > 0 && n > 0 {
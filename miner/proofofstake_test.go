@@ -30,9 +30,9 @@ func TestProofOfStake(t *testing.T) {
 	diff := 10
 
 	commitmentProof, _ := crypto.SignMessageWithRSAKey(CommPrivKeyAccA, fmt.Sprint(height))
-	timestamp, _ := proofOfStake(uint8(diff), lastBlock.Hash, prevProofs, height, balance, commitmentProof)
+	timestamp, _ := proofOfStake(targetFromBits(uint8(diff)), lastBlock.Hash, prevProofs, height, balance, commitmentProof)
 
-	if !validateProofOfStake(uint8(diff), prevProofs, height, balance, commitmentProof, timestamp) {
+	if !validateProofOfStake(targetFromBits(uint8(diff)), prevProofs, height, balance, commitmentProof, timestamp) {
 		fmt.Printf("Invalid PoS calculation\n")
 	}
 }
@@ -45,7 +45,7 @@ func TestGetLatestProofs(t *testing.T) {
 	proofs = append([][crypto.COMM_KEY_LENGTH]byte{genesisCommitmentProof}, proofs...)
 	//Initially we expect only the genesis commitment proof
 
-	b := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 
 	prevProofs := GetLatestProofs(1, b)
 
@@ -57,21 +57,21 @@ func TestGetLatestProofs(t *testing.T) {
 	}
 
 	//Two new blocks are added with random commitment proofs
-	b1 := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b1 := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	if err := finalizeBlock(b1); err != nil {
 		t.Error("Error finalizing b1", err)
 	}
 	proofs = append([][crypto.COMM_KEY_LENGTH]byte{b1.CommitmentProof}, proofs...)
 	validate(b1, false)
 
-	b2 := newBlock(b1.Hash, [crypto.COMM_KEY_LENGTH]byte{}, b1.Height+1)
+	b2 := newBlock(b1.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, b1.Height+1)
 	if err := finalizeBlock(b2); err != nil {
 		t.Error("Error finalizing b2", err)
 	}
 	validate(b2, false)
 	proofs = append([][crypto.COMM_KEY_LENGTH]byte{b2.CommitmentProof}, proofs...)
 
-	b3 := newBlock(b2.Hash, [crypto.COMM_KEY_LENGTH]byte{}, b2.Height+1)
+	b3 := newBlock(b2.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, b2.Height+1)
 
 	prevProofs = GetLatestProofs(3, b3)
 
@@ -33,12 +33,12 @@ func seekSlashingProof(block *protocol.Block) error {
 		}
 		for _, prevBlock := range prevBlocks {
 			if IsInSameChain(prevBlock, block) {
-				return nil
+				continue
 			}
 			if prevBlock.Beneficiary == block.Beneficiary &&
 				(uint64(prevBlock.Height) < uint64(block.Height)+activeParameters.Slashing_window_size ||
 					uint64(block.Height) < uint64(prevBlock.Height)+activeParameters.Slashing_window_size) {
-				slashingDict[block.Beneficiary] = SlashingProof{ConflictingBlockHash1: block.Hash, ConflictingBlockHash2: prevBlock.Hash, ConflictingBlockHashWithoutTx1: block.HashWithoutTx, ConflictingBlockHashWithoutTx2: block.PrevHashWithoutTx}
+				slashingDict[block.Beneficiary] = SlashingProof{ConflictingBlockHash1: block.Hash, ConflictingBlockHash2: prevBlock.Hash, ConflictingBlockHashWithoutTx1: block.HashWithoutTx, ConflictingBlockHashWithoutTx2: prevBlock.HashWithoutTx}
 			}
 		}
 	}
@@ -12,9 +12,10 @@ func TestSlashingCondition(t *testing.T) {
 	cleanAndPrepare()
 
 	myAcc, _ := storage.GetAccount(protocol.SerializeHashContent(validatorAccAddress))
+	myAcc.StakedAmount = activeParameters.Staking_minimum
 	initBalance := myAcc.Balance
 
-	forkBlock := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	forkBlock := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	if err := finalizeBlock(forkBlock); err != nil {
 		t.Errorf("Block finalization for b1 (%v) failed: %v\n", forkBlock, err)
 	}
@@ -23,7 +24,7 @@ func TestSlashingCondition(t *testing.T) {
 	}
 
 	// genesis <- forkBlock <- b
-	b := newBlock(forkBlock.Hash, [crypto.COMM_KEY_LENGTH]byte{}, 2)
+	b := newBlock(forkBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 2)
 	if err := finalizeBlock(b); err != nil {
 		t.Errorf("Block finalization for b1 (%v) failed: %v\n", b, err)
 	}
@@ -35,7 +36,7 @@ func TestSlashingCondition(t *testing.T) {
 	lastBlock = forkBlock
 
 	// genesis <- forkBlock <- b2
-	b2 := newBlock(forkBlock.Hash, [crypto.COMM_KEY_LENGTH]byte{}, 2)
+	b2 := newBlock(forkBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 2)
 	if err := finalizeBlock(b2); err != nil {
 		t.Errorf("Block finalization for b2 (%v) failed: %v\n", b2, err)
 	}
@@ -46,21 +47,21 @@ func TestSlashingCondition(t *testing.T) {
 	}
 
 	slashingDict2 := make(map[[32]byte]SlashingProof)
-	slashingDict2[b.Beneficiary] = SlashingProof{b2.Hash, b.Hash}
+	slashingDict2[b.Beneficiary] = SlashingProof{b2.Hash, b.Hash, b2.HashWithoutTx, b.HashWithoutTx}
 
 	if !reflect.DeepEqual(slashingDict, slashingDict2) {
 		t.Error("Slashing dictionary was not built correctly.", slashingDict, slashingDict2)
 	}
 
 	//third block contains the slashing proof
-	b3 := newBlock(b2.Hash, [crypto.COMM_KEY_LENGTH]byte{}, 3)
+	b3 := newBlock(b2.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 3)
 	if err := finalizeBlock(b3); err != nil {
 		t.Errorf("Block finalization for b3 (%v) failed: %v\n", b3, err)
 	}
 
 	//Check whether the right proof was included in b3
 	slashingDict3 := make(map[[32]byte]SlashingProof)
-	slashingDict3[b3.Beneficiary] = SlashingProof{b3.ConflictingBlockHash1, b3.ConflictingBlockHash2}
+	slashingDict3[b3.Beneficiary] = SlashingProof{b3.ConflictingBlockHash1, b3.ConflictingBlockHash2, b3.ConflictingBlockHashWithoutTx1, b3.ConflictingBlockHashWithoutTx2}
 
 	if !reflect.DeepEqual(slashingDict, slashingDict3) {
 		t.Error("Slashing proof was not correctly included in b3.", slashingDict, slashingDict3)
@@ -70,9 +71,9 @@ func TestSlashingCondition(t *testing.T) {
 		t.Errorf("Block validation for b3 (%v) failed: %v\n", b3, err)
 	}
 
-	//Check whether the slashing reward is added after a slashing proof is provided
-	expectedBalance := initBalance+4*activeParameters.Block_reward+activeParameters.Slash_reward-activeParameters.Staking_minimum
-	if !reflect.DeepEqual(expectedBalance, myAcc.Balance) {
+	//Check whether the slashing reward is added after a slashing proof is provided, the locked stake is lost
+	expectedBalance := initBalance+4*activeParameters.Block_reward+activeParameters.Slash_reward
+	if !reflect.DeepEqual(expectedBalance, myAcc.Balance) || myAcc.StakedAmount != 0 || myAcc.IsStaking {
 		t.Error("Slashing reward is not properly added.", initBalance, myAcc.Balance, expectedBalance)
 	}
 }
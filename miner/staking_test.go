@@ -0,0 +1,89 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//The stake is locked while staking and unbonding, it can only be withdrawn after the unbonding period
+func TestStakeUnbondingAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	activeParameters.Unbonding_period = 10
+	accA.Balance = activeParameters.Staking_minimum + 100
+	balanceA := accA.Balance
+
+	stake, _ := protocol.ConstrStakeTx(0, 1, true, 10, accAHash, PrivKeyAccA, &CommPrivKeyAccA.PublicKey)
+	if err := stakeStateChange([]*protocol.StakeTx{stake}, 5); err != nil {
+		t.Fatalf("Staking failed: %v\n", err)
+	}
+	if !accA.IsStaking || accA.StakedAmount != activeParameters.Staking_minimum || accA.Balance != balanceA-activeParameters.Staking_minimum {
+		t.Errorf("Stake not locked: %v\n", accA)
+	}
+
+	unstake, _ := protocol.ConstrStakeTx(0, 1, false, 0, accAHash, PrivKeyAccA, &CommPrivKeyAccA.PublicKey)
+	if err := stakeStateChange([]*protocol.StakeTx{unstake}, 20); err != nil {
+		t.Fatalf("Unstaking failed: %v\n", err)
+	}
+	if accA.IsStaking || accA.StakedAmount != 0 || accA.UnbondingAmount != activeParameters.Staking_minimum || accA.UnbondingHeight != 30 {
+		t.Errorf("Unbonding not started: %v\n", accA)
+	}
+	if accA.Balance != balanceA-activeParameters.Staking_minimum {
+		t.Errorf("Unbonding stake can be spent: %v\n", accA)
+	}
+
+	//The stake can't be withdrawn before the end of the unbonding period
+	withdraw, _ := protocol.ConstrStakeTx(protocol.STAKETX_WITHDRAW, 1, false, 0, accAHash, PrivKeyAccA, &CommPrivKeyAccA.PublicKey)
+	if err := stakeStateChange([]*protocol.StakeTx{withdraw}, 29); err == nil {
+		t.Error("Stake withdrawn during the unbonding period.")
+	}
+	if len(undoLog.Entries) != 2 {
+		t.Errorf("Rejected tx recorded in the undo log: %v\n", undoLog.Entries)
+	}
+
+	if err := stakeStateChange([]*protocol.StakeTx{withdraw}, 30); err != nil {
+		t.Fatalf("Withdrawal failed: %v\n", err)
+	}
+	if accA.IsUnbonding() || accA.UnbondingAmount != 0 || accA.Balance != balanceA {
+		t.Errorf("Stake not withdrawn: %v\n", accA)
+	}
+
+	stakeStateChangeRollback([]*protocol.StakeTx{stake, unstake, withdraw})
+	if accA.IsStaking || accA.IsUnbonding() || accA.StakedAmount != 0 || accA.Balance != balanceA {
+		t.Errorf("Stake rollback failed: %v\n", accA)
+	}
+	if len(undoLog.Entries) != 0 {
+		t.Errorf("Undo log not replayed: %v\n", undoLog.Entries)
+	}
+}
+
+//A validator which is unbonding can still be slashed
+func TestSlashUnbondingStakeAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	validatorHash := protocol.SerializeHashContent(validatorAcc.Address)
+	validatorAcc.IsStaking = false
+	validatorAcc.UnbondingAmount = activeParameters.Staking_minimum
+	validatorAcc.UnbondingHeight = 100
+	balance := validatorAcc.Balance
+
+	b := newBlock([32]byte{}, [32]byte{}, [256]byte{}, 1)
+	b.Hash = [32]byte{0x02}
+	b.Beneficiary = protocol.SerializeHashContent(accB.Address)
+	b.SlashedAddress = validatorHash
+	balanceB := accB.Balance
+
+	if err := collectSlashReward(activeParameters.Slash_reward, b); err != nil {
+		t.Fatalf("Slashing failed: %v\n", err)
+	}
+	if validatorAcc.IsUnbonding() || validatorAcc.UnbondingAmount != 0 || validatorAcc.Balance != balance || accB.Balance != balanceB+activeParameters.Slash_reward {
+		t.Errorf("Unbonding stake not slashed: %v\n", validatorAcc)
+	}
+
+	collectSlashRewardRollback(activeParameters.Slash_reward, b)
+	if validatorAcc.UnbondingAmount != activeParameters.Staking_minimum || validatorAcc.UnbondingHeight != 100 || accB.Balance != balanceB {
+		t.Errorf("Slashing rollback failed: %v\n", validatorAcc)
+	}
+}
@@ -4,76 +4,92 @@ import (
 	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
-	"github.com/bazo-blockchain/bazo-miner/p2p"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
-	"strconv"
 	"time"
 )
 
 //Separate function to reuse mechanism in client implementation
+//ConfigTxs with an activation height are skipped, they are put in force by configStateChange at their height.
 func CheckAndChangeParameters(parameters *Parameters, configTxSlice *[]*protocol.ConfigTx) (change bool) {
 	for _, tx := range *configTxSlice {
-		switch tx.Id {
-		case protocol.FEE_MINIMUM_ID:
-			if parameterBoundsChecking(protocol.FEE_MINIMUM_ID, tx.Payload) {
-				parameters.Fee_minimum = tx.Payload
-				change = true
-			}
-		case protocol.BLOCK_SIZE_ID:
-			if parameterBoundsChecking(protocol.BLOCK_SIZE_ID, tx.Payload) {
-				parameters.Block_size = tx.Payload
-				logger.Printf("BLOCK_SIZE: %v", parameters.Block_size)
-				change = true
-			}
-		case protocol.BLOCK_REWARD_ID:
-			if parameterBoundsChecking(protocol.BLOCK_REWARD_ID, tx.Payload) {
-				parameters.Block_reward = tx.Payload
-				change = true
-			}
-		case protocol.DIFF_INTERVAL_ID:
-			if parameterBoundsChecking(protocol.DIFF_INTERVAL_ID, tx.Payload) {
-				parameters.Diff_interval = tx.Payload
-				logger.Printf("BLOCK_DIFF: %v", parameters.Diff_interval)
-				change = true
-			}
-		case protocol.BLOCK_INTERVAL_ID:
-			if parameterBoundsChecking(protocol.BLOCK_INTERVAL_ID, tx.Payload) {
-				parameters.Block_interval = tx.Payload
-				logger.Printf("BLOCK_INVTERVAL: %v", parameters.Block_interval)
-				change = true
-			}
-		case protocol.STAKING_MINIMUM_ID:
-			if parameterBoundsChecking(protocol.STAKING_MINIMUM_ID, tx.Payload) {
-				parameters.Staking_minimum = tx.Payload
-				change = true
-				//Go through all accounts and remove all validators from the validator sett that no longer fulfill the minimum staking amount
-				for _, account := range storage.State {
-					if account.IsStaking && account.Balance < 0+tx.Payload {
-						account.IsStaking = false
-					}
-				}
-			}
-		case protocol.WAITING_MINIMUM_ID:
-			if parameterBoundsChecking(protocol.WAITING_MINIMUM_ID, tx.Payload) {
-				parameters.Waiting_minimum = tx.Payload
-				change = true
-			}
-		case protocol.ACCEPTANCE_TIME_DIFF_ID:
-			if parameterBoundsChecking(protocol.ACCEPTANCE_TIME_DIFF_ID, tx.Payload) {
-				parameters.Accepted_time_diff = tx.Payload
-				change = true
-			}
-		case protocol.SLASHING_WINDOW_SIZE_ID:
-			if parameterBoundsChecking(protocol.SLASHING_WINDOW_SIZE_ID, tx.Payload) {
-				parameters.Slashing_window_size = tx.Payload
-				change = true
-			}
-		case protocol.SLASHING_REWARD_ID:
-			if parameterBoundsChecking(protocol.SLASHING_REWARD_ID, tx.Payload) {
-				parameters.Slash_reward = tx.Payload
-				change = true
-			}
+		if tx.ActivationHeight != 0 {
+			continue
+		}
+		if changeParameter(parameters, tx.Id, tx.Payload) {
+			change = true
+		}
+	}
+
+	return change
+}
+
+//Sets the system parameter id to payload if it is within its bounds, used by ConfigTxs and scheduled changes.
+//The state is not touched, such that it can also be used to compute the parameters of future heights.
+func changeParameter(parameters *Parameters, id uint8, payload uint64) (change bool) {
+	switch id {
+	case protocol.FEE_MINIMUM_ID:
+		if parameterBoundsChecking(protocol.FEE_MINIMUM_ID, payload) {
+			parameters.Fee_minimum = payload
+			change = true
+		}
+	case protocol.BLOCK_SIZE_ID:
+		if parameterBoundsChecking(protocol.BLOCK_SIZE_ID, payload) {
+			parameters.Block_size = payload
+			logger.Printf("BLOCK_SIZE: %v", parameters.Block_size)
+			change = true
+		}
+	case protocol.BLOCK_REWARD_ID:
+		if parameterBoundsChecking(protocol.BLOCK_REWARD_ID, payload) {
+			parameters.Block_reward = payload
+			change = true
+		}
+	case protocol.DIFF_INTERVAL_ID:
+		if parameterBoundsChecking(protocol.DIFF_INTERVAL_ID, payload) {
+			parameters.Diff_interval = payload
+			logger.Printf("BLOCK_DIFF: %v", parameters.Diff_interval)
+			change = true
+		}
+	case protocol.BLOCK_INTERVAL_ID:
+		if parameterBoundsChecking(protocol.BLOCK_INTERVAL_ID, payload) {
+			parameters.Block_interval = payload
+			logger.Printf("BLOCK_INVTERVAL: %v", parameters.Block_interval)
+			change = true
+		}
+	case protocol.STAKING_MINIMUM_ID:
+		if parameterBoundsChecking(protocol.STAKING_MINIMUM_ID, payload) {
+			parameters.Staking_minimum = payload
+			change = true
+		}
+	case protocol.WAITING_MINIMUM_ID:
+		if parameterBoundsChecking(protocol.WAITING_MINIMUM_ID, payload) {
+			parameters.Waiting_minimum = payload
+			change = true
+		}
+	case protocol.ACCEPTANCE_TIME_DIFF_ID:
+		if parameterBoundsChecking(protocol.ACCEPTANCE_TIME_DIFF_ID, payload) {
+			parameters.Accepted_time_diff = payload
+			change = true
+		}
+	case protocol.SLASHING_WINDOW_SIZE_ID:
+		if parameterBoundsChecking(protocol.SLASHING_WINDOW_SIZE_ID, payload) {
+			parameters.Slashing_window_size = payload
+			change = true
+		}
+	case protocol.SLASHING_REWARD_ID:
+		if parameterBoundsChecking(protocol.SLASHING_REWARD_ID, payload) {
+			parameters.Slash_reward = payload
+			change = true
+		}
+	case protocol.UNBONDING_PERIOD_ID:
+		if parameterBoundsChecking(protocol.UNBONDING_PERIOD_ID, payload) {
+			parameters.Unbonding_period = payload
+			change = true
+		}
+	case protocol.TARGET_ADJUSTMENT_HEIGHT_ID:
+		if parameterBoundsChecking(protocol.TARGET_ADJUSTMENT_HEIGHT_ID, payload) {
+			parameters.Target_adjustment_height = payload
+			change = true
 		}
 	}
 
@@ -99,14 +115,14 @@ func getState() (state string) {
 
 func initState() (initialBlock *protocol.Block, err error) {
 	var allClosedBlocks []*protocol.Block
-	if p2p.IsBootstrap() {
+	if p2pNode.IsBootstrap() {
 		allClosedBlocks = storage.ReadAllClosedBlocks()
 	} else {
-		p2p.LastBlockReq()
+		p2pNode.LastBlockReq()
 		var lastBlock *protocol.Block
 		//Blocking wait
 		select {
-		case encodedBlock := <-p2p.BlockReqChan:
+		case encodedBlock := <-p2pNode.BlockReqChan:
 			lastBlock = lastBlock.Decode(encodedBlock)
 			//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
 		case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
@@ -122,10 +138,10 @@ func initState() (initialBlock *protocol.Block, err error) {
 		}
 
 		for {
-			p2p.BlockReq(lastBlock.PrevHash, lastBlock.PrevHashWithoutTx)
+			p2pNode.BlockReq(lastBlock.PrevHash, lastBlock.PrevHashWithoutTx)
 			//p2p.BlockReq(lastBlock.PrevHash, lastBlock.PrevHashWithoutTx)
 			select {
-			case encodedBlock := <-p2p.BlockReqChan:
+			case encodedBlock := <-p2pNode.BlockReqChan:
 				lastBlock = lastBlock.Decode(encodedBlock)
 				//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
 			case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
@@ -174,7 +190,7 @@ func initState() (initialBlock *protocol.Block, err error) {
 		storage.WriteClosedBlock(initialBlock)
 	}
 
-	if !p2p.IsBootstrap() {
+	if !p2pNode.IsBootstrap() {
 		allClosedBlocks = InvertBlockArray(allClosedBlocks)
 	}
 
@@ -186,12 +202,12 @@ func initState() (initialBlock *protocol.Block, err error) {
 		//Do not validate the genesis block, since a lot of properties are set to nil
 		if blockToValidate.Hash != [32]byte{} {
 			//Fetching payload data from the txs (if necessary, ask other miners)
-			accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, err := preValidate(blockToValidate, true)
+			accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, contractTxs, iotBatchTxs, delegateTxs, evidenceTxs, governanceTxs, err := preValidate(blockToValidate, true)
 			if err != nil {
 				return nil, errors.New(fmt.Sprintf("Block (%x) could not be prevalidated: %v\n", blockToValidate.Hash[0:8], err))
 			}
 
-			blockDataMap[blockToValidate.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs,blockToValidate}
+			blockDataMap[blockToValidate.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, aggTxs, iotTxs, contractTxs, iotBatchTxs, delegateTxs, evidenceTxs, governanceTxs, blockToValidate}
 
 			err = validateState(blockDataMap[blockToValidate.Hash])
 			if err != nil {
@@ -200,7 +216,7 @@ func initState() (initialBlock *protocol.Block, err error) {
 
 			postValidate(blockDataMap[blockToValidate.Hash], true)
 		} else {
-			blockDataMap[blockToValidate.Hash] = blockData{nil, nil, nil, nil, nil, nil, blockToValidate}
+			blockDataMap[blockToValidate.Hash] = blockData{nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, blockToValidate}
 
 			postValidate(blockDataMap[blockToValidate.Hash], true)
 		}
@@ -263,7 +279,7 @@ func accStateChange(txSlice []*protocol.AccTx) error {
 }
 
 func iotStateChange(txSlice []*protocol.IotTx) (err error) {
-	for _, tx := range txSlice {
+	for cnt, tx := range txSlice {
 		var rootAcc *protocol.Account
 		//Check if we have to issue new coins (in case a root account signed the tx)
 		if rootAcc, err = storage.GetRootAccount(tx.From); err != nil {
@@ -278,30 +294,29 @@ func iotStateChange(txSlice []*protocol.IotTx) (err error) {
 		if rootAcc != nil {
 			//rootAcc.Balance += tx.Amount
 			rootAcc.Balance += tx.Fee
+			supplyChange.Issued += tx.Fee
 		}
 		var accSender, accReceiver *protocol.Account
 		accSender, err = storage.GetAccount(tx.From)
-		accReceiver, err = storage.GetAccount(tx.To)
-
-		//Check transaction counter
-		//TODO: @ilecipi check again
-		//if tx.TxCnt != accSender.TxCnt {
-		//	err = errors.New(fmt.Sprintf("Sender txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt).", tx.TxCnt, accSender.TxCnt))
-		//}
-
-		//Check sender balance
-		if (tx.Fee) > accSender.Balance {
-			err = errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Fee = %v.", accSender.Balance, tx.Fee))
+		if err == nil {
+			accReceiver, err = storage.GetAccount(tx.To)
 		}
 
-		//After Tx fees, account must still have more than the minimum staking amount
-		if accSender.IsStaking && ((tx.Fee + protocol.MIN_STAKING_MINIMUM) > accSender.Balance) {
-			err = errors.New("Sender is staking and does not have enough funds in order to fulfill the required staking minimum.")
-		}
+		if err == nil {
+			//Check transaction counter
+			if tx.TxCnt != accSender.TxCnt {
+				err = errors.New(fmt.Sprintf("Sender txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt).", tx.TxCnt, accSender.TxCnt))
+			}
 
-		//Overflow protection
-		if accReceiver.Balance > MAX_MONEY {
-			err = errors.New("Transaction amount would lead to balance overflow at the receiver account.")
+			//Check sender balance
+			if (tx.Fee) > accSender.Balance {
+				err = errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Fee = %v.", accSender.Balance, tx.Fee))
+			}
+
+			//Overflow protection
+			if accReceiver.Balance > MAX_MONEY {
+				err = errors.New("Transaction amount would lead to balance overflow at the receiver account.")
+			}
 		}
 
 		if err != nil {
@@ -310,17 +325,330 @@ func iotStateChange(txSlice []*protocol.IotTx) (err error) {
 				rootAcc.Balance -= tx.Fee
 			}
 
+			//Rollback the IoT txs of this slice that were already applied
+			iotStateChangeRollback(txSlice[:cnt])
+			return err
+		}
+
+		//The fee is taken right away, such that the next tx of the same sender is checked against what is left.
+		//We're manipulating pointer, no need to write back
+		accSender.TxCnt += 1
+		accSender.Balance -= tx.Fee
+	}
+
+	return nil
+}
+
+func iotBatchStateChange(txSlice []*protocol.IotBatchTx) (err error) {
+	for cnt, tx := range txSlice {
+		var accSender *protocol.Account
+		accSender, err = storage.GetAccount(tx.From)
+		if err == nil {
+			_, err = storage.GetAccount(tx.To)
+		}
+
+		if err == nil {
+			//Check transaction counter
+			if tx.TxCnt != accSender.TxCnt {
+				err = errors.New(fmt.Sprintf("Sender txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt).", tx.TxCnt, accSender.TxCnt))
+			}
+
+			//Check sender balance, root accounts are exempt
+			if !storage.IsRootKey(tx.From) && tx.Fee > accSender.Balance {
+				err = errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Fee = %v.", accSender.Balance, tx.Fee))
+			}
+		}
+
+		if err != nil {
+			//Rollback the IoT batches of this slice that were already applied
+			iotBatchStateChangeRollback(txSlice[:cnt])
+			return err
+		}
+
+		//Like for iotTxs the fee is taken right away, root senders pay it with coins issued in collectTxFees.
+		//We're manipulating pointer, no need to write back
+		accSender.TxCnt += 1
+		if !storage.IsRootKey(tx.From) {
+			accSender.Balance -= tx.Fee
+		}
+	}
+
+	return nil
+}
+
+//Checks a delegateTx against the current state of the delegator and the validator account.
+func checkDelegateTx(tx *protocol.DelegateTx, acc *protocol.Account, validatorAcc *protocol.Account) error {
+	//Transaction count need to match the state, preventing replay attacks.
+	if tx.TxCnt != acc.TxCnt {
+		return errors.New(fmt.Sprintf("Sender txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt).", tx.TxCnt, acc.TxCnt))
+	}
+
+	if tx.From == tx.Validator || tx.Amount == 0 {
+		return errors.New("Invalid delegation.")
+	}
+
+	switch tx.Header {
+	case protocol.DELEGATE:
+		if !validatorAcc.IsStaking {
+			return errors.New("Validator is not part of the validator set.")
+		}
+		//Written without additions, such that huge amounts can't wrap around
+		if tx.Amount > acc.Balance || tx.Fee > acc.Balance-tx.Amount {
+			return errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Amount = %v, Fee = %v.", acc.Balance, tx.Amount, tx.Fee))
+		}
+		if validatorAcc.EffectiveStake() > MAX_MONEY || tx.Amount > MAX_MONEY-validatorAcc.EffectiveStake() {
+			return errors.New("Delegation would lead to stake overflow at the validator account.")
+		}
+	case protocol.UNDELEGATE:
+		if validatorAcc.Delegations[tx.From] < tx.Amount {
+			return errors.New(fmt.Sprintf("Sender did not delegate enough coins to the validator: Delegated = %v, Amount = %v.", validatorAcc.Delegations[tx.From], tx.Amount))
+		}
+		if acc.Balance > MAX_MONEY || tx.Amount > MAX_MONEY-acc.Balance {
+			return errors.New("Transaction amount would lead to balance overflow at the sender account.")
+		}
+		if tx.Fee > acc.Balance+tx.Amount {
+			return errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Fee = %v.", acc.Balance, tx.Fee))
+		}
+	default:
+		return errors.New(fmt.Sprintf("Invalid delegation operation: %v", tx.Header))
+	}
+
+	return nil
+}
+
+//Moves coins between the balance of the delegator and its delegation to the validator according to an already checked
+//delegateTx. The fee is taken right away, such that the next tx of the same delegator is checked against what is left.
+func applyDelegateTx(tx *protocol.DelegateTx, acc *protocol.Account, validatorAcc *protocol.Account) {
+	switch tx.Header {
+	case protocol.DELEGATE:
+		if validatorAcc.Delegations == nil {
+			validatorAcc.Delegations = make(map[[32]byte]uint64)
+		}
+		acc.Balance -= tx.Amount
+		validatorAcc.Delegations[tx.From] += tx.Amount
+	case protocol.UNDELEGATE:
+		validatorAcc.Delegations[tx.From] -= tx.Amount
+		if validatorAcc.Delegations[tx.From] == 0 {
+			delete(validatorAcc.Delegations, tx.From)
+		}
+		acc.Balance += tx.Amount
+	}
+
+	acc.Balance -= tx.Fee
+	acc.TxCnt += 1
+}
+
+func evidenceStateChange(txSlice []*protocol.EvidenceTx) (err error) {
+	for cnt, tx := range txSlice {
+		var acc, offenderAcc *protocol.Account
+		acc, err = storage.GetAccount(tx.From)
+		if err == nil {
+			offenderAcc, err = storage.GetAccount(tx.Offender)
+		}
+
+		if err == nil {
+			err = checkEvidenceTx(tx, acc, offenderAcc)
+		}
+
+		if err == nil {
+			err = storage.WriteEvidence(tx.EvidenceID(), tx.Hash())
+		}
+
+		if err != nil {
+			//Rollback the evidences of this slice that were already applied
+			evidenceStateChangeRollback(txSlice[:cnt])
 			return err
 		}
 
+		supplyChange.Issued += activeParameters.Slash_reward
+		supplyChange.Burned += offenderAcc.StakedAmount + offenderAcc.UnbondingAmount
+
 		//We're manipulating pointer, no need to write back
-		accSender.TxCnt += 1;
+		recordStakeState(protocol.UNDO_EVIDENCETX, tx.Offender, offenderAcc)
+		applyEvidenceTx(tx, acc, offenderAcc)
+	}
+
+	return nil
+}
+
+func delegateStateChange(txSlice []*protocol.DelegateTx) (err error) {
+	for cnt, tx := range txSlice {
+		var acc, validatorAcc *protocol.Account
+		acc, err = storage.GetAccount(tx.From)
+		if err == nil {
+			validatorAcc, err = storage.GetAccount(tx.Validator)
+		}
+
+		if err == nil {
+			err = checkDelegateTx(tx, acc, validatorAcc)
+		}
+
+		if err != nil {
+			//Rollback the delegations of this slice that were already applied
+			delegateStateChangeRollback(txSlice[:cnt])
+			return err
+		}
 
+		//We're manipulating pointer, no need to write back
+		applyDelegateTx(tx, acc, validatorAcc)
 	}
 
 	return nil
 }
 
+func governanceStateChange(txSlice []*protocol.GovernanceTx, height uint32) (err error) {
+	for cnt, tx := range txSlice {
+		var acc *protocol.Account
+		acc, err = storage.GetAccount(tx.From)
+		if err == nil {
+			err = checkGovernanceTx(tx, acc, height)
+		}
+
+		var proposalHash [32]byte
+		var proposal *protocol.Proposal
+		if err == nil {
+			//We're manipulating pointer, no need to write back the account
+			proposalHash, proposal = applyGovernanceTx(tx, acc)
+			err = storage.WriteProposal(proposalHash, proposal)
+			if err != nil {
+				acc.TxCnt -= 1
+				acc.Balance += tx.Fee
+			}
+		}
+
+		if err != nil {
+			//Rollback the proposals and votes of this slice that were already applied
+			governanceStateChangeRollback(txSlice[:cnt])
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Checks a contractTx against the current state of the contract, issuer and (for self-destruct) beneficiary account.
+func checkContractTx(tx *protocol.ContractTx, acc *protocol.Account, issuerAcc *protocol.Account, beneficiaryAcc *protocol.Account) error {
+	issuerHash := protocol.SerializeHashContent(tx.Issuer)
+
+	if acc.Issuer != issuerHash {
+		return errors.New("ContractTx is not signed by the issuer of the account.")
+	}
+
+	if acc.Contract == nil {
+		return errors.New("Account does not hold a contract.")
+	}
+
+	//Transaction count need to match the state of the contract account, preventing replay attacks.
+	if tx.TxCnt != acc.TxCnt {
+		return errors.New(fmt.Sprintf("Contract txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt).", tx.TxCnt, acc.TxCnt))
+	}
+
+	if !storage.IsRootKey(issuerHash) && tx.Fee > issuerAcc.Balance {
+		return errors.New(fmt.Sprintf("Issuer does not have enough funds for the transaction: Balance = %v, Fee = %v.", issuerAcc.Balance, tx.Fee))
+	}
+
+	switch tx.Header {
+	case protocol.CONTRACT_UPGRADE:
+		if len(tx.Contract) == 0 {
+			return errors.New("Contract upgrade without new contract code.")
+		}
+	case protocol.CONTRACT_FREEZE:
+		if acc.IsFrozen {
+			return errors.New("Contract is already frozen.")
+		}
+	case protocol.CONTRACT_SELFDESTRUCT:
+		if tx.Beneficiary == tx.Account || beneficiaryAcc == nil {
+			return errors.New("Invalid beneficiary for the contract self-destruct.")
+		}
+		if beneficiaryAcc.Balance+acc.Balance > MAX_MONEY {
+			return errors.New("Contract balance would lead to balance overflow at the beneficiary account.")
+		}
+	default:
+		return errors.New(fmt.Sprintf("Invalid contract operation: %v", tx.Header))
+	}
+
+	return nil
+}
+
+//Changes the contract account (and the beneficiary on self-destruct) according to an already checked contractTx.
+//An upgrade unfreezes the contract, such that a frozen buggy contract can be fixed and put back into service.
+func applyContractTx(tx *protocol.ContractTx, acc *protocol.Account, beneficiaryAcc *protocol.Account) {
+	switch tx.Header {
+	case protocol.CONTRACT_UPGRADE:
+		acc.ContractVariables = migrateContractVariables(acc.ContractVariables, tx.ContractVariables)
+		acc.Contract = tx.Contract
+		acc.IsFrozen = false
+	case protocol.CONTRACT_FREEZE:
+		acc.IsFrozen = true
+	case protocol.CONTRACT_SELFDESTRUCT:
+		beneficiaryAcc.Balance += acc.Balance
+		acc.Balance = 0
+		acc.Contract = nil
+		acc.ContractVariables = nil
+		acc.IsFrozen = true
+	}
+
+	acc.TxCnt += 1
+}
+
+//The new variables define the layout of the upgraded contract. Values of variables that exist in both layouts are
+//carried over from the old contract, newly added variables get the initial value given in the upgrade.
+func migrateContractVariables(oldVariables []protocol.ByteArray, newVariables []protocol.ByteArray) []protocol.ByteArray {
+	migrated := make([]protocol.ByteArray, len(newVariables))
+	for i := range newVariables {
+		if i < len(oldVariables) {
+			migrated[i] = append(protocol.ByteArray{}, oldVariables[i]...)
+		} else {
+			migrated[i] = append(protocol.ByteArray{}, newVariables[i]...)
+		}
+	}
+
+	return migrated
+}
+
+func contractStateChange(txSlice []*protocol.ContractTx, height uint32) (err error) {
+	for cnt, tx := range txSlice {
+		var acc, issuerAcc, beneficiaryAcc *protocol.Account
+		if acc, err = storage.GetAccount(tx.Account); err != nil {
+			contractStateChangeRollback(txSlice[:cnt], height)
+			return err
+		}
+
+		issuerAcc, _ = storage.GetAccount(protocol.SerializeHashContent(tx.Issuer))
+		if issuerAcc == nil && !storage.IsRootKey(protocol.SerializeHashContent(tx.Issuer)) {
+			contractStateChangeRollback(txSlice[:cnt], height)
+			return errors.New("Issuer account not present in the state.")
+		}
+
+		if tx.Header == protocol.CONTRACT_SELFDESTRUCT {
+			beneficiaryAcc, _ = storage.GetAccount(tx.Beneficiary)
+		}
+
+		if err = checkContractTx(tx, acc, issuerAcc, beneficiaryAcc); err != nil {
+			contractStateChangeRollback(txSlice[:cnt], height)
+			return err
+		}
+
+		//The previous account state is needed to revert upgrades and self-destructs.
+		if err = storage.WriteContractSnapshot(height, tx.Hash(), acc); err != nil {
+			contractStateChangeRollback(txSlice[:cnt], height)
+			return err
+		}
+
+		//We're manipulating pointer, no need to write back
+		applyContractTx(tx, acc, beneficiaryAcc)
+	}
+
+	return nil
+}
+
+//Snapshots are only needed as long as the block containing the ContractTx can be rolled back.
+func pruneContractSnapshots(height uint32) {
+	if height >= ROLLBACK_DEPTH {
+		storage.DeleteContractSnapshotsBelow(height - ROLLBACK_DEPTH + 1)
+	}
+}
+
 //this method does inititate the state change for aggregated Transactions. It does
 func aggTxStateChange(txSlice []*protocol.AggTx) (err error) {
 	for _, tx1 := range txSlice {
@@ -364,6 +692,7 @@ func fundsStateChange(txSlice []*protocol.FundsTx) (err error) {
 		if rootAcc != nil {
 			rootAcc.Balance += tx.Amount
 			rootAcc.Balance += tx.Fee
+			supplyChange.Issued += tx.Amount + tx.Fee
 		}
 
 		var accSender, accReceiver *protocol.Account
@@ -377,16 +706,16 @@ func fundsStateChange(txSlice []*protocol.FundsTx) (err error) {
 			//err = errors.New(fmt.Sprintf("Sender txCnt does not match: %v (tx.txCnt) vs. %v (state txCnt).", tx.TxCnt, accSender.TxCnt))
 		}
 
+		//Frozen (or self-destructed) contracts neither send nor receive funds anymore.
+		if accSender.IsFrozen || accReceiver.IsFrozen {
+			err = errors.New("Sender or receiver account is frozen.")
+		}
+
 		//Check sender balance
 		if (tx.Amount + tx.Fee) > accSender.Balance {
 			err = errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Amount = %v, Fee = %v.", accSender.Balance, tx.Amount, tx.Fee))
 		}
 
-		//After Tx fees, account must still have more than the minimum staking amount
-		if accSender.IsStaking && ((tx.Fee + protocol.MIN_STAKING_MINIMUM + tx.Amount) > accSender.Balance) {
-			err = errors.New("Sender is staking and does not have enough funds in order to fulfill the required staking minimum.")
-		}
-
 		//Overflow protection
 		if tx.Amount+accReceiver.Balance > MAX_MONEY {
 			err = errors.New("Transaction amount would lead to balance overflow at the receiver account.")
@@ -413,63 +742,194 @@ func fundsStateChange(txSlice []*protocol.FundsTx) (err error) {
 
 //We accept config slices with unknown id, but don't act on the payload. This is in case we have not updated to a new
 //software with corresponding code to act on the configTx id/payload
-func configStateChange(configTxSlice []*protocol.ConfigTx, blockHash [32]byte) {
+func configStateChange(configTxSlice []*protocol.ConfigTx, blockHash [32]byte, height uint32) {
 	var newParameters Parameters
 	//Initialize it to state right now (before validating config txs)
 	newParameters = *activeParameters
 
-	if len(configTxSlice) == 0 {
-		return
+	change := CheckAndChangeParameters(&newParameters, &configTxSlice)
+
+	//ConfigTxs with an activation height are queued, also if they are in force from the next block on already
+	for _, tx := range configTxSlice {
+		if tx.ActivationHeight != 0 {
+			storage.WriteParameterChange(&protocol.ParameterChange{
+				Id:               tx.Id,
+				Value:            tx.Payload,
+				ActivationHeight: tx.ActivationHeight,
+				Source:           tx.Hash(),
+			})
+		}
+	}
+
+	//Queued changes and accepted proposals are put in force from their activation height on
+	for _, scheduled := range storage.ReadScheduledParameterChanges() {
+		if scheduled.ActivationHeight != height+1 {
+			continue
+		}
+		//Proposals are counted again with the stake at their activation, voters might have left or been slashed
+		if scheduled.Proposal && !hasQuorum(proposalTally(storage.ReadProposal(scheduled.Source))) {
+			logger.Printf("Proposal %x lost its quorum and is not activated.", scheduled.Source[0:8])
+			continue
+		}
+		if changeParameter(&newParameters, scheduled.Id, scheduled.Value) {
+			logger.Printf("Scheduled parameter change activated: %v", scheduled)
+			change = true
+		}
 	}
 
 	//Only add a new parameter struct if a relevant system parameter changed
-	if CheckAndChangeParameters(&newParameters, &configTxSlice) {
+	if change {
+		//Go through all accounts and remove all validators from the validator set that no longer fulfill the minimum staking amount
+		if newParameters.Staking_minimum != activeParameters.Staking_minimum {
+			for hash, account := range storage.State {
+				if account.IsStaking && account.StakedAmount < newParameters.Staking_minimum {
+					recordStakeState(protocol.UNDO_STAKING_MINIMUM, hash, account)
+					account.IsStaking = false
+				}
+			}
+		}
+
 		newParameters.BlockHash = blockHash
 		parameterSlice = append(parameterSlice, newParameters)
 		activeParameters = &parameterSlice[len(parameterSlice)-1]
+		p2pNode.SetAcceptedTimeDiff(int64(activeParameters.Accepted_time_diff))
 		logger.Printf("Config parameters changed. New configuration: %v", *activeParameters)
 	}
 }
 
-func stakeStateChange(txSlice []*protocol.StakeTx, height uint32) (err error) {
-	for _, tx := range txSlice {
-		var accSender *protocol.Account
-		accSender, err = storage.GetAccount(tx.Account)
+//System parameters in force at the given height. The active parameters are in force at the height after the last
+//block, the changes scheduled up to the given height are applied to a copy of them.
+func parametersAt(height uint32) *Parameters {
+	var lastHeight uint32
+	if lastBlock != nil {
+		lastHeight = lastBlock.Height
+	}
 
-		//Check staking state
-		if tx.IsStaking == accSender.IsStaking {
-			err = errors.New("IsStaking state is already set to " + strconv.FormatBool(accSender.IsStaking) + ".")
-		}
+	if height <= lastHeight+1 {
+		return activeParameters
+	}
 
-		//Check minimum amount
-		if tx.IsStaking && accSender.Balance < tx.Fee+activeParameters.Staking_minimum {
-			err = errors.New(fmt.Sprintf("Sender wants to stake but does not have enough funds (%v) in order to fulfill the required staking minimum (%v).", accSender.Balance, STAKING_MINIMUM))
+	parameters := *activeParameters
+	for _, scheduled := range storage.ReadScheduledParameterChanges() {
+		if scheduled.ActivationHeight > lastHeight+1 && scheduled.ActivationHeight <= height {
+			changeParameter(&parameters, scheduled.Id, scheduled.Value)
 		}
+	}
 
-		//Check sender balance
-		if tx.Fee > accSender.Balance {
-			err = errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Amount = %v, Fee = %v.", accSender.Balance, 0, tx.Fee))
+	return &parameters
+}
+
+func stakeStateChange(txSlice []*protocol.StakeTx, height uint32) (err error) {
+	for cnt, tx := range txSlice {
+		var accSender *protocol.Account
+		accSender, err = storage.GetAccount(tx.Account)
+		if err == nil {
+			err = checkStakeTx(tx, accSender, height)
 		}
 
 		if err != nil {
+			stakeStateChangeRollback(txSlice[:cnt])
 			return err
 		}
 
 		//We're manipulating pointer, no need to write back
-		accSender.IsStaking = tx.IsStaking
-		accSender.CommitmentKey = tx.CommitmentKey
-		accSender.StakingBlockHeight = height
+		recordStakeState(protocol.UNDO_STAKETX, tx.Account, accSender)
+		applyStakeTx(tx, accSender, height)
 	}
 
 	return nil
 }
 
-func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsTx, configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, aggTxSlice []*protocol.AggTx, iotTxSlice []*protocol.IotTx, minerHash [32]byte) (err error) {
+//Checks whether the account can join the validator set, leave it or withdraw its stake after the unbonding period.
+func checkStakeTx(tx *protocol.StakeTx, acc *protocol.Account, height uint32) error {
+	if tx.Header&protocol.STAKETX_WITHDRAW != 0 {
+		if tx.IsStaking {
+			return errors.New("A withdrawal can't join the validator set.")
+		}
+		if !acc.IsUnbonding() {
+			return errors.New("Account has no unbonding stake to withdraw.")
+		}
+		if height < acc.UnbondingHeight {
+			return errors.New(fmt.Sprintf("Stake is unbonding until block %v.", acc.UnbondingHeight))
+		}
+		if tx.Fee > acc.Balance+acc.UnbondingAmount {
+			return errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Amount = %v, Fee = %v.", acc.Balance+acc.UnbondingAmount, 0, tx.Fee))
+		}
+
+		return nil
+	}
+
+	if tx.IsStaking {
+		if acc.IsStaking {
+			return errors.New("Account is already staking.")
+		}
+		//The stake of a previous validation period must be withdrawn first
+		if acc.IsUnbonding() || acc.StakedAmount != 0 {
+			return errors.New("Account still has stake which is not withdrawn.")
+		}
+		if acc.Balance < tx.Fee+activeParameters.Staking_minimum {
+			return errors.New(fmt.Sprintf("Sender wants to stake but does not have enough funds (%v) in order to fulfill the required staking minimum (%v).", acc.Balance, activeParameters.Staking_minimum))
+		}
+		if tx.Commission > protocol.MAX_COMMISSION {
+			return errors.New(fmt.Sprintf("Commission %v is higher than the maximum of %v.", tx.Commission, protocol.MAX_COMMISSION))
+		}
+
+		return nil
+	}
+
+	//Validators which were removed from the validator set (e.g. by a higher staking minimum) still need to unstake
+	//in order to get their stake back
+	if acc.StakedAmount == 0 {
+		return errors.New("Account has no stake to unbond.")
+	}
+	if tx.Fee > acc.Balance {
+		return errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction: Balance = %v, Amount = %v, Fee = %v.", acc.Balance, 0, tx.Fee))
+	}
+
+	return nil
+}
+
+//Locks the staking minimum when joining the validator set, starts the unbonding period when leaving it and releases
+//the stake on withdrawal. The stake stays slashable until it is withdrawn.
+func applyStakeTx(tx *protocol.StakeTx, acc *protocol.Account, height uint32) {
+	if tx.Header&protocol.STAKETX_WITHDRAW != 0 {
+		acc.Balance += acc.UnbondingAmount
+		acc.UnbondingAmount = 0
+		acc.UnbondingHeight = 0
+		return
+	}
+
+	if tx.IsStaking {
+		acc.Balance -= activeParameters.Staking_minimum
+		acc.StakedAmount = activeParameters.Staking_minimum
+		//The commission is only relevant while staking, it is set anew whenever the account joins the validator set.
+		acc.Commission = tx.Commission
+	} else {
+		acc.UnbondingAmount = acc.StakedAmount
+		acc.StakedAmount = 0
+		acc.UnbondingHeight = height + uint32(activeParameters.Unbonding_period)
+		//An unbonding period of 0 would make the stake withdrawable at once, the height marks the account as unbonding
+		if acc.UnbondingHeight == 0 {
+			acc.UnbondingHeight = 1
+		}
+	}
+
+	acc.IsStaking = tx.IsStaking
+	acc.CommitmentKey = tx.CommitmentKey
+	acc.StakingBlockHeight = height
+}
+
+func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsTx, configTxSlice []*protocol.ConfigTx, stakeTxSlice []*protocol.StakeTx, aggTxSlice []*protocol.AggTx, iotTxSlice []*protocol.IotTx, contractTxSlice []*protocol.ContractTx, iotBatchTxSlice []*protocol.IotBatchTx, delegateTxSlice []*protocol.DelegateTx, evidenceTxSlice []*protocol.EvidenceTx, governanceTxSlice []*protocol.GovernanceTx, minerHash [32]byte) (err error) {
 	var tmpAccTx []*protocol.AccTx
 	var tmpFundsTx []*protocol.FundsTx
 	var tmpConfigTx []*protocol.ConfigTx
 	var tmpStakeTx []*protocol.StakeTx
 	var tmpIoTTx []*protocol.IotTx
+	var tmpContractTx []*protocol.ContractTx
+	var tmpIoTBatchTx []*protocol.IotBatchTx
+	var tmpDelegateTx []*protocol.DelegateTx
+	var tmpEvidenceTx []*protocol.EvidenceTx
+	var tmpGovernanceTx []*protocol.GovernanceTx
 
 	minerAcc, err := storage.GetAccount(minerHash)
 	if err != nil {
@@ -503,12 +963,13 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 
 		if err != nil {
 			//Rollback of all perviously transferred transaction fees to the protocol's account
-			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
 			return err
 		}
 
 		//Money gets created from thin air, no need to subtract money from root key
-		minerAcc.Balance += tx.Fee
+		payTxFee(minerAcc, tx.Fee)
+		supplyChange.Issued += tx.Fee
 		tmpAccTx = append(tmpAccTx, tx)
 	}
 
@@ -519,15 +980,22 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
 		}
 
-		senderAcc, err = storage.GetAccount(tx.From)
+		if err == nil {
+			senderAcc, err = storage.GetAccount(tx.From)
+		}
+
+		//The fees of all txs of a sender together may exceed its balance, never let it wrap around
+		if err == nil && senderAcc.Balance < tx.Fee {
+			err = errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction fee: Balance = %v, Fee = %v.", senderAcc.Balance, tx.Fee))
+		}
 
 		if err != nil {
 			//Rollback of all perviously transferred transaction fees to the protocol's account
-			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
 			return err
 		}
 
-		minerAcc.Balance += tx.Fee
+		payTxFee(minerAcc, tx.Fee)
 		senderAcc.Balance -= tx.Fee
 		tmpFundsTx = append(tmpFundsTx, tx)
 	}
@@ -539,12 +1007,13 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 
 		if err != nil {
 			//Rollback of all perviously transferred transaction fees to the protocol's account
-			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
 			return err
 		}
 
 		//No need to subtract money because signed by root account
-		minerAcc.Balance += tx.Fee
+		payTxFee(minerAcc, tx.Fee)
+		supplyChange.Issued += tx.Fee
 		tmpConfigTx = append(tmpConfigTx, tx)
 	}
 
@@ -553,16 +1022,22 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
 		}
 
-		senderAcc, err = storage.GetAccount(tx.Account)
+		if err == nil {
+			senderAcc, err = storage.GetAccount(tx.Account)
+		}
+
+		if err == nil && senderAcc.Balance < tx.Fee {
+			err = errors.New(fmt.Sprintf("Sender does not have enough funds for the transaction fee: Balance = %v, Fee = %v.", senderAcc.Balance, tx.Fee))
+		}
 
 		if err != nil {
 			//Rollback of all perviously transferred transaction fees to the protocol's account
-			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, minerHash)
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
 			return err
 		}
 
 		senderAcc.Balance -= tx.Fee
-		minerAcc.Balance += tx.Fee
+		payTxFee(minerAcc, tx.Fee)
 		tmpStakeTx = append(tmpStakeTx, tx)
 	}
 
@@ -571,20 +1046,126 @@ func collectTxFees(accTxSlice []*protocol.AccTx, fundsTxSlice []*protocol.FundsT
 			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
 		}
 
-		senderAcc, err = storage.GetAccount(tx.From)
+		if err != nil {
+			//Rollback of all perviously transferred transaction fees to the protocol's account
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
+			return err
+		}
 
-		minerAcc.Balance += tx.Fee
-		senderAcc.Balance -= tx.Fee
+		//The fee has already been taken from the sender in iotStateChange
+		payTxFee(minerAcc, tx.Fee)
 		tmpIoTTx = append(tmpIoTTx, tx)
 	}
 
+	for _, tx := range contractTxSlice {
+		if minerAcc.Balance+tx.Fee > MAX_MONEY {
+			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
+		}
+
+		if err != nil {
+			//Rollback of all perviously transferred transaction fees to the protocol's account
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
+			return err
+		}
+
+		//Root issuers are exempt from paying fees, like for accTx and configTx the fee is created from thin air
+		issuerHash := protocol.SerializeHashContent(tx.Issuer)
+		if !storage.IsRootKey(issuerHash) {
+			senderAcc, _ = storage.GetAccount(issuerHash)
+			if senderAcc.Balance < tx.Fee {
+				collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
+				return errors.New(fmt.Sprintf("Issuer does not have enough funds for the transaction fee: Balance = %v, Fee = %v.", senderAcc.Balance, tx.Fee))
+			}
+			senderAcc.Balance -= tx.Fee
+		} else {
+			supplyChange.Issued += tx.Fee
+		}
+
+		payTxFee(minerAcc, tx.Fee)
+		tmpContractTx = append(tmpContractTx, tx)
+	}
+
+	for _, tx := range iotBatchTxSlice {
+		if minerAcc.Balance+tx.Fee > MAX_MONEY {
+			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
+		}
+
+		if err != nil {
+			//Rollback of all perviously transferred transaction fees to the protocol's account
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
+			return err
+		}
+
+		//The fee has already been taken from the sender in iotBatchStateChange. Root senders are exempt from paying
+		//fees, the fee is created from thin air.
+		if storage.IsRootKey(tx.From) {
+			supplyChange.Issued += tx.Fee
+		}
+
+		payTxFee(minerAcc, tx.Fee)
+		tmpIoTBatchTx = append(tmpIoTBatchTx, tx)
+	}
+
+	for _, tx := range delegateTxSlice {
+		if minerAcc.Balance+tx.Fee > MAX_MONEY {
+			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
+		}
+
+		if err != nil {
+			//Rollback of all perviously transferred transaction fees to the protocol's account
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
+			return err
+		}
+
+		//The fee has already been taken from the delegator in applyDelegateTx
+		payTxFee(minerAcc, tx.Fee)
+		tmpDelegateTx = append(tmpDelegateTx, tx)
+	}
+
+	for _, tx := range evidenceTxSlice {
+		if minerAcc.Balance+tx.Fee > MAX_MONEY {
+			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
+		}
+
+		if err != nil {
+			//Rollback of all perviously transferred transaction fees to the protocol's account
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
+			return err
+		}
+
+		//The fee has already been taken from the sender in applyEvidenceTx
+		payTxFee(minerAcc, tx.Fee)
+		tmpEvidenceTx = append(tmpEvidenceTx, tx)
+	}
+
+	for _, tx := range governanceTxSlice {
+		if minerAcc.Balance+tx.Fee > MAX_MONEY {
+			err = errors.New("Fee amount would lead to balance overflow at the miner account.")
+		}
+
+		if err != nil {
+			//Rollback of all perviously transferred transaction fees to the protocol's account
+			collectTxFeesRollback(tmpAccTx, tmpFundsTx, tmpConfigTx, tmpStakeTx, tmpIoTTx, tmpContractTx, tmpIoTBatchTx, tmpDelegateTx, tmpEvidenceTx, tmpGovernanceTx, minerHash)
+			return err
+		}
+
+		//The fee has already been taken from the validator in applyGovernanceTx
+		payTxFee(minerAcc, tx.Fee)
+		tmpGovernanceTx = append(tmpGovernanceTx, tx)
+	}
+
 	return nil
 }
 
+//The reward is capped by the maximum supply of the reward schedule, the reward actually paid is recorded as minted.
 func collectBlockReward(reward uint64, minerHash [32]byte) (err error) {
 	var miner *protocol.Account
 	miner, err = storage.GetAccount(minerHash)
+	if err != nil {
+		return err
+	}
 
+	reward = capBlockReward(reward)
 	if miner.Balance+reward > MAX_MONEY {
 		err = errors.New("Block reward would lead to balance overflow at the miner account.")
 	}
@@ -594,6 +1175,7 @@ func collectBlockReward(reward uint64, minerHash [32]byte) (err error) {
 	}
 
 	miner.Balance += reward
+	supplyChange.Minted += reward
 
 	return nil
 }
@@ -613,10 +1195,16 @@ func collectSlashReward(reward uint64, block *protocol.Block) (err error) {
 			return err
 		}
 
+		recordStakeState(protocol.UNDO_SLASH, block.SlashedAddress, slashedAcc)
+
 		//Validator is rewarded with slashing reward for providing a valid slashing proof
 		minerAcc.Balance += reward
-		//Slashed account looses the minimum staking amount
-		slashedAcc.Balance -= activeParameters.Staking_minimum
+		supplyChange.Issued += reward
+		//Slashed account looses its locked stake, also if it is still unbonding
+		supplyChange.Burned += slashedAcc.StakedAmount + slashedAcc.UnbondingAmount
+		slashedAcc.StakedAmount = 0
+		slashedAcc.UnbondingAmount = 0
+		slashedAcc.UnbondingHeight = 0
 		//Slashed account is being removed from the validator set
 		slashedAcc.IsStaking = false
 	}
@@ -624,13 +1212,14 @@ func collectSlashReward(reward uint64, block *protocol.Block) (err error) {
 	return nil
 }
 
-//No rollback method exists
+//The staking height of the beneficiary before the block is recorded in the undo log, see updateStakingHeightRollback
 func updateStakingHeight(block *protocol.Block) error {
 	acc, err := storage.GetAccount(block.Beneficiary)
 	if err != nil {
 		return err
 	}
 
+	recordStakeState(protocol.UNDO_STAKING_HEIGHT, block.Beneficiary, acc)
 	acc.StakingBlockHeight = block.Height
 
 	return nil
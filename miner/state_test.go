@@ -24,9 +24,10 @@ func TestFundsTxStateChange(t *testing.T) {
 	var testSize uint32
 	testSize = 1000
 
-	b := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	var funds []*protocol.FundsTx
 
+	//Only the tips are paid to the validator, the base fee is burned
 	var feeA, feeB uint64
 
 	//we're testing an overflowing balance in another test, this is that no interference occurs
@@ -38,20 +39,20 @@ func TestFundsTxStateChange(t *testing.T) {
 
 	loopMax := int(randVar.Uint32()%testSize + 1)
 	for i := 0; i < loopMax+1; i++ {
-		ftx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000000+1, randVar.Uint64()%100+1, uint32(i), accAHash, accBHash, PrivKeyAccA, nil, nil)
+		ftx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000000+1, randVar.Uint64()%100+1, uint32(i), accAHash, accBHash, PrivKeyAccA, nil)
 		if addTx(b, ftx) == nil {
 			funds = append(funds, ftx)
 			balanceA -= ftx.Amount
-			feeA += ftx.Fee
+			feeA += txTip(ftx.Fee)
 
 			balanceB += ftx.Amount
 		}
 
-		ftx2, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000+1, randVar.Uint64()%100+1, uint32(i), accAHash, accAHash, PrivKeyAccB, nil, nil)
+		ftx2, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000+1, randVar.Uint64()%100+1, uint32(i), accAHash, accAHash, PrivKeyAccB, nil)
 		if addTx(b, ftx2) == nil {
 			funds = append(funds, ftx2)
 			balanceB -= ftx2.Amount
-			feeB += ftx2.Fee
+			feeB += txTip(ftx2.Fee)
 
 			balanceA += ftx2.Amount
 		}
@@ -63,7 +64,7 @@ func TestFundsTxStateChange(t *testing.T) {
 		t.Errorf("State update failed: %v != %v or %v != %v\n", accA.Balance, balanceA, accB.Balance, balanceB)
 	}
 
-	collectTxFees(nil, funds, nil, nil, minerAccHash)
+	collectTxFees(nil, funds, nil, nil, nil, nil, nil, nil, nil, nil, nil, minerAccHash)
 	if feeA+feeB != validatorAcc.Balance-minerBal {
 		t.Error("Fee Collection failed!")
 	}
@@ -85,7 +86,7 @@ func TestAccountOverflow(t *testing.T) {
 
 	accA.Balance = MAX_MONEY
 	accA.TxCnt = 0
-	tx, err := protocol.ConstrFundsTx(0x01, 1, 1, 0, accBHash, accAHash, PrivKeyAccB, PrivKeyMultiSig, nil)
+	tx, err := protocol.ConstrFundsTx(0x01, 1, 1, 0, accBHash, accAHash, PrivKeyAccB, nil)
 	if !verifyFundsTx(tx) || err != nil {
 		t.Error("Failed to create reasonable fundsTx\n")
 		return
@@ -110,7 +111,7 @@ func TestAccTxStateChange(t *testing.T) {
 
 	var accs []*protocol.AccTx
 
-	nullAddress := [64]byte{}
+	nullAddress := [32]byte{}
 	loopMax := int(randVar.Uint32()%testSize) + 1
 	for i := 0; i < loopMax; i++ {
 		tx, _, _ := protocol.ConstrAccTx(0, randVar.Uint64()%1000, nullAddress, PrivKeyRoot, nil, nil)
@@ -132,7 +133,7 @@ func TestAccTxStateChange(t *testing.T) {
 	var singleSlice []*protocol.AccTx
 	tx, _, _ := protocol.ConstrAccTx(0x01, randVar.Uint64()%1000, nullAddress, PrivKeyRoot, nil, nil)
 	singleSlice = append(singleSlice, tx)
-	var pubKeyTmp [64]byte
+	var pubKeyTmp [32]byte
 	copy(pubKeyTmp[:], tx.PubKey[:])
 
 	accStateChange(singleSlice)
@@ -162,7 +163,7 @@ func TestConfigTxStateChange(t *testing.T) {
 
 	loopMax := int(randVar.Uint32()%testSize) + 1
 	for i := 0; i < loopMax; i++ {
-		tx, err := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), uint8(randVar.Uint32()%5+1), randVar.Uint64()%10000000, randVar.Uint64(), uint8(i), PrivKeyRoot)
+		tx, err := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), uint8(randVar.Uint32()%5+1), randVar.Uint64()%10000000, randVar.Uint64(), uint8(i), 0, PrivKeyRoot)
 		if err != nil {
 			t.Errorf("ConfigTx Creation failed (%v)\n", err)
 		}
@@ -173,7 +174,7 @@ func TestConfigTxStateChange(t *testing.T) {
 
 	parameterSet := *activeParameters
 	tmpLen := len(parameterSlice)
-	configStateChange(configs, [32]byte{'0', '1'})
+	configStateChange(configs, [32]byte{'0', '1'}, 1)
 	parameterSet2 := *activeParameters
 	if tmpLen != len(parameterSlice)-1 || reflect.DeepEqual(parameterSet, parameterSet2) {
 		t.Errorf("Config State Change malfunctioned: %v != %v\n", tmpLen, len(parameterSlice)-1)
@@ -182,16 +183,16 @@ func TestConfigTxStateChange(t *testing.T) {
 	cleanAndPrepare()
 	var configs2 []*protocol.ConfigTx
 	//test the inner workings of configStateChange as well...
-	tx, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 1, 1000, randVar.Uint64(), 0, PrivKeyRoot)
-	tx2, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 2, 2000, randVar.Uint64(), 0, PrivKeyRoot)
-	tx3, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 3, 3000, randVar.Uint64(), 0, PrivKeyRoot)
-	tx4, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 4, 4000, randVar.Uint64(), 0, PrivKeyRoot)
-	tx5, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 5, 5000, randVar.Uint64(), 0, PrivKeyRoot)
-	tx6, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 6, 6000, randVar.Uint64(), 0, PrivKeyRoot)
-	tx7, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 7, 7, randVar.Uint64(), 0, PrivKeyRoot)
-	tx8, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 8, 8, randVar.Uint64(), 0, PrivKeyRoot)
-	tx9, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 9, 9000, randVar.Uint64(), 0, PrivKeyRoot)
-	tx10, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 10, 10000, randVar.Uint64(), 0, PrivKeyRoot)
+	tx, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 1, 1000, randVar.Uint64(), 0, 0, PrivKeyRoot)
+	tx2, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 2, 2000, randVar.Uint64(), 0, 0, PrivKeyRoot)
+	tx3, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 3, 3000, randVar.Uint64(), 0, 0, PrivKeyRoot)
+	tx4, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 4, 4000, randVar.Uint64(), 0, 0, PrivKeyRoot)
+	tx5, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 5, 5000, randVar.Uint64(), 0, 0, PrivKeyRoot)
+	tx6, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 6, 6000, randVar.Uint64(), 0, 0, PrivKeyRoot)
+	tx7, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 7, 7, randVar.Uint64(), 0, 0, PrivKeyRoot)
+	tx8, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 8, 8, randVar.Uint64(), 0, 0, PrivKeyRoot)
+	tx9, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 9, 9000, randVar.Uint64(), 0, 0, PrivKeyRoot)
+	tx10, _ := protocol.ConstrConfigTx(uint8(randVar.Uint32()%256), 10, 10000, randVar.Uint64(), 0, 0, PrivKeyRoot)
 
 	configs2 = append(configs2, tx)
 	configs2 = append(configs2, tx2)
@@ -204,7 +205,7 @@ func TestConfigTxStateChange(t *testing.T) {
 	configs2 = append(configs2, tx9)
 	configs2 = append(configs2, tx10)
 
-	configStateChange(configs2, [32]byte{})
+	configStateChange(configs2, [32]byte{}, 1)
 	if activeParameters.Block_size != 1000 ||
 		activeParameters.Diff_interval != 2000 ||
 		activeParameters.Fee_minimum != 3000 ||
@@ -225,9 +226,9 @@ func TestConfigTxStateChangeUnknown(t *testing.T) {
 
 	//Issuing configTxs with unknown Id
 	var configs []*protocol.ConfigTx
-	tx, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 11, 1000, rand.Uint64(), 0, PrivKeyRoot)
-	tx2, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 11, 2000, rand.Uint64(), 0, PrivKeyRoot)
-	tx3, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 11, 3000, rand.Uint64(), 0, PrivKeyRoot)
+	tx, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 99, 1000, rand.Uint64(), 0, 0, PrivKeyRoot)
+	tx2, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 99, 2000, rand.Uint64(), 0, 0, PrivKeyRoot)
+	tx3, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 99, 3000, rand.Uint64(), 0, 0, PrivKeyRoot)
 
 	//save parameter state
 	tmpParameter := parameterSlice[len(parameterSlice)-1]
@@ -236,7 +237,7 @@ func TestConfigTxStateChangeUnknown(t *testing.T) {
 	configs = append(configs, tx2)
 	configs = append(configs, tx3)
 
-	configStateChange(configs, [32]byte{'0', '1'})
+	configStateChange(configs, [32]byte{'0', '1'}, 1)
 
 	if !reflect.DeepEqual(tmpParameter, *activeParameters) {
 		t.Error("Parameter state changed even though it shouldn't have.")
@@ -249,10 +250,10 @@ func TestConfigTxStateChangeUnknown(t *testing.T) {
 	}
 
 	//Adding a tx that changes state
-	tx4, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 2, 3000, rand.Uint64(), 0, PrivKeyRoot)
+	tx4, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 2, 3000, rand.Uint64(), 0, 0, PrivKeyRoot)
 	configs = append(configs, tx4)
 
-	configStateChange(configs, [32]byte{'0', '1'})
+	configStateChange(configs, [32]byte{'0', '1'}, 1)
 
 	if reflect.DeepEqual(tmpParameter, *activeParameters) {
 		t.Error("Parameter state changed even though it shouldn't have.")
@@ -264,7 +265,7 @@ func TestConfigTxStateChangeUnknown(t *testing.T) {
 		t.Error("Parameter state changed even though it shouldn't have.")
 	}
 
-	configStateChange(configs, [32]byte{'0', '1'})
+	configStateChange(configs, [32]byte{'0', '1'}, 1)
 	configStateChangeRollback(configs, [32]byte{'0'})
 	//Only change if block hashes match
 	if reflect.DeepEqual(tmpParameter, *activeParameters) {
@@ -280,13 +281,13 @@ func TestStakeTxStateChange(t *testing.T) {
 
 	accAHash := protocol.SerializeHashContent(accA.Address)
 
-	b := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	var stake, stake2 []*protocol.StakeTx
 
 	accA.IsStaking = false
 	stakingA := accA.IsStaking
 
-	stx, _ := protocol.ConstrStakeTx(0x01, randVar.Uint64()%100+1, true, accAHash, PrivKeyAccA, &CommPrivKeyAccA.PublicKey)
+	stx, _ := protocol.ConstrStakeTx(0x01, randVar.Uint64()%100+1, true, 0, accAHash, PrivKeyAccA, &CommPrivKeyAccA.PublicKey)
 	if addTx(b, stx) == nil {
 		stakingA = true
 		stake = append(stake, stx)
@@ -297,7 +298,7 @@ func TestStakeTxStateChange(t *testing.T) {
 		t.Errorf("State update failed: %v != %v", accA.IsStaking, stakingA)
 	}
 
-	stx2, _ := protocol.ConstrStakeTx(0x01, randVar.Uint64()%100+1, false, accAHash, PrivKeyAccA, &CommPrivKeyAccA.PublicKey)
+	stx2, _ := protocol.ConstrStakeTx(0x01, randVar.Uint64()%100+1, false, 0, accAHash, PrivKeyAccA, &CommPrivKeyAccA.PublicKey)
 	if addTx(b, stx) == nil {
 		stakingA = false
 		stake2 = append(stake2, stx2)
@@ -47,6 +47,108 @@ func fundsStateChangeRollback(txSlice []*protocol.FundsTx) {
 	}
 }
 
+func iotStateChangeRollback(txSlice []*protocol.IotTx) {
+	//Rollback in reverse order than original state change
+	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
+		tx := txSlice[cnt]
+
+		accSender, _ := storage.GetAccount(tx.From)
+		accSender.TxCnt -= 1
+		accSender.Balance += tx.Fee
+
+		//If new coins were issued, revert
+		if rootAcc, _ := storage.GetRootAccount(tx.From); rootAcc != nil {
+			rootAcc.Balance -= tx.Fee
+		}
+	}
+}
+
+func iotBatchStateChangeRollback(txSlice []*protocol.IotBatchTx) {
+	//Rollback in reverse order than original state change
+	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
+		tx := txSlice[cnt]
+
+		accSender, _ := storage.GetAccount(tx.From)
+		accSender.TxCnt -= 1
+		if !storage.IsRootKey(tx.From) {
+			accSender.Balance += tx.Fee
+		}
+	}
+}
+
+func delegateStateChangeRollback(txSlice []*protocol.DelegateTx) {
+	//Rollback in reverse order than original state change
+	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
+		tx := txSlice[cnt]
+
+		acc, _ := storage.GetAccount(tx.From)
+		validatorAcc, _ := storage.GetAccount(tx.Validator)
+
+		switch tx.Header {
+		case protocol.DELEGATE:
+			validatorAcc.Delegations[tx.From] -= tx.Amount
+			if validatorAcc.Delegations[tx.From] == 0 {
+				delete(validatorAcc.Delegations, tx.From)
+			}
+			acc.Balance += tx.Amount
+		case protocol.UNDELEGATE:
+			if validatorAcc.Delegations == nil {
+				validatorAcc.Delegations = make(map[[32]byte]uint64)
+			}
+			acc.Balance -= tx.Amount
+			validatorAcc.Delegations[tx.From] += tx.Amount
+		}
+
+		acc.Balance += tx.Fee
+		acc.TxCnt -= 1
+	}
+}
+
+func evidenceStateChangeRollback(txSlice []*protocol.EvidenceTx) {
+	//Rollback in reverse order than original state change
+	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
+		tx := txSlice[cnt]
+
+		acc, _ := storage.GetAccount(tx.From)
+		offenderAcc, _ := storage.GetAccount(tx.Offender)
+
+		acc.Balance -= activeParameters.Slash_reward
+		acc.Balance += tx.Fee
+		acc.TxCnt -= 1
+		undoStakeState(protocol.UNDO_EVIDENCETX, tx.Offender, offenderAcc)
+
+		storage.DeleteEvidence(tx.EvidenceID())
+	}
+}
+
+func governanceStateChangeRollback(txSlice []*protocol.GovernanceTx) {
+	//Rollback in reverse order than original state change
+	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
+		tx := txSlice[cnt]
+
+		acc, _ := storage.GetAccount(tx.From)
+		acc.TxCnt -= 1
+		acc.Balance += tx.Fee
+
+		if tx.Header == protocol.GOVERNANCE_PROPOSAL {
+			storage.DeleteProposal(tx.Hash())
+			continue
+		}
+
+		proposal := storage.ReadProposal(tx.ProposalHash)
+		if proposal == nil || len(proposal.Votes) == 0 || proposal.Votes[len(proposal.Votes)-1].Voter != tx.From {
+			logger.Fatalf("CRITICAL: The vote of %x for proposal %x that should have been saved does not exist.", tx.From[0:8], tx.ProposalHash[0:8])
+		}
+
+		proposal.Votes = proposal.Votes[:len(proposal.Votes)-1]
+		proposal.Tally = proposalTally(proposal)
+		if proposal.AcceptedBy == tx.Hash() {
+			proposal.AcceptedBy = [32]byte{}
+		}
+		storage.WriteProposal(tx.ProposalHash, proposal)
+	}
+}
+
 func aggregatedSenderStateRollback(txSlice []*protocol.AggTx) {
 	//Rollback in reverse order than original state change
 
@@ -74,20 +176,32 @@ func aggregatedSenderStateRollback(txSlice []*protocol.AggTx) {
 }
 
 func configStateChangeRollback(txSlice []*protocol.ConfigTx, blockHash [32]byte) {
-	if len(txSlice) == 0 {
-		return
+	//Changes queued by the block are dropped, also those which were not in force yet
+	for _, tx := range txSlice {
+		if tx.ActivationHeight != 0 {
+			storage.DeleteParameterChange(tx.Hash())
+		}
 	}
 
-	//Only rollback if the config changes lead to a parameterChange
+	//Only rollback if the config changes or activated proposals lead to a parameterChange
 	//there might be the case that the client is not running the latest version, it's still confirming
 	//the transaction but does not understand the ID and thus is not changing the state
-	if parameterSlice[len(parameterSlice)-1].BlockHash != blockHash {
+	//The initial parameters are never rolled back.
+	if len(parameterSlice) == 1 || parameterSlice[len(parameterSlice)-1].BlockHash != blockHash {
 		return
 	}
 
+	//Validators which were removed from the validator set by a higher staking minimum join it again
+	for lastUndoOp() == protocol.UNDO_STAKING_MINIMUM {
+		entry := undoLog.Entries[len(undoLog.Entries)-1]
+		acc, _ := storage.GetAccount(entry.Account)
+		undoStakeState(protocol.UNDO_STAKING_MINIMUM, entry.Account, acc)
+	}
+
 	//remove the latest entry in the parameters slice$
 	parameterSlice = parameterSlice[:len(parameterSlice)-1]
 	activeParameters = &parameterSlice[len(parameterSlice)-1]
+	p2pNode.SetAcceptedTimeDiff(int64(activeParameters.Accepted_time_diff))
 	logger.Printf("Config parameters rolled back. New configuration: %v", *activeParameters)
 }
 
@@ -97,22 +211,52 @@ func stakeStateChangeRollback(txSlice []*protocol.StakeTx) {
 		tx := txSlice[cnt]
 
 		accSender, _ := storage.GetAccount(tx.Account)
-		//Rolling back stakingBlockHeight not needed
-		accSender.IsStaking = !accSender.IsStaking
+		locked := accSender.StakedAmount + accSender.UnbondingAmount
+		before := undoStakeState(protocol.UNDO_STAKETX, tx.Account, accSender)
+
+		//Coins which were locked or released by the tx are moved back
+		accSender.Balance = accSender.Balance + locked - before.StakedAmount - before.UnbondingAmount
 	}
 }
 
-func collectTxFeesRollback(accTx []*protocol.AccTx, fundsTx []*protocol.FundsTx, configTx []*protocol.ConfigTx, stakeTx []*protocol.StakeTx, minerHash [32]byte) {
+func contractStateChangeRollback(txSlice []*protocol.ContractTx, height uint32) {
+	//Rollback in reverse order than original state change
+	for cnt := len(txSlice) - 1; cnt >= 0; cnt-- {
+		tx := txSlice[cnt]
+		txHash := tx.Hash()
+
+		acc, _ := storage.GetAccount(tx.Account)
+		snapshot := storage.ReadContractSnapshot(height, txHash)
+		if snapshot == nil {
+			logger.Fatal("CRITICAL: The snapshot of a contract that should have been saved does not exist.")
+		}
+
+		if tx.Header == protocol.CONTRACT_SELFDESTRUCT {
+			beneficiaryAcc, _ := storage.GetAccount(tx.Beneficiary)
+			beneficiaryAcc.Balance -= snapshot.Balance
+			acc.Balance = snapshot.Balance
+		}
+
+		acc.Contract = snapshot.Contract
+		acc.ContractVariables = snapshot.ContractVariables
+		acc.IsFrozen = snapshot.IsFrozen
+		acc.TxCnt -= 1
+
+		storage.DeleteContractSnapshot(height, txHash)
+	}
+}
+
+func collectTxFeesRollback(accTx []*protocol.AccTx, fundsTx []*protocol.FundsTx, configTx []*protocol.ConfigTx, stakeTx []*protocol.StakeTx, iotTx []*protocol.IotTx, contractTx []*protocol.ContractTx, iotBatchTx []*protocol.IotBatchTx, delegateTx []*protocol.DelegateTx, evidenceTx []*protocol.EvidenceTx, governanceTx []*protocol.GovernanceTx, minerHash [32]byte) {
 	minerAcc, _ := storage.GetAccount(minerHash)
 
 	//Subtract fees from sender (check if that is allowed has already been done in the block validation)
 	for _, tx := range accTx {
 		//Money was created out of thin air, no need to write back
-		minerAcc.Balance -= tx.Fee
+		payTxFeeRollback(minerAcc, tx.Fee)
 	}
 
 	for _, tx := range fundsTx {
-		minerAcc.Balance -= tx.Fee
+		payTxFeeRollback(minerAcc, tx.Fee)
 
 		senderAcc, _ := storage.GetAccount(tx.From)
 		senderAcc.Balance += tx.Fee
@@ -120,20 +264,49 @@ func collectTxFeesRollback(accTx []*protocol.AccTx, fundsTx []*protocol.FundsTx,
 
 	for _, tx := range configTx {
 		//Money was created out of thin air, no need to write back
-		minerAcc.Balance -= tx.Fee
+		payTxFeeRollback(minerAcc, tx.Fee)
 	}
 
 	for _, tx := range stakeTx {
-		minerAcc.Balance -= tx.Fee
+		payTxFeeRollback(minerAcc, tx.Fee)
 
 		senderAcc, _ := storage.GetAccount(tx.Account)
 		senderAcc.Balance += tx.Fee
 	}
-	for _, tx := range stakeTx {
-		minerAcc.Balance -= tx.Fee
 
-		senderAcc, _ := storage.GetAccount(tx.Account)
-		senderAcc.Balance += tx.Fee
+	for _, tx := range iotTx {
+		//The fee is given back to the sender in iotStateChangeRollback
+		payTxFeeRollback(minerAcc, tx.Fee)
+	}
+
+	for _, tx := range contractTx {
+		payTxFeeRollback(minerAcc, tx.Fee)
+
+		issuerHash := protocol.SerializeHashContent(tx.Issuer)
+		if !storage.IsRootKey(issuerHash) {
+			issuerAcc, _ := storage.GetAccount(issuerHash)
+			issuerAcc.Balance += tx.Fee
+		}
+	}
+
+	for _, tx := range iotBatchTx {
+		//The fee is given back to the sender in iotBatchStateChangeRollback
+		payTxFeeRollback(minerAcc, tx.Fee)
+	}
+
+	for _, tx := range delegateTx {
+		//The fee is given back to the delegator in delegateStateChangeRollback
+		payTxFeeRollback(minerAcc, tx.Fee)
+	}
+
+	for _, tx := range evidenceTx {
+		//The fee is given back to the sender in evidenceStateChangeRollback
+		payTxFeeRollback(minerAcc, tx.Fee)
+	}
+
+	for _, tx := range governanceTx {
+		//The fee is given back to the validator in governanceStateChangeRollback
+		payTxFeeRollback(minerAcc, tx.Fee)
 	}
 }
 
@@ -148,7 +321,13 @@ func collectSlashRewardRollback(reward uint64, block *protocol.Block) {
 		slashedAcc, _ := storage.GetAccount(block.SlashedAddress)
 
 		minerAcc.Balance -= reward
-		slashedAcc.Balance += activeParameters.Staking_minimum
-		slashedAcc.IsStaking = true
+
+		//The burnt stake is restored, the balance of the slashed account was not touched
+		undoStakeState(protocol.UNDO_SLASH, block.SlashedAddress, slashedAcc)
 	}
 }
+
+func updateStakingHeightRollback(block *protocol.Block) {
+	acc, _ := storage.GetAccount(block.Beneficiary)
+	undoStakeState(protocol.UNDO_STAKING_HEIGHT, block.Beneficiary, acc)
+}
@@ -24,7 +24,7 @@ func TestFundsStateChangeRollback(t *testing.T) {
 	var testSize uint32
 	testSize = 1000
 
-	b := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	var funds []*protocol.FundsTx
 
 	var feeA, feeB uint64
@@ -39,7 +39,7 @@ func TestFundsStateChangeRollback(t *testing.T) {
 
 	loopMax := int(randVar.Uint32()%testSize + 1)
 	for i := 0; i < loopMax+1; i++ {
-		ftx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000000+1, randVar.Uint64()%100+1, uint32(i), accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+		ftx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000000+1, randVar.Uint64()%100+1, uint32(i), accAHash, accBHash, PrivKeyAccA, nil)
 		if addTx(b, ftx) == nil {
 			funds = append(funds, ftx)
 			balanceA -= ftx.Amount
@@ -50,7 +50,7 @@ func TestFundsStateChangeRollback(t *testing.T) {
 			t.Errorf("Block rejected a valid transaction: %v\n", ftx)
 		}
 
-		ftx2, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000+1, randVar.Uint64()%100+1, uint32(i), accBHash, accAHash, PrivKeyAccB, PrivKeyMultiSig, nil)
+		ftx2, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000+1, randVar.Uint64()%100+1, uint32(i), accBHash, accAHash, PrivKeyAccB, nil)
 		if addTx(b, ftx2) == nil {
 			funds = append(funds, ftx2)
 			balanceB -= ftx2.Amount
@@ -94,7 +94,7 @@ func TestAccStateChangeRollback(t *testing.T) {
 	var accs []*protocol.AccTx
 
 	//Store accs that are to be changed and rolled back in a accTx slice
-	nullAddress := [64]byte{}
+	nullAddress := [32]byte{}
 	loopMax := int(randVar.Uint32()%testSize) + 1
 	for i := 0; i < loopMax; i++ {
 		tx, _, _ := protocol.ConstrAccTx(0, randVar.Uint64()%1000, nullAddress, PrivKeyRoot, nil, nil)
@@ -127,11 +127,11 @@ func TestConfigStateChangeRollback(t *testing.T) {
 
 	var configSlice []*protocol.ConfigTx
 
-	tx, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 1, 1000, rand.Uint64(), 0, PrivKeyRoot)
-	tx2, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 2, 2000, rand.Uint64(), 0, PrivKeyRoot)
-	tx3, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 3, 3000, rand.Uint64(), 0, PrivKeyRoot)
-	tx4, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 4, 4000, rand.Uint64(), 0, PrivKeyRoot)
-	tx5, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 5, 5000, rand.Uint64(), 0, PrivKeyRoot)
+	tx, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 1, 1000, rand.Uint64(), 0, 0, PrivKeyRoot)
+	tx2, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 2, 2000, rand.Uint64(), 0, 0, PrivKeyRoot)
+	tx3, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 3, 3000, rand.Uint64(), 0, 0, PrivKeyRoot)
+	tx4, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 4, 4000, rand.Uint64(), 0, 0, PrivKeyRoot)
+	tx5, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), 5, 5000, rand.Uint64(), 0, 0, PrivKeyRoot)
 
 	configSlice = append(configSlice, tx)
 	configSlice = append(configSlice, tx2)
@@ -140,7 +140,7 @@ func TestConfigStateChangeRollback(t *testing.T) {
 	configSlice = append(configSlice, tx5)
 
 	before := *activeParameters
-	configStateChange(configSlice, [32]byte{'0', '1', '2'})
+	configStateChange(configSlice, [32]byte{'0', '1', '2'}, 1)
 	if reflect.DeepEqual(before, *activeParameters) {
 		t.Error("No config state change.")
 	}
@@ -166,17 +166,17 @@ func TestCollectTxFeesRollback(t *testing.T) {
 	var fee uint64
 	loopMax := int(randVar.Uint64() % 1000)
 	for i := 0; i < loopMax+1; i++ {
-		tx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000000+1, randVar.Uint64()%100+1, uint32(i), accAHash, accBHash, PrivKeyAccA, nil, nil)
+		tx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000000+1, randVar.Uint64()%100+1, uint32(i), accAHash, accBHash, PrivKeyAccA, nil)
 
 		funds = append(funds, tx)
-		fee += tx.Fee
+		fee += txTip(tx.Fee)
 	}
 
-	collectTxFees(nil, funds, nil, nil, minerHash)
+	collectTxFees(nil, funds, nil, nil, nil, nil, nil, nil, nil, nil, nil, minerHash)
 	if minerBal+fee != validatorAcc.Balance {
 		t.Errorf("%v + %v != %v\n", minerBal, fee, validatorAcc.Balance)
 	}
-	collectTxFeesRollback(nil, funds, nil, nil, minerHash)
+	collectTxFeesRollback(nil, funds, nil, nil, nil, nil, nil, nil, nil, nil, minerHash)
 	if minerBal != validatorAcc.Balance {
 		t.Errorf("Tx fees rollback failed: %v != %v\n", minerBal, validatorAcc.Balance)
 	}
@@ -186,7 +186,7 @@ func TestCollectTxFeesRollback(t *testing.T) {
 	minerBal = validatorAcc.Balance
 	//Miner gets fees, the miner account balance will overflow at some point
 	for i := 2; i < 100; i++ {
-		tx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000000+1, uint64(i), uint32(i), accAHash, accBHash, PrivKeyAccA, nil, nil)
+		tx, _ := protocol.ConstrFundsTx(0x01, randVar.Uint64()%1000000+1, uint64(i), uint32(i), accAHash, accBHash, PrivKeyAccA, nil)
 		funds2 = append(funds2, tx)
 		fee2 += tx.Fee
 	}
@@ -194,9 +194,9 @@ func TestCollectTxFeesRollback(t *testing.T) {
 	accABal := accA.Balance
 	accBBal := accB.Balance
 	//Should throw an error and result in a rollback, because of acc balance overflow
-	tmpBlock := newBlock([32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	tmpBlock := newBlock([32]byte{}, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
 	tmpBlock.Beneficiary = minerHash
-	data := blockData{nil, funds2, nil, nil, tmpBlock}
+	data := blockData{fundsTxSlice: funds2, block: tmpBlock}
 	if err := validateState(data); err == nil ||
 		minerBal != validatorAcc.Balance ||
 		accA.Balance != accABal ||
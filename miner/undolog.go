@@ -0,0 +1,38 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//Consensus related account changes of the block which is validated or rolled back at the moment. The log is stored
+//with the block once it is validated and replayed in reverse order when the block is rolled back.
+var undoLog = new(protocol.UndoLog)
+
+//Records the consensus related fields of the account before op changes them.
+func recordStakeState(op uint8, accHash [32]byte, acc *protocol.Account) {
+	undoLog.Entries = append(undoLog.Entries, protocol.UndoEntry{Op: op, Account: accHash, Before: acc.StakeState()})
+}
+
+//Restores the account from the latest entry of the undo log and returns the restored state. The entry must have been
+//recorded by op for this account, otherwise the rollback doesn't replay the log in the right order.
+func undoStakeState(op uint8, accHash [32]byte, acc *protocol.Account) protocol.StakeState {
+	last := len(undoLog.Entries) - 1
+	if last < 0 || undoLog.Entries[last].Op != op || undoLog.Entries[last].Account != accHash {
+		logger.Fatalf("CRITICAL: The undo log does not match the rollback of operation %v on account %x.", op, accHash[0:8])
+	}
+
+	entry := undoLog.Entries[last]
+	undoLog.Entries = undoLog.Entries[:last]
+	acc.RestoreStakeState(entry.Before)
+
+	return entry.Before
+}
+
+//Operation of the latest entry of the undo log, 0 if the log is empty.
+func lastUndoOp() uint8 {
+	if len(undoLog.Entries) == 0 {
+		return 0
+	}
+
+	return undoLog.Entries[len(undoLog.Entries)-1].Op
+}
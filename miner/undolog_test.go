@@ -0,0 +1,86 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//A block with stake changes, an evidence, a slashing proof and a higher staking minimum is rolled back exactly
+func TestUndoLogRollbackWithSlashingProof(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	validatorHash := protocol.SerializeHashContent(validatorAcc.Address)
+	rootHash := protocol.SerializeHashContent(rootAcc.Address)
+
+	//accB validates with exactly the staking minimum, the validator is reported by accA, the root is slashed by the block
+	accB.IsStaking = true
+	accB.StakedAmount = activeParameters.Staking_minimum
+	accB.StakingBlockHeight = 3
+	validatorAcc.StakedAmount = activeParameters.Staking_minimum
+	rootAcc.StakedAmount = activeParameters.Staking_minimum
+	rootAcc.StakingBlockHeight = 2
+	before := map[*protocol.Account]protocol.Account{}
+	for _, acc := range []*protocol.Account{accA, accB, validatorAcc, rootAcc} {
+		before[acc] = *acc
+	}
+	stakingMinimum := activeParameters.Staking_minimum
+
+	stake, _ := protocol.ConstrStakeTx(0, 1, true, 10, accAHash, PrivKeyAccA, &CommPrivKeyAccA.PublicKey)
+	header1 := signedHeader(5, [32]byte{0x01}, [32]byte{0x10}, validatorHash, commPrivKey)
+	header2 := signedHeader(5, [32]byte{0x02}, [32]byte{0x10}, validatorHash, commPrivKey)
+	evidence, _ := protocol.ConstrEvidenceTx(protocol.EVIDENCE_DOUBLE_PROPOSAL, 1, 0, accAHash, header1, header2, PrivKeyAccA)
+	config, _ := protocol.ConstrConfigTx(0, protocol.STAKING_MINIMUM_ID, stakingMinimum+1, 1, 0, 0, PrivKeyRoot)
+
+	b := newBlock(genesisBlock.Hash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 1)
+	b.Hash = [32]byte{0x01}
+	b.Beneficiary = accBHash
+	b.SlashedAddress = rootHash
+	b.ConflictingBlockHash1 = [32]byte{0x11}
+	b.ConflictingBlockHash2 = [32]byte{0x12}
+	b.StakeTxData = [][32]byte{stake.Hash()}
+	b.EvidenceTxData = [][32]byte{evidence.Hash()}
+	b.ConfigTxData = [][32]byte{config.Hash()}
+	data := blockData{nil, nil, []*protocol.ConfigTx{config}, []*protocol.StakeTx{stake}, nil, nil, nil, nil, nil, []*protocol.EvidenceTx{evidence}, nil, b}
+
+	if err := validateState(data); err != nil {
+		t.Fatalf("Block could not be validated: %v\n", err)
+	}
+	postValidate(data, false)
+
+	//accA only staked the old minimum and is removed from the validator set again by the config tx
+	if accA.StakedAmount != stakingMinimum || accA.IsStaking || accB.IsStaking || accB.StakingBlockHeight != 1 || validatorAcc.IsStaking || rootAcc.IsStaking || rootAcc.StakedAmount != 0 {
+		t.Errorf("Block not applied: %v %v %v %v\n", accA, accB, validatorAcc, rootAcc)
+	}
+	if log := storage.ReadUndoLog(b.Hash); log == nil || len(log.Entries) != 6 {
+		t.Fatalf("Undo log not stored with the block: %v\n", log)
+	}
+
+	if err := rollback(b); err != nil {
+		t.Fatalf("Block could not be rolled back: %v\n", err)
+	}
+
+	for acc, state := range before {
+		if acc.StakeState() != state.StakeState() || acc.Balance != state.Balance || acc.TxCnt != state.TxCnt {
+			t.Errorf("Account not restored: %v\nvs.\n%v\n", acc, state)
+		}
+	}
+	if activeParameters.Staking_minimum != stakingMinimum {
+		t.Errorf("Staking minimum not restored: %v\n", activeParameters.Staking_minimum)
+	}
+	if storage.ReadUndoLog(b.Hash) != nil {
+		t.Error("Undo log not deleted after rollback.")
+	}
+	if _, found := storage.ReadEvidence(evidence.EvidenceID()); found {
+		t.Error("Evidence not deleted after rollback.")
+	}
+
+	//A block without undo log can't be rolled back
+	if err := rollback(b); err == nil {
+		t.Error("Block without undo log rolled back.")
+	}
+}
@@ -0,0 +1,142 @@
+package miner
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+var validatorSet = new(protocol.ValidatorSet) //Validator registry after the last block
+
+//Accounts which might be listed after the block of data: the listed ones and those which staked or were slashed with
+//the block. Without a registry yet, it is built from the staking accounts of the state.
+func validatorCandidates(data blockData, slashed map[[32]byte]bool) (candidates map[[32]byte]bool) {
+	candidates = make(map[[32]byte]bool)
+	if len(validatorSet.Validators) == 0 {
+		for accHash, acc := range storage.State {
+			if acc.IsStaking {
+				candidates[accHash] = true
+			}
+		}
+	}
+
+	for _, validator := range validatorSet.Validators {
+		candidates[validator.Account] = true
+	}
+	for _, tx := range data.stakeTxSlice {
+		candidates[tx.Account] = true
+	}
+	for accHash := range slashed {
+		candidates[accHash] = true
+	}
+
+	return candidates
+}
+
+//Registry after the block of data, it is derived from the registry before the block and the state after it. Accounts
+//which staked once stay listed, such that their history is not lost when they leave or are slashed.
+func updateValidatorSet(data blockData) *protocol.ValidatorSet {
+	height := data.block.Height
+
+	//Validators which lose their stake for an offence with this block
+	slashed := make(map[[32]byte]bool)
+	if hasSlashingProof(data.block) {
+		slashed[data.block.SlashedAddress] = true
+	}
+	for _, tx := range data.evidenceTxSlice {
+		slashed[tx.Offender] = true
+	}
+
+	//Each validator active for this block was expected to produce its share of the stake of the block
+	var activeStake uint64
+	for _, validator := range validatorSet.Validators {
+		if validator.Status == protocol.VALIDATOR_ACTIVE {
+			activeStake += validator.Stake
+		}
+	}
+
+	set := &protocol.ValidatorSet{Height: height}
+	for accHash := range validatorCandidates(data, slashed) {
+		acc := storage.State[accHash]
+		if acc == nil {
+			continue
+		}
+
+		var validator protocol.ValidatorInfo
+		if prev := validatorSet.Get(accHash); prev != nil {
+			validator = *prev
+			if prev.Status == protocol.VALIDATOR_ACTIVE {
+				validator.ExpectedBlocks += mulDiv(protocol.EXPECTED_BLOCKS_PRECISION, prev.Stake, activeStake)
+			}
+		} else if acc.IsStaking || slashed[accHash] {
+			validator.Account = accHash
+		} else {
+			continue
+		}
+
+		validator.CommitmentKey = acc.CommitmentKey
+		validator.Stake = acc.EffectiveStake()
+		validator.StakingBlockHeight = acc.StakingBlockHeight
+
+		switch {
+		case slashed[accHash]:
+			validator.Status = protocol.VALIDATOR_SLASHED
+		case acc.IsStaking && height+1 >= acc.StakingBlockHeight+uint32(activeParameters.Waiting_minimum):
+			//The status is the one for the next block
+			validator.Status = protocol.VALIDATOR_ACTIVE
+		case acc.IsStaking:
+			validator.Status = protocol.VALIDATOR_WAITING
+		case validator.Status != protocol.VALIDATOR_SLASHED:
+			validator.Status = protocol.VALIDATOR_INACTIVE
+		}
+
+		if accHash == data.block.Beneficiary {
+			validator.BlocksProduced++
+			validator.LastProducedHeight = height
+		}
+
+		validator.MissedSlots = 0
+		if expected := uint32(validator.ExpectedBlocks / protocol.EXPECTED_BLOCKS_PRECISION); expected > validator.BlocksProduced {
+			validator.MissedSlots = expected - validator.BlocksProduced
+		}
+
+		set.Validators = append(set.Validators, &validator)
+	}
+
+	sort.Slice(set.Validators, func(i, j int) bool {
+		return bytes.Compare(set.Validators[i].Account[:], set.Validators[j].Account[:]) < 0
+	})
+
+	return set
+}
+
+//The registry after the block becomes the current one and is stored with the block. Registries of blocks which can't
+//be rolled back anymore are deleted.
+func commitValidatorSet(data blockData) {
+	validatorSet = updateValidatorSet(data)
+	storage.WriteValidatorSet(data.block.Hash, validatorSet)
+
+	if data.block.Height > ROLLBACK_DEPTH {
+		storage.DeleteValidatorSetsBelow(data.block.Height - ROLLBACK_DEPTH)
+	}
+}
+
+//The registry before the block becomes the current one again. Returns false if the block was never committed.
+func revertValidatorSet(block *protocol.Block) bool {
+	if storage.ReadValidatorSet(block.Height, block.Hash) == nil {
+		return false
+	}
+
+	validatorSet = nil
+	if block.Height > 0 {
+		validatorSet = storage.ReadValidatorSet(block.Height-1, block.PrevHash)
+	}
+	if validatorSet == nil {
+		validatorSet = new(protocol.ValidatorSet)
+	}
+	storage.DeleteValidatorSet(block.Height, block.Hash)
+
+	return true
+}
@@ -0,0 +1,113 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//The registry lists the validators with their status and performance after every block and is restored on rollback
+func TestValidatorRegistryAndRollback(t *testing.T) {
+	cleanAndPrepare()
+
+	validatorHash := protocol.SerializeHashContent(validatorAcc.Address)
+	rootHash := protocol.SerializeHashContent(rootAcc.Address)
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	//All validators take part with the staking minimum, accA starts staking at height 2 and waits 2 blocks
+	activeParameters.Waiting_minimum = 2
+	validatorAcc.Balance, rootAcc.Balance = 0, 0
+	validatorAcc.StakedAmount = activeParameters.Staking_minimum
+	rootAcc.StakedAmount = activeParameters.Staking_minimum
+	accA.Balance = 0
+	accA.IsStaking = true
+	accA.StakedAmount = activeParameters.Staking_minimum
+	accA.StakingBlockHeight = 2
+
+	var blocks []*protocol.Block
+	prevHash := genesisBlock.Hash
+	for height := uint32(1); height <= 5; height++ {
+		b := newBlock(prevHash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, height)
+		b.Hash = [32]byte{byte(height)}
+		b.Beneficiary = validatorHash
+		commitValidatorSet(blockData{block: b})
+		blocks = append(blocks, b)
+		prevHash = b.Hash
+
+		if height == 1 {
+			if validatorSet.Get(accAHash) == nil || validatorSet.Get(accAHash).Status != protocol.VALIDATOR_WAITING {
+				t.Errorf("Validator in the waiting period not listed as waiting: %v\n", validatorSet.Get(accAHash))
+			}
+		}
+	}
+
+	//The validator and root shared blocks 2 and 3, accA is active from block 4 on and all three shared blocks 4 and 5
+	validator, root, a := validatorSet.Get(validatorHash), validatorSet.Get(rootHash), validatorSet.Get(accAHash)
+	if validator == nil || root == nil || a == nil || validatorSet.Get(accBHash) != nil || len(validatorSet.Validators) != 3 {
+		t.Fatalf("Unexpected validators: %v\n", validatorSet.Validators)
+	}
+	if validator.Status != protocol.VALIDATOR_ACTIVE || validator.BlocksProduced != 5 || validator.LastProducedHeight != 5 || validator.MissedSlots != 0 {
+		t.Errorf("Producing validator not tracked: %v\n", validator)
+	}
+	if root.Status != protocol.VALIDATOR_ACTIVE || root.BlocksProduced != 0 || root.ExpectedBlocks != 1666666 || root.MissedSlots != 1 {
+		t.Errorf("Missed slots not tracked: %v, expected %v\n", root, root.ExpectedBlocks)
+	}
+	if a.Status != protocol.VALIDATOR_ACTIVE || a.ExpectedBlocks != 666666 || a.MissedSlots != 0 || a.CommitmentKey != accA.CommitmentKey {
+		t.Errorf("Validator after the waiting period not tracked: %v, expected %v\n", a, a.ExpectedBlocks)
+	}
+
+	storage.DeleteAllLastClosedBlock()
+	storage.WriteLastClosedBlock(blocks[4])
+	if current := storage.ReadCurrentValidatorSet(); current == nil || current.Height != 5 || len(current.Validators) != 3 {
+		t.Errorf("Registry not stored with the block: %v\n", current)
+	}
+
+	//The root is slashed by the next block and stays listed as slashed
+	b := newBlock(prevHash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, 6)
+	b.Hash = [32]byte{0x06}
+	b.Beneficiary = validatorHash
+	b.SlashedAddress = rootHash
+	b.ConflictingBlockHash1 = [32]byte{0x11}
+	rootAcc.IsStaking = false
+	rootAcc.StakedAmount = 0
+	commitValidatorSet(blockData{block: b})
+	if root := validatorSet.Get(rootHash); root == nil || root.Status != protocol.VALIDATOR_SLASHED {
+		t.Errorf("Slashed validator not listed as slashed: %v\n", root)
+	}
+
+	if !revertValidatorSet(b) || validatorSet.Height != 5 || validatorSet.Get(rootHash).Status != protocol.VALIDATOR_ACTIVE {
+		t.Errorf("Registry not restored on rollback: %v\n", validatorSet.Validators)
+	}
+	if storage.ReadValidatorSet(b.Height, b.Hash) != nil || revertValidatorSet(b) {
+		t.Error("Registry of a rolled back block still stored.")
+	}
+}
+
+//Only the registries of blocks which can still be rolled back are kept
+func TestValidatorRegistryPruning(t *testing.T) {
+	cleanAndPrepare()
+
+	var blocks []*protocol.Block
+	prevHash := genesisBlock.Hash
+	for height := uint32(1); height <= ROLLBACK_DEPTH+2; height++ {
+		b := newBlock(prevHash, [32]byte{}, [crypto.COMM_KEY_LENGTH]byte{}, height)
+		b.Hash = [32]byte{byte(height), byte(height >> 8)}
+		commitValidatorSet(blockData{block: b})
+		blocks = append(blocks, b)
+		prevHash = b.Hash
+	}
+
+	if storage.ReadValidatorSet(blocks[0].Height, blocks[0].Hash) != nil {
+		t.Error("Registry of a block deeper than the rollback depth still stored.")
+	}
+	//The registry before the deepest block which can be rolled back is needed to revert it
+	if storage.ReadValidatorSet(blocks[1].Height, blocks[1].Hash) == nil {
+		t.Error("Registry needed for a rollback deleted.")
+	}
+	if len(validatorSet.Validators) != 2 || validatorSet.Get(protocol.SerializeHashContent(validatorAcc.Address)) == nil {
+		t.Errorf("Staking accounts not listed: %v\n", validatorSet.Validators)
+	}
+}
@@ -29,6 +29,18 @@ func verify(tx protocol.Transaction) bool {
 		verified = verifyAggTx(tx.(*protocol.AggTx))
 	case *protocol.IotTx:
 		verified = verifyIotTx(tx.(*protocol.IotTx))
+	case *protocol.ContractTx:
+		verified = verifyContractTx(tx.(*protocol.ContractTx))
+	case *protocol.IotBatchTx:
+		verified = verifyIotBatchTx(tx.(*protocol.IotBatchTx))
+	case *protocol.IotAggTx:
+		verified = verifyIotAggTx(tx.(*protocol.IotAggTx))
+	case *protocol.DelegateTx:
+		verified = verifyDelegateTx(tx.(*protocol.DelegateTx))
+	case *protocol.EvidenceTx:
+		verified = verifyEvidenceTx(tx.(*protocol.EvidenceTx))
+	case *protocol.GovernanceTx:
+		verified = verifyGovernanceTx(tx.(*protocol.GovernanceTx))
 	}
 
 	return verified
@@ -48,6 +60,13 @@ func verifyIotTx(tx *protocol.IotTx) bool {
 		logger.Printf("Account non existent. From: %v\nTo: %v\n", accFrom, accTo)
 		return false
 	}
+
+	//The payload of an encrypted tx can't be read, but it has to at least hold the ephemeral key and the tag
+	if tx.IsEncrypted() && len(tx.Data) < crypto.ENCRYPTION_OVERHEAD {
+		logger.Printf("Encrypted IoT data too short: %v bytes\n", len(tx.Data))
+		return false
+	}
+
 	//IoT devices can hash the address differently. Usually we transform both addresses (From & To) into a string using sprintf
 	//and then we hash it, in arduino this does not give the same hash and therefore the only way is to
 	//hash it as a byte[] directly without converting it first into a string.
@@ -70,6 +89,39 @@ func verifyIotTx(tx *protocol.IotTx) bool {
 	}
 }
 
+func verifyIotBatchTx(tx *protocol.IotBatchTx) bool {
+	if tx == nil {
+		return false
+	}
+
+	//Check if accounts are present in the actual state
+	accFrom := storage.State[tx.From]
+	accTo := storage.State[tx.To]
+	if accFrom == nil || accTo == nil {
+		logger.Printf("Account non existent. From: %v\nTo: %v\n", accFrom, accTo)
+		return false
+	}
+
+	if tx.From == tx.To {
+		return false
+	}
+
+	//The signature only covers the readings root, so the readings have to match it.
+	if err := tx.CheckReadings(); err != nil {
+		logger.Printf("Invalid IoT batch %x: %v\n", tx.Hash(), err)
+		return false
+	}
+	if tx.CalcReadingsRoot() != tx.ReadingsRoot {
+		logger.Printf("Readings root of IoT batch %x does not match its readings.\n", tx.Hash())
+		return false
+	}
+
+	txHash := tx.Hash()
+	pubKey := crypto.GetPubKeyFromAddressED(accFrom.Address)
+
+	return ed25519.Verify(pubKey, txHash[:], tx.Sig[:])
+}
+
 func verifyAccTx(tx *protocol.AccTx) bool {
 	if tx == nil {
 		return false
@@ -111,12 +163,116 @@ func verifyConfigTx(tx *protocol.ConfigTx) bool {
 	return false
 }
 
+func verifyContractTx(tx *protocol.ContractTx) bool {
+	if tx == nil {
+		return false
+	}
+
+	//Check if the contract account is present in the actual state
+	acc := storage.State[tx.Account]
+	if acc == nil {
+		logger.Printf("Contract account non existent: %x\n", tx.Account[0:8])
+		return false
+	}
+
+	//Only the issuer of the account is allowed to change the contract's lifecycle
+	if acc.Issuer != protocol.SerializeHashContent(tx.Issuer) {
+		logger.Printf("ContractTx not signed by the issuer of account %x\n", tx.Account[0:8])
+		return false
+	}
+
+	txHash := tx.Hash()
+	pubKey := crypto.GetPubKeyFromAddressED(tx.Issuer)
+
+	return ed25519.Verify(pubKey, txHash[:], tx.Sig[:])
+}
+
+func verifyDelegateTx(tx *protocol.DelegateTx) bool {
+	if tx == nil {
+		return false
+	}
+
+	//Check if accounts are present in the actual state
+	accFrom := storage.State[tx.From]
+	accValidator := storage.State[tx.Validator]
+	if accFrom == nil || accValidator == nil {
+		logger.Printf("Account non existent. From: %v\nValidator: %v\n", accFrom, accValidator)
+		return false
+	}
+
+	if tx.From == tx.Validator || tx.Amount == 0 {
+		return false
+	}
+
+	if tx.Header != protocol.DELEGATE && tx.Header != protocol.UNDELEGATE {
+		logger.Printf("Invalid delegation operation: %v\n", tx.Header)
+		return false
+	}
+
+	txHash := tx.Hash()
+	pubKey := crypto.GetPubKeyFromAddressED(accFrom.Address)
+
+	return ed25519.Verify(pubKey, txHash[:], tx.Sig[:])
+}
+
+func verifyEvidenceTx(tx *protocol.EvidenceTx) bool {
+	if tx == nil {
+		return false
+	}
+
+	//Check if accounts are present in the actual state
+	accFrom := storage.State[tx.From]
+	accOffender := storage.State[tx.Offender]
+	if accFrom == nil || accOffender == nil {
+		logger.Printf("Account non existent. From: %v\nOffender: %v\n", accFrom, accOffender)
+		return false
+	}
+
+	if tx.Header != protocol.EVIDENCE_DOUBLE_PROPOSAL && tx.Header != protocol.EVIDENCE_CONFLICTING_CHAINS {
+		logger.Printf("Invalid offence: %v\n", tx.Header)
+		return false
+	}
+
+	txHash := tx.Hash()
+	pubKey := crypto.GetPubKeyFromAddressED(accFrom.Address)
+
+	return ed25519.Verify(pubKey, txHash[:], tx.Sig[:])
+}
+
+func verifyGovernanceTx(tx *protocol.GovernanceTx) bool {
+	if tx == nil {
+		return false
+	}
+
+	//Check if account is present in the actual state
+	accFrom := storage.State[tx.From]
+	if accFrom == nil {
+		logger.Printf("Account non existent. From: %v\n", accFrom)
+		return false
+	}
+
+	if tx.Header != protocol.GOVERNANCE_PROPOSAL && tx.Header != protocol.GOVERNANCE_VOTE {
+		logger.Printf("Invalid governance operation: %v\n", tx.Header)
+		return false
+	}
+
+	txHash := tx.Hash()
+	pubKey := crypto.GetPubKeyFromAddressED(accFrom.Address)
+
+	return ed25519.Verify(pubKey, txHash[:], tx.Sig[:])
+}
+
 func verifyStakeTx(tx *protocol.StakeTx) bool {
 	if tx == nil {
 		logger.Println("Transactions does not exist.")
 		return false
 	}
 
+	if tx.Commission > protocol.MAX_COMMISSION {
+		logger.Printf("Commission too high: %v (maximum is: %v)\n", tx.Commission, protocol.MAX_COMMISSION)
+		return false
+	}
+
 	//Check if account is present in the actual state
 	acc := storage.State[tx.Account]
 	if acc == nil {
@@ -156,6 +312,15 @@ func verifyAggTx(tx *protocol.AggTx) bool {
 	return true
 }
 
+//The aggregated batches are verified on their own, a single batch is never aggregated.
+func verifyIotAggTx(tx *protocol.IotAggTx) bool {
+	if tx == nil {
+		return false
+	}
+
+	return len(tx.AggregatedTxSlice) >= 2
+}
+
 func verifyFundsTx(tx *protocol.FundsTx) bool {
 	if tx == nil {
 		return false
@@ -237,6 +402,14 @@ func parameterBoundsChecking(id uint8, payload uint64) bool {
 		if payload >= protocol.MIN_SLASHING_REWARD && payload <= protocol.MAX_SLASHING_REWARD {
 			return true
 		}
+	case protocol.UNBONDING_PERIOD_ID:
+		if payload >= protocol.MIN_UNBONDING_PERIOD && payload <= protocol.MAX_UNBONDING_PERIOD {
+			return true
+		}
+	case protocol.TARGET_ADJUSTMENT_HEIGHT_ID:
+		if payload >= protocol.MIN_TARGET_ADJUSTMENT_HEIGHT && payload <= protocol.MAX_TARGET_ADJUSTMENT_HEIGHT {
+			return true
+		}
 	}
 
 	return false
@@ -0,0 +1,373 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//Upper bound of addresses in the address book, the worst ones are evicted first
+	MAX_KNOWN_ADDRS = 1000
+	//Upper bound of addresses sent in a NEIGHBOR_RES
+	MAX_NEIGHBOR_ADDRS = 100
+	//Addresses not seen for ADDR_MAX_AGE seconds are not gossiped anymore
+	ADDR_MAX_AGE = 3 * 24 * 3600
+	//After a failed connection attempt, an address is not tried for failures * ADDR_RETRY_BACKOFF seconds
+	ADDR_RETRY_BACKOFF = 60
+	MAX_RETRY_BACKOFF  = 3600
+	//Every ADDR_GOSSIP_INTERVAL health checks, a random miner is asked for its neighbors
+	ADDR_GOSSIP_INTERVAL = 30
+
+	KNOWN_ADDR_SIZE = 32
+)
+
+type knownAddr struct {
+	lastSeen    int64
+	lastAttempt int64
+	lastSuccess int64
+	successes   uint32
+	failures    uint32
+}
+
+func (addr *knownAddr) encode() []byte {
+	encoded := make([]byte, KNOWN_ADDR_SIZE)
+	binary.BigEndian.PutUint64(encoded[0:8], uint64(addr.lastSeen))
+	binary.BigEndian.PutUint64(encoded[8:16], uint64(addr.lastAttempt))
+	binary.BigEndian.PutUint64(encoded[16:24], uint64(addr.lastSuccess))
+	binary.BigEndian.PutUint32(encoded[24:28], addr.successes)
+	binary.BigEndian.PutUint32(encoded[28:32], addr.failures)
+	return encoded
+}
+
+func decodeKnownAddr(encoded []byte) *knownAddr {
+	if len(encoded) != KNOWN_ADDR_SIZE {
+		return nil
+	}
+
+	return &knownAddr{
+		lastSeen:    int64(binary.BigEndian.Uint64(encoded[0:8])),
+		lastAttempt: int64(binary.BigEndian.Uint64(encoded[8:16])),
+		lastSuccess: int64(binary.BigEndian.Uint64(encoded[16:24])),
+		successes:   binary.BigEndian.Uint32(encoded[24:28]),
+		failures:    binary.BigEndian.Uint32(encoded[28:32]),
+	}
+}
+
+//Addresses which were reachable recently rank first, failures rank them down.
+func (addr *knownAddr) score(now int64) int64 {
+	score := int64(addr.successes) - 2*int64(addr.failures)
+	if now-addr.lastSeen < ADDR_MAX_AGE {
+		score += 10
+	}
+	return score
+}
+
+//Returns the time until which the address is not tried again.
+func (addr *knownAddr) retryAt() int64 {
+	backoff := int64(addr.failures) * ADDR_RETRY_BACKOFF
+	if backoff > MAX_RETRY_BACKOFF {
+		backoff = MAX_RETRY_BACKOFF
+	}
+	return addr.lastAttempt + backoff
+}
+
+//The address book survives restarts, such that a node can rejoin the network without the bootstrap node being up.
+func (n *Node) loadAddrBook() {
+	if !n.store.IsOpen() {
+		return
+	}
+
+	n.addrBookL.Lock()
+	defer n.addrBookL.Unlock()
+
+	for ipport, encoded := range n.store.ReadAllPeerAddresses() {
+		if addr := decodeKnownAddr(encoded); addr != nil {
+			n.addrBook[ipport] = addr
+		}
+	}
+}
+
+//Has to be called with addrBookL locked.
+func (n *Node) saveAddr(ipport string, addr *knownAddr) {
+	if n.store.IsOpen() {
+		n.store.WritePeerAddress(ipport, addr.encode())
+	}
+}
+
+//Only routable listener addresses are added, never our own.
+func (n *Node) validAddr(ipport string) bool {
+	host, port, err := net.SplitHostPort(ipport)
+	if err != nil || host == "" {
+		return false
+	}
+	if portNr, err := strconv.Atoi(port); err != nil || portNr <= 0 || portNr > 65535 {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() || ip == nil && !validHostname(host) {
+		return false
+	}
+
+	return !n.peerSelfConn(ipport)
+}
+
+//Hostnames consist of labels of letters, digits and hyphens separated by dots.
+func validHostname(host string) bool {
+	if len(host) == 0 || len(host) > MAX_HOSTNAME_SIZE {
+		return false
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+//Adds a newly learned address. Known addresses are left untouched, such that gossip can't overwrite statistics.
+func (n *Node) addAddress(ipport string) {
+	if !n.validAddr(ipport) {
+		return
+	}
+
+	n.addrBookL.Lock()
+	defer n.addrBookL.Unlock()
+
+	if _, exists := n.addrBook[ipport]; exists {
+		return
+	}
+
+	if len(n.addrBook) >= MAX_KNOWN_ADDRS {
+		n.evictAddr()
+	}
+
+	addr := &knownAddr{lastSeen: time.Now().Unix()}
+	n.addrBook[ipport] = addr
+	n.saveAddr(ipport, addr)
+}
+
+//Removes an address which reached our own node id.
+func (n *Node) removeSelfAddr(ipport string) {
+	n.selfAddrsL.Lock()
+	n.selfAddrs[ipport] = true
+	n.selfAddrsL.Unlock()
+
+	n.addrBookL.Lock()
+	defer n.addrBookL.Unlock()
+
+	delete(n.addrBook, ipport)
+	if n.store.IsOpen() {
+		n.store.DeletePeerAddress(ipport)
+	}
+}
+
+func (n *Node) isSelfAddr(ipport string) bool {
+	n.selfAddrsL.Lock()
+	defer n.selfAddrsL.Unlock()
+
+	return n.selfAddrs[ipport]
+}
+
+//Has to be called with addrBookL locked.
+func (n *Node) evictAddr() {
+	now := time.Now().Unix()
+	var worst string
+	for ipport, addr := range n.addrBook {
+		if worst == "" || addr.score(now) < n.addrBook[worst].score(now) ||
+			(addr.score(now) == n.addrBook[worst].score(now) && addr.lastSeen < n.addrBook[worst].lastSeen) {
+			worst = ipport
+		}
+	}
+
+	delete(n.addrBook, worst)
+	if n.store.IsOpen() {
+		n.store.DeletePeerAddress(worst)
+	}
+}
+
+func (n *Node) updateAddr(ipport string, update func(addr *knownAddr, now int64)) {
+	if !n.validAddr(ipport) {
+		return
+	}
+
+	n.addrBookL.Lock()
+	defer n.addrBookL.Unlock()
+
+	addr, exists := n.addrBook[ipport]
+	if !exists {
+		if len(n.addrBook) >= MAX_KNOWN_ADDRS {
+			n.evictAddr()
+		}
+		addr = new(knownAddr)
+		n.addrBook[ipport] = addr
+	}
+
+	update(addr, time.Now().Unix())
+	n.saveAddr(ipport, addr)
+}
+
+func (n *Node) markAttempt(ipport string) {
+	n.updateAddr(ipport, func(addr *knownAddr, now int64) {
+		addr.lastAttempt = now
+	})
+}
+
+func (n *Node) markSuccess(ipport string) {
+	n.updateAddr(ipport, func(addr *knownAddr, now int64) {
+		addr.lastSeen = now
+		addr.lastSuccess = now
+		addr.successes++
+		addr.failures = 0
+	})
+}
+
+func (n *Node) markFailure(ipport string) {
+	n.updateAddr(ipport, func(addr *knownAddr, now int64) {
+		addr.failures++
+	})
+}
+
+func (n *Node) markSeen(ipport string) {
+	n.updateAddr(ipport, func(addr *knownAddr, now int64) {
+		addr.lastSeen = now
+	})
+}
+
+//Network group of an address, outbound connections are spread over as many groups as possible, such that a single
+//network can't surround us. IPv4 addresses are grouped by /16, IPv6 addresses by /32.
+func addrGroup(ipport string) string {
+	host, _, err := net.SplitHostPort(ipport)
+	if err != nil {
+		return ipport
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(16, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(32, 128)).String()
+}
+
+//Selects up to count addresses to connect to. Addresses of groups we are already connected to are only chosen if
+//there are not enough others.
+func (n *Node) selectOutbound(count int) (selected []string) {
+	now := time.Now().Unix()
+
+	usedGroups := make(map[string]bool)
+	for _, p := range n.peers.getAllPeers(PEERTYPE_MINER) {
+		usedGroups[addrGroup(p.getIPPort())] = true
+	}
+
+	n.addrBookL.Lock()
+	var candidates []string
+	scores := make(map[string]int64)
+	for ipport, addr := range n.addrBook {
+		if addr.retryAt() > now || n.peerExists(ipport) || n.peerSelfConn(ipport) {
+			continue
+		}
+		if host, _, err := net.SplitHostPort(ipport); err == nil && n.isBanned(host) {
+			continue
+		}
+		candidates = append(candidates, ipport)
+		scores[ipport] = addr.score(now)
+	}
+	n.addrBookL.Unlock()
+
+	//Shuffle first, such that equally good addresses are chosen randomly
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	sort.SliceStable(candidates, func(i, j int) bool { return scores[candidates[i]] > scores[candidates[j]] })
+
+	var sameGroup []string
+	for _, ipport := range candidates {
+		if len(selected) >= count {
+			return selected
+		}
+		group := addrGroup(ipport)
+		if usedGroups[group] {
+			sameGroup = append(sameGroup, ipport)
+			continue
+		}
+		usedGroups[group] = true
+		selected = append(selected, ipport)
+	}
+
+	for _, ipport := range sameGroup {
+		if len(selected) >= count {
+			break
+		}
+		selected = append(selected, ipport)
+	}
+
+	return selected
+}
+
+//Addresses we gossip: connected miners first, then the best recently seen addresses of the address book.
+func (n *Node) gossipAddrs() (ipportList []string) {
+	included := make(map[string]bool)
+	for _, p := range n.peers.getAllPeers(PEERTYPE_MINER) {
+		ipport := p.getIPPort()
+		if !included[ipport] {
+			included[ipport] = true
+			ipportList = append(ipportList, ipport)
+		}
+	}
+
+	now := time.Now().Unix()
+	n.addrBookL.Lock()
+	var known []string
+	for ipport, addr := range n.addrBook {
+		if !included[ipport] && addr.lastSuccess > 0 && now-addr.lastSeen < ADDR_MAX_AGE {
+			known = append(known, ipport)
+		}
+	}
+	sort.Slice(known, func(i, j int) bool { return n.addrBook[known[i]].lastSeen > n.addrBook[known[j]].lastSeen })
+	n.addrBookL.Unlock()
+
+	ipportList = append(ipportList, known...)
+	if len(ipportList) > MAX_NEIGHBOR_ADDRS {
+		ipportList = ipportList[:MAX_NEIGHBOR_ADDRS]
+	}
+
+	return ipportList
+}
+
+//Connects to up to count miners of the address book. The bootstrap server is only contacted directly if we are not
+//connected to any miner at all and none of the known addresses could be reached.
+func (n *Node) connectOutbound(count int) (connected int) {
+	for _, ipport := range n.selectOutbound(count) {
+		p, err := n.initiateNewMinerConnection(ipport)
+		if err != nil {
+			logger.Printf("%v\n", err)
+			continue
+		}
+		go n.peerConn(p)
+		connected++
+	}
+
+	if connected == 0 && n.peers.len(PEERTYPE_MINER) == 0 && !n.IsBootstrap() && n.bootstrapAddr != "" &&
+		!n.peerExists(n.bootstrapAddr) {
+		p, err := n.initiateNewMinerConnection(n.bootstrapAddr)
+		if err != nil {
+			logger.Printf("%v\n", err)
+			return connected
+		}
+		go n.peerConn(p)
+		connected++
+	}
+
+	return connected
+}
@@ -0,0 +1,132 @@
+package p2p
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func resetAddrBook() {
+	testNode.addrBookL.Lock()
+	testNode.addrBook = make(map[string]*knownAddr)
+	testNode.addrBookL.Unlock()
+}
+
+func TestAddAddress(t *testing.T) {
+	resetAddrBook()
+	defer resetAddrBook()
+
+	valid := []string{"10.0.0.1:8000", "[2001:db8::1]:8000", "localhost:8001"}
+	invalid := []string{testNode.addr, "localhost:9000", "bad_host:8000", "10.0.0.1:0", "0.0.0.0:8000", "[::]:8000", "10.0.0.1", "10.0.0.1:70000", ":8000"}
+
+	for _, ipport := range append(valid, invalid...) {
+		testNode.addAddress(ipport)
+	}
+
+	if len(testNode.addrBook) != len(valid) {
+		t.Errorf("Unexpected address book size: %v vs. %v\n", len(testNode.addrBook), len(valid))
+	}
+	for _, ipport := range valid {
+		if testNode.addrBook[ipport] == nil {
+			t.Errorf("Valid address %v not added.\n", ipport)
+		}
+	}
+
+	//Gossip doesn't overwrite statistics of known addresses
+	testNode.markSuccess("10.0.0.1:8000")
+	testNode.addAddress("10.0.0.1:8000")
+	if testNode.addrBook["10.0.0.1:8000"].successes != 1 {
+		t.Error("Known address was overwritten.")
+	}
+}
+
+func TestKnownAddrEncoding(t *testing.T) {
+	addr := knownAddr{lastSeen: 1, lastAttempt: 2, lastSuccess: 3, successes: 4, failures: 5}
+
+	decoded := decodeKnownAddr(addr.encode())
+	if decoded == nil || *decoded != addr {
+		t.Errorf("Address encoding failed: %v vs. %v\n", addr, decoded)
+	}
+	if decodeKnownAddr([]byte{1, 2, 3}) != nil {
+		t.Error("Malformed address was decoded.")
+	}
+}
+
+func TestAddrBackoff(t *testing.T) {
+	resetAddrBook()
+	defer resetAddrBook()
+
+	testNode.addAddress("10.0.0.1:8000")
+	testNode.markAttempt("10.0.0.1:8000")
+	testNode.markFailure("10.0.0.1:8000")
+
+	if selected := testNode.selectOutbound(1); len(selected) != 0 {
+		t.Errorf("Address was selected during its backoff: %v\n", selected)
+	}
+
+	//Once the backoff is over, the address is tried again
+	testNode.addrBook["10.0.0.1:8000"].lastAttempt = time.Now().Unix() - ADDR_RETRY_BACKOFF - 1
+	if selected := testNode.selectOutbound(1); len(selected) != 1 {
+		t.Errorf("Address was not selected after its backoff: %v\n", selected)
+	}
+
+	//A success resets the failures
+	testNode.markSuccess("10.0.0.1:8000")
+	if addr := testNode.addrBook["10.0.0.1:8000"]; addr.failures != 0 || addr.successes != 1 || addr.retryAt() > time.Now().Unix() {
+		t.Errorf("Success not recorded: %v\n", addr)
+	}
+}
+
+func TestSelectOutboundDiversity(t *testing.T) {
+	resetAddrBook()
+	defer resetAddrBook()
+
+	//Three addresses of the same /16 and one of each other group
+	for _, ipport := range []string{"10.0.1.1:8000", "10.0.2.1:8000", "10.0.3.1:8000", "10.1.0.1:8000", "[2001:db8::1]:8000"} {
+		testNode.addAddress(ipport)
+	}
+
+	selected := testNode.selectOutbound(3)
+	if len(selected) != 3 {
+		t.Fatalf("Unexpected number of selected addresses: %v\n", selected)
+	}
+
+	groups := make(map[string]bool)
+	for _, ipport := range selected {
+		groups[addrGroup(ipport)] = true
+	}
+	if len(groups) != 3 {
+		t.Errorf("Selected addresses are not diverse: %v\n", selected)
+	}
+
+	//Addresses of the same group are only selected if there are not enough others
+	if selected := testNode.selectOutbound(5); len(selected) != 5 {
+		t.Errorf("Unexpected number of selected addresses: %v\n", selected)
+	}
+
+	if addrGroup("10.0.1.1:8000") != addrGroup("10.0.200.3:9000") || addrGroup("10.0.1.1:8000") == addrGroup("10.1.1.1:8000") {
+		t.Error("IPv4 addresses not grouped by /16.")
+	}
+	if addrGroup("[2001:db8:1::1]:8000") != addrGroup("[2001:db8:2::1]:8000") || addrGroup("[2001:db8::1]:8000") == addrGroup("[2001:db9::1]:8000") {
+		t.Error("IPv6 addresses not grouped by /32.")
+	}
+}
+
+func TestAddrEviction(t *testing.T) {
+	resetAddrBook()
+	defer resetAddrBook()
+
+	testNode.addAddress("10.0.0.1:8000")
+	testNode.markAttempt("10.0.0.1:8000")
+	testNode.markFailure("10.0.0.1:8000")
+
+	for i := 0; len(testNode.addrBook) < MAX_KNOWN_ADDRS; i++ {
+		testNode.addAddress(fmt.Sprintf("10.1.%v.%v:8000", i/256, i%256))
+	}
+
+	//The address which failed is evicted first
+	testNode.addAddress("10.255.255.255:8000")
+	if len(testNode.addrBook) != MAX_KNOWN_ADDRS || testNode.addrBook["10.0.0.1:8000"] != nil || testNode.addrBook["10.255.255.255:8000"] == nil {
+		t.Errorf("Worst address was not evicted (%v addresses).\n", len(testNode.addrBook))
+	}
+}
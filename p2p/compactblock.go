@@ -0,0 +1,376 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"golang.org/x/crypto/sha3"
+	"time"
+)
+
+//Blocks are relayed as compact blocks: the block without its tx hashes, a short id per tx and the txs the receiver
+//most likely lacks. The receiver looks the short ids up in its mempool and requests all remaining txs with a single
+//GETBLOCKTXN. The txs are written to the mempool before the block is passed to the miner, such that the miner does
+//not need to fetch them one by one.
+const (
+	SHORT_ID_SIZE = 6
+	//Upper bound of blocks being reconstructed at the same time
+	MAX_PENDING_BLOCKS = 10
+)
+
+//Tx hash lists of a block, in the order of the short ids of a compact block, and their broadcast types.
+var blockTxTypes = []uint8{ACCTX_BRDCST, FUNDSTX_BRDCST, CONFIGTX_BRDCST, STAKETX_BRDCST, AGGTX_BRDCST, IOTTX_BRDCST,
+	CONTRACTTX_BRDCST, IOTBATCHTX_BRDCST, DELEGATETX_BRDCST, EVIDENCETX_BRDCST, GOVERNANCETX_BRDCST, IOTAGGTX_BRDCST}
+
+func blockTxLists(block *protocol.Block) []*[][32]byte {
+	return []*[][32]byte{&block.AccTxData, &block.FundsTxData, &block.ConfigTxData, &block.StakeTxData,
+		&block.AggTxData, &block.IoTTxData, &block.ContractTxData, &block.IoTBatchTxData, &block.DelegateTxData,
+		&block.EvidenceTxData, &block.GovernanceTxData, &block.IoTAggTxData}
+}
+
+type CompactBlock struct {
+	Block     []byte
+	ShortIDs  [][][SHORT_ID_SIZE]byte
+	Prefilled []BlockTx
+}
+
+//Position of a tx in a block, List is the index in blockTxLists.
+type TxPos struct {
+	List  uint8
+	Index uint16
+}
+
+type BlockTx struct {
+	Pos TxPos
+	Tx  []byte
+}
+
+type BlockTxnReq struct {
+	BlockHash [32]byte
+	Positions []TxPos
+}
+
+type BlockTxn struct {
+	BlockHash [32]byte
+	Txs       []BlockTx
+}
+
+//Block waiting for the txs requested with GETBLOCKTXN.
+type pendingBlock struct {
+	block    *protocol.Block
+	shortIDs [][][SHORT_ID_SIZE]byte
+	missing  map[TxPos]bool
+	txs      []protocol.Transaction
+	p        *peer
+	time     int64
+}
+
+//Short ids are salted with the block hash, such that collisions can't be precomputed.
+func shortTxID(blockHash, txHash [32]byte) (shortID [SHORT_ID_SIZE]byte) {
+	hash := sha3.Sum256(append(blockHash[:], txHash[:]...))
+	copy(shortID[:], hash[:SHORT_ID_SIZE])
+	return shortID
+}
+
+//Txs which are not known to the peer are sent along with the compact block.
+func (n *Node) newCompactBlock(block *protocol.Block, known *hashSet) *CompactBlock {
+	cb := new(CompactBlock)
+
+	stripped := *block
+	for list, txHashes := range blockTxLists(&stripped) {
+		var shortIDs [][SHORT_ID_SIZE]byte
+		for index, txHash := range *txHashes {
+			shortIDs = append(shortIDs, shortTxID(block.Hash, txHash))
+			if known.contains(txHash) {
+				continue
+			}
+			if tx := n.readTx(txHash); tx != nil {
+				cb.Prefilled = append(cb.Prefilled, BlockTx{TxPos{uint8(list), uint16(index)}, tx.Encode()})
+			}
+		}
+		cb.ShortIDs = append(cb.ShortIDs, shortIDs)
+		*txHashes = nil
+	}
+	cb.Block = stripped.Encode()
+
+	return cb
+}
+
+//Rebuilds the tx hashes of the block from the prefilled txs and the mempool. Returns the positions of all txs which
+//could not be found (or were ambiguous) and the prefilled txs.
+func (cb *CompactBlock) reconstruct(store Store) (block *protocol.Block, missing map[TxPos]bool, txs []protocol.Transaction) {
+	if block = block.Decode(cb.Block); block == nil || len(cb.ShortIDs) != len(blockTxTypes) {
+		return nil, nil, nil
+	}
+
+	lists := blockTxLists(block)
+	for list, shortIDs := range cb.ShortIDs {
+		*lists[list] = make([][32]byte, len(shortIDs))
+	}
+
+	prefilled := make(map[TxPos]bool)
+	for _, blockTx := range cb.Prefilled {
+		if int(blockTx.Pos.List) >= len(lists) || int(blockTx.Pos.Index) >= len(*lists[blockTx.Pos.List]) {
+			return nil, nil, nil
+		}
+		tx := decodeTx(blockTxTypes[blockTx.Pos.List], blockTx.Tx)
+		if tx == nil {
+			return nil, nil, nil
+		}
+		(*lists[blockTx.Pos.List])[blockTx.Pos.Index] = tx.Hash()
+		prefilled[blockTx.Pos] = true
+		txs = append(txs, tx)
+	}
+
+	//Short ids of the mempool, a short id matching several txs is requested
+	mempool := make(map[[SHORT_ID_SIZE]byte][][32]byte)
+	for _, tx := range store.ReadAllOpenTxs() {
+		shortID := shortTxID(block.Hash, tx.Hash())
+		mempool[shortID] = append(mempool[shortID], tx.Hash())
+	}
+
+	missing = make(map[TxPos]bool)
+	for list, shortIDs := range cb.ShortIDs {
+		for index, shortID := range shortIDs {
+			pos := TxPos{uint8(list), uint16(index)}
+			if prefilled[pos] {
+				continue
+			}
+			if candidates := mempool[shortID]; len(candidates) == 1 {
+				(*lists[list])[index] = candidates[0]
+			} else {
+				missing[pos] = true
+			}
+		}
+	}
+
+	return block, missing, txs
+}
+
+func (n *Node) processCompactBlock(p *peer, payload []byte) {
+	cb := new(CompactBlock).Decode(payload)
+	if cb == nil {
+		p.penalize(SCORE_MALFORMED_PACKET, "Malformed compact block")
+		return
+	}
+
+	block, missing, txs := cb.reconstruct(n.store)
+	if block == nil {
+		p.penalize(SCORE_MALFORMED_PACKET, "Malformed compact block")
+		return
+	}
+
+	item := &invItem{typeID: BLOCK_BRDCST, hash: block.Hash}
+	p.knownInv.add(block.Hash)
+	if n.haveInv(item) {
+		return
+	}
+
+	//Other peers announcing the block are not asked for it while we are reconstructing it
+	n.requestedInvL.Lock()
+	n.requestedInv[block.Hash] = time.Now().Unix()
+	n.requestedInvL.Unlock()
+
+	if len(missing) == 0 {
+		n.completeBlock(p, block, txs)
+		return
+	}
+
+	n.pendingBlocksL.Lock()
+	if _, exists := n.pendingBlocks[block.Hash]; exists {
+		n.pendingBlocksL.Unlock()
+		return
+	}
+	n.expirePendingBlocks(time.Now().Unix())
+	if len(n.pendingBlocks) >= MAX_PENDING_BLOCKS {
+		n.pendingBlocksL.Unlock()
+		n.requestBlock(p, block.Hash)
+		return
+	}
+	n.pendingBlocks[block.Hash] = &pendingBlock{block, cb.ShortIDs, missing, txs, p, time.Now().Unix()}
+	n.pendingBlocksL.Unlock()
+
+	req := BlockTxnReq{BlockHash: block.Hash}
+	for pos := range missing {
+		req.Positions = append(req.Positions, pos)
+	}
+	sendData(p, BuildPacket(GETBLOCKTXN, req.Encode()))
+}
+
+//Has to be called with pendingBlocksL locked.
+func (n *Node) expirePendingBlocks(now int64) {
+	for hash, pending := range n.pendingBlocks {
+		if pending.time+GETDATA_TIMEOUT < now {
+			delete(n.pendingBlocks, hash)
+		}
+	}
+}
+
+//Answers with all requested txs we have, missing ones are left out.
+func (n *Node) processGetBlockTxn(p *peer, payload []byte) {
+	req := new(BlockTxnReq).Decode(payload)
+	if req == nil {
+		p.penalize(SCORE_MALFORMED_PACKET, "Malformed GETBLOCKTXN")
+		return
+	}
+
+	var block *protocol.Block
+	n.relayCacheL.Lock()
+	if item, exists := n.relayCache[req.BlockHash]; exists {
+		block = item.block
+	}
+	n.relayCacheL.Unlock()
+	if block == nil && n.store.IsOpen() {
+		block = n.store.ReadClosedBlock(req.BlockHash)
+	}
+
+	res := BlockTxn{BlockHash: req.BlockHash}
+	if block != nil {
+		lists := blockTxLists(block)
+		for _, pos := range req.Positions {
+			if int(pos.List) >= len(lists) || int(pos.Index) >= len(*lists[pos.List]) {
+				continue
+			}
+			if tx := n.readTx((*lists[pos.List])[pos.Index]); tx != nil {
+				res.Txs = append(res.Txs, BlockTx{pos, tx.Encode()})
+			}
+		}
+	}
+
+	sendData(p, BuildPacket(BLOCKTXN, res.Encode()))
+}
+
+//Fills in the requested txs. If any tx is still missing, the full block is requested instead.
+func (n *Node) processBlockTxn(p *peer, payload []byte) {
+	res := new(BlockTxn).Decode(payload)
+	if res == nil {
+		p.penalize(SCORE_MALFORMED_PACKET, "Malformed BLOCKTXN")
+		return
+	}
+
+	n.pendingBlocksL.Lock()
+	pending, exists := n.pendingBlocks[res.BlockHash]
+	if exists && pending.p == p {
+		delete(n.pendingBlocks, res.BlockHash)
+	}
+	n.pendingBlocksL.Unlock()
+
+	if !exists || pending.p != p {
+		return
+	}
+
+	lists := blockTxLists(pending.block)
+	for _, blockTx := range res.Txs {
+		if !pending.missing[blockTx.Pos] {
+			continue
+		}
+		tx := decodeTx(blockTxTypes[blockTx.Pos.List], blockTx.Tx)
+		if tx == nil || shortTxID(res.BlockHash, tx.Hash()) != pending.shortIDs[blockTx.Pos.List][blockTx.Pos.Index] {
+			continue
+		}
+		(*lists[blockTx.Pos.List])[blockTx.Pos.Index] = tx.Hash()
+		delete(pending.missing, blockTx.Pos)
+		pending.txs = append(pending.txs, tx)
+	}
+
+	if len(pending.missing) > 0 {
+		n.requestBlock(p, res.BlockHash)
+		return
+	}
+
+	n.completeBlock(p, pending.block, pending.txs)
+}
+
+//A reconstructed block is only passed on if its tx hashes match the merkle root, a short id collision would
+//otherwise make a valid block look invalid. The full block is requested instead. Like in the block validation,
+//aggregated blocks are not checked.
+//The txs which were not in the mempool are kept with the block until the miner verified them, like for broadcast txs
+//the peer is penalized for invalid ones. If too many blocks are waiting, the miner fetches them again instead.
+func (n *Node) completeBlock(p *peer, block *protocol.Block, txs []protocol.Transaction) {
+	if !block.Aggregated && protocol.BuildMerkleTree(block).MerkleRoot() != block.MerkleRoot {
+		n.requestBlock(p, block.Hash)
+		return
+	}
+
+	for _, tx := range txs {
+		n.recordOrigin(tx.Hash(), p)
+	}
+
+	if len(txs) > 0 {
+		n.blockTxsL.Lock()
+		if len(n.blockTxs) < MAX_PENDING_BLOCKS {
+			n.blockTxs[block.Hash] = txs
+		}
+		n.blockTxsL.Unlock()
+	}
+
+	n.forwardBlockToMiner(p, block.Encode())
+}
+
+func (n *Node) requestBlock(p *peer, hash [32]byte) {
+	n.requestedInvL.Lock()
+	n.requestedInv[hash] = time.Now().Unix()
+	n.requestedInvL.Unlock()
+
+	sendData(p, BuildPacket(GETDATA, encodeInv([]*invItem{{typeID: BLOCK_BRDCST, hash: hash}})))
+}
+
+func (cb *CompactBlock) Encode() []byte {
+	if cb == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(cb)
+	return buffer.Bytes()
+}
+
+func (*CompactBlock) Decode(encoded []byte) (cb *CompactBlock) {
+	var decoded CompactBlock
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+func (req *BlockTxnReq) Encode() []byte {
+	if req == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(req)
+	return buffer.Bytes()
+}
+
+func (*BlockTxnReq) Decode(encoded []byte) (req *BlockTxnReq) {
+	var decoded BlockTxnReq
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+func (res *BlockTxn) Encode() []byte {
+	if res == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(res)
+	return buffer.Bytes()
+}
+
+func (*BlockTxn) Decode(encoded []byte) (res *BlockTxn) {
+	var decoded BlockTxn
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
@@ -0,0 +1,133 @@
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func newTestBlock(txs ...*protocol.FundsTx) *protocol.Block {
+	block := protocol.NewBlock([32]byte{0xcc}, 1)
+	for _, tx := range txs {
+		block.FundsTxData = append(block.FundsTxData, tx.Hash())
+	}
+	block.MerkleRoot = protocol.BuildMerkleTree(block).MerkleRoot()
+	block.Hash = block.HashBlock()
+
+	return block
+}
+
+func TestCompactBlockReconstruction(t *testing.T) {
+
+	inMempool := &protocol.FundsTx{Amount: 1, TxCnt: 1}
+	prefilled := &protocol.FundsTx{Amount: 2, TxCnt: 2}
+	missing := &protocol.FundsTx{Amount: 3, TxCnt: 3}
+	block := newTestBlock(inMempool, prefilled, missing)
+
+	//The peer knows the first and the last tx, we only have the first two
+	known := newHashSet(MAX_KNOWN_INV)
+	known.add(inMempool.Hash())
+	known.add(missing.Hash())
+	storage.WriteOpenTx(inMempool)
+	storage.WriteOpenTx(prefilled)
+
+	cb := new(CompactBlock).Decode(testNode.newCompactBlock(block, known).Encode())
+	if cb == nil || len(cb.Prefilled) != 1 || cb.Prefilled[0].Pos != (TxPos{1, 1}) || len(cb.ShortIDs[1]) != 3 {
+		t.Fatalf("Unexpected compact block: %v\n", cb)
+	}
+	if len(block.FundsTxData) != 3 {
+		t.Error("Building the compact block altered the block.")
+	}
+
+	storage.DeleteOpenTx(prefilled)
+	defer storage.DeleteOpenTx(inMempool)
+
+	reconstructed, missingPos, txs := cb.reconstruct(testNode.store)
+	if reconstructed == nil || reconstructed.Hash != block.Hash || len(txs) != 1 || txs[0].Hash() != prefilled.Hash() {
+		t.Fatalf("Compact block not reconstructed: %v\n", reconstructed)
+	}
+	if len(missingPos) != 1 || !missingPos[TxPos{1, 2}] {
+		t.Errorf("Unexpected missing txs: %v\n", missingPos)
+	}
+	if reconstructed.FundsTxData[0] != inMempool.Hash() || reconstructed.FundsTxData[1] != prefilled.Hash() {
+		t.Error("Tx hashes not reconstructed.")
+	}
+
+	//Malformed compact blocks are rejected
+	cb.Prefilled[0].Pos.Index = 3
+	if block, _, _ := cb.reconstruct(testNode.store); block != nil {
+		t.Error("Compact block with invalid position reconstructed.")
+	}
+}
+
+//Missing txs are requested with a single GETBLOCKTXN, if they can't be delivered the full block is requested
+func TestCompactBlockRelay(t *testing.T) {
+
+	inMempool := &protocol.FundsTx{Amount: 11, TxCnt: 1}
+	missing1 := &protocol.FundsTx{Amount: 12, TxCnt: 2}
+	missing2 := &protocol.FundsTx{Amount: 13, TxCnt: 3}
+	block := newTestBlock(inMempool, missing1, missing2)
+
+	storage.WriteOpenTx(inMempool)
+	defer storage.DeleteOpenTx(inMempool)
+
+	known := newHashSet(MAX_KNOWN_INV)
+	for _, txHash := range block.FundsTxData {
+		known.add(txHash)
+	}
+	payload := testNode.newCompactBlock(block, known).Encode()
+
+	conn1, conn2 := net.Pipe()
+	p1 := testNode.newPeer(conn1, "", PEERTYPE_MINER)
+	p2 := testNode.newPeer(conn2, "", PEERTYPE_MINER)
+	defer conn1.Close()
+	defer conn2.Close()
+
+	go testNode.processCompactBlock(p1, payload)
+	header, reqPayload, err := RcvData(p2)
+	req := new(BlockTxnReq).Decode(reqPayload)
+	if err != nil || header.TypeID != GETBLOCKTXN || req == nil || req.BlockHash != block.Hash || len(req.Positions) != 2 {
+		t.Fatalf("Unexpected GETBLOCKTXN: %v, %v\n", header, req)
+	}
+
+	res := BlockTxn{block.Hash, []BlockTx{{TxPos{1, 1}, missing1.Encode()}, {TxPos{1, 2}, missing2.Encode()}}}
+	go testNode.processBlockTxn(p1, res.Encode())
+
+	select {
+	case encoded := <-testNode.BlockIn:
+		received := new(protocol.Block).Decode(encoded)
+		if received == nil || received.Hash != block.Hash || len(received.FundsTxData) != 3 || received.FundsTxData[2] != missing2.Hash() {
+			t.Errorf("Unexpected block: %v\n", received)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Reconstructed block not passed to the miner.")
+	}
+	if storage.ReadOpenTx(missing1.Hash()) != nil || storage.ReadOpenTx(missing2.Hash()) != nil {
+		t.Error("Received txs written to the mempool before the miner verified them.")
+	}
+	if txs := testNode.BlockTxs(block.Hash); len(txs) != 2 || txs[0].Hash() != missing1.Hash() || txs[1].Hash() != missing2.Hash() {
+		t.Errorf("Received txs not handed to the miner: %v\n", txs)
+	}
+	if testNode.BlockTxs(block.Hash) != nil {
+		t.Error("Received txs handed to the miner twice.")
+	}
+
+	//A tx not matching the short id is not accepted, the full block is requested instead
+	testNode.requestedInvL.Lock()
+	delete(testNode.requestedInv, block.Hash)
+	testNode.requestedInvL.Unlock()
+
+	go testNode.processCompactBlock(p1, payload)
+	RcvData(p2)
+
+	res = BlockTxn{block.Hash, []BlockTx{{TxPos{1, 1}, missing1.Encode()}, {TxPos{1, 2}, inMempool.Encode()}}}
+	go testNode.processBlockTxn(p1, res.Encode())
+	header, getData, err := RcvData(p2)
+	items := decodeInv(getData)
+	if err != nil || header.TypeID != GETDATA || len(items) != 1 || items[0].typeID != BLOCK_BRDCST || items[0].hash != block.Hash {
+		t.Errorf("Full block not requested: %v, %v\n", header, items)
+	}
+}
@@ -16,8 +16,52 @@ const (
 	TIME_BRDCST_INTERVAL = 60
 	//Calculate system time every UPDATE_SYS_TIME seconds
 	UPDATE_SYS_TIME = 90
+	//Clock offsets are the median of the last MAX_TIME_SAMPLES measurements of a peer. Measurements with a round-trip
+	//time above MAX_TIME_RTT milliseconds are dropped
+	MAX_TIME_SAMPLES = 8
+	MAX_TIME_RTT     = 5000
+	//Offsets deviating more than TIME_OUTLIER_FACTOR median absolute deviations (but at least MIN_TIME_OUTLIER
+	//milliseconds) from the median offset are not taken into account
+	TIME_OUTLIER_FACTOR = 3
+	MIN_TIME_OUTLIER    = 2000
+	//The network time is not adjusted further than MAX_TIME_ADJUSTMENT seconds from the local clock
+	MAX_TIME_ADJUSTMENT = 70 * 60
+	//Default of the seconds the local clock may differ from the network time before a warning is logged
+	ACCEPTED_TIME_DIFF = 60
+
+	//Peers start with INITIAL_PEER_SCORE and are banned as soon as their score drops to 0. Every
+	//REPUTATION_INTERVAL seconds, scores recover by SCORE_RECOVERY.
+	INITIAL_PEER_SCORE  = 100
+	REPUTATION_INTERVAL = 60
+	SCORE_RECOVERY      = 1
+	//Penalties
+	SCORE_MALFORMED_PACKET   = 20
+	SCORE_INVALID_BLOCK      = 10
+	SCORE_INVALID_TX         = 5
+	SCORE_UNANSWERED_REQUEST = 2
+	SCORE_RATE_EXCEEDED      = 1
+	//Requests not answered within REQUEST_TIMEOUT seconds are penalized
+	REQUEST_TIMEOUT = 30
+	//Duration of a temporary ban in seconds. After MAX_TEMP_BANS temporary bans, the ban becomes persistent
+	TEMP_BAN_DURATION = 3600
+	MAX_TEMP_BANS     = 3
+	//Upper bound of messages of a single peer being processed at the same time
+	MAX_CONCURRENT_HANDLERS = 16
+	//Messages and bytes per second a peer may send, the burst is the capacity of one second
+	MINER_MSG_RATE    = 500
+	MINER_BYTE_RATE   = 20000000
+	CLIENT_MSG_RATE   = 50
+	CLIENT_BYTE_RATE  = 1000000
+	//Upper bound of all payloads except blocks, which are bounded by protocol.MAX_BLOCK_SIZE
+	MAX_MSG_SIZE = 10000000
 
 	//Protocol constants
 	IPV4ADDR_SIZE = 4
+	IPV6ADDR_SIZE = 16
 	PORT_SIZE     = 2
+	//Address families of the NEIGHBOR_RES entries, hostnames are prefixed with their length
+	ADDR_HOSTNAME     = 1
+	ADDR_IPV4         = 4
+	ADDR_IPV6         = 6
+	MAX_HOSTNAME_SIZE = 253
 )
@@ -0,0 +1,293 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	//Version of the message set. Peers below MIN_PROTOCOL_VERSION are disconnected.
+	PROTOCOL_VERSION     = 2
+	MIN_PROTOCOL_VERSION = 1
+
+	//Upper bound of an encoded HELLO (or the DISCONNECT answering it), at most 256 message types and a hostname.
+	MAX_HELLO_SIZE = 2048
+)
+
+//Optional features, only used with peers which announced them as well.
+const (
+	FEATURE_AGGREGATION = 1 << iota
+	FEATURE_IOT
+	FEATURE_CONTRACTS
+	FEATURE_IOT_QUERY
+	FEATURE_IOT_BATCH
+	FEATURE_DELEGATION
+	FEATURE_EVIDENCE
+	FEATURE_GOVERNANCE
+	FEATURE_PARAMETER_SCHEDULE
+	FEATURE_SUPPLY
+	FEATURE_BASE_FEE
+	FEATURE_VALIDATORS
+	FEATURE_IOT_AGGREGATION
+
+	LOCAL_FEATURES = FEATURE_AGGREGATION | FEATURE_IOT | FEATURE_CONTRACTS | FEATURE_IOT_QUERY | FEATURE_IOT_BATCH |
+		FEATURE_DELEGATION | FEATURE_EVIDENCE | FEATURE_GOVERNANCE | FEATURE_PARAMETER_SCHEDULE | FEATURE_SUPPLY | FEATURE_BASE_FEE |
+		FEATURE_VALIDATORS | FEATURE_IOT_AGGREGATION
+)
+
+//Message types which are only sent to peers which negotiated the corresponding feature.
+var featureMessageTypes = map[uint8]uint64{
+	AGGTX_BRDCST:        FEATURE_AGGREGATION,
+	AGGTX_REQ:           FEATURE_AGGREGATION,
+	AGGTX_RES:           FEATURE_AGGREGATION,
+	IOTTX_BRDCST:        FEATURE_IOT,
+	IOTTX_REQ:           FEATURE_IOT,
+	IOTTX_RES:           FEATURE_IOT,
+	CONTRACTTX_BRDCST:   FEATURE_CONTRACTS,
+	CONTRACTTX_REQ:      FEATURE_CONTRACTS,
+	CONTRACTTX_RES:      FEATURE_CONTRACTS,
+	IOTDATA_REQ:         FEATURE_IOT_QUERY,
+	IOTDATA_RES:         FEATURE_IOT_QUERY,
+	IOTBATCHTX_BRDCST:   FEATURE_IOT_BATCH,
+	IOTBATCHTX_REQ:      FEATURE_IOT_BATCH,
+	IOTBATCHTX_RES:      FEATURE_IOT_BATCH,
+	DELEGATETX_BRDCST:   FEATURE_DELEGATION,
+	DELEGATETX_REQ:      FEATURE_DELEGATION,
+	DELEGATETX_RES:      FEATURE_DELEGATION,
+	EVIDENCETX_BRDCST:   FEATURE_EVIDENCE,
+	EVIDENCETX_REQ:      FEATURE_EVIDENCE,
+	EVIDENCETX_RES:      FEATURE_EVIDENCE,
+	GOVERNANCETX_BRDCST: FEATURE_GOVERNANCE,
+	GOVERNANCETX_REQ:    FEATURE_GOVERNANCE,
+	GOVERNANCETX_RES:    FEATURE_GOVERNANCE,
+	PARAMETERS_REQ:      FEATURE_PARAMETER_SCHEDULE,
+	PARAMETERS_RES:      FEATURE_PARAMETER_SCHEDULE,
+	SUPPLY_REQ:          FEATURE_SUPPLY,
+	SUPPLY_RES:          FEATURE_SUPPLY,
+	BASEFEE_REQ:         FEATURE_BASE_FEE,
+	BASEFEE_RES:         FEATURE_BASE_FEE,
+	VALIDATORS_REQ:      FEATURE_VALIDATORS,
+	VALIDATORS_RES:      FEATURE_VALIDATORS,
+	IOTAGGTX_BRDCST:     FEATURE_IOT_AGGREGATION,
+	IOTAGGTX_REQ:        FEATURE_IOT_AGGREGATION,
+	IOTAGGTX_RES:        FEATURE_IOT_AGGREGATION,
+}
+
+//HELLO is the first (encrypted) message both sides send after the handshake. A zero ChainID or GenesisHash means the
+//value is not known yet (e.g. clients or miners which did not sync the genesis block) and is not compared. Miners
+//announce the address other miners can connect to with Addr, which is empty for clients.
+type Hello struct {
+	Version      uint32
+	ChainID      [32]byte
+	GenesisHash  [32]byte
+	BestHeight   uint32
+	BestHash     [32]byte
+	Features     uint64
+	MessageTypes []uint8
+	Addr         string
+	//Local time of the sender in milliseconds, used to measure its clock offset
+	Time int64
+}
+
+//Peers of another chain than the one of the node are disconnected.
+func (n *Node) newLocalHello() *Hello {
+	hello := &Hello{
+		Version:  PROTOCOL_VERSION,
+		ChainID:  n.chainID,
+		Features: LOCAL_FEATURES,
+		Addr:     n.addr,
+		Time:     n.localTime(),
+	}
+
+	if n.store.IsOpen() {
+		if genesis := n.store.ReadClosedBlock([32]byte{}); genesis != nil {
+			hello.GenesisHash = genesis.HashBlock()
+		}
+		if lastBlock := n.store.ReadLastClosedBlock(); lastBlock != nil {
+			hello.BestHeight = lastBlock.Height
+			hello.BestHash = lastBlock.Hash
+		}
+	}
+	hello.MessageTypes = localMessageTypes()
+
+	return hello
+}
+
+//Clients don't belong to a chain and don't run a node, their HELLO only announces the message types they know.
+func newClientHello() *Hello {
+	return &Hello{
+		Version:      PROTOCOL_VERSION,
+		Features:     LOCAL_FEATURES,
+		MessageTypes: localMessageTypes(),
+	}
+}
+
+func localMessageTypes() (messageTypes []uint8) {
+	for typeID := range LogMapping {
+		messageTypes = append(messageTypes, typeID)
+	}
+
+	return messageTypes
+}
+
+//Returns the reason why a peer with the given HELLO can't be talked to, nil if it is compatible.
+func (local *Hello) checkCompatible(remote *Hello) error {
+	if remote.Version < MIN_PROTOCOL_VERSION {
+		return errors.New(fmt.Sprintf("Protocol version %v not supported (minimum is %v).", remote.Version, MIN_PROTOCOL_VERSION))
+	}
+	if local.ChainID != [32]byte{} && remote.ChainID != [32]byte{} && local.ChainID != remote.ChainID {
+		return errors.New(fmt.Sprintf("Different chain: %x", remote.ChainID[0:8]))
+	}
+	if local.GenesisHash != [32]byte{} && remote.GenesisHash != [32]byte{} && local.GenesisHash != remote.GenesisHash {
+		return errors.New(fmt.Sprintf("Different genesis block: %x", remote.GenesisHash[0:8]))
+	}
+
+	return nil
+}
+
+//Sends our HELLO and waits for the one of the peer. Incompatible peers are sent a DISCONNECT with the reason.
+//On success, the negotiated version, features and message types are stored with the peer.
+func (n *Node) exchangeHello(p *peer) error {
+	local := n.newLocalHello()
+	sentTime := local.Time
+
+	var receivedTime int64
+	remote, err := swapHello(p.conn, local, func() (*Header, []byte, error) {
+		header, payload, err := RcvData(p)
+		receivedTime = n.localTime()
+		return header, payload, err
+	})
+	if err != nil {
+		return err
+	}
+
+	p.setHello(remote)
+
+	//Both HELLOs are sent at the same time, the one of the peer is assumed to be sent halfway through the round trip
+	if remote.Time != 0 {
+		addTimeSample(p, remote.Time-(sentTime+receivedTime)/2, receivedTime-sentTime)
+	}
+
+	return nil
+}
+
+//Both sides send their HELLO at the same time, rcv reads the one of the other side. The connection is closed on every
+//error. Once the HELLO is exchanged, the connection accepts frames up to the block size.
+func swapHello(conn net.Conn, local *Hello, rcv func() (*Header, []byte, error)) (remote *Hello, err error) {
+	sent := make(chan error, 1)
+	go func() {
+		_, err := conn.Write(BuildPacket(HELLO, local.Encode()))
+		sent <- err
+	}()
+
+	header, payload, err := rcv()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := <-sent; err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if header.TypeID == DISCONNECT {
+		conn.Close()
+		return nil, errors.New(fmt.Sprintf("Peer disconnected: %s", payload))
+	}
+	if header.TypeID != HELLO {
+		conn.Close()
+		return nil, errors.New(fmt.Sprintf("Expected HELLO, got: %v", LogMapping[header.TypeID]))
+	}
+
+	if remote = new(Hello).Decode(payload); remote == nil {
+		conn.Close()
+		return nil, errors.New("Malformed HELLO.")
+	}
+
+	if err := local.checkCompatible(remote); err != nil {
+		conn.Write(BuildPacket(DISCONNECT, []byte(err.Error())))
+		conn.Close()
+		return nil, err
+	}
+
+	if secure, ok := conn.(*secureConn); ok {
+		secure.helloExchanged()
+	}
+
+	return remote, nil
+}
+
+func (p *peer) setHello(remote *Hello) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.version = remote.Version
+	if p.version > PROTOCOL_VERSION {
+		p.version = PROTOCOL_VERSION
+	}
+	p.features = remote.Features & LOCAL_FEATURES
+	p.bestHeight = remote.BestHeight
+	p.bestHash = remote.BestHash
+	p.advertisedAddr = remote.Addr
+	p.messageTypes = make(map[uint8]bool)
+	for _, typeID := range remote.MessageTypes {
+		p.messageTypes[typeID] = true
+	}
+}
+
+//A message is only sent if the peer can parse it and negotiated the feature it belongs to. Peers without a
+//HELLO (e.g. during the handshake) are sent everything.
+func (p *peer) supports(typeID uint8) bool {
+	if p.messageTypes == nil {
+		return true
+	}
+	if feature, optional := featureMessageTypes[typeID]; optional && p.features&feature == 0 {
+		return false
+	}
+
+	return p.messageTypes[typeID]
+}
+
+func (hello *Hello) Encode() []byte {
+	if hello == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(hello)
+	return buffer.Bytes()
+}
+
+func (*Hello) Decode(encoded []byte) (hello *Hello) {
+	var decoded Hello
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+func (hello Hello) String() string {
+	return fmt.Sprintf(
+		"\nVersion: %v\n"+
+			"ChainID: %x\n"+
+			"GenesisHash: %x\n"+
+			"BestHeight: %v\n"+
+			"BestHash: %x\n"+
+			"Features: %b\n"+
+			"MessageTypes: %v\n"+
+			"Addr: %v\n",
+		hello.Version,
+		hello.ChainID[0:8],
+		hello.GenesisHash[0:8],
+		hello.BestHeight,
+		hello.BestHash[0:8],
+		hello.Features,
+		len(hello.MessageTypes),
+		hello.Addr,
+	)
+}
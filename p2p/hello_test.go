@@ -0,0 +1,172 @@
+package p2p
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestHelloCompatible(t *testing.T) {
+
+	local := &Hello{Version: PROTOCOL_VERSION, ChainID: [32]byte{1}, GenesisHash: [32]byte{2}}
+
+	if err := local.checkCompatible(&Hello{Version: PROTOCOL_VERSION, ChainID: [32]byte{1}, GenesisHash: [32]byte{2}}); err != nil {
+		t.Errorf("Compatible peer rejected: %v\n", err)
+	}
+	//Unknown chain id and genesis hash are not compared
+	if err := local.checkCompatible(&Hello{Version: PROTOCOL_VERSION}); err != nil {
+		t.Errorf("Peer without chain information rejected: %v\n", err)
+	}
+	if err := local.checkCompatible(&Hello{Version: MIN_PROTOCOL_VERSION - 1, ChainID: [32]byte{1}}); err == nil {
+		t.Error("Peer with outdated protocol version accepted\n")
+	}
+	if err := local.checkCompatible(&Hello{Version: PROTOCOL_VERSION, ChainID: [32]byte{3}}); err == nil {
+		t.Error("Peer of another chain accepted\n")
+	}
+	if err := local.checkCompatible(&Hello{Version: PROTOCOL_VERSION, ChainID: [32]byte{1}, GenesisHash: [32]byte{3}}); err == nil {
+		t.Error("Peer with another genesis block accepted\n")
+	}
+}
+
+func TestHelloEncoding(t *testing.T) {
+
+	hello := testNode.newLocalHello()
+	hello.BestHeight = 42
+	hello.BestHash = [32]byte{7}
+
+	decoded := new(Hello).Decode(hello.Encode())
+	if decoded == nil ||
+		decoded.Version != PROTOCOL_VERSION ||
+		decoded.BestHeight != 42 ||
+		decoded.BestHash != hello.BestHash ||
+		len(decoded.MessageTypes) != len(LogMapping) {
+		t.Errorf("HELLO not correctly encoded: %v\n", decoded)
+	}
+
+	if new(Hello).Decode([]byte{1, 2, 3}) != nil {
+		t.Error("Malformed HELLO decoded\n")
+	}
+}
+
+//A peer without a feature is not sent the messages belonging to it, and no messages it does not know
+func TestHelloNegotiation(t *testing.T) {
+
+	p := testNode.newPeer(nil, "", PEERTYPE_MINER)
+	if !p.supports(IOTTX_BRDCST) {
+		t.Error("Peer without HELLO should be sent everything\n")
+	}
+
+	p.setHello(&Hello{
+		Version:      PROTOCOL_VERSION + 1,
+		Features:     FEATURE_AGGREGATION,
+		MessageTypes: []uint8{BLOCK_BRDCST, AGGTX_BRDCST, IOTTX_BRDCST},
+	})
+
+	if p.version != PROTOCOL_VERSION {
+		t.Errorf("Negotiated version %v, expected %v\n", p.version, PROTOCOL_VERSION)
+	}
+	if !p.supports(BLOCK_BRDCST) || !p.supports(AGGTX_BRDCST) {
+		t.Error("Supported message not sent\n")
+	}
+	if p.supports(IOTTX_BRDCST) {
+		t.Error("Message of a feature that was not negotiated sent\n")
+	}
+	if p.supports(FUNDSTX_BRDCST) {
+		t.Error("Message the peer does not know sent\n")
+	}
+}
+
+//Miners behind a NAT are known by the address they announced, not by their remote ip
+func TestHelloAdvertisedAddr(t *testing.T) {
+
+	conn1, conn2 := net.Pipe()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	p := testNode.newPeer(conn1, "8000", PEERTYPE_MINER)
+	if p.getAdvertisedAddr() != p.getIPPort() {
+		t.Errorf("Unexpected address without HELLO: %v\n", p.getAdvertisedAddr())
+	}
+
+	p.setHello(&Hello{Addr: "seed.bazo.test:8010"})
+	if p.getAdvertisedAddr() != "seed.bazo.test:8010" {
+		t.Errorf("Advertised address not used: %v\n", p.getAdvertisedAddr())
+	}
+
+	p.setHello(&Hello{Addr: "0.0.0.0:8010"})
+	if p.getAdvertisedAddr() != p.getIPPort() {
+		t.Errorf("Invalid advertised address used: %v\n", p.getAdvertisedAddr())
+	}
+}
+
+//Incompatible peers are disconnected with the reason
+func TestExchangeHello(t *testing.T) {
+
+	conn1, conn2 := net.Pipe()
+	p1 := testNode.newPeer(conn1, "", PEERTYPE_MINER)
+	p2 := testNode.newPeer(conn2, "", PEERTYPE_MINER)
+
+	errChan := make(chan error)
+	go func() { errChan <- testNode.exchangeHello(p2) }()
+	if err := testNode.exchangeHello(p1); err != nil {
+		t.Errorf("HELLO exchange failed: %v\n", err)
+	}
+	if err := <-errChan; err != nil {
+		t.Errorf("HELLO exchange failed: %v\n", err)
+	}
+	if !p1.supports(IOTBATCHTX_BRDCST) || p1.features != LOCAL_FEATURES {
+		t.Errorf("Features not negotiated: %b\n", p1.features)
+	}
+
+	conn3, conn4 := net.Pipe()
+	other := testNode.newPeer(conn4, "", PEERTYPE_MINER)
+	go func() {
+		RcvData(other)
+		otherHello := testNode.newLocalHello()
+		otherHello.ChainID = [32]byte{42}
+		conn4.Write(BuildPacket(HELLO, otherHello.Encode()))
+		_, payload, _ := RcvData(other)
+		if !strings.Contains(string(payload), "Different chain") {
+			t.Errorf("No disconnect reason received: %s\n", payload)
+		}
+		errChan <- nil
+	}()
+
+	testNode.chainID = [32]byte{1}
+	err := testNode.exchangeHello(testNode.newPeer(conn3, "", PEERTYPE_MINER))
+	testNode.chainID = [32]byte{}
+	<-errChan
+	if err == nil {
+		t.Error("Peer of another chain was not disconnected\n")
+	}
+	if _, err := conn3.Write([]byte{0}); err == nil {
+		t.Error("Connection not closed after a failed HELLO\n")
+	}
+}
+
+//Clients exchange a HELLO without running a node, the connection is closed if it fails
+func TestClientHello(t *testing.T) {
+
+	conn1, conn2 := net.Pipe()
+	errChan := make(chan error)
+	go func() { errChan <- testNode.exchangeHello(testNode.newPeer(conn2, "", PEERTYPE_CLIENT)) }()
+	remote, err := swapHello(conn1, newClientHello(), func() (*Header, []byte, error) { return RcvData_(conn1) })
+	if err != nil || remote.Features != LOCAL_FEATURES {
+		t.Errorf("Client HELLO failed: %v\n", err)
+	}
+	if err := <-errChan; err != nil {
+		t.Errorf("Client HELLO not accepted: %v\n", err)
+	}
+
+	conn3, conn4 := net.Pipe()
+	go func() {
+		RcvData_(conn4)
+		conn4.Write(BuildPacket(TIME_BRDCST, []byte{1}))
+	}()
+	if _, err := swapHello(conn3, newClientHello(), func() (*Header, []byte, error) { return RcvData_(conn3) }); err == nil {
+		t.Error("Client accepted a packet other than HELLO\n")
+	}
+	if _, err := conn3.Write([]byte{0}); err == nil {
+		t.Error("Connection not closed after a failed HELLO\n")
+	}
+}
@@ -1,74 +1,140 @@
 package p2p
 
 //All incoming messages are processed here and acted upon accordingly
-func processIncomingMsg(p *peer, header *Header, payload []byte) {
+func (n *Node) processIncomingMsg(p *peer, header *Header, payload []byte) {
+	if isResponse(header.TypeID) {
+		p.responseReceived()
+	}
 
 	switch header.TypeID {
 	//BROADCASTING
 	case FUNDSTX_BRDCST:
-		processTxBrdcst(p, payload, FUNDSTX_BRDCST)
+		n.processTxBrdcst(p, payload, FUNDSTX_BRDCST)
 	case ACCTX_BRDCST:
-		processTxBrdcst(p, payload, ACCTX_BRDCST)
+		n.processTxBrdcst(p, payload, ACCTX_BRDCST)
 	case CONFIGTX_BRDCST:
-		processTxBrdcst(p, payload, CONFIGTX_BRDCST)
+		n.processTxBrdcst(p, payload, CONFIGTX_BRDCST)
 	case STAKETX_BRDCST:
-		processTxBrdcst(p, payload, STAKETX_BRDCST)
+		n.processTxBrdcst(p, payload, STAKETX_BRDCST)
 	case AGGTX_BRDCST:
-		processTxBrdcst(p, payload, AGGTX_BRDCST)
+		n.processTxBrdcst(p, payload, AGGTX_BRDCST)
+	case CONTRACTTX_BRDCST:
+		n.processTxBrdcst(p, payload, CONTRACTTX_BRDCST)
+	case IOTBATCHTX_BRDCST:
+		n.processTxBrdcst(p, payload, IOTBATCHTX_BRDCST)
+	case DELEGATETX_BRDCST:
+		n.processTxBrdcst(p, payload, DELEGATETX_BRDCST)
+	case EVIDENCETX_BRDCST:
+		n.processTxBrdcst(p, payload, EVIDENCETX_BRDCST)
+	case GOVERNANCETX_BRDCST:
+		n.processTxBrdcst(p, payload, GOVERNANCETX_BRDCST)
 	case BLOCK_BRDCST:
-		forwardBlockToMiner(p, payload)
+		n.forwardBlockToMiner(p, payload)
 	case TIME_BRDCST:
-		processTimeRes(p, payload)
+		n.processTimeBrdcst(p, payload)
 	case IOTTX_BRDCST:
-		processIotTxBrdcst(p, payload, IOTTX_BRDCST)
+		n.processIotTxBrdcst(p, payload, IOTTX_BRDCST)
 		//REQUESTS
 	case FUNDSTX_REQ:
-		txRes(p, payload, FUNDSTX_REQ)
+		n.txRes(p, payload, FUNDSTX_REQ)
 	case ACCTX_REQ:
-		txRes(p, payload, ACCTX_REQ)
+		n.txRes(p, payload, ACCTX_REQ)
 	case CONFIGTX_REQ:
-		txRes(p, payload, CONFIGTX_REQ)
+		n.txRes(p, payload, CONFIGTX_REQ)
 	case STAKETX_REQ:
-		txRes(p, payload, STAKETX_REQ)
+		n.txRes(p, payload, STAKETX_REQ)
 	case AGGTX_REQ:
-		txRes(p, payload, AGGTX_REQ)
+		n.txRes(p, payload, AGGTX_REQ)
+	case CONTRACTTX_REQ:
+		n.txRes(p, payload, CONTRACTTX_REQ)
+	case IOTBATCHTX_REQ:
+		n.txRes(p, payload, IOTBATCHTX_REQ)
+	case IOTAGGTX_REQ:
+		n.txRes(p, payload, IOTAGGTX_REQ)
+	case DELEGATETX_REQ:
+		n.txRes(p, payload, DELEGATETX_REQ)
+	case EVIDENCETX_REQ:
+		n.txRes(p, payload, EVIDENCETX_REQ)
+	case GOVERNANCETX_REQ:
+		n.txRes(p, payload, GOVERNANCETX_REQ)
+	case TX_BATCH_REQ:
+		n.processTxBatchReq(p, payload)
 	case IOTTX_REQ:
-		txRes(p, payload, IOTTX_REQ)
+		n.txRes(p, payload, IOTTX_REQ)
 	case BLOCK_REQ:
-		blockRes(p, payload)
+		n.blockRes(p, payload)
 	case BLOCK_HEADER_REQ:
-		blockHeaderRes(p, payload)
+		n.blockHeaderRes(p, payload)
 	case ACC_REQ:
-		accRes(p, payload)
+		n.accRes(p, payload)
 	case ROOTACC_REQ:
-		rootAccRes(p, payload)
-	case MINER_PING:
-		pongRes(p, payload, MINER_PING)
-	case CLIENT_PING:
-		pongRes(p, payload, CLIENT_PING)
+		n.rootAccRes(p, payload)
 	case NEIGHBOR_REQ:
-		neighborRes(p)
+		n.neighborRes(p)
+	case TIME_REQ:
+		n.timeRes(p, payload)
 	case INTERMEDIATE_NODES_REQ:
-		intermediateNodesRes(p, payload)
+		n.intermediateNodesRes(p, payload)
+	case IOTDATA_REQ:
+		n.iotDataRes(p, payload)
+	case PARAMETERS_REQ:
+		n.parametersRes(p)
+	case SUPPLY_REQ:
+		n.supplyRes(p)
+	case BASEFEE_REQ:
+		n.baseFeeRes(p)
+	case VALIDATORS_REQ:
+		n.validatorsRes(p)
+
+
+	case INV:
+		n.processInv(p, payload)
+	case GETDATA:
+		n.processGetData(p, payload)
+	case CMPCTBLOCK:
+		n.processCompactBlock(p, payload)
+	case GETBLOCKTXN:
+		n.processGetBlockTxn(p, payload)
+	case BLOCKTXN:
+		n.processBlockTxn(p, payload)
 
+	case DISCONNECT:
+		logger.Printf("%v disconnected: %s\n", p, payload)
+		p.conn.Close()
 
 		//RESPONSES
 	case NEIGHBOR_RES:
-		processNeighborRes(p, payload)
+		n.processNeighborRes(p, payload)
+	case TIME_RES:
+		n.processTimeRes(p, payload)
 	case BLOCK_RES:
-		forwardBlockReqToMiner(p, payload)
+		n.forwardBlockReqToMiner(p, payload)
 	case FUNDSTX_RES:
-		forwardTxReqToMiner(p, payload, FUNDSTX_RES)
+		n.forwardTxReqToMiner(p, payload, FUNDSTX_RES)
 	case ACCTX_RES:
-		forwardTxReqToMiner(p, payload, ACCTX_RES)
+		n.forwardTxReqToMiner(p, payload, ACCTX_RES)
 	case CONFIGTX_RES:
-		forwardTxReqToMiner(p, payload, CONFIGTX_RES)
+		n.forwardTxReqToMiner(p, payload, CONFIGTX_RES)
 	case STAKETX_RES:
-		forwardTxReqToMiner(p, payload, STAKETX_RES)
+		n.forwardTxReqToMiner(p, payload, STAKETX_RES)
 	case AGGTX_RES:
-		forwardTxReqToMiner(p, payload, AGGTX_RES)
+		n.forwardTxReqToMiner(p, payload, AGGTX_RES)
+	case CONTRACTTX_RES:
+		n.forwardTxReqToMiner(p, payload, CONTRACTTX_RES)
+	case IOTBATCHTX_RES:
+		n.forwardTxReqToMiner(p, payload, IOTBATCHTX_RES)
+	case IOTAGGTX_RES:
+		n.forwardTxReqToMiner(p, payload, IOTAGGTX_RES)
+	case DELEGATETX_RES:
+		n.forwardTxReqToMiner(p, payload, DELEGATETX_RES)
+	case EVIDENCETX_RES:
+		n.forwardTxReqToMiner(p, payload, EVIDENCETX_RES)
+	case GOVERNANCETX_RES:
+		n.forwardTxReqToMiner(p, payload, GOVERNANCETX_RES)
+	case TX_BATCH_RES:
+		n.processTxBatchRes(p, payload)
 	case IOTTX_RES:
-		forwardTxReqToMiner(p, payload, IOTTX_RES)
+		n.forwardTxReqToMiner(p, payload, IOTTX_RES)
 	}
 
 }
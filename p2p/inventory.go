@@ -0,0 +1,353 @@
+package p2p
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"sync"
+	"time"
+)
+
+//Blocks and txs are not pushed to all miners anymore. They are announced with an INV carrying their hashes and only
+//sent to miners asking for them with a GETDATA. Every inventory item is identified by the broadcast type it is sent
+//with (e.g. FUNDSTX_BRDCST or BLOCK_BRDCST), such that requested items are processed like a broadcast.
+const (
+	INV_ITEM_SIZE = 33
+	//Upper bound of items in a single INV or GETDATA
+	MAX_INV_ITEMS = 1000
+	//Upper bound of hashes a peer is remembered to know
+	MAX_KNOWN_INV = 10000
+	//Upper bound of items kept to answer GETDATA without reading the storage
+	MAX_RELAY_CACHE = 5000
+	//Items requested with GETDATA are not requested again (from another peer) for GETDATA_TIMEOUT seconds
+	GETDATA_TIMEOUT = 10
+)
+
+type invItem struct {
+	typeID  uint8
+	hash    [32]byte
+	payload []byte
+	//Only set for blocks, used to build compact blocks
+	block *protocol.Block
+}
+
+//Bounded set of hashes, the oldest hash is removed first.
+type hashSet struct {
+	hashes map[[32]byte]bool
+	order  [][32]byte
+	limit  int
+	l      sync.Mutex
+}
+
+func newHashSet(limit int) *hashSet {
+	return &hashSet{hashes: make(map[[32]byte]bool), limit: limit}
+}
+
+//Adds the hash and returns whether it was new.
+func (set *hashSet) add(hash [32]byte) bool {
+	set.l.Lock()
+	defer set.l.Unlock()
+
+	if set.hashes[hash] {
+		return false
+	}
+
+	set.hashes[hash] = true
+	set.order = append(set.order, hash)
+	if len(set.order) > set.limit {
+		delete(set.hashes, set.order[0])
+		set.order = set.order[1:]
+	}
+
+	return true
+}
+
+func (set *hashSet) contains(hash [32]byte) bool {
+	set.l.Lock()
+	defer set.l.Unlock()
+
+	return set.hashes[hash]
+}
+
+func isInvType(typeID uint8) bool {
+	switch typeID {
+	case FUNDSTX_BRDCST, ACCTX_BRDCST, CONFIGTX_BRDCST, STAKETX_BRDCST, AGGTX_BRDCST, CONTRACTTX_BRDCST,
+		IOTBATCHTX_BRDCST, DELEGATETX_BRDCST, EVIDENCETX_BRDCST, GOVERNANCETX_BRDCST, IOTTX_BRDCST,
+		BLOCK_BRDCST:
+		return true
+	}
+	return false
+}
+
+func encodeInv(items []*invItem) (payload []byte) {
+	payload = make([]byte, 0, len(items)*INV_ITEM_SIZE)
+	for _, item := range items {
+		payload = append(payload, item.typeID)
+		payload = append(payload, item.hash[:]...)
+	}
+	return payload
+}
+
+//Unknown types and items beyond MAX_INV_ITEMS are dropped.
+func decodeInv(payload []byte) (items []*invItem) {
+	for index := 0; index+INV_ITEM_SIZE <= len(payload) && len(items) < MAX_INV_ITEMS; index += INV_ITEM_SIZE {
+		item := &invItem{typeID: payload[index]}
+		if !isInvType(item.typeID) {
+			continue
+		}
+		copy(item.hash[:], payload[index+1:index+INV_ITEM_SIZE])
+		items = append(items, item)
+	}
+	return items
+}
+
+//Announces a block or tx to all miners which do not know it yet. Called for valid blocks of the miner and for new
+//txs in the mempool.
+func (n *Node) relayInv(item *invItem) {
+	n.cacheInv(item)
+
+	select {
+	case n.invBrdcstMsg <- item:
+	case <-n.quit:
+	}
+}
+
+func (n *Node) cacheInv(item *invItem) {
+	n.relayCacheL.Lock()
+	defer n.relayCacheL.Unlock()
+
+	if _, exists := n.relayCache[item.hash]; !exists {
+		n.relayOrder = append(n.relayOrder, item.hash)
+		if len(n.relayOrder) > MAX_RELAY_CACHE {
+			delete(n.relayCache, n.relayOrder[0])
+			n.relayOrder = n.relayOrder[1:]
+		}
+	}
+	n.relayCache[item.hash] = item
+}
+
+//Belongs to the broadcast service. Blocks are pushed as compact blocks, peers which can't parse INV still get the
+//full payload.
+func (n *Node) brdcstInv(p *peer, item *invItem) {
+	if !p.knownInv.add(item.hash) {
+		return
+	}
+
+	if item.block != nil && p.supports(CMPCTBLOCK) {
+		p.ch <- BuildPacket(CMPCTBLOCK, n.newCompactBlock(item.block, p.knownInv).Encode())
+	} else if p.supports(INV) {
+		p.ch <- BuildPacket(INV, encodeInv([]*invItem{item}))
+	} else {
+		p.ch <- BuildPacket(item.typeID, item.payload)
+	}
+}
+
+//Requests all announced items we neither have nor requested from another peer within GETDATA_TIMEOUT.
+func (n *Node) processInv(p *peer, payload []byte) {
+	var missing []*invItem
+	now := time.Now().Unix()
+
+	for _, item := range decodeInv(payload) {
+		p.knownInv.add(item.hash)
+
+		if n.haveInv(item) {
+			continue
+		}
+
+		n.requestedInvL.Lock()
+		requested, exists := n.requestedInv[item.hash]
+		if !exists || requested+GETDATA_TIMEOUT < now {
+			n.requestedInv[item.hash] = now
+			missing = append(missing, item)
+		}
+		n.requestedInvL.Unlock()
+	}
+
+	if len(missing) > 0 {
+		sendData(p, BuildPacket(GETDATA, encodeInv(missing)))
+	}
+}
+
+//Sends every requested item with the type it was announced with. Items we don't have anymore are skipped, the
+//requesting peer asks another one after GETDATA_TIMEOUT.
+func (n *Node) processGetData(p *peer, payload []byte) {
+	for _, item := range decodeInv(payload) {
+		if data := n.readInv(item); data != nil {
+			p.knownInv.add(item.hash)
+			sendData(p, BuildPacket(item.typeID, data))
+		}
+	}
+}
+
+//Called for every received block and tx, announced to us or not.
+func (n *Node) receivedInv(p *peer, hash [32]byte) {
+	p.knownInv.add(hash)
+
+	n.requestedInvL.Lock()
+	delete(n.requestedInv, hash)
+	n.requestedInvL.Unlock()
+
+	n.expireRequestedInv(time.Now().Unix())
+}
+
+func (n *Node) expireRequestedInv(now int64) {
+	n.requestedInvL.Lock()
+	defer n.requestedInvL.Unlock()
+
+	for hash, requested := range n.requestedInv {
+		if requested+GETDATA_TIMEOUT < now {
+			delete(n.requestedInv, hash)
+		}
+	}
+}
+
+func (n *Node) haveInv(item *invItem) bool {
+	n.relayCacheL.Lock()
+	_, cached := n.relayCache[item.hash]
+	n.relayCacheL.Unlock()
+
+	if cached {
+		return true
+	}
+	if !n.store.IsOpen() {
+		return false
+	}
+	if item.typeID == BLOCK_BRDCST {
+		return n.store.ReadClosedBlock(item.hash) != nil
+	}
+
+	return n.store.ReadOpenTx(item.hash) != nil || n.store.ReadClosedTx(item.hash) != nil
+}
+
+func (n *Node) readInv(item *invItem) []byte {
+	n.relayCacheL.Lock()
+	cached, exists := n.relayCache[item.hash]
+	n.relayCacheL.Unlock()
+
+	if exists && cached.typeID == item.typeID {
+		return cached.payload
+	}
+	if !n.store.IsOpen() {
+		return nil
+	}
+
+	if item.typeID == BLOCK_BRDCST {
+		if block := n.store.ReadClosedBlock(item.hash); block != nil {
+			return block.Encode()
+		}
+		return nil
+	}
+
+	tx := n.readTx(item.hash)
+	if tx == nil || txBrdcstType(tx) != item.typeID {
+		return nil
+	}
+
+	return tx.Encode()
+}
+
+//Reads a tx from the mempool or, if the storage is open, from the closed txs.
+func (n *Node) readTx(hash [32]byte) protocol.Transaction {
+	if tx := n.store.ReadOpenTx(hash); tx != nil {
+		return tx
+	}
+	if n.store.IsOpen() {
+		return n.store.ReadClosedTx(hash)
+	}
+	return nil
+}
+
+//Decodes a tx sent with the given broadcast type, nil if it is malformed.
+func decodeTx(typeID uint8, payload []byte) protocol.Transaction {
+	switch typeID {
+	case FUNDSTX_BRDCST:
+		var fTx *protocol.FundsTx
+		if fTx = fTx.Decode(payload); fTx != nil {
+			return fTx
+		}
+	case ACCTX_BRDCST:
+		var aTx *protocol.AccTx
+		if aTx = aTx.Decode(payload); aTx != nil {
+			return aTx
+		}
+	case CONFIGTX_BRDCST:
+		var cTx *protocol.ConfigTx
+		if cTx = cTx.Decode(payload); cTx != nil {
+			return cTx
+		}
+	case STAKETX_BRDCST:
+		var sTx *protocol.StakeTx
+		if sTx = sTx.Decode(payload); sTx != nil {
+			return sTx
+		}
+	case AGGTX_BRDCST:
+		var aTx *protocol.AggTx
+		if aTx = aTx.Decode(payload); aTx != nil {
+			return aTx
+		}
+	case CONTRACTTX_BRDCST:
+		var cTx *protocol.ContractTx
+		if cTx = cTx.Decode(payload); cTx != nil {
+			return cTx
+		}
+	case IOTBATCHTX_BRDCST:
+		var bTx *protocol.IotBatchTx
+		if bTx = bTx.Decode(payload); bTx != nil {
+			return bTx
+		}
+	case IOTAGGTX_BRDCST:
+		var aTx *protocol.IotAggTx
+		if aTx = aTx.Decode(payload); aTx != nil {
+			return aTx
+		}
+	case DELEGATETX_BRDCST:
+		var dTx *protocol.DelegateTx
+		if dTx = dTx.Decode(payload); dTx != nil {
+			return dTx
+		}
+	case EVIDENCETX_BRDCST:
+		var eTx *protocol.EvidenceTx
+		if eTx = eTx.Decode(payload); eTx != nil {
+			return eTx
+		}
+	case GOVERNANCETX_BRDCST:
+		var gTx *protocol.GovernanceTx
+		if gTx = gTx.Decode(payload); gTx != nil {
+			return gTx
+		}
+	case IOTTX_BRDCST:
+		var iTx *protocol.IotTx
+		if iTx = iTx.Decode(payload); iTx != nil {
+			return iTx
+		}
+	}
+	return nil
+}
+
+func txBrdcstType(tx protocol.Transaction) uint8 {
+	switch tx.(type) {
+	case *protocol.FundsTx:
+		return FUNDSTX_BRDCST
+	case *protocol.AccTx:
+		return ACCTX_BRDCST
+	case *protocol.ConfigTx:
+		return CONFIGTX_BRDCST
+	case *protocol.StakeTx:
+		return STAKETX_BRDCST
+	case *protocol.AggTx:
+		return AGGTX_BRDCST
+	case *protocol.ContractTx:
+		return CONTRACTTX_BRDCST
+	case *protocol.IotBatchTx:
+		return IOTBATCHTX_BRDCST
+	case *protocol.IotAggTx:
+		return IOTAGGTX_BRDCST
+	case *protocol.DelegateTx:
+		return DELEGATETX_BRDCST
+	case *protocol.EvidenceTx:
+		return EVIDENCETX_BRDCST
+	case *protocol.GovernanceTx:
+		return GOVERNANCETX_BRDCST
+	case *protocol.IotTx:
+		return IOTTX_BRDCST
+	}
+	return 0
+}
@@ -0,0 +1,131 @@
+package p2p
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestInvEncoding(t *testing.T) {
+
+	items := []*invItem{{typeID: FUNDSTX_BRDCST, hash: [32]byte{1}}, {typeID: BLOCK_BRDCST, hash: [32]byte{2}}}
+	payload := encodeInv(items)
+
+	decoded := decodeInv(payload)
+	if len(decoded) != 2 || decoded[0].typeID != FUNDSTX_BRDCST || decoded[0].hash != items[0].hash ||
+		decoded[1].typeID != BLOCK_BRDCST || decoded[1].hash != items[1].hash {
+		t.Errorf("INV not correctly decoded: %v\n", decoded)
+	}
+
+	//Unknown types and truncated items are dropped
+	payload = append(encodeInv([]*invItem{{typeID: NEIGHBOR_REQ, hash: [32]byte{3}}}), payload...)
+	if decoded := decodeInv(payload[:len(payload)-1]); len(decoded) != 1 || decoded[0].hash != items[0].hash {
+		t.Errorf("Malformed INV items decoded: %v\n", decoded)
+	}
+
+	var large []*invItem
+	for i := 0; i < MAX_INV_ITEMS+1; i++ {
+		large = append(large, &invItem{typeID: FUNDSTX_BRDCST})
+	}
+	if decoded := decodeInv(encodeInv(large)); len(decoded) != MAX_INV_ITEMS {
+		t.Errorf("INV exceeding MAX_INV_ITEMS decoded: %v items\n", len(decoded))
+	}
+}
+
+func TestHashSet(t *testing.T) {
+
+	set := newHashSet(2)
+	if !set.add([32]byte{1}) || set.add([32]byte{1}) {
+		t.Error("Hash set does not detect known hashes.")
+	}
+
+	//The oldest hash is removed first
+	set.add([32]byte{2})
+	set.add([32]byte{3})
+	if !set.add([32]byte{1}) || set.add([32]byte{3}) {
+		t.Error("Hash set not bounded.")
+	}
+}
+
+//Announced items are only requested if they are unknown and were not requested before
+func TestInvGetData(t *testing.T) {
+
+	conn1, conn2 := net.Pipe()
+	p1 := testNode.newPeer(conn1, "", PEERTYPE_MINER)
+	p2 := testNode.newPeer(conn2, "", PEERTYPE_MINER)
+
+	known := &invItem{typeID: FUNDSTX_BRDCST, hash: [32]byte{0xaa, 1}, payload: []byte{1, 2, 3}}
+	unknown := &invItem{typeID: ACCTX_BRDCST, hash: [32]byte{0xaa, 2}}
+	testNode.cacheInv(known)
+
+	received := make(chan []byte)
+	go func() {
+		for i := 0; i < 2; i++ {
+			header, payload, err := RcvData(p2)
+			if err != nil {
+				t.Errorf("Receiving failed: %v\n", err)
+				close(received)
+				return
+			}
+			received <- append([]byte{header.TypeID}, payload...)
+		}
+	}()
+
+	go testNode.processInv(p1, encodeInv([]*invItem{known, unknown}))
+	getData := <-received
+	if getData[0] != GETDATA || !bytes.Equal(getData[1:], encodeInv([]*invItem{unknown})) {
+		t.Errorf("Unexpected GETDATA: %v\n", getData)
+	}
+
+	//The item was already requested, the next packet has to be the requested tx
+	go func() {
+		testNode.processInv(p1, encodeInv([]*invItem{unknown}))
+		testNode.processGetData(p1, encodeInv([]*invItem{unknown, known}))
+	}()
+	data := <-received
+	if data[0] != FUNDSTX_BRDCST || !bytes.Equal(data[1:], known.payload) {
+		t.Errorf("Unexpected response to GETDATA: %v\n", data)
+	}
+
+	testNode.receivedInv(p1, unknown.hash)
+	testNode.requestedInvL.Lock()
+	_, requested := testNode.requestedInv[unknown.hash]
+	testNode.requestedInvL.Unlock()
+	if requested {
+		t.Error("Received item still requested.")
+	}
+
+	conn1.Close()
+	conn2.Close()
+}
+
+//Items are announced once per peer, peers which don't know INV get the full payload
+func TestBrdcstInv(t *testing.T) {
+
+	item := &invItem{typeID: FUNDSTX_BRDCST, hash: [32]byte{0xbb}, payload: []byte{1, 2, 3}}
+
+	p := testNode.newPeer(nil, "", PEERTYPE_MINER)
+	p.ch = make(chan []byte, 2)
+	testNode.brdcstInv(p, item)
+	testNode.brdcstInv(p, item)
+	if len(p.ch) != 1 || !bytes.Equal(<-p.ch, BuildPacket(INV, encodeInv([]*invItem{item}))) {
+		t.Error("Item not announced exactly once.")
+	}
+
+	old := testNode.newPeer(nil, "", PEERTYPE_MINER)
+	old.ch = make(chan []byte, 1)
+	old.setHello(&Hello{Version: 1, MessageTypes: []uint8{FUNDSTX_BRDCST}})
+	testNode.brdcstInv(old, item)
+	if len(old.ch) != 1 || !bytes.Equal(<-old.ch, BuildPacket(FUNDSTX_BRDCST, item.payload)) {
+		t.Error("Peer without INV support did not get the payload.")
+	}
+
+	//Peers which sent us the item are not announced it
+	sender := testNode.newPeer(nil, "", PEERTYPE_MINER)
+	sender.ch = make(chan []byte, 1)
+	testNode.receivedInv(sender, item.hash)
+	testNode.brdcstInv(sender, item)
+	if len(sender.ch) != 0 {
+		t.Error("Item announced to the peer it was received from.")
+	}
+}
@@ -25,6 +25,12 @@ func InitLogging() {
 	LogMapping[7]  = "BLOCK_HEADER_BRDCST"
 	LogMapping[8]  = "TX_BRDCST_ACK"
 	LogMapping[9]  = "AGGTX_BRDCST"
+	LogMapping[10] = "CONTRACTTX_BRDCST"
+	LogMapping[11] = "IOTBATCHTX_BRDCST"
+	LogMapping[12] = "DELEGATETX_BRDCST"
+	LogMapping[13] = "EVIDENCETX_BRDCST"
+	LogMapping[14] = "GOVERNANCETX_BRDCST"
+	LogMapping[15] = "IOTAGGTX_BRDCST"
 
 	LogMapping[20] = "FUNDSTX_REQ"
 	LogMapping[21] = "ACCTX_REQ"
@@ -36,6 +42,16 @@ func InitLogging() {
 	LogMapping[27] = "ROOTACC_REQ"
 	LogMapping[28] = "INTERMEDIATE_NODES_REQ"
 	LogMapping[29] = "AGGTX_REQ"
+	LogMapping[30] = "CONTRACTTX_REQ"
+	LogMapping[31] = "IOTDATA_REQ"
+	LogMapping[32] = "IOTBATCHTX_REQ"
+	LogMapping[33] = "TX_BATCH_REQ"
+	LogMapping[34] = "DELEGATETX_REQ"
+	LogMapping[35] = "EVIDENCETX_REQ"
+	LogMapping[36] = "GOVERNANCETX_REQ"
+	LogMapping[37] = "PARAMETERS_REQ"
+	LogMapping[38] = "SUPPLY_REQ"
+	LogMapping[39] = "BASEFEE_REQ"
 
 	LogMapping[40] = "FUNDSTX_RES"
 	LogMapping[41] = "ACCTX_RES"
@@ -47,6 +63,16 @@ func InitLogging() {
 	LogMapping[47] = "ROOTACC_RES"
 	LogMapping[48] = "INTERMEDIATE_NODES_RES"
 	LogMapping[49] = "AGGTX_RES"
+	LogMapping[50] = "CONTRACTTX_RES"
+	LogMapping[51] = "IOTDATA_RES"
+	LogMapping[52] = "IOTBATCHTX_RES"
+	LogMapping[53] = "TX_BATCH_RES"
+	LogMapping[54] = "DELEGATETX_RES"
+	LogMapping[55] = "EVIDENCETX_RES"
+	LogMapping[56] = "GOVERNANCETX_RES"
+	LogMapping[57] = "PARAMETERS_RES"
+	LogMapping[58] = "SUPPLY_RES"
+	LogMapping[59] = "BASEFEE_RES"
 
 	LogMapping[105] = "IOTTX_BRDCST"
 	LogMapping[106] = "IOTTX_REQ"
@@ -56,11 +82,24 @@ func InitLogging() {
 	LogMapping[140] = "NEIGHBOR_RES"
 
 	LogMapping[150] = "TIME_BRDCST"
+	LogMapping[151] = "TIME_REQ"
+	LogMapping[152] = "TIME_RES"
 
 	LogMapping[100] = "MINER_PING"
 	LogMapping[101] = "MINER_PONG"
 	LogMapping[102] = "CLIENT_PING"
 	LogMapping[103] = "CLIENT_PONG"
+	LogMapping[108] = "HELLO"
+	LogMapping[109] = "DISCONNECT"
 
 	LogMapping[110] = "NOT_FOUND"
+	LogMapping[111] = "INV"
+	LogMapping[112] = "GETDATA"
+	LogMapping[113] = "CMPCTBLOCK"
+	LogMapping[114] = "GETBLOCKTXN"
+	LogMapping[115] = "BLOCKTXN"
+	LogMapping[116] = "VALIDATORS_REQ"
+	LogMapping[117] = "VALIDATORS_RES"
+	LogMapping[118] = "IOTAGGTX_REQ"
+	LogMapping[119] = "IOTAGGTX_RES"
 }
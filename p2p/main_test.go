@@ -1,40 +1,37 @@
 package p2p
 
 import (
+	"crypto/rand"
+	"golang.org/x/crypto/ed25519"
+	"net"
 	"os"
 	"testing"
 )
 
 var (
 	MINER_IPPORT = "127.0.0.1:8000"
+
+	testNode *Node
 )
 
-//Corresponds largely to server.go -> Init(...)
+//Corresponds largely to Node.Start()
 func TestMain(m *testing.M) {
 	//Used for some tests, the bootstarp server is listening at 8000 at the same time
-	Ipport = "127.0.0.1:9000"
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	testNode = NewNode(Config{Addr: "127.0.0.1:9000", Key: key})
 	InitLogging()
 
-	peers.minerConns = make(map[*peer]bool)
-	peers.clientConns = make(map[*peer]bool)
-
-	BlockIn = make(chan []byte)
-	BlockOut = make(chan []byte)
-
-	iplistChan = make(chan string, MIN_MINERS)
-	minerBrdcstMsg = make(chan []byte)
-	clientBrdcstMsg = make(chan []byte)
-	register = make(chan *peer)
-	disconnect = make(chan *peer)
-
-	go broadcastService()
-	go checkHealthService()
-	go timeService()
-	go forwardBlockBrdcstToMiner()
-	go peerService()
+	go testNode.broadcastService()
+	go testNode.checkHealthService()
+	go testNode.timeService()
+	go testNode.forwardBlockBrdcstToMiner()
+	go testNode.peerService()
 
 	//Bootstrap server
-	go listener("127.0.0.1:8000")
+	listener, err := net.Listen("tcp", MINER_IPPORT)
+	if err == nil {
+		go testNode.acceptConns(listener)
+	}
 
 	os.Exit(m.Run())
 }
@@ -0,0 +1,374 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//Like the backlog of a tcp listener, dialing succeeds as long as there are less pending connections
+const MEM_BACKLOG = 128
+
+//MemNetwork connects nodes of the same process without sockets. Every node gets the transport of its own host (an
+//ip address), such that addresses, bans and address groups work like on a real network. Latency and partitions can
+//be injected between hosts.
+type MemNetwork struct {
+	listeners map[string]*memListener
+	conns     map[*memConn]bool
+	latency   time.Duration
+	//Hosts in different groups can't reach each other, hosts without group are in group 0
+	groups   map[string]int
+	nextPort int
+	l        sync.Mutex
+}
+
+func NewMemNetwork() *MemNetwork {
+	return &MemNetwork{
+		listeners: make(map[string]*memListener),
+		conns:     make(map[*memConn]bool),
+		groups:    make(map[string]int),
+		nextPort:  40000,
+	}
+}
+
+//Transport of the node running on host.
+func (network *MemNetwork) Transport(host string) Transport {
+	return &memTransport{network, host}
+}
+
+//Delays every packet sent after the call by latency.
+func (network *MemNetwork) SetLatency(latency time.Duration) {
+	network.l.Lock()
+	defer network.l.Unlock()
+
+	network.latency = latency
+}
+
+//Separates the hosts from all other hosts, existing connections between them are closed. Hosts of several calls
+//can reach each other.
+func (network *MemNetwork) Partition(hosts ...string) {
+	network.l.Lock()
+	for _, host := range hosts {
+		network.groups[host] = 1
+	}
+	var cut []*memConn
+	for conn := range network.conns {
+		if !network.reachable(conn.localAddr.host, conn.remoteAddr.host) {
+			cut = append(cut, conn)
+		}
+	}
+	network.l.Unlock()
+
+	for _, conn := range cut {
+		conn.Close()
+	}
+}
+
+//Removes all partitions, closed connections are not reopened.
+func (network *MemNetwork) Heal() {
+	network.l.Lock()
+	defer network.l.Unlock()
+
+	network.groups = make(map[string]int)
+}
+
+//Has to be called with l locked.
+func (network *MemNetwork) reachable(host1, host2 string) bool {
+	return network.groups[host1] == network.groups[host2]
+}
+
+func (network *MemNetwork) dial(host string, addr string) (net.Conn, error) {
+	remoteHost, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	network.l.Lock()
+	listener, exists := network.listeners[net.JoinHostPort(remoteHost, port)]
+	if !exists || !network.reachable(host, remoteHost) {
+		network.l.Unlock()
+		return nil, errors.New(fmt.Sprintf("dial %v: connection refused", addr))
+	}
+
+	localAddr := memAddr{host, network.nextPort}
+	network.nextPort++
+	local, remote := newMemConnPair(network, localAddr, listener.addr, network.latency)
+	network.conns[local] = true
+	network.conns[remote] = true
+	network.l.Unlock()
+
+	select {
+	case listener.accept <- remote:
+		return local, nil
+	case <-listener.done:
+		local.Close()
+		return nil, errors.New(fmt.Sprintf("dial %v: connection refused", addr))
+	}
+}
+
+func (network *MemNetwork) listen(host string, addr string) (net.Listener, error) {
+	_, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	//The listener is always bound to the host of the transport, e.g. for bind addresses without host
+	listener := &memListener{
+		network: network,
+		addr:    memAddr{host, port},
+		accept:  make(chan net.Conn, MEM_BACKLOG),
+		done:    make(chan bool),
+	}
+
+	network.l.Lock()
+	defer network.l.Unlock()
+
+	if _, exists := network.listeners[listener.addr.String()]; exists {
+		return nil, errors.New(fmt.Sprintf("listen %v: address already in use", listener.addr))
+	}
+	network.listeners[listener.addr.String()] = listener
+
+	return listener, nil
+}
+
+func (network *MemNetwork) removeConn(conn *memConn) {
+	network.l.Lock()
+	defer network.l.Unlock()
+
+	delete(network.conns, conn)
+}
+
+type memTransport struct {
+	network *MemNetwork
+	host    string
+}
+
+func (transport *memTransport) Dial(addr string) (net.Conn, error) {
+	return transport.network.dial(transport.host, addr)
+}
+
+func (transport *memTransport) Listen(addr string) (net.Listener, error) {
+	return transport.network.listen(transport.host, addr)
+}
+
+type memAddr struct {
+	host string
+	port int
+}
+
+func (addr memAddr) Network() string {
+	return "mem"
+}
+
+func (addr memAddr) String() string {
+	return net.JoinHostPort(addr.host, strconv.Itoa(addr.port))
+}
+
+type memListener struct {
+	network   *MemNetwork
+	addr      memAddr
+	accept    chan net.Conn
+	done      chan bool
+	closeOnce sync.Once
+}
+
+func (listener *memListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-listener.accept:
+		return conn, nil
+	case <-listener.done:
+		return nil, errors.New(fmt.Sprintf("accept %v: listener closed", listener.addr))
+	}
+}
+
+func (listener *memListener) Close() error {
+	listener.closeOnce.Do(func() {
+		listener.network.l.Lock()
+		delete(listener.network.listeners, listener.addr.String())
+		listener.network.l.Unlock()
+		close(listener.done)
+	})
+
+	return nil
+}
+
+func (listener *memListener) Addr() net.Addr {
+	return listener.addr
+}
+
+//One direction of a connection. Written data is delivered to the reading side after the latency of the network.
+type memPipe struct {
+	buf      []byte
+	closed   bool
+	deadline time.Time
+	l        sync.Mutex
+	cond     *sync.Cond
+}
+
+func newMemPipe() *memPipe {
+	pipe := new(memPipe)
+	pipe.cond = sync.NewCond(&pipe.l)
+	return pipe
+}
+
+func (pipe *memPipe) deliver(data []byte) {
+	pipe.l.Lock()
+	defer pipe.l.Unlock()
+
+	pipe.buf = append(pipe.buf, data...)
+	pipe.cond.Broadcast()
+}
+
+func (pipe *memPipe) close() {
+	pipe.l.Lock()
+	defer pipe.l.Unlock()
+
+	pipe.closed = true
+	pipe.cond.Broadcast()
+}
+
+//Delivered data is read before the end of a closed pipe is reported, like on a TCP connection.
+func (pipe *memPipe) read(b []byte) (int, error) {
+	pipe.l.Lock()
+	defer pipe.l.Unlock()
+
+	for len(pipe.buf) == 0 {
+		if pipe.closed {
+			return 0, io.EOF
+		}
+		if !pipe.deadline.IsZero() && !time.Now().Before(pipe.deadline) {
+			return 0, errors.New("i/o timeout")
+		}
+		pipe.cond.Wait()
+	}
+
+	n := copy(b, pipe.buf)
+	pipe.buf = pipe.buf[n:]
+
+	return n, nil
+}
+
+func (pipe *memPipe) setDeadline(deadline time.Time) {
+	pipe.l.Lock()
+	pipe.deadline = deadline
+	pipe.l.Unlock()
+
+	if !deadline.IsZero() {
+		time.AfterFunc(time.Until(deadline), func() {
+			pipe.l.Lock()
+			pipe.cond.Broadcast()
+			pipe.l.Unlock()
+		})
+	}
+}
+
+type memChunk struct {
+	data      []byte
+	deliverAt time.Time
+}
+
+type memConn struct {
+	network    *MemNetwork
+	localAddr  memAddr
+	remoteAddr memAddr
+	latency    time.Duration
+	in         *memPipe
+	out        *memPipe
+	queue      chan memChunk
+	done       chan bool
+	closeOnce  sync.Once
+}
+
+func newMemConnPair(network *MemNetwork, addr1, addr2 memAddr, latency time.Duration) (conn1, conn2 *memConn) {
+	pipe1, pipe2 := newMemPipe(), newMemPipe()
+	conn1 = &memConn{network, addr1, addr2, latency, pipe1, pipe2, make(chan memChunk, 1024), make(chan bool), sync.Once{}}
+	conn2 = &memConn{network, addr2, addr1, latency, pipe2, pipe1, make(chan memChunk, 1024), make(chan bool), sync.Once{}}
+
+	go conn1.deliver()
+	go conn2.deliver()
+
+	return conn1, conn2
+}
+
+//Passes the written chunks on in order. Once the connection is closed, the chunks written so far are still
+//delivered before the other side reads the end of the connection.
+func (conn *memConn) deliver() {
+	for {
+		select {
+		case chunk := <-conn.queue:
+			time.Sleep(time.Until(chunk.deliverAt))
+			conn.out.deliver(chunk.data)
+		case <-conn.done:
+			for {
+				select {
+				case chunk := <-conn.queue:
+					conn.out.deliver(chunk.data)
+				default:
+					conn.out.close()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (conn *memConn) Read(b []byte) (int, error) {
+	return conn.in.read(b)
+}
+
+func (conn *memConn) Write(b []byte) (int, error) {
+	chunk := memChunk{append([]byte{}, b...), time.Now().Add(conn.latency)}
+
+	select {
+	case <-conn.done:
+		return 0, errors.New(fmt.Sprintf("write %v: connection closed", conn.remoteAddr))
+	default:
+	}
+
+	select {
+	case conn.queue <- chunk:
+		return len(b), nil
+	case <-conn.done:
+		return 0, errors.New(fmt.Sprintf("write %v: connection closed", conn.remoteAddr))
+	}
+}
+
+func (conn *memConn) Close() error {
+	conn.closeOnce.Do(func() {
+		close(conn.done)
+		conn.in.close()
+		conn.network.removeConn(conn)
+	})
+
+	return nil
+}
+
+func (conn *memConn) LocalAddr() net.Addr {
+	return conn.localAddr
+}
+
+func (conn *memConn) RemoteAddr() net.Addr {
+	return conn.remoteAddr
+}
+
+func (conn *memConn) SetDeadline(deadline time.Time) error {
+	return conn.SetReadDeadline(deadline)
+}
+
+func (conn *memConn) SetReadDeadline(deadline time.Time) error {
+	conn.in.setDeadline(deadline)
+	return nil
+}
+
+//Writes never block on the reading side, write deadlines are not needed.
+func (conn *memConn) SetWriteDeadline(deadline time.Time) error {
+	return nil
+}
@@ -2,86 +2,96 @@ package p2p
 
 import (
 	"github.com/bazo-blockchain/bazo-miner/protocol"
-	"sync"
 )
 
+//Channels of the node of the miner, see Node.
 var (
-	//Block from the network, to the miner
-	BlockIn = make(chan []byte)
-	//Block from the miner, to the network
-	BlockOut = make(chan []byte)
-	//BlockHeader from the miner, to the clients
-	BlockHeaderOut = make(chan []byte)
-
-	VerifiedTxsOut = make(chan []byte)
-
-	//Data requested by miner, to allow parallelism, we have a chan for every tx type.
-	FundsTxChan  		= make(chan *protocol.FundsTx)
-	AccTxChan    		= make(chan *protocol.AccTx)
-	ConfigTxChan 		= make(chan *protocol.ConfigTx)
-	StakeTxChan  		= make(chan *protocol.StakeTx)
-	AggTxChan    	= make(chan *protocol.AggTx)
-	IoTTxChan    		= make(chan *protocol.IotTx)
-
-
-	BlockReqChan = make(chan []byte)
-
-	receivedTXStash = make([]*protocol.FundsTx, 0)
-	receivedAggTxStash = make([]*protocol.AggTx, 0)
-
-	fundsTxSashMutex = &sync.Mutex{}
-	aggTxSashMutex = &sync.Mutex{}
+	BlockIn        = defaultNode.BlockIn
+	BlockOut       = defaultNode.BlockOut
+	BlockHeaderOut = defaultNode.BlockHeaderOut
+	VerifiedTxsOut = defaultNode.VerifiedTxsOut
+
+	FundsTxChan      = defaultNode.FundsTxChan
+	AccTxChan        = defaultNode.AccTxChan
+	ConfigTxChan     = defaultNode.ConfigTxChan
+	StakeTxChan      = defaultNode.StakeTxChan
+	AggTxChan        = defaultNode.AggTxChan
+	IoTTxChan        = defaultNode.IoTTxChan
+	ContractTxChan   = defaultNode.ContractTxChan
+	IoTBatchTxChan   = defaultNode.IoTBatchTxChan
+	IoTAggTxChan     = defaultNode.IoTAggTxChan
+	DelegateTxChan   = defaultNode.DelegateTxChan
+	EvidenceTxChan   = defaultNode.EvidenceTxChan
+	GovernanceTxChan = defaultNode.GovernanceTxChan
+
+	BlockReqChan = defaultNode.BlockReqChan
 )
 
-//This is for blocks and txs that the miner successfully validated.
-func forwardBlockBrdcstToMiner() {
+//This is for blocks and txs that the miner successfully validated. Blocks are announced and only sent on request.
+func (n *Node) forwardBlockBrdcstToMiner() {
 	for {
-		block := <-BlockOut
-		toBrdcst := BuildPacket(BLOCK_BRDCST, block)
-		minerBrdcstMsg <- toBrdcst
+		var payload []byte
+		select {
+		case payload = <-n.BlockOut:
+		case <-n.quit:
+			return
+		}
+		var block *protocol.Block
+		if block = block.Decode(payload); block == nil {
+			continue
+		}
+		n.relayInv(&invItem{typeID: BLOCK_BRDCST, hash: block.Hash, payload: payload, block: block})
 	}
 }
 
-func forwardBlockHeaderBrdcstToMiner() {
+func (n *Node) forwardBlockHeaderBrdcstToMiner() {
 	for {
-		blockHeader := <- BlockHeaderOut
-		clientBrdcstMsg <- BuildPacket(BLOCK_HEADER_BRDCST, blockHeader)
+		select {
+		case blockHeader := <-n.BlockHeaderOut:
+			n.clientBrdcstMsg <- BuildPacket(BLOCK_HEADER_BRDCST, blockHeader)
+		case <-n.quit:
+			return
+		}
 	}
 }
 
-func forwardVerifiedTxsToMiner() {
+func (n *Node) forwardVerifiedTxsToMiner() {
 	for {
-		verifiedTxs := <- VerifiedTxsOut
-		clientBrdcstMsg <- BuildPacket(VERIFIEDTX_BRDCST, verifiedTxs)
+		select {
+		case verifiedTxs := <-n.VerifiedTxsOut:
+			n.clientBrdcstMsg <- BuildPacket(VERIFIEDTX_BRDCST, verifiedTxs)
+		case <-n.quit:
+			return
+		}
 	}
 }
 
-func forwardBlockToMiner(p *peer, payload []byte) {
-	BlockIn <- payload
-}
-
-//Checks if Tx Is in the received stash. If true, we received the transaction with a request already.
-func txAlreadyInStash(slice []*protocol.FundsTx, newTXHash [32]byte) bool {
-	for _, txInStash := range slice {
-		if txInStash.Hash() == newTXHash {
-			return true
-		}
+func (n *Node) forwardBlockToMiner(p *peer, payload []byte) {
+	var block *protocol.Block
+	if block = block.Decode(payload); block != nil {
+		n.recordOrigin(block.Hash, p)
+		n.receivedInv(p, block.Hash)
 	}
-	return false
-}
 
-func aggTxAlreadyInStash(slice []*protocol.AggTx, newTXHash [32]byte) bool {
-	for _, txInStash := range slice {
-		if txInStash.Hash() == newTXHash {
-			return true
-		}
+	select {
+	case n.BlockIn <- payload:
+	case <-n.quit:
 	}
-	return false
 }
 
+//Txs which came with the reconstructed compact block of the given hash and were not in the mempool. They are handed
+//out once, the miner writes them to the mempool after verifying them and reports invalid ones with ReportInvalidTx.
+func (n *Node) BlockTxs(hash [32]byte) []protocol.Transaction {
+	n.blockTxsL.Lock()
+	defer n.blockTxsL.Unlock()
+
+	txs := n.blockTxs[hash]
+	delete(n.blockTxs, hash)
+	return txs
+}
 
 //These are transactions the miner specifically requested.
-func forwardTxReqToMiner(p *peer, payload []byte, txType uint8) {
+func (n *Node) forwardTxReqToMiner(p *peer, payload []byte, txType uint8) {
 	if payload == nil {
 		return
 	}
@@ -97,39 +107,33 @@ func forwardTxReqToMiner(p *peer, payload []byte, txType uint8) {
 	// Otherwise send nothing. This means, that the TX was sent before and we ensure, that only one TX per Broadcast
 	// request is going through to the FETCH Request. This should prevent the "Received txHash did not correspond to
 	// our request." error
-	// The Mutex Lock is needed, because sometimes the execution is too fast. And even with the stash transactions
-	// are sent multiple times through the channel.
+	// Adding to the stash is atomic, even if the same TX is received concurrently it is sent only once through
+	// the channel.
 		// The same concept is used for the AggTx below.
-		fundsTxSashMutex.Lock()
-		if !txAlreadyInStash(receivedTXStash, fundsTx.Hash()) {
-			receivedTXStash = append(receivedTXStash, fundsTx)
-			FundsTxChan <- fundsTx
-			if len(receivedTXStash) > 1000 {
-				receivedTXStash = append(receivedTXStash[:0], receivedTXStash[1:]...)
-			}
-		}
-		fundsTxSashMutex.Unlock()
+		if n.receivedTxStash.add(fundsTx.Hash()) {
+			n.FundsTxChan <- fundsTx
+		}
 	case ACCTX_RES:
 		var accTx *protocol.AccTx
 		accTx = accTx.Decode(payload)
 		if accTx == nil {
 			return
 		}
-		AccTxChan <- accTx
+		n.AccTxChan <- accTx
 	case CONFIGTX_RES:
 		var configTx *protocol.ConfigTx
 		configTx = configTx.Decode(payload)
 		if configTx == nil {
 			return
 		}
-		ConfigTxChan <- configTx
+		n.ConfigTxChan <- configTx
 	case STAKETX_RES:
 		var stakeTx *protocol.StakeTx
 		stakeTx = stakeTx.Decode(payload)
 		if stakeTx == nil {
 			return
 		}
-		StakeTxChan <- stakeTx
+		n.StakeTxChan <- stakeTx
 	case AGGTX_RES:
 		var aggTx *protocol.AggTx
 		aggTx = aggTx.Decode(payload)
@@ -137,30 +141,74 @@ func forwardTxReqToMiner(p *peer, payload []byte, txType uint8) {
 			return
 		}
 
-		aggTxSashMutex.Lock()
-		if !aggTxAlreadyInStash(receivedAggTxStash, aggTx.Hash()) {
-			receivedAggTxStash = append(receivedAggTxStash, aggTx)
-			AggTxChan <- aggTx
-			if len(receivedAggTxStash) > 1000 {
-				receivedAggTxStash = append(receivedAggTxStash[:0], receivedAggTxStash[1:]...)
-			}
+		if n.receivedAggTxStash.add(aggTx.Hash()) {
+			n.AggTxChan <- aggTx
 		}
-		aggTxSashMutex.Unlock()
 	case IOTTX_RES:
 		var IoTTx *protocol.IotTx
 		IoTTx = IoTTx.Decode(payload)
 		if IoTTx == nil {
 			return
 		}
-		IoTTxChan <- IoTTx
+		n.IoTTxChan <- IoTTx
+	case CONTRACTTX_RES:
+		var contractTx *protocol.ContractTx
+		contractTx = contractTx.Decode(payload)
+		if contractTx == nil {
+			return
+		}
+		n.ContractTxChan <- contractTx
+	case IOTBATCHTX_RES:
+		var iotBatchTx *protocol.IotBatchTx
+		iotBatchTx = iotBatchTx.Decode(payload)
+		if iotBatchTx == nil {
+			return
+		}
+		n.IoTBatchTxChan <- iotBatchTx
+	case IOTAGGTX_RES:
+		var iotAggTx *protocol.IotAggTx
+		iotAggTx = iotAggTx.Decode(payload)
+		if iotAggTx == nil {
+			return
+		}
+		n.IoTAggTxChan <- iotAggTx
+	case DELEGATETX_RES:
+		var delegateTx *protocol.DelegateTx
+		delegateTx = delegateTx.Decode(payload)
+		if delegateTx == nil {
+			return
+		}
+		n.DelegateTxChan <- delegateTx
+	case EVIDENCETX_RES:
+		var evidenceTx *protocol.EvidenceTx
+		evidenceTx = evidenceTx.Decode(payload)
+		if evidenceTx == nil {
+			return
+		}
+		n.EvidenceTxChan <- evidenceTx
+	case GOVERNANCETX_RES:
+		var governanceTx *protocol.GovernanceTx
+		governanceTx = governanceTx.Decode(payload)
+		if governanceTx == nil {
+			return
+		}
+		n.GovernanceTxChan <- governanceTx
 	}
 
 }
 
-func forwardBlockReqToMiner(p *peer, payload []byte) {
-	BlockReqChan <- payload
+func (n *Node) forwardBlockReqToMiner(p *peer, payload []byte) {
+	select {
+	case n.BlockReqChan <- payload:
+	case <-n.quit:
+	}
+}
+
+//Network time in seconds, the local time corrected by the clock offset of the network.
+func (n *Node) ReadSystemTime() int64 {
+	return n.networkTime() / 1000
 }
 
 func ReadSystemTime() int64 {
-	return systemTime
+	return defaultNode.ReadSystemTime()
 }
@@ -7,7 +7,7 @@ import (
 //Both block and tx requests are handled asymmetricaly, using channels as inter-communication
 //All the request in this file are specifically initiated by the miner package
 //func BlockReq(hash [32]byte, hashWithoutTx [32]byte) error {
-func BlockReq(hash [32]byte, hashWithoutTx [32]byte) error {
+func (n *Node) BlockReq(hash [32]byte, hashWithoutTx [32]byte) error {
 
 	payload := hash[:]
 	payloadTEMP := hashWithoutTx[:]
@@ -15,7 +15,7 @@ func BlockReq(hash [32]byte, hashWithoutTx [32]byte) error {
 	payload = append(payload, payloadTEMP...)
 
 	// Block Request with a Broadcast request. This does rise the possibility of a valid answer.
-	for p := range peers.minerConns {
+	for _, p := range n.peers.getAllPeers(PEERTYPE_MINER) {
 		//Write to the channel, which the peerBroadcast(*peer) running in a seperate goroutine consumes right away.
 
 		if p == nil {
@@ -28,9 +28,13 @@ func BlockReq(hash [32]byte, hashWithoutTx [32]byte) error {
 	return nil
 }
 
-func LastBlockReq() error {
+func BlockReq(hash [32]byte, hashWithoutTx [32]byte) error {
+	return defaultNode.BlockReq(hash, hashWithoutTx)
+}
+
+func (n *Node) LastBlockReq() error {
 
-	p := peers.getRandomPeer(PEERTYPE_MINER)
+	p := n.peers.getRandomPeer(PEERTYPE_MINER)
 	if p == nil {
 		return errors.New("Couldn't get a connection, request not transmitted.")
 	}
@@ -40,11 +44,15 @@ func LastBlockReq() error {
 	return nil
 }
 
+func LastBlockReq() error {
+	return defaultNode.LastBlockReq()
+}
+
 //Request specific transaction
-func TxReq(hash [32]byte, reqType uint8) error {
+func (n *Node) TxReq(hash [32]byte, reqType uint8) error {
 
 	// Tx Request also as brodcast so that teh possibility of an answer is higher.
-	for p := range peers.minerConns {
+	for _, p := range n.peers.getAllPeers(PEERTYPE_MINER) {
 		//Write to the channel, which the peerBroadcast(*peer) running in a seperate goroutine consumes right away.
 
 		if p == nil {
@@ -56,3 +64,7 @@ func TxReq(hash [32]byte, reqType uint8) error {
 
 	return nil
 }
+
+func TxReq(hash [32]byte, reqType uint8) error {
+	return defaultNode.TxReq(hash, reqType)
+}
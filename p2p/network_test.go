@@ -0,0 +1,276 @@
+package p2p
+
+import (
+	"crypto/rand"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//Stands in for the miner of a simulated node: blocks from the network are stored and announced, the highest block
+//is the best block. Missing predecessors are requested like the miner does while syncing. The miner talks to its own
+//node as well, but keeps its state (storage, last block) in package variables, so only one runs per process.
+type testMiner struct {
+	node  *Node
+	store *MemStore
+}
+
+func (miner *testMiner) run() {
+	for {
+		var payload []byte
+		select {
+		case payload = <-miner.node.BlockIn:
+		case payload = <-miner.node.BlockReqChan:
+		case <-miner.node.quit:
+			return
+		}
+
+		var block *protocol.Block
+		if block = block.Decode(payload); block != nil {
+			miner.receive(block)
+		}
+	}
+}
+
+func (miner *testMiner) receive(block *protocol.Block) {
+	if miner.store.ReadClosedBlock(block.Hash) != nil {
+		return
+	}
+
+	var txs []protocol.Transaction
+	for _, txHash := range block.FundsTxData {
+		if tx := miner.store.ReadOpenTx(txHash); tx != nil {
+			txs = append(txs, tx)
+		}
+	}
+	miner.store.WriteClosedBlock(block, txs)
+
+	if block.PrevHash != [32]byte{} && miner.store.ReadClosedBlock(block.PrevHash) == nil {
+		miner.node.BlockReq(block.PrevHash, [32]byte{})
+	}
+
+	select {
+	case miner.node.BlockOut <- block.Encode():
+	case <-miner.node.quit:
+	}
+}
+
+//Mines a block on top of the best block of the miner.
+func (miner *testMiner) mine(txs ...protocol.Transaction) *protocol.Block {
+	var prevHash [32]byte
+	var height uint32 = 1
+	if last := miner.store.ReadLastClosedBlock(); last != nil {
+		prevHash, height = last.Hash, last.Height+1
+	}
+
+	block := protocol.NewBlock(prevHash, height)
+	for _, tx := range txs {
+		block.FundsTxData = append(block.FundsTxData, tx.Hash())
+	}
+	block.MerkleRoot = protocol.BuildMerkleTree(block).MerkleRoot()
+	block.Hash = block.HashBlock()
+
+	miner.receive(block)
+
+	return block
+}
+
+func (miner *testMiner) bestHash() (hash [32]byte) {
+	if last := miner.store.ReadLastClosedBlock(); last != nil {
+		hash = last.Hash
+	}
+	return hash
+}
+
+//Starts the miners one after the other, all of them bootstrap from the first one.
+func startTestNetwork(t *testing.T, network *MemNetwork, hosts []string) (miners []*testMiner) {
+	bootstrapAddr := hosts[0] + ":8000"
+
+	for _, host := range hosts {
+		_, key, _ := ed25519.GenerateKey(rand.Reader)
+		store := NewMemStore()
+		node := NewNode(Config{
+			Addr:                host + ":8000",
+			BootstrapAddr:       bootstrapAddr,
+			Key:                 key,
+			Transport:           network.Transport(host),
+			Store:               store,
+			HealthCheckInterval: 50 * time.Millisecond,
+		})
+
+		miner := &testMiner{node, store}
+		go miner.run()
+		if err := node.Start(); err != nil {
+			t.Fatalf("Node %v could not be started: %v\n", host, err)
+		}
+		miners = append(miners, miner)
+	}
+
+	return miners
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %v.\n", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func converged(miners []*testMiner, hash [32]byte) func() bool {
+	return func() bool {
+		for _, miner := range miners {
+			if miner.bestHash() != hash {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+//Blocks and txs reach all miners of a network with latency, a partitioned miner catches up once the partition is
+//healed.
+func TestNetworkConvergence(t *testing.T) {
+
+	network := NewMemNetwork()
+	network.SetLatency(10 * time.Millisecond)
+
+	var hosts []string
+	for i := 1; i <= 5; i++ {
+		hosts = append(hosts, fmt.Sprintf("10.%v.0.1", i))
+	}
+	miners := startTestNetwork(t, network, hosts)
+	for _, miner := range miners {
+		defer miner.node.Stop()
+	}
+
+	waitFor(t, "connections", func() bool {
+		for _, miner := range miners {
+			if miner.node.MinerCount() < MIN_MINERS {
+				return false
+			}
+		}
+		return true
+	})
+
+	block := miners[0].mine()
+	waitFor(t, "the first block", converged(miners, block.Hash))
+
+	//A tx is relayed to all mempools, the block containing it is reconstructed from them
+	tx := &protocol.FundsTx{Amount: 1, TxCnt: 1}
+	miners[1].store.WriteOpenTx(tx)
+	miners[1].node.relayInv(&invItem{typeID: FUNDSTX_BRDCST, hash: tx.Hash(), payload: tx.Encode()})
+	waitFor(t, "the tx", func() bool {
+		for _, miner := range miners {
+			if miner.store.ReadOpenTx(tx.Hash()) == nil {
+				return false
+			}
+		}
+		return true
+	})
+
+	block = miners[2].mine(tx)
+	waitFor(t, "the block with the tx", converged(miners, block.Hash))
+	for _, miner := range miners {
+		if miner.store.ReadClosedTx(tx.Hash()) == nil {
+			t.Errorf("Tx not closed by %v.\n", miner.node.Addr())
+		}
+	}
+
+	//The partitioned miner forks off, the others keep extending their chain
+	isolated := miners[4]
+	network.Partition(hosts[4])
+	waitFor(t, "the partition", func() bool { return isolated.node.MinerCount() == 0 })
+
+	fork := isolated.mine()
+	var missed []*protocol.Block
+	for i := 0; i < 2; i++ {
+		block = miners[0].mine()
+		missed = append(missed, block)
+	}
+	waitFor(t, "the majority chain", converged(miners[:4], block.Hash))
+	if isolated.bestHash() != fork.Hash {
+		t.Error("Block reached the partitioned miner.")
+	}
+
+	//After healing, the isolated miner reconnects and syncs the missing blocks of the longer chain
+	network.Heal()
+	waitFor(t, "the reconnection", func() bool {
+		//Both ends have to register the connection, otherwise the next block is not relayed to the isolated miner
+		for _, miner := range miners[:4] {
+			if miner.node.peers.containsNode(isolated.node.NodeID()) {
+				return true
+			}
+		}
+		return false
+	})
+
+	block = miners[1].mine()
+	waitFor(t, "convergence", converged(miners, block.Hash))
+	waitFor(t, "the missed blocks", func() bool {
+		for _, block := range missed {
+			if isolated.store.ReadClosedBlock(block.Hash) == nil {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestMemNetwork(t *testing.T) {
+
+	network := NewMemNetwork()
+	listener, err := network.Transport("10.0.0.1").Listen(":8000")
+	if err != nil {
+		t.Fatalf("Listening failed: %v\n", err)
+	}
+	defer listener.Close()
+
+	if _, err := network.Transport("10.0.0.2").Dial("10.0.0.1:8001"); err == nil {
+		t.Error("Connected to an address without listener.")
+	}
+
+	network.SetLatency(50 * time.Millisecond)
+	conn, err := network.Transport("10.0.0.2").Dial("10.0.0.1:8000")
+	if err != nil {
+		t.Fatalf("Dialing failed: %v\n", err)
+	}
+	accepted, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Accepting failed: %v\n", err)
+	}
+	if remoteIP(accepted) != "10.0.0.2" || conn.RemoteAddr().String() != "10.0.0.1:8000" {
+		t.Errorf("Unexpected addresses: %v, %v\n", accepted.RemoteAddr(), conn.RemoteAddr())
+	}
+
+	sent := time.Now()
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(accepted, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("Unexpected data: %s, %v\n", buf, err)
+	}
+	if time.Since(sent) < 50*time.Millisecond {
+		t.Error("Data delivered without latency.")
+	}
+
+	//Partitions close existing connections and refuse new ones until they are healed
+	network.Partition("10.0.0.2")
+	if _, err := accepted.Read(buf); err == nil {
+		t.Error("Connection across the partition still open.")
+	}
+	if _, err := network.Transport("10.0.0.2").Dial("10.0.0.1:8000"); err == nil {
+		t.Error("Connected across the partition.")
+	}
+
+	network.Heal()
+	go listener.Accept()
+	if _, err := network.Transport("10.0.0.2").Dial("10.0.0.1:8000"); err != nil {
+		t.Errorf("Dialing after healing failed: %v\n", err)
+	}
+}
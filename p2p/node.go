@@ -0,0 +1,291 @@
+package p2p
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"golang.org/x/crypto/ed25519"
+	"net"
+	"sync"
+	"time"
+)
+
+//Config of a node. Addr is the address other miners connect to (e.g. the public address of our NAT), BindAddr the
+//local address the listener is bound to. A bind address without host listens on all interfaces, an empty one on the
+//port of Addr. The bootstrap node is recognized by BootstrapID, if it is not zero, and by BootstrapAddr otherwise.
+type Config struct {
+	Addr          string
+	BindAddr      string
+	BootstrapAddr string
+	BootstrapID   [32]byte
+	Key           ed25519.PrivateKey
+	//Peers of other chains are disconnected
+	ChainID [32]byte
+	//TCP and the storage package if not set
+	Transport Transport
+	Store     Store
+	//HEALTH_CHECK_INTERVAL seconds if not set
+	HealthCheckInterval time.Duration
+}
+
+//Node is a single p2p node with its own connections, address book, inventory and reputation. The miner talks to its
+//node through the channels below, several nodes can run in the same process (e.g. on a MemNetwork).
+type Node struct {
+	//Block from the network, to the miner
+	BlockIn chan []byte
+	//Block from the miner, to the network
+	BlockOut chan []byte
+	//BlockHeader from the miner, to the clients
+	BlockHeaderOut chan []byte
+
+	VerifiedTxsOut chan []byte
+
+	//Data requested by miner, to allow parallelism, we have a chan for every tx type.
+	FundsTxChan      chan *protocol.FundsTx
+	AccTxChan        chan *protocol.AccTx
+	ConfigTxChan     chan *protocol.ConfigTx
+	StakeTxChan      chan *protocol.StakeTx
+	AggTxChan        chan *protocol.AggTx
+	IoTTxChan        chan *protocol.IotTx
+	ContractTxChan   chan *protocol.ContractTx
+	IoTBatchTxChan   chan *protocol.IotBatchTx
+	IoTAggTxChan     chan *protocol.IotAggTx
+	DelegateTxChan   chan *protocol.DelegateTx
+	EvidenceTxChan   chan *protocol.EvidenceTx
+	GovernanceTxChan chan *protocol.GovernanceTx
+
+	BlockReqChan chan []byte
+
+	addr          string
+	bindAddr      string
+	bootstrapAddr string
+	//Node id of the bootstrap server, zero if it is only known by its address
+	bootstrapID [32]byte
+	//Node key of this node, its public key is the node id other peers know us by
+	key     ed25519.PrivateKey
+	chainID [32]byte
+
+	transport           Transport
+	store               Store
+	healthCheckInterval time.Duration
+
+	//Addresses of other miners are kept in the address book (addrbook.go). A connection to a subset of it will be
+	//established as soon as the network health monitor triggers.
+	peers           *peersStruct
+	minerBrdcstMsg  chan []byte
+	clientBrdcstMsg chan []byte
+	register        chan *peer
+	disconnect      chan *peer
+
+	//Address book of all miners we know of, see addrbook.go
+	addrBook  map[string]*knownAddr
+	addrBookL sync.Mutex
+	//Addresses which turned out to reach ourselves (e.g. the public address of our NAT), they are never added again
+	selfAddrs  map[string]bool
+	selfAddrsL sync.Mutex
+
+	//Items announced by us, the payload is sent to peers requesting them
+	relayCache  map[[32]byte]*invItem
+	relayOrder  [][32]byte
+	relayCacheL sync.Mutex
+	//Items we asked for and did not receive yet
+	requestedInv  map[[32]byte]int64
+	requestedInvL sync.Mutex
+	invBrdcstMsg  chan *invItem
+
+	pendingBlocks   map[[32]byte]*pendingBlock
+	pendingBlocksL  sync.Mutex
+	pendingBatches  map[uint32]*pendingBatch
+	pendingBatchesL sync.Mutex
+	//Txs of reconstructed compact blocks which were not in the mempool, the miner verifies them (see BlockTxs)
+	blockTxs  map[[32]byte][]protocol.Transaction
+	blockTxsL sync.Mutex
+
+	//Hashes of the last requested txs, such that a tx is only sent once to the miner
+	receivedTxStash    *hashSet
+	receivedAggTxStash *hashSet
+
+	processTxBroadcastMutex sync.Mutex
+
+	//Scores are kept per node id (and not per connection), such that reconnecting does not reset them
+	scores      map[[32]byte]int
+	bans        map[string]peerBan
+	reputationL sync.Mutex
+	//Origin of received blocks and txs, such that the miner can report invalid ones. Bounded by MAX_ORIGINS.
+	origins      map[[32]byte][32]byte
+	originsOrder [][32]byte
+	originsL     sync.Mutex
+
+	//Source of the local time, replaced in tests
+	clock func() time.Time
+	//Offset of the network time to the local time in milliseconds
+	timeOffset int64
+	//Offsets beyond acceptedTimeDiff seconds are reported as a drift of the local clock
+	acceptedTimeDiff int64
+	timeL            sync.Mutex
+
+	listener  net.Listener
+	listenerL sync.Mutex
+	quit      chan bool
+	stopOnce  sync.Once
+}
+
+func NewNode(config Config) *Node {
+	n := &Node{
+		BlockIn:        make(chan []byte),
+		BlockOut:       make(chan []byte),
+		BlockHeaderOut: make(chan []byte),
+		VerifiedTxsOut: make(chan []byte),
+
+		FundsTxChan:      make(chan *protocol.FundsTx),
+		AccTxChan:        make(chan *protocol.AccTx),
+		ConfigTxChan:     make(chan *protocol.ConfigTx),
+		StakeTxChan:      make(chan *protocol.StakeTx),
+		AggTxChan:        make(chan *protocol.AggTx),
+		IoTTxChan:        make(chan *protocol.IotTx),
+		ContractTxChan:   make(chan *protocol.ContractTx),
+		IoTBatchTxChan:   make(chan *protocol.IotBatchTx),
+		IoTAggTxChan:     make(chan *protocol.IotAggTx),
+		DelegateTxChan:   make(chan *protocol.DelegateTx),
+		EvidenceTxChan:   make(chan *protocol.EvidenceTx),
+		GovernanceTxChan: make(chan *protocol.GovernanceTx),
+
+		BlockReqChan: make(chan []byte),
+
+		peers:           &peersStruct{minerConns: make(map[*peer]bool), clientConns: make(map[*peer]bool)},
+		minerBrdcstMsg:  make(chan []byte),
+		clientBrdcstMsg: make(chan []byte),
+		register:        make(chan *peer),
+		disconnect:      make(chan *peer),
+
+		addrBook:  make(map[string]*knownAddr),
+		selfAddrs: make(map[string]bool),
+
+		relayCache:   make(map[[32]byte]*invItem),
+		requestedInv: make(map[[32]byte]int64),
+		invBrdcstMsg: make(chan *invItem),
+
+		pendingBlocks:  make(map[[32]byte]*pendingBlock),
+		pendingBatches: make(map[uint32]*pendingBatch),
+		blockTxs:       make(map[[32]byte][]protocol.Transaction),
+
+		receivedTxStash:    newHashSet(1000),
+		receivedAggTxStash: newHashSet(1000),
+
+		scores:  make(map[[32]byte]int),
+		bans:    make(map[string]peerBan),
+		origins: make(map[[32]byte][32]byte),
+
+		clock:            time.Now,
+		acceptedTimeDiff: ACCEPTED_TIME_DIFF,
+
+		quit: make(chan bool),
+	}
+	n.configure(config)
+
+	return n
+}
+
+func (n *Node) configure(config Config) {
+	n.addr = config.Addr
+	n.bindAddr = config.BindAddr
+	if n.bindAddr == "" {
+		if _, port, err := net.SplitHostPort(config.Addr); err == nil {
+			n.bindAddr = net.JoinHostPort("", port)
+		}
+	}
+	n.bootstrapAddr = config.BootstrapAddr
+	n.bootstrapID = config.BootstrapID
+	n.key = config.Key
+	n.chainID = config.ChainID
+
+	n.transport = config.Transport
+	if n.transport == nil {
+		n.transport = NewTCPTransport()
+	}
+	n.store = config.Store
+	if n.store == nil {
+		n.store = dbStore{}
+	}
+	n.healthCheckInterval = config.HealthCheckInterval
+	if n.healthCheckInterval == 0 {
+		n.healthCheckInterval = HEALTH_CHECK_INTERVAL * time.Second
+	}
+}
+
+//Starts all services, connects to the network and listens for incoming connections. Fails if the listener can't be
+//bound.
+func (n *Node) Start() error {
+	listener, err := n.transport.Listen(n.bindAddr)
+	if err != nil {
+		return err
+	}
+	n.listenerL.Lock()
+	n.listener = listener
+	n.listenerL.Unlock()
+
+	//Load the miners known from previous runs, the bootstrap server is only one of them
+	n.loadAddrBook()
+	if !n.IsBootstrap() {
+		n.addAddress(n.bootstrapAddr)
+	}
+
+	//Start all services that are running concurrently
+	go n.peerService()
+	go n.broadcastService()
+	go n.checkHealthService()
+	go n.timeService()
+	go n.reputationService()
+	go n.forwardBlockBrdcstToMiner()
+	go n.forwardBlockHeaderBrdcstToMiner()
+	go n.forwardVerifiedTxsToMiner()
+
+	if !n.IsBootstrap() {
+		n.bootstrap()
+	}
+
+	//Listen for all subsequent incoming connections on the bind address
+	go n.acceptConns(listener)
+
+	return nil
+}
+
+//Stops all services and closes the listener and all connections.
+func (n *Node) Stop() {
+	n.stopOnce.Do(func() {
+		close(n.quit)
+
+		n.listenerL.Lock()
+		if n.listener != nil {
+			n.listener.Close()
+		}
+		n.listenerL.Unlock()
+
+		for _, peerType := range []uint{PEERTYPE_MINER, PEERTYPE_CLIENT} {
+			for _, p := range n.peers.getAllPeers(peerType) {
+				p.conn.Close()
+			}
+		}
+	})
+}
+
+//Address other miners connect to.
+func (n *Node) Addr() string {
+	return n.addr
+}
+
+func (n *Node) NodeID() [32]byte {
+	return nodeIDFromKey(n.key)
+}
+
+//Number of connected miners.
+func (n *Node) MinerCount() int {
+	return n.peers.len(PEERTYPE_MINER)
+}
+
+func (n *Node) stopped() bool {
+	select {
+	case <-n.quit:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,9 +1,9 @@
 package p2p
 
 import (
+	"fmt"
 	"math/rand"
 	"net"
-	"strings"
 	"sync"
 )
 
@@ -15,24 +15,45 @@ const (
 //The reason we use an additional listener port is because the port the miner connected to this peer
 //is not the same as the one it listens to for new connections. When we are queried for neighbors
 //we send the IP address in p.conn.RemotAddr() with the listenerPort.
+//Peers are identified by their node id, the public node key they proved to own during the handshake.
 type peer struct {
+	//Node the peer is connected to
+	node         *Node
 	conn         net.Conn
+	nodeID       [32]byte
 	ch           chan []byte
 	l            sync.Mutex
 	listenerPort string
-	time         int64
 	peerType     uint
+	//Last measured clock offsets in milliseconds, see time.go
+	timeOffsets  []int64
+	//Negotiated with the HELLO message
+	version      uint32
+	features     uint64
+	messageTypes map[uint8]bool
+	bestHeight   uint32
+	bestHash     [32]byte
+	//Address the peer announced to be reachable at, e.g. the public address of its NAT
+	advertisedAddr string
+	//DoS protection, see reputation.go
+	limiter      *rateLimiter
+	handlers     chan bool
+	pendingReqs  []int64
+	reqL         sync.Mutex
+	//Hashes of blocks and txs the peer is known to have, see inventory.go
+	knownInv *hashSet
 }
 
 //Block constructor, argument is the previous block in the blockchain.
-func newPeer(conn net.Conn, listenerPort string, peerType uint) *peer {
+func (n *Node) newPeer(conn net.Conn, listenerPort string, peerType uint) *peer {
 	p := new(peer)
+	p.node = n
 	p.conn = conn
 	p.ch = nil
 	p.l = sync.Mutex{}
 	p.listenerPort = listenerPort
-	p.time = 0
 	p.peerType = peerType
+	p.knownInv = newHashSet(MAX_KNOWN_INV)
 
 	return p
 }
@@ -44,18 +65,29 @@ type peersStruct struct {
 	peerMutex   sync.Mutex
 }
 
-func (peers peersStruct) contains(ipport string, peerType uint) bool {
-	var peerConns map[*peer]bool
-
-	if peerType == PEERTYPE_MINER {
-		peerConns = peers.minerConns
-	}
-	if peerType == PEERTYPE_CLIENT {
-		peerConns = peers.clientConns
+func (peers *peersStruct) contains(ipport string, peerType uint) bool {
+	//Acquire list before comparing, getIPPort locks the peer
+	for _, peer := range peers.getAllPeers(peerType) {
+		if peer.getIPPort() == ipport {
+			return true
+		}
 	}
 
-	for peer := range peerConns {
-		if peer.getIPPort() == ipport {
+	return false
+}
+
+//Checks whether a node is already connected, regardless of the address it connected from.
+func (peers *peersStruct) containsNode(nodeID [32]byte) bool {
+	peers.peerMutex.Lock()
+	defer peers.peerMutex.Unlock()
+
+	for peer := range peers.minerConns {
+		if peer.nodeID == nodeID {
+			return true
+		}
+	}
+	for peer := range peers.clientConns {
+		if peer.nodeID == nodeID {
 			return true
 		}
 	}
@@ -63,15 +95,53 @@ func (peers peersStruct) contains(ipport string, peerType uint) bool {
 	return false
 }
 
+func (peers *peersStruct) containsPeer(p *peer) bool {
+	peers.peerMutex.Lock()
+	defer peers.peerMutex.Unlock()
+
+	return peers.minerConns[p] || peers.clientConns[p]
+}
+
+func (peers *peersStruct) containsMiner(p *peer) bool {
+	peers.peerMutex.Lock()
+	defer peers.peerMutex.Unlock()
+
+	return peers.minerConns[p]
+}
+
+func (p *peer) String() string {
+	return fmt.Sprintf("%x@%v", p.nodeID[0:8], p.getIPPort())
+}
+
 func (p *peer) getIPPort() string {
-	ip := strings.Split(p.conn.RemoteAddr().String(), ":")
+	p.l.Lock()
+	remoteAddr := p.conn.RemoteAddr().String()
 	//Cut off original port.
 	port := p.listenerPort
+	p.l.Unlock()
 
-	return ip[0] + ":" + port
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		ip = remoteAddr
+	}
+
+	return net.JoinHostPort(ip, port)
 }
 
-func (peers peersStruct) add(p *peer) {
+//Address other miners can connect to. Miners behind a NAT announce their public address with the HELLO, otherwise
+//the remote ip with the listener port is used.
+func (p *peer) getAdvertisedAddr() string {
+	p.l.Lock()
+	advertised := p.advertisedAddr
+	p.l.Unlock()
+
+	if p.node.validAddr(advertised) {
+		return advertised
+	}
+	return p.getIPPort()
+}
+
+func (peers *peersStruct) add(p *peer) {
 	peers.peerMutex.Lock()
 	defer peers.peerMutex.Unlock()
 
@@ -83,7 +153,7 @@ func (peers peersStruct) add(p *peer) {
 	}
 }
 
-func (peers peersStruct) delete(p *peer) {
+func (peers *peersStruct) delete(p *peer) {
 	peers.peerMutex.Lock()
 	defer peers.peerMutex.Unlock()
 
@@ -95,7 +165,10 @@ func (peers peersStruct) delete(p *peer) {
 	}
 }
 
-func (peers peersStruct) len(peerType uint) (length int) {
+func (peers *peersStruct) len(peerType uint) (length int) {
+	peers.peerMutex.Lock()
+	defer peers.peerMutex.Unlock()
+
 	if peerType == PEERTYPE_MINER {
 		length = len(peers.minerConns)
 	}
@@ -106,7 +179,7 @@ func (peers peersStruct) len(peerType uint) (length int) {
 	return length
 }
 
-func (peers peersStruct) getRandomPeer(peerType uint) (p *peer) {
+func (peers *peersStruct) getRandomPeer(peerType uint) (p *peer) {
 	//Acquire list before locking, otherwise deadlock
 	peerList := peers.getAllPeers(peerType)
 
@@ -117,7 +190,7 @@ func (peers peersStruct) getRandomPeer(peerType uint) (p *peer) {
 	}
 }
 
-func (peers peersStruct) getAllPeers(peerType uint) []*peer {
+func (peers *peersStruct) getAllPeers(peerType uint) []*peer {
 	peers.peerMutex.Lock()
 	defer peers.peerMutex.Unlock()
 
@@ -137,17 +210,12 @@ func (peers peersStruct) getAllPeers(peerType uint) []*peer {
 	return peerList
 }
 
-func (peers peersStruct) getMinerTimes() (peerTimes []int64) {
-	peers.peerMutex.Lock()
-	defer peers.peerMutex.Unlock()
-
-	for p := range peers.minerConns {
-		p.l.Lock()
-		peerTimes = append(peerTimes, p.time)
-		//Concurrent writes need to protected. We set the time to 0 again as an indicator that the value has been consumed.
-		p.time = 0
-		p.l.Unlock()
+func (peers *peersStruct) getMinerTimeOffsets() (offsets []int64) {
+	for _, p := range peers.getAllPeers(PEERTYPE_MINER) {
+		if offset, measured := p.getTimeOffset(); measured {
+			offsets = append(offsets, offset)
+		}
 	}
 
-	return peerTimes
+	return offsets
 }
@@ -3,91 +3,47 @@ package p2p
 import (
 	"encoding/binary"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
-	"github.com/bazo-blockchain/bazo-miner/storage"
+	"net"
 	"strconv"
-	"sync"
-)
-
-var (
-	processTxBroadcastMutex = &sync.Mutex{}
 )
 
 //Process tx broadcasts from other miners. We can't broadcast incoming messages directly, first check if
 //the tx has already been broadcast before, whether it is a valid tx etc.
-func processTxBrdcst(p *peer, payload []byte, brdcstType uint8) {
-	processTxBroadcastMutex.Lock()
-	defer processTxBroadcastMutex.Unlock()
+func (n *Node) processTxBrdcst(p *peer, payload []byte, brdcstType uint8) {
+	n.processTxBroadcastMutex.Lock()
+	defer n.processTxBroadcastMutex.Unlock()
 
-	var tx protocol.Transaction
 	//Make sure the transaction can be properly decoded, verification is done at a later stage to reduce latency
-	switch brdcstType {
-	case FUNDSTX_BRDCST:
-		var fTx *protocol.FundsTx
-		fTx = fTx.Decode(payload)
-		if fTx == nil {
-			return
-		}
-		tx = fTx
-	case ACCTX_BRDCST:
-		var aTx *protocol.AccTx
-		aTx = aTx.Decode(payload)
-		if aTx == nil {
-			return
-		}
-		tx = aTx
-	case CONFIGTX_BRDCST:
-		var cTx *protocol.ConfigTx
-		cTx = cTx.Decode(payload)
-		if cTx == nil {
-			return
-		}
-		tx = cTx
-	case STAKETX_BRDCST:
-		var sTx *protocol.StakeTx
-		sTx = sTx.Decode(payload)
-		if sTx == nil {
-			return
-		}
-		tx = sTx
-	case AGGTX_BRDCST:
-		var fTx *protocol.AggTx
-		fTx = fTx.Decode(payload)
-		if fTx == nil {
-			return
-		}
-		tx = fTx
-	case IOTTX_BRDCST:
-		var iTx *protocol.IotTx
-		iTx = iTx.Decode(payload)
-		if iTx == nil {
-			return
-		}
-		tx = iTx
+	tx := decodeTx(brdcstType, payload)
+	if tx == nil {
+		return
 	}
 
+	n.recordOrigin(tx.Hash(), p)
+	n.receivedInv(p, tx.Hash())
+
 	//Response tx acknowledgment if the peer is a client
-	if !peers.minerConns[p] {
+	if !n.peers.containsMiner(p) {
 		packet := BuildPacket(TX_BRDCST_ACK, nil)
 		sendData(p, packet)
 	}
 
-	if storage.ReadOpenTx(tx.Hash()) != nil {
+	if n.store.ReadOpenTx(tx.Hash()) != nil {
 		//logger.Printf("Received transaction (%x) already in the mempool.\n", tx.Hash())
 		return
 	}
-	if storage.ReadClosedTx(tx.Hash()) != nil {
+	if n.store.ReadClosedTx(tx.Hash()) != nil {
 		//logger.Printf("Received transaction (%x) already validated.\n", tx.Hash())
 		return
 	}
 
-	//Write to mempool and rebroadcast
+	//Write to mempool and announce it to the other miners
 	//logger.Printf("Writing transaction (%x) in the mempool.\n", tx.Hash())
-	storage.WriteOpenTx(tx)
-	toBrdcst := BuildPacket(brdcstType, payload)
-	minerBrdcstMsg <- toBrdcst
+	n.store.WriteOpenTx(tx)
+	n.relayInv(&invItem{typeID: brdcstType, hash: tx.Hash(), payload: payload})
 }
 
-func processIotTxBrdcst(p *peer, payload []byte, brdcstType uint8) {
+func (n *Node) processIotTxBrdcst(p *peer, payload []byte, brdcstType uint8) {
 	var tx protocol.Iot
 	//Make sure the transaction can be properly decoded, verification is done at a later stage to reduce latency
 	switch brdcstType {
@@ -100,73 +56,83 @@ func processIotTxBrdcst(p *peer, payload []byte, brdcstType uint8) {
 		tx = sTx
 	}
 
+	n.recordOrigin(tx.Hash(), p)
+	n.receivedInv(p, tx.Hash())
+
 	//Response tx acknowledgment if the peer is a client
-	if !peers.minerConns[p] {
+	if !n.peers.containsMiner(p) {
 		//TODO: check if TX_BRDCST_ACK can still be used here
 		packet := BuildPacket(TX_BRDCST_ACK, nil)
 		sendData(p, packet)
 	}
 
-	if storage.ReadOpenTx(tx.Hash()) != nil {
+	if n.store.ReadOpenTx(tx.Hash()) != nil {
 		logger.Printf("Received  IoT transaction (%x) already in the mempool.\n", tx.Hash())
 		return
 	}
-	if storage.ReadClosedTx(tx.Hash()) != nil {
+	if n.store.ReadClosedTx(tx.Hash()) != nil {
 		logger.Printf("Received  IoT transaction (%x) already validated.\n", tx.Hash())
 		return
 	}
 
-	//Write to mempool and rebroadcast
+	//Write to mempool and announce it to the other miners
 	logger.Printf("Writing IoT transaction (%x) in the mempool.\n", tx.Hash())
 
-	storage.WriteOpenTx(tx)
-
-
-	toBrdcst := BuildPacket(brdcstType, payload)
-	minerBrdcstMsg <- toBrdcst
-}
-
-func processTimeRes(p *peer, payload []byte) {
-	time := int64(binary.BigEndian.Uint64(payload))
-	//Concurrent writes need to be protected.
-	//We use the same peer lock to prevent concurrent writes (on the network). It would be more efficient to use
-	//different locks but the speedup is so marginal that it's not worth it.
-
-	p.l.Lock()
-	defer p.l.Unlock()
-	p.time = time
+	n.store.WriteOpenTx(tx)
+	n.relayInv(&invItem{typeID: brdcstType, hash: tx.Hash(), payload: payload})
 }
 
-func processNeighborRes(p *peer, payload []byte) {
-	//Parse the incoming ipv4 addresses.
+func (n *Node) processNeighborRes(p *peer, payload []byte) {
+	//Parse the incoming ipv4 and ipv6 addresses.
 	ipportList := _processNeighborRes(payload)
 
 	for _, ipportIter := range ipportList {
-		logger.Printf("IP/Port received: %v\n", ipportIter)
-		//iplistChan is a buffered channel to handle ips asynchronously.
-		iplistChan <- ipportIter
+		n.addAddress(ipportIter)
 	}
 }
 
-//Split the processNeighborRes function in two for cleaner testing.
+//Split the processNeighborRes function in two for cleaner testing. Parsing stops at the first malformed entry.
 func _processNeighborRes(payload []byte) (ipportList []string) {
 	index := 0
 
-	for cnt := 0; cnt < len(payload)/(IPV4ADDR_SIZE+PORT_SIZE); cnt++ {
-		var addr string
-		for singleAddr := index; singleAddr < index+IPV4ADDR_SIZE; singleAddr++ {
-			tmp := int(payload[singleAddr])
-			addr += strconv.Itoa(tmp)
-			addr += "."
+	for index < len(payload) {
+		var host string
+		family := payload[index]
+		index++
+
+		switch family {
+		case ADDR_IPV4, ADDR_IPV6:
+			ipSize := IPV4ADDR_SIZE
+			if family == ADDR_IPV6 {
+				ipSize = IPV6ADDR_SIZE
+			}
+			if index+ipSize > len(payload) {
+				return ipportList
+			}
+			host = net.IP(payload[index : index+ipSize]).String()
+			index += ipSize
+		case ADDR_HOSTNAME:
+			if index >= len(payload) {
+				return ipportList
+			}
+			hostSize := int(payload[index])
+			index++
+			if index+hostSize > len(payload) || !validHostname(string(payload[index:index+hostSize])) {
+				return ipportList
+			}
+			host = string(payload[index : index+hostSize])
+			index += hostSize
+		default:
+			return ipportList
 		}
-		//Remove trailing dot.
-		addr = addr[:len(addr)-1]
-		addr += ":"
-		//Extract port number.
-		addr += strconv.Itoa(int(binary.BigEndian.Uint16(payload[index+4 : index+6])))
-
-		ipportList = append(ipportList, addr)
-		index += IPV4ADDR_SIZE + PORT_SIZE
+
+		if index+PORT_SIZE > len(payload) {
+			return ipportList
+		}
+		port := binary.BigEndian.Uint16(payload[index : index+PORT_SIZE])
+		ipportList = append(ipportList, net.JoinHostPort(host, strconv.Itoa(int(port))))
+
+		index += PORT_SIZE
 	}
 
 	return ipportList
@@ -9,14 +9,18 @@ func TestProcessNeighborRes(t *testing.T) {
 
 	//Build some ip addresses
 	payload := []byte{
-		127, 0, 0, 1, 31, 64,
-		23, 24, 122, 66, 31, 69,
-		0, 0, 0, 0, 0, 0,
-		255, 255, 255, 255, 156, 64,
+		4, 127, 0, 0, 1, 31, 64,
+		4, 23, 24, 122, 66, 31, 69,
+		4, 0, 0, 0, 0, 0, 0,
+		4, 255, 255, 255, 255, 156, 64,
+		6, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 31, 64,
 	}
 
 	ipportList := _processNeighborRes(payload)
 
+	if len(ipportList) != 5 {
+		t.Fatalf("Parsing IP addresses failed: %v\n", ipportList)
+	}
 	if ipportList[0] != "127.0.0.1:8000" {
 		t.Errorf("Parsing IP address failed: %v\n", ipportList[0])
 	}
@@ -29,4 +33,28 @@ func TestProcessNeighborRes(t *testing.T) {
 	if ipportList[3] != "255.255.255.255:40000" {
 		t.Errorf("Parsing IP address failed: %v\n", ipportList[3])
 	}
+	if ipportList[4] != "[2001:db8::1]:8000" {
+		t.Errorf("Parsing IP address failed: %v\n", ipportList[4])
+	}
+
+	//Truncated entries and unknown address families are not parsed
+	if ipportList := _processNeighborRes(payload[:len(payload)-1]); len(ipportList) != 4 {
+		t.Errorf("Truncated address was parsed: %v\n", ipportList)
+	}
+	if ipportList := _processNeighborRes([]byte{5, 127, 0, 0, 1, 31, 64}); len(ipportList) != 0 {
+		t.Errorf("Unknown address family was parsed: %v\n", ipportList)
+	}
+
+	//Hostnames are length prefixed, hostnames with invalid characters are not parsed
+	hostname := append([]byte{ADDR_HOSTNAME, 14}, "seed.bazo.test"...)
+	if ipportList := _processNeighborRes(append(hostname, 31, 64)); len(ipportList) != 1 || ipportList[0] != "seed.bazo.test:8000" {
+		t.Errorf("Parsing hostname failed: %v\n", ipportList)
+	}
+	if ipportList := _processNeighborRes(hostname); len(ipportList) != 0 {
+		t.Errorf("Hostname without port was parsed: %v\n", ipportList)
+	}
+	invalid := append([]byte{ADDR_HOSTNAME, 8}, "seed/../"...)
+	if ipportList := _processNeighborRes(append(invalid, 31, 64)); len(ipportList) != 0 {
+		t.Errorf("Invalid hostname was parsed: %v\n", ipportList)
+	}
 }
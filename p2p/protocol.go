@@ -15,6 +15,12 @@ const (
 	BLOCK_HEADER_BRDCST		= 7
 	TX_BRDCST_ACK      		= 8
 	AGGTX_BRDCST      = 9
+	CONTRACTTX_BRDCST		= 10
+	IOTBATCHTX_BRDCST		= 11
+	DELEGATETX_BRDCST		= 12
+	EVIDENCETX_BRDCST		= 13
+	GOVERNANCETX_BRDCST		= 14
+	IOTAGGTX_BRDCST			= 15
 
 	FUNDSTX_REQ            	= 20
 	ACCTX_REQ              	= 21
@@ -26,6 +32,16 @@ const (
 	ROOTACC_REQ            	= 27
 	INTERMEDIATE_NODES_REQ 	= 28
 	AGGTX_REQ			= 29
+	CONTRACTTX_REQ			= 30
+	IOTDATA_REQ			= 31
+	IOTBATCHTX_REQ			= 32
+	TX_BATCH_REQ			= 33
+	DELEGATETX_REQ			= 34
+	EVIDENCETX_REQ			= 35
+	GOVERNANCETX_REQ		= 36
+	PARAMETERS_REQ			= 37
+	SUPPLY_REQ			= 38
+	BASEFEE_REQ			= 39
 
 
 	FUNDSTX_RES            	= 40
@@ -38,25 +54,89 @@ const (
 	ROOTACC_RES            	= 47
 	INTERMEDIATE_NODES_RES 	= 48
 	AGGTX_RES			= 49
+	CONTRACTTX_RES			= 50
+	IOTDATA_RES			= 51
+	IOTBATCHTX_RES			= 52
+	TX_BATCH_RES			= 53
+	DELEGATETX_RES			= 54
+	EVIDENCETX_RES			= 55
+	GOVERNANCETX_RES		= 56
+	PARAMETERS_RES			= 57
+	SUPPLY_RES			= 58
+	BASEFEE_RES			= 59
 
 	NEIGHBOR_REQ = 130
 	NEIGHBOR_RES = 140
 
 	TIME_BRDCST = 150
+	//Clock offset measurement, see time.go
+	TIME_REQ = 151
+	TIME_RES = 152
 
 	MINER_PING  = 100
 	MINER_PONG  = 101
 	CLIENT_PING = 102
 	CLIENT_PONG = 103
 
+	//First message after the handshake and the reason of a refused connection
+	HELLO      = 108
+	DISCONNECT = 109
+
+	//Announcement of block and tx hashes and the request of the announced payloads, see inventory.go
+	INV     = 111
+	GETDATA = 112
+
+	//Compact block relay, see compactblock.go
+	CMPCTBLOCK  = 113
+	GETBLOCKTXN = 114
+	BLOCKTXN    = 115
+
 	IOTTX_BRDCST	= 105
 	IOTTX_REQ		= 106
 	IOTTX_RES		= 107
 
+	//Validator registry of the miner
+	VALIDATORS_REQ = 116
+	VALIDATORS_RES = 117
+
+	//IoT batch aggregations of a block, the request ranges above are used up
+	IOTAGGTX_REQ = 118
+	IOTAGGTX_RES = 119
+
 	//Used to signal error
 	NOT_FOUND = 110
 )
 
+//Response types of all requests, every request sent to a peer has to be answered with its response type or NOT_FOUND.
+var responseTypes = map[uint8]uint8{
+	FUNDSTX_REQ:            FUNDSTX_RES,
+	ACCTX_REQ:              ACCTX_RES,
+	CONFIGTX_REQ:           CONFIGTX_RES,
+	STAKETX_REQ:            STAKETX_RES,
+	BLOCK_REQ:              BLOCK_RES,
+	BLOCK_HEADER_REQ:       BlOCK_HEADER_RES,
+	ACC_REQ:                ACC_RES,
+	ROOTACC_REQ:            ROOTACC_RES,
+	INTERMEDIATE_NODES_REQ: INTERMEDIATE_NODES_RES,
+	AGGTX_REQ:              AGGTX_RES,
+	CONTRACTTX_REQ:         CONTRACTTX_RES,
+	IOTDATA_REQ:            IOTDATA_RES,
+	IOTBATCHTX_REQ:         IOTBATCHTX_RES,
+	TX_BATCH_REQ:           TX_BATCH_RES,
+	DELEGATETX_REQ:         DELEGATETX_RES,
+	EVIDENCETX_REQ:         EVIDENCETX_RES,
+	GOVERNANCETX_REQ:       GOVERNANCETX_RES,
+	PARAMETERS_REQ:         PARAMETERS_RES,
+	SUPPLY_REQ:             SUPPLY_RES,
+	BASEFEE_REQ:            BASEFEE_RES,
+	NEIGHBOR_REQ:           NEIGHBOR_RES,
+	TIME_REQ:               TIME_RES,
+	GETBLOCKTXN:            BLOCKTXN,
+	IOTTX_REQ:              IOTTX_RES,
+	VALIDATORS_REQ:         VALIDATORS_RES,
+	IOTAGGTX_REQ:           IOTAGGTX_RES,
+}
+
 type Header struct {
 	Len    uint32
 	TypeID uint8
@@ -0,0 +1,302 @@
+package p2p
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const MAX_ORIGINS = 10000
+
+type peerBan struct {
+	until int64
+	count uint32
+}
+
+//Penalizes the node of p and bans it (and its IP address) once its score drops to 0.
+func (p *peer) penalize(penalty int, reason string) {
+	p.node.penalizeNode(p.nodeID, remoteIP(p.conn), penalty, reason)
+}
+
+func (n *Node) penalizeNode(nodeID [32]byte, ip string, penalty int, reason string) {
+	n.reputationL.Lock()
+	score, exists := n.scores[nodeID]
+	if !exists {
+		score = INITIAL_PEER_SCORE
+	}
+	score -= penalty
+	n.scores[nodeID] = score
+	n.reputationL.Unlock()
+
+	logger.Printf("Penalized node %x by %v (score %v): %v\n", nodeID[0:8], penalty, score, reason)
+
+	if score <= 0 {
+		n.banNode(nodeID, ip)
+	}
+}
+
+//Temporarily bans the node and its IP address and closes all its connections. Repeated offenders are banned
+//persistently.
+func (n *Node) banNode(nodeID [32]byte, ip string) {
+	n.reputationL.Lock()
+	delete(n.scores, nodeID)
+	ban := n.addBan(string(nodeID[:]))
+	if ip != "" {
+		n.addBan(ip)
+	}
+	n.reputationL.Unlock()
+
+	logger.Printf("Banned node %x (%v) until %v\n", nodeID[0:8], ip, ban.until)
+
+	for _, peerType := range []uint{PEERTYPE_MINER, PEERTYPE_CLIENT} {
+		for _, p := range n.peers.getAllPeers(peerType) {
+			if p.nodeID == nodeID {
+				p.conn.Close()
+			}
+		}
+	}
+}
+
+//Has to be called with reputationL locked.
+func (n *Node) addBan(key string) peerBan {
+	ban := n.readBan(key)
+	ban.count++
+	if ban.count >= MAX_TEMP_BANS {
+		ban.until = 0
+	} else {
+		ban.until = time.Now().Unix() + TEMP_BAN_DURATION
+	}
+
+	n.bans[key] = ban
+	if n.store.IsOpen() {
+		n.store.WritePeerBan([]byte(key), ban.until, ban.count)
+	}
+
+	return ban
+}
+
+//Has to be called with reputationL locked. Bans of the DB are cached in memory.
+func (n *Node) readBan(key string) peerBan {
+	if ban, exists := n.bans[key]; exists {
+		return ban
+	}
+
+	if n.store.IsOpen() {
+		if until, count, found := n.store.ReadPeerBan([]byte(key)); found {
+			n.bans[key] = peerBan{until, count}
+			return n.bans[key]
+		}
+	}
+
+	return peerBan{}
+}
+
+func (n *Node) isBanned(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	n.reputationL.Lock()
+	defer n.reputationL.Unlock()
+
+	ban := n.readBan(key)
+	return ban.count > 0 && (ban.until == 0 || ban.until > time.Now().Unix())
+}
+
+func (n *Node) isNodeBanned(nodeID [32]byte) bool {
+	return n.isBanned(string(nodeID[:]))
+}
+
+func remoteIP(conn net.Conn) string {
+	if conn == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+//Remembers from which node a block or tx was received first.
+func (n *Node) recordOrigin(hash [32]byte, p *peer) {
+	n.originsL.Lock()
+	defer n.originsL.Unlock()
+
+	if _, exists := n.origins[hash]; exists {
+		return
+	}
+	n.origins[hash] = p.nodeID
+	n.originsOrder = append(n.originsOrder, hash)
+	if len(n.originsOrder) > MAX_ORIGINS {
+		delete(n.origins, n.originsOrder[0])
+		n.originsOrder = n.originsOrder[1:]
+	}
+}
+
+func (n *Node) reportInvalid(hash [32]byte, penalty int, kind string) {
+	n.originsL.Lock()
+	nodeID, exists := n.origins[hash]
+	n.originsL.Unlock()
+
+	if !exists {
+		return
+	}
+
+	ip := ""
+	for _, peerType := range []uint{PEERTYPE_MINER, PEERTYPE_CLIENT} {
+		for _, p := range n.peers.getAllPeers(peerType) {
+			if p.nodeID == nodeID {
+				ip = remoteIP(p.conn)
+			}
+		}
+	}
+
+	n.penalizeNode(nodeID, ip, penalty, fmt.Sprintf("Invalid %v (%x)", kind, hash[0:8]))
+}
+
+//Called by the miner for received blocks which could not be validated.
+func (n *Node) ReportInvalidBlock(hash [32]byte) {
+	n.reportInvalid(hash, SCORE_INVALID_BLOCK, "block")
+}
+
+func ReportInvalidBlock(hash [32]byte) {
+	defaultNode.ReportInvalidBlock(hash)
+}
+
+//Called by the miner for received txs which could not be verified.
+func (n *Node) ReportInvalidTx(hash [32]byte) {
+	n.reportInvalid(hash, SCORE_INVALID_TX, "tx")
+}
+
+func ReportInvalidTx(hash [32]byte) {
+	defaultNode.ReportInvalidTx(hash)
+}
+
+func isRequest(typeID uint8) bool {
+	_, exists := responseTypes[typeID]
+	return exists
+}
+
+func isResponse(typeID uint8) bool {
+	if typeID == NOT_FOUND {
+		return true
+	}
+	for _, resType := range responseTypes {
+		if resType == typeID {
+			return true
+		}
+	}
+	return false
+}
+
+//Every request sent to a peer has to be answered (possibly with NOT_FOUND) within REQUEST_TIMEOUT.
+func (p *peer) requestSent() {
+	p.reqL.Lock()
+	defer p.reqL.Unlock()
+
+	p.pendingReqs = append(p.pendingReqs, time.Now().Unix())
+}
+
+func (p *peer) responseReceived() {
+	p.reqL.Lock()
+	defer p.reqL.Unlock()
+
+	if len(p.pendingReqs) > 0 {
+		p.pendingReqs = p.pendingReqs[1:]
+	}
+}
+
+func (p *peer) expireRequests(now int64) (expired int) {
+	p.reqL.Lock()
+	defer p.reqL.Unlock()
+
+	for len(p.pendingReqs) > 0 && p.pendingReqs[0]+REQUEST_TIMEOUT < now {
+		p.pendingReqs = p.pendingReqs[1:]
+		expired++
+	}
+
+	return expired
+}
+
+//Token buckets for messages and bytes. A message is allowed as long as there are tokens left, such that a single
+//block larger than the byte rate can still be received.
+type rateLimiter struct {
+	msgRate   float64
+	byteRate  float64
+	msgs      float64
+	bytes     float64
+	lastCheck time.Time
+}
+
+func newRateLimiter(peerType uint) *rateLimiter {
+	limiter := &rateLimiter{msgRate: CLIENT_MSG_RATE, byteRate: CLIENT_BYTE_RATE, lastCheck: time.Now()}
+	if peerType == PEERTYPE_MINER {
+		limiter.msgRate, limiter.byteRate = MINER_MSG_RATE, MINER_BYTE_RATE
+	}
+	limiter.msgs, limiter.bytes = limiter.msgRate, limiter.byteRate
+
+	return limiter
+}
+
+func (limiter *rateLimiter) allow(size int) bool {
+	now := time.Now()
+	elapsed := now.Sub(limiter.lastCheck).Seconds()
+	limiter.lastCheck = now
+
+	limiter.msgs = minFloat(limiter.msgs+elapsed*limiter.msgRate, limiter.msgRate)
+	limiter.bytes = minFloat(limiter.bytes+elapsed*limiter.byteRate, limiter.byteRate)
+
+	if limiter.msgs < 1 || limiter.bytes <= 0 {
+		return false
+	}
+
+	limiter.msgs--
+	limiter.bytes -= float64(size)
+
+	return true
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+//Single goroutine that penalizes unanswered requests and lets scores recover. Expired bans are kept, such that
+//repeated offences are counted.
+func (n *Node) reputationService() {
+	ticker := time.NewTicker(REPUTATION_INTERVAL * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n.updateReputation(time.Now().Unix())
+		case <-n.quit:
+			return
+		}
+	}
+}
+
+func (n *Node) updateReputation(now int64) {
+	for _, peerType := range []uint{PEERTYPE_MINER, PEERTYPE_CLIENT} {
+		for _, p := range n.peers.getAllPeers(peerType) {
+			if expired := p.expireRequests(now); expired > 0 {
+				p.penalize(expired*SCORE_UNANSWERED_REQUEST, fmt.Sprintf("%v unanswered requests", expired))
+			}
+		}
+	}
+
+	n.reputationL.Lock()
+	defer n.reputationL.Unlock()
+
+	for nodeID, score := range n.scores {
+		if score+SCORE_RECOVERY >= INITIAL_PEER_SCORE {
+			delete(n.scores, nodeID)
+		} else {
+			n.scores[nodeID] = score + SCORE_RECOVERY
+		}
+	}
+}
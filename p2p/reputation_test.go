@@ -0,0 +1,175 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPenalizeAndBan(t *testing.T) {
+
+	nodeID := [32]byte{0xaa}
+	ip := "10.0.0.1"
+
+	testNode.penalizeNode(nodeID, ip, INITIAL_PEER_SCORE-1, "test")
+	if testNode.isNodeBanned(nodeID) || testNode.isBanned(ip) {
+		t.Error("Node banned before its score dropped to 0\n")
+	}
+
+	testNode.penalizeNode(nodeID, ip, 1, "test")
+	if !testNode.isNodeBanned(nodeID) || !testNode.isBanned(ip) {
+		t.Error("Node with score 0 not banned\n")
+	}
+
+	//Temporary bans are lifted after TEMP_BAN_DURATION, repeated offenders are banned persistently
+	for i := 1; i < MAX_TEMP_BANS; i++ {
+		testNode.reputationL.Lock()
+		ban := testNode.bans[string(nodeID[:])]
+		ban.until = time.Now().Unix() - 1
+		testNode.bans[string(nodeID[:])] = ban
+		testNode.reputationL.Unlock()
+		if testNode.isNodeBanned(nodeID) {
+			t.Error("Expired temporary ban still active\n")
+		}
+		testNode.penalizeNode(nodeID, ip, INITIAL_PEER_SCORE, "test")
+	}
+
+	testNode.reputationL.Lock()
+	ban := testNode.bans[string(nodeID[:])]
+	testNode.reputationL.Unlock()
+	if ban.until != 0 || ban.count != MAX_TEMP_BANS {
+		t.Errorf("Repeated offender not banned persistently: %v\n", ban)
+	}
+}
+
+func TestScoreRecovery(t *testing.T) {
+
+	nodeID := [32]byte{0xbb}
+	testNode.penalizeNode(nodeID, "", 2*SCORE_RECOVERY, "test")
+
+	testNode.updateReputation(time.Now().Unix())
+	testNode.reputationL.Lock()
+	score := testNode.scores[nodeID]
+	testNode.reputationL.Unlock()
+	if score != INITIAL_PEER_SCORE-SCORE_RECOVERY {
+		t.Errorf("Score did not recover: %v\n", score)
+	}
+
+	testNode.updateReputation(time.Now().Unix())
+	testNode.reputationL.Lock()
+	_, exists := testNode.scores[nodeID]
+	testNode.reputationL.Unlock()
+	if exists {
+		t.Error("Fully recovered score not removed\n")
+	}
+}
+
+func TestReportInvalid(t *testing.T) {
+
+	p := testNode.newPeer(nil, "", PEERTYPE_MINER)
+	p.nodeID = [32]byte{0xcc}
+
+	testNode.recordOrigin([32]byte{1}, p)
+	testNode.ReportInvalidTx([32]byte{1})
+	testNode.ReportInvalidBlock([32]byte{2})
+
+	testNode.reputationL.Lock()
+	score := testNode.scores[p.nodeID]
+	testNode.reputationL.Unlock()
+	if score != INITIAL_PEER_SCORE-SCORE_INVALID_TX {
+		t.Errorf("Origin of invalid tx not penalized correctly: %v\n", score)
+	}
+}
+
+func TestUnansweredRequests(t *testing.T) {
+
+	p := testNode.newPeer(nil, "", PEERTYPE_MINER)
+	now := time.Now().Unix()
+
+	p.requestSent()
+	p.requestSent()
+	p.responseReceived()
+	if expired := p.expireRequests(now + REQUEST_TIMEOUT + 1); expired != 1 {
+		t.Errorf("Expected 1 unanswered request, got %v\n", expired)
+	}
+	if expired := p.expireRequests(now + REQUEST_TIMEOUT + 1); expired != 0 {
+		t.Errorf("Unanswered request counted twice\n")
+	}
+}
+
+func TestRequestResponseTypes(t *testing.T) {
+
+	for reqType, resType := range responseTypes {
+		if !isRequest(reqType) || isResponse(reqType) {
+			t.Errorf("Request type %v not classified as request\n", reqType)
+		}
+		if !isResponse(resType) || isRequest(resType) {
+			t.Errorf("Response type %v of request %v not classified as response\n", resType, reqType)
+		}
+	}
+
+	for _, typeID := range []uint8{FUNDSTX_BRDCST, BLOCK_BRDCST, INV, GETDATA, HELLO, MINER_PING, TIME_BRDCST} {
+		if isRequest(typeID) || isResponse(typeID) {
+			t.Errorf("Message type %v classified as request or response\n", typeID)
+		}
+	}
+	if !isResponse(NOT_FOUND) {
+		t.Error("NOT_FOUND not classified as response\n")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+
+	limiter := newRateLimiter(PEERTYPE_CLIENT)
+	allowed := 0
+	for i := 0; i < 2*CLIENT_MSG_RATE; i++ {
+		if limiter.allow(1) {
+			allowed++
+		}
+	}
+	if allowed > CLIENT_MSG_RATE+1 {
+		t.Errorf("Rate limiter allowed %v messages, rate is %v\n", allowed, CLIENT_MSG_RATE)
+	}
+
+	//A single large message is allowed, but exhausts the bandwidth
+	limiter = newRateLimiter(PEERTYPE_MINER)
+	if !limiter.allow(3 * MINER_BYTE_RATE) {
+		t.Error("Single large message rejected\n")
+	}
+	if limiter.allow(1) {
+		t.Error("Bandwidth limit not enforced\n")
+	}
+}
+
+func TestReadHeaderMaxSize(t *testing.T) {
+
+	packet := BuildPacket(FUNDSTX_BRDCST, nil)
+	packet[0] = 0xff
+	if _, err := ReadHeader(bufio.NewReader(bytes.NewReader(packet))); err != errPayloadTooLarge {
+		t.Errorf("Oversized tx payload accepted: %v\n", err)
+	}
+
+	packet = BuildPacket(BLOCK_BRDCST, nil)
+	packet[1] = 0xff
+	if _, err := ReadHeader(bufio.NewReader(bytes.NewReader(packet))); err != nil {
+		t.Errorf("Block payload below MAX_BLOCK_SIZE rejected: %v\n", err)
+	}
+
+	//Malformed packets penalize the sender
+	conn1, conn2 := net.Pipe()
+	p := testNode.newPeer(conn1, "", PEERTYPE_MINER)
+	p.nodeID = [32]byte{0xdd}
+	go conn2.Write([]byte{0, 0, 0, 0, 0xfe})
+	if _, _, err := RcvData(p); err == nil {
+		t.Error("Packet with unknown type accepted\n")
+	}
+
+	testNode.reputationL.Lock()
+	score := testNode.scores[p.nodeID]
+	testNode.reputationL.Unlock()
+	if score != INITIAL_PEER_SCORE-SCORE_MALFORMED_PACKET {
+		t.Errorf("Sender of malformed packet not penalized: %v\n", score)
+	}
+}
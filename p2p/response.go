@@ -1,23 +1,21 @@
 package p2p
 
 import (
-	"bytes"
 	"encoding/binary"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
-	"github.com/bazo-blockchain/bazo-miner/storage"
+	"net"
 	"strconv"
-	"strings"
 )
 
 //This file responds to incoming requests from miners in a synchronous fashion
-func txRes(p *peer, payload []byte, txKind uint8) {
+func (n *Node) txRes(p *peer, payload []byte, txKind uint8) {
 	var txHash [32]byte
 	copy(txHash[:], payload[0:32])
 
 	var tx protocol.Transaction
 	//Check closed and open storage if the tx is available
-	openTx := storage.ReadOpenTx(txHash)
-	closedTx := storage.ReadClosedTx(txHash)
+	openTx := n.store.ReadOpenTx(txHash)
+	closedTx := n.store.ReadClosedTx(txHash)
 
 	if openTx != nil {
 		tx = openTx
@@ -46,13 +44,25 @@ func txRes(p *peer, payload []byte, txKind uint8) {
 		packet = BuildPacket(AGGTX_RES, tx.Encode())
 	case IOTTX_REQ:
 		packet = BuildPacket(IOTTX_RES, tx.Encode())
+	case CONTRACTTX_REQ:
+		packet = BuildPacket(CONTRACTTX_RES, tx.Encode())
+	case IOTBATCHTX_REQ:
+		packet = BuildPacket(IOTBATCHTX_RES, tx.Encode())
+	case IOTAGGTX_REQ:
+		packet = BuildPacket(IOTAGGTX_RES, tx.Encode())
+	case DELEGATETX_REQ:
+		packet = BuildPacket(DELEGATETX_RES, tx.Encode())
+	case EVIDENCETX_REQ:
+		packet = BuildPacket(EVIDENCETX_RES, tx.Encode())
+	case GOVERNANCETX_REQ:
+		packet = BuildPacket(GOVERNANCETX_RES, tx.Encode())
 	}
 
 	sendData(p, packet)
 }
 
 //Here as well, checking open and closed block storage
-func blockRes(p *peer, payload []byte) {
+func (n *Node) blockRes(p *peer, payload []byte) {
 	var packet []byte
 	var block *protocol.Block
 	var blockHash [32]byte
@@ -65,13 +75,13 @@ func blockRes(p *peer, payload []byte) {
 
 		//TODO Block Response need to be searched in the closedBlockwithouthtx as well... therefore probably the
 		//hash without tx needs to be sent in the Payload as well...
-		if block = storage.ReadClosedBlock(blockHash); block == nil {
-			if block = storage.ReadClosedBlockWithoutTx(blockHashWithoutTx); block == nil {
-				block = storage.ReadOpenBlock(blockHash)
+		if block = n.store.ReadClosedBlock(blockHash); block == nil {
+			if block = n.store.ReadClosedBlockWithoutTx(blockHashWithoutTx); block == nil {
+				block = n.store.ReadOpenBlock(blockHash)
 			}
 		}
 	} else {
-		block = storage.ReadLastClosedBlock()
+		block = n.store.ReadLastClosedBlock()
 	}
 
 	if block != nil {
@@ -84,20 +94,20 @@ func blockRes(p *peer, payload []byte) {
 }
 
 //Response the requested block SPV header
-func blockHeaderRes(p *peer, payload []byte) {
+func (n *Node) blockHeaderRes(p *peer, payload []byte) {
 	var encodedHeader, packet []byte
 
 	//If no specific header is requested, send latest
 	if len(payload) > 0 {
 		var blockHash [32]byte
 		copy(blockHash[:], payload[:32])
-		if block := storage.ReadClosedBlock(blockHash); block != nil {
-			block.InitBloomFilter(append(storage.GetTxPubKeys(block)))
+		if block := n.store.ReadClosedBlock(blockHash); block != nil {
+			block.InitBloomFilter(append(n.store.GetTxPubKeys(block)))
 			encodedHeader = block.EncodeHeader()
 		}
 	} else {
-		if block := storage.ReadLastClosedBlock(); block != nil {
-			block.InitBloomFilter(append(storage.GetTxPubKeys(block)))
+		if block := n.store.ReadLastClosedBlock(); block != nil {
+			block.InitBloomFilter(append(n.store.GetTxPubKeys(block)))
 			encodedHeader = block.EncodeHeader()
 		}
 	}
@@ -112,119 +122,139 @@ func blockHeaderRes(p *peer, payload []byte) {
 }
 
 //Responds to an account request from another miner
-func accRes(p *peer, payload []byte) {
+func (n *Node) accRes(p *peer, payload []byte) {
 	var packet []byte
 	var hash [32]byte
 	copy(hash[:], payload[0:32])
 
-	acc, _ := storage.GetAccount(hash)
+	acc, _ := n.store.GetAccount(hash)
 	packet = BuildPacket(ACC_RES, acc.Encode())
 
 	sendData(p, packet)
 }
 
-func rootAccRes(p *peer, payload []byte) {
+func (n *Node) rootAccRes(p *peer, payload []byte) {
 	var packet []byte
 	var hash [32]byte
 	copy(hash[:], payload[0:32])
 
-	acc, _ := storage.GetRootAccount(hash)
+	acc, _ := n.store.GetRootAccount(hash)
 	packet = BuildPacket(ROOTACC_RES, acc.Encode())
 
 	sendData(p, packet)
 }
 
-//Completes the handshake with another miner.
-func pongRes(p *peer, payload []byte, peerType uint) {
-	//Payload consists of a 2 bytes array (port number [big endian encoded]).
-	port := _pongRes(payload)
+//Completes the handshake with another miner or client. The initiator has to prove ownership of its node key, a node
+//key can only be connected once.
+func (n *Node) pongRes(p *peer, payload []byte, pingType uint8) {
+	port, nodeID, ephPubKey, err := _pongRes(payload, pingType)
+	if err != nil {
+		logger.Printf("Handshake with %v failed: %v\n", p.conn.RemoteAddr(), err)
+		p.conn.Close()
+		return
+	}
 
-	if port != "" {
-		p.listenerPort = port
-	} else {
+	if n.isNodeBanned(nodeID) {
+		p.conn.Close()
+		return
+	}
+
+	if n.peers.containsNode(nodeID) {
+		logger.Printf("Node %x is already connected.\n", nodeID[0:8])
 		p.conn.Close()
 		return
 	}
 
 	//Restrict amount of connected miners
-	if peers.len(PEERTYPE_MINER) >= MAX_MINERS {
+	if pingType == MINER_PING && n.peers.len(PEERTYPE_MINER) >= MAX_MINERS {
+		p.conn.Close()
 		return
 	}
 
 	//Complete handshake
-	var packet []byte
-	if peerType == MINER_PING {
+	var pongType uint8
+	if pingType == MINER_PING {
 		p.peerType = PEERTYPE_MINER
-		packet = BuildPacket(MINER_PONG, nil)
-	} else if peerType == CLIENT_PING {
+		pongType = MINER_PONG
+	} else if pingType == CLIENT_PING {
 		p.peerType = PEERTYPE_CLIENT
-		packet = BuildPacket(CLIENT_PONG, nil)
+		pongType = CLIENT_PONG
 	}
 
-	go peerConn(p)
+	secure, err := acceptHandshake(p.conn, n.key, pongType, nodeID, ephPubKey)
+	if err != nil {
+		logger.Printf("Handshake with %v failed: %v\n", p.conn.RemoteAddr(), err)
+		p.conn.Close()
+		return
+	}
 
-	sendData(p, packet)
-}
+	p.l.Lock()
+	p.conn = secure
+	p.nodeID = nodeID
+	p.listenerPort = port
+	p.l.Unlock()
 
-//Decouple the function for testing.
-func _pongRes(payload []byte) string {
-	if len(payload) == PORT_SIZE {
-		return strconv.Itoa(int(binary.BigEndian.Uint16(payload[0:PORT_SIZE])))
-	} else {
-		return ""
+	if err := n.exchangeHello(p); err != nil {
+		logger.Printf("HELLO with %v failed: %v\n", p, err)
+		return
 	}
-}
 
-func neighborRes(p *peer) {
-	//only supporting ipv4 addresses for now, makes fixed-size structure easier
-	//in the future following structure is possible:
-	//1) nr of ipv4 addresses, 2) nr of ipv6 addresses, followed by list of both
-	var packet []byte
-	var ipportList []string
-	peerList := peers.getAllPeers(PEERTYPE_MINER)
-
-	for _, p := range peerList {
-		ipportList = append(ipportList, p.getIPPort())
+	//Inbound miners announced their address, other miners can connect to them as well
+	if p.peerType == PEERTYPE_MINER {
+		n.addAddress(p.getAdvertisedAddr())
 	}
 
-	packet = BuildPacket(NEIGHBOR_RES, _neighborRes(ipportList))
+	go n.peerConn(p)
+}
+
+//Sends the connected miners and the best known addresses of the address book.
+func (n *Node) neighborRes(p *peer) {
+	packet := BuildPacket(NEIGHBOR_RES, _neighborRes(n.gossipAddrs()))
 	sendData(p, packet)
 }
 
-//Decouple functionality to facilitate testing
+//Decouple functionality to facilitate testing. Every address is serialized as 1) the address family, 2) the ipv4 or
+//ipv6 address or the length prefixed hostname and 3) the port. Hostnames are appended after all ips, such that peers
+//which don't know them yet still parse all ips (they stop at the first unknown family).
 func _neighborRes(ipportList []string) (payload []byte) {
-
-	payload = make([]byte, len(ipportList)*6) //6 = size of ipv4 address + port
-	index := 0
+	var hostnames []byte
 	for _, ipportIter := range ipportList {
-		ipport := strings.Split(ipportIter, ":")
-		split := strings.Split(ipport[0], ".")
-
-		//Serializing IP:Port addr tuples
-		for ipv4addr := 0; ipv4addr < 4; ipv4addr++ {
-			addrPart, err := strconv.Atoi(split[ipv4addr])
-			if err != nil {
-				return nil
-			}
-			payload[index] = byte(addrPart)
-			index++
+		host, portStr, err := net.SplitHostPort(ipportIter)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			continue
 		}
 
-		port, _ := strconv.ParseUint(ipport[1], 10, 16)
+		portBuf := make([]byte, PORT_SIZE)
+		binary.BigEndian.PutUint16(portBuf, uint16(port))
 
-		//serialize port number
-		var buf bytes.Buffer
-		binary.Write(&buf, binary.BigEndian, port)
-		payload[index] = buf.Bytes()[len(buf.Bytes())-2]
-		index++
-		payload[index] = buf.Bytes()[len(buf.Bytes())-1]
-		index++
+		ip := net.ParseIP(host)
+		if ip == nil {
+			if validHostname(host) {
+				hostnames = append(hostnames, ADDR_HOSTNAME, byte(len(host)))
+				hostnames = append(hostnames, host...)
+				hostnames = append(hostnames, portBuf...)
+			}
+			continue
+		}
+
+		if ip4 := ip.To4(); ip4 != nil {
+			payload = append(payload, ADDR_IPV4)
+			payload = append(payload, ip4...)
+		} else {
+			payload = append(payload, ADDR_IPV6)
+			payload = append(payload, ip.To16()...)
+		}
+		payload = append(payload, portBuf...)
 	}
 
-	return payload
+	return append(payload, hostnames...)
 }
 
-func intermediateNodesRes(p *peer, payload []byte) {
+func (n *Node) intermediateNodesRes(p *peer, payload []byte) {
 	var blockHash, txHash [32]byte
 	var nodeHashes [][]byte
 	var packet []byte
@@ -232,7 +262,7 @@ func intermediateNodesRes(p *peer, payload []byte) {
 	copy(blockHash[:], payload[:32])
 	copy(txHash[:], payload[32:64])
 
-	merkleTree := protocol.BuildMerkleTree(storage.ReadClosedBlock(blockHash))
+	merkleTree := protocol.BuildMerkleTree(n.store.ReadClosedBlock(blockHash))
 
 	if intermediates, _ := protocol.GetIntermediate(protocol.GetLeaf(merkleTree, txHash)); intermediates != nil {
 		for _, node := range intermediates {
@@ -246,3 +276,70 @@ func intermediateNodesRes(p *peer, payload []byte) {
 
 	sendData(p, packet)
 }
+
+//Responds with the parameter changes which are scheduled but not in force yet
+func (n *Node) parametersRes(p *peer) {
+	schedule := n.store.ReadParameterSchedule()
+	sendData(p, BuildPacket(PARAMETERS_RES, schedule.Encode()))
+}
+
+//Responds with the supply after the last closed block
+func (n *Node) supplyRes(p *peer) {
+	var packet []byte
+
+	if supply := n.store.ReadCurrentSupply(); supply != nil {
+		packet = BuildPacket(SUPPLY_RES, supply.Encode())
+	} else {
+		packet = BuildPacket(NOT_FOUND, nil)
+	}
+
+	sendData(p, packet)
+}
+
+//Responds with the base fee txs of the next block have to pay at least
+func (n *Node) baseFeeRes(p *peer) {
+	var packet []byte
+
+	if baseFee, found := n.store.ReadCurrentBaseFee(); found {
+		var encoded [8]byte
+		binary.BigEndian.PutUint64(encoded[:], baseFee)
+		packet = BuildPacket(BASEFEE_RES, encoded[:])
+	} else {
+		packet = BuildPacket(NOT_FOUND, nil)
+	}
+
+	sendData(p, packet)
+}
+
+//Responds with the validator registry after the last closed block
+func (n *Node) validatorsRes(p *peer) {
+	var packet []byte
+
+	if set := n.store.ReadCurrentValidatorSet(); set != nil {
+		packet = BuildPacket(VALIDATORS_RES, set.Encode())
+	} else {
+		packet = BuildPacket(NOT_FOUND, nil)
+	}
+
+	sendData(p, packet)
+}
+
+//Responds to a query for the IoT readings of a device
+func (n *Node) iotDataRes(p *peer, payload []byte) {
+	var packet []byte
+	var query *protocol.IotQuery
+
+	if query = query.Decode(payload); query == nil {
+		sendData(p, BuildPacket(NOT_FOUND, nil))
+		return
+	}
+
+	if result, err := n.store.QueryIotData(query); err == nil {
+		packet = BuildPacket(IOTDATA_RES, result.Encode())
+	} else {
+		logger.Printf("IoT data query failed: %v\n", err)
+		packet = BuildPacket(NOT_FOUND, nil)
+	}
+
+	sendData(p, packet)
+}
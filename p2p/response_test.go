@@ -2,6 +2,7 @@ package p2p
 
 import (
 	"encoding/binary"
+	"net"
 	"strconv"
 	"testing"
 )
@@ -11,53 +12,72 @@ func Test_NeighborRes(t *testing.T) {
 
 	ipportList := []string{
 		"127.0.0.1:8000",
+		"localhost:8000",
 		"127.0.0.1:8005",
 		"127.0.0.1:40000",
+		"[2001:db8::1]:8000",
+		"invalid_host:8000",
 	}
 
 	payload := _neighborRes(ipportList)
 
 	//Check for correct deserialization
 	index := 0
-	if payload[index] != 127 || payload[index+1] != 0 || payload[index+2] != 0 || payload[index+3] != 1 ||
-		strconv.Itoa(int(binary.BigEndian.Uint16(payload[index+4:index+6]))) != "8000" {
-		t.Error("IP/Port Deserialization failed.")
+	for _, port := range []string{"8000", "8005", "40000"} {
+		if payload[index] != ADDR_IPV4 || payload[index+1] != 127 || payload[index+2] != 0 || payload[index+3] != 0 || payload[index+4] != 1 ||
+			strconv.Itoa(int(binary.BigEndian.Uint16(payload[index+5:index+7]))) != port {
+			t.Error("IP/Port Deserialization failed.")
+		}
+		index += 1 + IPV4ADDR_SIZE + PORT_SIZE
 	}
 
-	index += 6
-	if payload[index] != 127 || payload[index+1] != 0 || payload[index+2] != 0 || payload[index+3] != 1 ||
-		strconv.Itoa(int(binary.BigEndian.Uint16(payload[index+4:index+6]))) != "8005" {
-		t.Error("IP/Port Deserialization failed.")
+	if payload[index] != ADDR_IPV6 || !net.IP(payload[index+1:index+17]).Equal(net.ParseIP("2001:db8::1")) ||
+		strconv.Itoa(int(binary.BigEndian.Uint16(payload[index+17:index+19]))) != "8000" {
+		t.Error("IPv6 IP/Port Deserialization failed.")
 	}
 
-	index += 6
-	if payload[index] != 127 || payload[index+1] != 0 || payload[index+2] != 0 || payload[index+3] != 1 ||
-		strconv.Itoa(int(binary.BigEndian.Uint16(payload[index+4:index+6]))) != "40000" {
-		t.Error("IP/Port Deserialization failed.")
+	//Hostnames are serialized after all ips, invalid hostnames are skipped
+	index += 1 + IPV6ADDR_SIZE + PORT_SIZE
+	if payload[index] != ADDR_HOSTNAME || int(payload[index+1]) != len("localhost") || string(payload[index+2:index+11]) != "localhost" ||
+		strconv.Itoa(int(binary.BigEndian.Uint16(payload[index+11:index+13]))) != "8000" {
+		t.Error("Hostname/Port Deserialization failed.")
+	}
+	index += 2 + len("localhost") + PORT_SIZE
+	if len(payload) != index {
+		t.Errorf("Unexpected payload length: %v vs. %v\n", len(payload), index)
+	}
+
+	if parsed := _processNeighborRes(payload); len(parsed) != 5 || parsed[3] != "[2001:db8::1]:8000" || parsed[4] != "localhost:8000" {
+		t.Errorf("Serialized addresses could not be parsed: %v\n", parsed)
 	}
 }
 
 func Test_PongRes(t *testing.T) {
 
-	//This corresponds to the IP:Port 8.8.8.8:8000
-	ipport := []byte{
-		31, 64,
-	}
+	_, ephPubKey, _ := newEphemeralKey()
+	packet, _ := PrepareHandshake(testNode.key, MINER_PING, 8000, ephPubKey)
 
-	//The IP address from the sender is 9.9.9.9:8000
-	ipportRet := _pongRes(ipport)
+	port, nodeID, ephRet, err := _pongRes(packet[HEADER_LEN:], MINER_PING)
+	if err != nil || port != "8000" {
+		t.Errorf("Failed to extract port: (%v) vs. (%v): %v\n", "8000", port, err)
+	}
+	if nodeID != testNode.NodeID() || ephRet != ephPubKey {
+		t.Errorf("Failed to extract node key and ephemeral key\n")
+	}
 
-	//A remote miner has the opportunity to send an additional IP:Port if he wishes to receive connection on this tuple
-	if ipportRet != "8000" {
-		t.Errorf("Failed to extract IP:Port: (%v) vs. (%v)\n", "8000", ipportRet)
+	//The signature covers the ping type, a MINER_PING cannot be turned into a CLIENT_PING
+	if _, _, _, err := _pongRes(packet[HEADER_LEN:], CLIENT_PING); err == nil {
+		t.Errorf("Handshake with wrong ping type was accepted\n")
 	}
 
-	ipport = []byte{
-		31, 64,
+	//A different listener port invalidates the signature
+	packet[HEADER_LEN+1]++
+	if _, _, _, err := _pongRes(packet[HEADER_LEN:], MINER_PING); err == nil {
+		t.Errorf("Handshake with tampered port was accepted\n")
 	}
 
-	ipportRet = _pongRes(ipport)
-	if ipportRet != "8000" {
-		t.Errorf("Failed to extract IP:Port: (%v) vs. (%v)\n", "8000", ipportRet)
+	//The old port-only handshake is rejected
+	if _, _, _, err := _pongRes([]byte{31, 64}, MINER_PING); err == nil {
+		t.Errorf("Handshake without node key was accepted\n")
 	}
 }
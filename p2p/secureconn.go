@@ -0,0 +1,322 @@
+package p2p
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/sha3"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+//The handshake authenticates both nodes by their persistent ed25519 node key and agrees on the session keys:
+//1) PING: listener port | node key | ephemeral X25519 key | signature of the initiator
+//2) PONG: node key | ephemeral X25519 key | signature of the responder over both ephemeral keys
+//Every packet sent afterwards is encrypted and authenticated with the session key of its direction.
+const (
+	NODE_ID_SIZE        = ed25519.PublicKeySize
+	EPH_KEY_SIZE        = 32
+	HANDSHAKE_PING_SIZE = PORT_SIZE + NODE_ID_SIZE + EPH_KEY_SIZE + ed25519.SignatureSize
+	HANDSHAKE_PONG_SIZE = NODE_ID_SIZE + EPH_KEY_SIZE + ed25519.SignatureSize
+
+	FRAME_LEN_SIZE = 4
+	MAX_FRAME_SIZE = HEADER_LEN + protocol.MAX_BLOCK_SIZE + chacha20poly1305.Overhead
+	//Until the HELLO is exchanged, unauthorized peers can't make us allocate block sized frames.
+	MAX_HELLO_FRAME_SIZE = HEADER_LEN + MAX_HELLO_SIZE + chacha20poly1305.Overhead
+)
+
+var (
+	handshakeContext = []byte("bazo-p2p-handshake")
+	sessionContext   = []byte("bazo-p2p-session")
+)
+
+func NodeID() [32]byte {
+	return defaultNode.NodeID()
+}
+
+func nodeIDFromKey(key ed25519.PrivateKey) (nodeID [32]byte) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nodeID
+	}
+	copy(nodeID[:], key[32:])
+	return nodeID
+}
+
+//secureConn encrypts every packet written to it as a single frame (length | ciphertext) and returns the
+//decrypted packets when read from. Nonces are counters, which also rejects replayed or reordered frames.
+type secureConn struct {
+	net.Conn
+	sendAEAD  cipher.AEAD
+	rcvAEAD   cipher.AEAD
+	sendNonce uint64
+	rcvNonce  uint64
+	sendLock  sync.Mutex
+	rcvBuf    []byte
+	//Largest frame accepted, see helloExchanged
+	maxRcvFrameSize uint32
+}
+
+func newSecureConn(conn net.Conn, sendKey, rcvKey [32]byte) (*secureConn, error) {
+	sendAEAD, err := chacha20poly1305.New(sendKey[:])
+	if err != nil {
+		return nil, err
+	}
+	rcvAEAD, err := chacha20poly1305.New(rcvKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &secureConn{Conn: conn, sendAEAD: sendAEAD, rcvAEAD: rcvAEAD, maxRcvFrameSize: MAX_HELLO_FRAME_SIZE}, nil
+}
+
+//Frames are read by a single goroutine, which is the one exchanging the HELLO as well.
+func (c *secureConn) helloExchanged() {
+	c.maxRcvFrameSize = MAX_FRAME_SIZE
+}
+
+func (c *secureConn) Write(packet []byte) (int, error) {
+	c.sendLock.Lock()
+	defer c.sendLock.Unlock()
+
+	if len(packet)+chacha20poly1305.Overhead > MAX_FRAME_SIZE {
+		return 0, errors.New(fmt.Sprintf("Packet too large: %v bytes", len(packet)))
+	}
+
+	frame := make([]byte, FRAME_LEN_SIZE, FRAME_LEN_SIZE+len(packet)+chacha20poly1305.Overhead)
+	frame = c.sendAEAD.Seal(frame, counterNonce(c.sendNonce), packet, nil)
+	binary.BigEndian.PutUint32(frame[:FRAME_LEN_SIZE], uint32(len(frame)-FRAME_LEN_SIZE))
+	c.sendNonce++
+
+	if _, err := c.Conn.Write(frame); err != nil {
+		return 0, err
+	}
+
+	return len(packet), nil
+}
+
+//Only returns bytes of the current frame, such that buffered readers never read ahead into the next packet.
+func (c *secureConn) Read(b []byte) (int, error) {
+	if len(c.rcvBuf) == 0 {
+		var lenBuf [FRAME_LEN_SIZE]byte
+		if _, err := io.ReadFull(c.Conn, lenBuf[:]); err != nil {
+			return 0, err
+		}
+
+		frameLen := binary.BigEndian.Uint32(lenBuf[:])
+		if frameLen < chacha20poly1305.Overhead || frameLen > c.maxRcvFrameSize {
+			return 0, errors.New(fmt.Sprintf("Invalid frame length: %v", frameLen))
+		}
+
+		frame := make([]byte, frameLen)
+		if _, err := io.ReadFull(c.Conn, frame); err != nil {
+			return 0, err
+		}
+
+		packet, err := c.rcvAEAD.Open(frame[:0], counterNonce(c.rcvNonce), frame, nil)
+		if err != nil {
+			return 0, errors.New("Frame could not be authenticated.")
+		}
+		c.rcvNonce++
+		c.rcvBuf = packet
+	}
+
+	n := copy(b, c.rcvBuf)
+	c.rcvBuf = c.rcvBuf[n:]
+
+	return n, nil
+}
+
+func counterNonce(counter uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], counter)
+	return nonce
+}
+
+func newEphemeralKey() (privKey, pubKey [32]byte, err error) {
+	if _, err = io.ReadFull(rand.Reader, privKey[:]); err != nil {
+		return privKey, pubKey, err
+	}
+
+	pub, err := curve25519.X25519(privKey[:], curve25519.Basepoint)
+	if err != nil {
+		return privKey, pubKey, err
+	}
+	copy(pubKey[:], pub)
+
+	return privKey, pubKey, nil
+}
+
+//Derives one key per direction from the ephemeral Diffie-Hellman secret, bound to both node ids and ephemeral keys.
+func deriveSessionKeys(ephPrivKey, remoteEphPubKey, initiatorEph, responderEph, initiatorID, responderID [32]byte) (initiatorKey, responderKey [32]byte, err error) {
+	shared, err := curve25519.X25519(ephPrivKey[:], remoteEphPubKey[:])
+	if err != nil {
+		return initiatorKey, responderKey, err
+	}
+
+	var secret []byte
+	secret = append(secret, sessionContext...)
+	secret = append(secret, shared...)
+	secret = append(secret, initiatorEph[:]...)
+	secret = append(secret, responderEph[:]...)
+	secret = append(secret, initiatorID[:]...)
+	secret = append(secret, responderID[:]...)
+	master := sha3.Sum256(secret)
+
+	initiatorKey = sha3.Sum256(append(master[:], 1))
+	responderKey = sha3.Sum256(append(master[:], 2))
+
+	return initiatorKey, responderKey, nil
+}
+
+func pingSigContent(pingType uint8, port []byte, nodeID, ephPubKey [32]byte) []byte {
+	content := append([]byte{}, handshakeContext...)
+	content = append(content, pingType)
+	content = append(content, port...)
+	content = append(content, nodeID[:]...)
+	return append(content, ephPubKey[:]...)
+}
+
+func pongSigContent(initiatorID, initiatorEph, responderID, responderEph [32]byte) []byte {
+	content := append([]byte{}, handshakeContext...)
+	content = append(content, initiatorID[:]...)
+	content = append(content, initiatorEph[:]...)
+	content = append(content, responderID[:]...)
+	return append(content, responderEph[:]...)
+}
+
+//Builds the first handshake message. Besides our listening port it contains our node key and a fresh ephemeral key,
+//signed with the node key.
+func PrepareHandshake(key ed25519.PrivateKey, pingType uint8, localPort int, ephPubKey [32]byte) ([]byte, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, errors.New("Node key not set.")
+	}
+
+	nodeID := nodeIDFromKey(key)
+	portBuf := make([]byte, PORT_SIZE)
+	binary.BigEndian.PutUint16(portBuf[:], uint16(localPort))
+
+	payload := append([]byte{}, portBuf...)
+	payload = append(payload, nodeID[:]...)
+	payload = append(payload, ephPubKey[:]...)
+	payload = append(payload, ed25519.Sign(key, pingSigContent(pingType, portBuf, nodeID, ephPubKey))...)
+
+	return BuildPacket(pingType, payload), nil
+}
+
+//Decouple the function for testing. Returns the listener port, node id and ephemeral key of the initiator.
+func _pongRes(payload []byte, pingType uint8) (port string, nodeID, ephPubKey [32]byte, err error) {
+	if len(payload) != HANDSHAKE_PING_SIZE {
+		return "", nodeID, ephPubKey, errors.New(fmt.Sprintf("Invalid handshake size: %v", len(payload)))
+	}
+
+	portBuf := payload[0:PORT_SIZE]
+	copy(nodeID[:], payload[PORT_SIZE:PORT_SIZE+NODE_ID_SIZE])
+	copy(ephPubKey[:], payload[PORT_SIZE+NODE_ID_SIZE:PORT_SIZE+NODE_ID_SIZE+EPH_KEY_SIZE])
+	sig := payload[PORT_SIZE+NODE_ID_SIZE+EPH_KEY_SIZE:]
+
+	if !ed25519.Verify(nodeID[:], pingSigContent(pingType, portBuf, nodeID, ephPubKey), sig) {
+		return "", nodeID, ephPubKey, errors.New(fmt.Sprintf("Invalid handshake signature of node %x.", nodeID[0:8]))
+	}
+
+	return strconv.Itoa(int(binary.BigEndian.Uint16(portBuf))), nodeID, ephPubKey, nil
+}
+
+//Answers a valid PING with our node key and returns the encrypted connection which replaces the plain one afterwards.
+func acceptHandshake(conn net.Conn, key ed25519.PrivateKey, pongType uint8, initiatorID, initiatorEph [32]byte) (net.Conn, error) {
+	ephPrivKey, ephPubKey, err := newEphemeralKey()
+	if err != nil {
+		return nil, err
+	}
+
+	nodeID := nodeIDFromKey(key)
+	initiatorKey, responderKey, err := deriveSessionKeys(ephPrivKey, initiatorEph, initiatorEph, ephPubKey, initiatorID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := append([]byte{}, nodeID[:]...)
+	payload = append(payload, ephPubKey[:]...)
+	payload = append(payload, ed25519.Sign(key, pongSigContent(initiatorID, initiatorEph, nodeID, ephPubKey))...)
+
+	if _, err := conn.Write(BuildPacket(pongType, payload)); err != nil {
+		return nil, err
+	}
+
+	return newSecureConn(conn, responderKey, initiatorKey)
+}
+
+//Performs the handshake on a freshly opened connection and returns the encrypted connection together with the
+//node id of the remote node.
+func initiateHandshake(conn net.Conn, key ed25519.PrivateKey, pingType uint8, localPort int) (secure net.Conn, remoteID [32]byte, err error) {
+	ephPrivKey, ephPubKey, err := newEphemeralKey()
+	if err != nil {
+		return nil, remoteID, err
+	}
+
+	packet, err := PrepareHandshake(key, pingType, localPort, ephPubKey)
+	if err != nil {
+		return nil, remoteID, err
+	}
+	if _, err := conn.Write(packet); err != nil {
+		return nil, remoteID, err
+	}
+
+	//Wait for the other party to finish the handshake with the corresponding message
+	header, payload, err := readPacket(conn)
+	if err != nil {
+		return nil, remoteID, err
+	}
+	if (pingType == MINER_PING && header.TypeID != MINER_PONG) || (pingType == CLIENT_PING && header.TypeID != CLIENT_PONG) {
+		return nil, remoteID, errors.New(fmt.Sprintf("Unexpected handshake response: %v", LogMapping[header.TypeID]))
+	}
+	if len(payload) != HANDSHAKE_PONG_SIZE {
+		return nil, remoteID, errors.New(fmt.Sprintf("Invalid handshake response size: %v", len(payload)))
+	}
+
+	var remoteEph [32]byte
+	copy(remoteID[:], payload[0:NODE_ID_SIZE])
+	copy(remoteEph[:], payload[NODE_ID_SIZE:NODE_ID_SIZE+EPH_KEY_SIZE])
+	nodeID := nodeIDFromKey(key)
+	if !ed25519.Verify(remoteID[:], pongSigContent(nodeID, ephPubKey, remoteID, remoteEph), payload[NODE_ID_SIZE+EPH_KEY_SIZE:]) {
+		return nil, remoteID, errors.New(fmt.Sprintf("Invalid handshake signature of node %x.", remoteID[0:8]))
+	}
+
+	initiatorKey, responderKey, err := deriveSessionKeys(ephPrivKey, remoteEph, ephPubKey, remoteEph, nodeID, remoteID)
+	if err != nil {
+		return nil, remoteID, err
+	}
+
+	secure, err = newSecureConn(conn, initiatorKey, responderKey)
+	return secure, remoteID, err
+}
+
+//Reads exactly one packet without buffering, such that no bytes of the following (encrypted) frames are consumed.
+func readPacket(conn net.Conn) (header *Header, payload []byte, err error) {
+	var headerArr [HEADER_LEN]byte
+	if _, err := io.ReadFull(conn, headerArr[:]); err != nil {
+		return nil, nil, err
+	}
+
+	header = extractHeader(headerArr[:])
+	if LogMapping[header.TypeID] == "" {
+		return nil, nil, errors.New("Header: TypeID not found.")
+	}
+	if header.Len > HANDSHAKE_PING_SIZE {
+		return nil, nil, errors.New(fmt.Sprintf("Handshake payload too large: %v", header.Len))
+	}
+
+	payload = make([]byte, header.Len)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return nil, nil, err
+	}
+
+	return header, payload, nil
+}
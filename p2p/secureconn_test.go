@@ -0,0 +1,129 @@
+package p2p
+
+import (
+	"crypto/rand"
+	"golang.org/x/crypto/ed25519"
+	"net"
+	"reflect"
+	"testing"
+)
+
+//Both sides of the handshake authenticate each other and exchange encrypted packets afterwards
+func TestHandshake(t *testing.T) {
+
+	_, clientKey, _ := ed25519.GenerateKey(rand.Reader)
+	conn1, conn2 := net.Pipe()
+
+	type result struct {
+		conn   net.Conn
+		nodeID [32]byte
+		err    error
+	}
+	initiated := make(chan result)
+	go func() {
+		secure, nodeID, err := initiateHandshake(conn1, clientKey, CLIENT_PING, 0)
+		initiated <- result{secure, nodeID, err}
+	}()
+
+	header, payload, err := readPacket(conn2)
+	if err != nil || header.TypeID != CLIENT_PING {
+		t.Fatalf("Reading handshake failed: %v\n", err)
+	}
+	_, clientID, ephPubKey, err := _pongRes(payload, CLIENT_PING)
+	if err != nil || clientID != nodeIDFromKey(clientKey) {
+		t.Fatalf("Handshake of initiator not verified: %v\n", err)
+	}
+	responder, err := acceptHandshake(conn2, testNode.key, CLIENT_PONG, clientID, ephPubKey)
+	if err != nil {
+		t.Fatalf("Accepting handshake failed: %v\n", err)
+	}
+
+	res := <-initiated
+	if res.err != nil || res.nodeID != testNode.NodeID() {
+		t.Fatalf("Handshake of responder not verified: %v\n", res.err)
+	}
+
+	packet := BuildPacket(BLOCK_BRDCST, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	go res.conn.Write(packet)
+
+	header, payload, err = RcvData(&peer{conn: responder})
+	if err != nil ||
+		header.TypeID != BLOCK_BRDCST ||
+		!reflect.DeepEqual([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, payload) {
+		t.Errorf("Receiving encrypted packet failed: %v\n", err)
+	}
+
+	go responder.Write(BuildPacket(TIME_BRDCST, []byte{1}))
+	header, _, err = RcvData_(res.conn)
+	if err != nil || header.TypeID != TIME_BRDCST {
+		t.Errorf("Receiving encrypted response failed: %v\n", err)
+	}
+}
+
+func TestSecureConnTampered(t *testing.T) {
+
+	var key1, key2 [32]byte
+	rand.Read(key1[:])
+	rand.Read(key2[:])
+
+	conn1, conn2 := net.Pipe()
+	sender, _ := newSecureConn(conn1, key1, key2)
+	receiver, _ := newSecureConn(&tamperConn{conn2}, key2, key1)
+
+	go sender.Write(BuildPacket(BLOCK_BRDCST, []byte{0, 1, 2, 3}))
+	buf := make([]byte, 100)
+	if _, err := receiver.Read(buf); err == nil {
+		t.Error("Tampered frame was accepted\n")
+	}
+
+	//Frames encrypted with another session key are rejected
+	conn3, conn4 := net.Pipe()
+	sender, _ = newSecureConn(conn3, key1, key2)
+	receiver, _ = newSecureConn(conn4, key1, key2)
+
+	go sender.Write(BuildPacket(BLOCK_BRDCST, []byte{0, 1, 2, 3}))
+	if _, err := receiver.Read(buf); err == nil {
+		t.Error("Frame with wrong session key was accepted\n")
+	}
+}
+
+//Block sized frames are only accepted after the HELLO
+func TestSecureConnHelloFrameSize(t *testing.T) {
+
+	var key1, key2 [32]byte
+	rand.Read(key1[:])
+	rand.Read(key2[:])
+	payload := make([]byte, MAX_HELLO_SIZE+1)
+
+	conn1, conn2 := net.Pipe()
+	sender, _ := newSecureConn(conn1, key1, key2)
+	receiver, _ := newSecureConn(conn2, key2, key1)
+
+	go sender.Write(BuildPacket(BLOCK_BRDCST, payload))
+	if _, _, err := RcvData_(receiver); err == nil {
+		t.Error("Frame larger than a HELLO was accepted before the HELLO\n")
+	}
+
+	conn3, conn4 := net.Pipe()
+	sender, _ = newSecureConn(conn3, key1, key2)
+	receiver, _ = newSecureConn(conn4, key2, key1)
+	receiver.helloExchanged()
+
+	go sender.Write(BuildPacket(BLOCK_BRDCST, payload))
+	if _, received, err := RcvData_(receiver); err != nil || len(received) != len(payload) {
+		t.Errorf("Frame larger than a HELLO was rejected after the HELLO: %v\n", err)
+	}
+}
+
+//Flips a bit in the last byte of every read, which is part of the ciphertext or tag
+type tamperConn struct {
+	net.Conn
+}
+
+func (c *tamperConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 && n == len(b) && len(b) > FRAME_LEN_SIZE {
+		b[n-1] ^= 0x01
+	}
+	return n, err
+}
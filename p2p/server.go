@@ -1,166 +1,180 @@
 package p2p
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/ed25519"
 	"net"
 	"strconv"
-	"strings"
-	"time"
 )
 
-var (
-	//List of ip addresses. A connection to a subset of the list will be established as soon as the network health
-	//monitor triggers.
-	Ipport string
-	peers  peersStruct
-
-	iplistChan      = make(chan string, MIN_MINERS)
-	minerBrdcstMsg  = make(chan []byte)
-	clientBrdcstMsg = make(chan []byte)
-	register        = make(chan *peer)
-	disconnect      = make(chan *peer)
-)
+//Node of the miner, the package level functions and channels belong to it.
+var defaultNode = NewNode(Config{})
 
-//Entry point for p2p package
-func Init(ipport string) {
-	Ipport = ipport
+//Entry point for p2p package. The node key authenticates this node towards its peers, peers of other chains than
+//chain are disconnected. If bindAddr is empty, the listener is bound to all interfaces and the port of ipport. Returns
+//the node the miner talks to.
+func Init(ipport string, bindAddr string, bootstrapNodeID [32]byte, key ed25519.PrivateKey, chain [32]byte) *Node {
 	InitLogging()
 
-	//Initialize peer map
-	peers.minerConns = make(map[*peer]bool)
-	peers.clientConns = make(map[*peer]bool)
-
-	//Start all services that are running concurrently
-	go peerService()
-	go broadcastService()
-	go checkHealthService()
-	go timeService()
-	go forwardBlockBrdcstToMiner()
-	go forwardBlockHeaderBrdcstToMiner()
-	go forwardVerifiedTxsToMiner()
+	defaultNode.configure(Config{
+		Addr:          ipport,
+		BindAddr:      bindAddr,
+		BootstrapAddr: storage.Bootstrap_Server,
+		BootstrapID:   bootstrapNodeID,
+		Key:           key,
+		ChainID:       chain,
+	})
 
-	if !IsBootstrap() {
-		bootstrap()
+	if err := defaultNode.Start(); err != nil {
+		logger.Printf("%v\n", err)
 	}
 
-	//Listen for all subsequent incoming connections on specified local address/listening port
-	go listener(Ipport)
+	return defaultNode
 }
 
-func bootstrap() {
-	//Connect to bootstrap server. To make it more fault-tolerant, we can increase the number of bootstrap servers in
-	//the future. initiateNewMinerConn(...) starts with MINER_PING to perform the initial handshake message
-	p, err := initiateNewMinerConnection(storage.Bootstrap_Server)
-	if err != nil {
-		logger.Printf("Initiating new miner connection failed: %v", err)
+func (n *Node) bootstrap() {
+	//Connect to miners of the address book. The bootstrap server is only needed if none of them can be reached.
+	//initiateNewMinerConn(...) starts with MINER_PING to perform the initial handshake message
+	if n.connectOutbound(MIN_MINERS) == 0 {
+		logger.Printf("Could not connect to any miner.\n")
 	}
-
-	go peerConn(p)
 }
 
-func initiateNewMinerConnection(dial string) (*peer, error) {
+func (n *Node) initiateNewMinerConnection(dial string) (*peer, error) {
 	var conn net.Conn
 
 	//Check if we already established a dial with that ip or if the ip belongs to us
-	if peerExists(dial) {
+	if n.peerExists(dial) {
 		return nil, errors.New(fmt.Sprintf("Connection with %v already established.", dial))
 	}
 
-	if peerSelfConn(dial) {
+	if n.peerSelfConn(dial) {
 		return nil, errors.New(fmt.Sprintf("Cannot self-connect %v.", dial))
 	}
 
-	//Open up a tcp dial and instantiate a peer struct, wait for adding it to the peerStruct before we finalize
-	//the handshake
-	conn, err := net.Dial("tcp", dial)
-	p := newPeer(conn, strings.Split(dial, ":")[1], PEERTYPE_MINER)
+	if host, _, err := net.SplitHostPort(dial); err == nil && n.isBanned(host) {
+		return nil, errors.New(fmt.Sprintf("%v is banned.", dial))
+	}
+
+	_, dialPort, err := net.SplitHostPort(dial)
 	if err != nil {
 		return nil, err
 	}
 
 	//Extracts the port from our localConn variable (which is in the form IP:Port)
-	localPort, err := strconv.Atoi(strings.Split(Ipport, ":")[1])
+	_, port, err := net.SplitHostPort(n.addr)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("Parsing port failed: %v\n", err))
 	}
+	localPort, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Parsing port failed: %v\n", err))
+	}
+
+	n.markAttempt(dial)
 
-	packet, err := PrepareHandshake(MINER_PING, localPort)
+	conn, err = n.transport.Dial(dial)
 	if err != nil {
+		n.markFailure(dial)
 		return nil, err
 	}
 
-	conn.Write(packet)
-
-	//Wait for the other party to finish the handshake with the corresponding message
-	header, _, err := RcvData(p)
-	if err != nil || header.TypeID != MINER_PONG {
+	//Authenticate the other miner and replace the plain connection with the encrypted one
+	secure, nodeID, err := initiateHandshake(conn, n.key, MINER_PING, localPort)
+	if err != nil {
+		conn.Close()
+		n.markFailure(dial)
 		return nil, errors.New(fmt.Sprintf("Failed to complete miner handshake: %v", err))
 	}
 
-	return p, nil
-}
+	if n.isNodeBanned(nodeID) {
+		conn.Close()
+		return nil, errors.New(fmt.Sprintf("Node %x is banned.", nodeID[0:8]))
+	}
 
-func PrepareHandshake(pingType uint8, localPort int) ([]byte, error) {
-	//We need to additionally send our local listening port in order to construct a valid first message
-	//This will be the only time we need it so we don't save it
-	portBuf := make([]byte, PORT_SIZE)
-	binary.BigEndian.PutUint16(portBuf[:], uint16(localPort))
-	packet := BuildPacket(pingType, portBuf)
+	//Behind a NAT, our own public address is not recognizable as such, but our node id is
+	if nodeID == n.NodeID() {
+		conn.Close()
+		n.removeSelfAddr(dial)
+		return nil, errors.New(fmt.Sprintf("Cannot self-connect %v.", dial))
+	}
 
-	return packet, nil
-}
+	p := n.newPeer(secure, dialPort, PEERTYPE_MINER)
+	p.nodeID = nodeID
 
-func listener(ipport string) {
-	//Listen on all interfaces, this NAT stuff easier
-	listener, err := net.Listen("tcp", ":"+strings.Split(ipport, ":")[1])
-	if err != nil {
-		logger.Printf("%v\n", err)
-		return
+	if err := n.exchangeHello(p); err != nil {
+		n.markFailure(dial)
+		return nil, errors.New(fmt.Sprintf("Failed to complete miner handshake: %v", err))
 	}
 
-	for {
-		conn, err := listener.Accept()
+	n.markSuccess(dial)
 
-		conn.(*net.TCPConn).SetKeepAlive(true)
-		conn.(*net.TCPConn).SetKeepAlivePeriod(1 * time.Minute)
+	return p, nil
+}
 
+//Accepts incoming connections until the listener is closed.
+func (n *Node) acceptConns(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
 		if err != nil {
+			if n.stopped() {
+				return
+			}
 			logger.Printf("%v\n", err)
 			continue
 		}
 
-		p := newPeer(conn, "", 0)
-		go handleNewConn(p)
+		if n.isBanned(remoteIP(conn)) {
+			conn.Close()
+			continue
+		}
+
+		p := n.newPeer(conn, "", 0)
+		go n.handleNewConn(p)
 	}
 }
 
-func handleNewConn(p *peer) {
+func (n *Node) handleNewConn(p *peer) {
 	//logger.Printf("New incoming connection: %v\n", p.conn.RemoteAddr().String())
 
-	header, payload, err := RcvData(p)
+	//The first message has to be a handshake, nothing else is accepted over an unauthenticated connection
+	header, payload, err := readPacket(p.conn)
 	if err != nil {
+		p.conn.Close()
 		logger.Printf("Failed to handle incoming connection: %v\n", err)
 		return
 	}
 
-	processIncomingMsg(p, header, payload)
+	if header.TypeID != MINER_PING && header.TypeID != CLIENT_PING {
+		p.conn.Close()
+		logger.Printf("Incoming connection did not start with a handshake: %v\n", LogMapping[header.TypeID])
+		return
+	}
+
+	n.pongRes(p, payload, header.TypeID)
 }
 
-func peerConn(p *peer) {
+func (n *Node) peerConn(p *peer) {
 	if p.peerType == PEERTYPE_MINER {
-		logger.Printf("Adding a new miner: %v\n", p.getIPPort())
+		logger.Printf("Adding a new miner: %v\n", p)
 	} else if p.peerType == PEERTYPE_CLIENT {
 		//logger.Printf("Adding a new client: %v\n", p.getIPPort())
 	}
 
 	//Give the peer a channel
 	p.ch = make(chan []byte)
+	p.limiter = newRateLimiter(p.peerType)
+	p.handlers = make(chan bool, MAX_CONCURRENT_HANDLERS)
 
 	//Register withe the broadcast service and start the additional writer
-	register <- p
+	select {
+	case n.register <- p:
+	case <-n.quit:
+		p.conn.Close()
+		return
+	}
 	go peerBroadcast(p)
 
 	for {
@@ -173,10 +187,24 @@ func peerConn(p *peer) {
 			}
 
 			//In case of a comm fail, disconnect cleanly from the broadcast service
-			disconnect <- p
+			select {
+			case n.disconnect <- p:
+			case <-n.quit:
+			}
 			return
 		}
 
-		go processIncomingMsg(p, header, payload)
+		//Messages above the rate are dropped
+		if !p.limiter.allow(HEADER_LEN + len(payload)) {
+			p.penalize(SCORE_RATE_EXCEEDED, "Rate limit exceeded")
+			continue
+		}
+
+		//Blocks reading further messages as long as MAX_CONCURRENT_HANDLERS messages are being processed
+		p.handlers <- true
+		go func(header *Header, payload []byte) {
+			n.processIncomingMsg(p, header, payload)
+			<-p.handlers
+		}(header, payload)
 	}
 }
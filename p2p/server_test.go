@@ -1,49 +1,76 @@
 package p2p
 
 import (
+	"bytes"
+	"net"
+	"strings"
 	"testing"
 	"time"
 )
 
-//Testing handshake and initiating new miner connections with the broadcast server (both run locally)
+//Testing handshake and initiating new miner connections with the broadcast server (both run locally). As both use
+//the same node key, the broadcast server is recognized as ourselves.
 func TestInitiateNewMinerConnection(t *testing.T) {
 
 	//wait until connections are safely opened
 	time.Sleep(time.Second)
 
-	p, err := initiateNewMinerConnection(MINER_IPPORT)
-	if err != nil {
-		t.Errorf("Could not establish connection to the boostrap server\n")
-	}
-
 	//Check that self-connection is not allowed
-	_, err = initiateNewMinerConnection("127.0.0.1:9000")
+	_, err := testNode.initiateNewMinerConnection("127.0.0.1:9000")
 	if err == nil {
 		t.Errorf("Self-connection was not prevented\n")
 	}
 
-	//We initiate a miner connection so we can test whether already established connections are recognized
-	go peerConn(p)
-	time.Sleep(time.Second)
 	//Check that already established connections are recognized
-	_, err = initiateNewMinerConnection("127.0.0.1:8000")
-	if err == nil {
-		t.Errorf("Connecting to already established connection was not prevented\n")
+	conn, err := net.Dial("tcp", MINER_IPPORT)
+	if err != nil {
+		t.Fatalf("Could not connect to the bootstrap server: %v\n", err)
+	}
+	p := testNode.newPeer(conn, "8000", PEERTYPE_MINER)
+	testNode.peers.add(p)
+	_, err = testNode.initiateNewMinerConnection(MINER_IPPORT)
+	if err == nil || !strings.Contains(err.Error(), "already established") {
+		t.Errorf("Connecting to already established connection was not prevented: %v\n", err)
+	}
+	testNode.peers.delete(p)
+	conn.Close()
+
+	//Self-connections behind another address are recognized by the node id, the address is not used again
+	testNode.addAddress(MINER_IPPORT)
+	_, err = testNode.initiateNewMinerConnection(MINER_IPPORT)
+	if err == nil || !strings.Contains(err.Error(), "self-connect") {
+		t.Errorf("Self-connection by node id was not prevented: %v\n", err)
+	}
+	if !testNode.peerSelfConn(MINER_IPPORT) {
+		t.Error("Address of self-connection not recognized.")
+	}
+	testNode.addrBookL.Lock()
+	_, known := testNode.addrBook[MINER_IPPORT]
+	testNode.addrBookL.Unlock()
+	if known {
+		t.Error("Address of self-connection still in the address book.")
 	}
 }
 
 func TestPrepareHandshake(t *testing.T) {
 
-	packet, err := PrepareHandshake(MINER_PING, 9000)
+	_, ephPubKey, _ := newEphemeralKey()
+	packet, err := PrepareHandshake(testNode.key, MINER_PING, 9000, ephPubKey)
 
 	if err != nil ||
 		packet[0] != 0x00 ||
 		packet[1] != 0x00 ||
 		packet[2] != 0x00 ||
-		packet[3] != 0x02 || //payload size is 2 bytes, listener port
+		packet[3] != HANDSHAKE_PING_SIZE || //listener port, node key, ephemeral key and signature
 		packet[4] != 0x64 || //dec(0x64) == 100, MINER_PING
 		packet[5] != 0x23 ||
 		packet[6] != 0x28 {
 		t.Errorf("Building MINER_PING packet failed")
 	}
+
+	nodeID := testNode.NodeID()
+	if !bytes.Equal(packet[HEADER_LEN+PORT_SIZE:HEADER_LEN+PORT_SIZE+NODE_ID_SIZE], nodeID[:]) ||
+		!bytes.Equal(packet[HEADER_LEN+PORT_SIZE+NODE_ID_SIZE:HEADER_LEN+PORT_SIZE+NODE_ID_SIZE+EPH_KEY_SIZE], ephPubKey[:]) {
+		t.Errorf("MINER_PING does not contain the node and ephemeral key")
+	}
 }
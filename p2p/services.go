@@ -1,46 +1,55 @@
 package p2p
 
 import (
-	"github.com/bazo-blockchain/bazo-miner/storage"
 	"time"
 )
 
 //This is not accessed concurrently, one single goroutine. However, the "peers" are accessed concurrently, therefore the
 //Thread-safe implementation.
-func peerService() {
+func (n *Node) peerService() {
 	for {
 		select {
-		case p := <-register:
-			peers.add(p)
-		case p := <-disconnect:
-			peers.delete(p)
+		case <-n.quit:
+			return
+		case p := <-n.register:
+			n.peers.add(p)
+		case p := <-n.disconnect:
+			n.peers.delete(p)
 			close(p.ch)
-			if peers.contains(p.getIPPort(), PEERTYPE_MINER){
-				logger.Printf("CHANNEL: Closed channel to %v", p.getIPPort())
+			if p.peerType == PEERTYPE_MINER {
+				logger.Printf("CHANNEL: Closed channel to %v", p)
 			}
 		}
 	}
 }
 
-func broadcastService() {
+func (n *Node) broadcastService() {
 	for {
 		select {
+		case <-n.quit:
+			return
 		//Broadcasting all messages.
-		case msg := <-minerBrdcstMsg:
-			for p := range peers.minerConns {
+		case msg := <-n.minerBrdcstMsg:
+			for _, p := range n.peers.getAllPeers(PEERTYPE_MINER) {
 				//Write to the channel, which the peerBroadcast(*peer) running in a seperate goroutine consumes right away.
-				if peers.contains(p.getIPPort(),PEERTYPE_MINER) {
+				if n.peers.containsPeer(p) {
 					p.ch <- msg
 				} else {
-					logger.Printf("CHANNEL_MINER: Wanted to send to %v, but %v is not in the peers.minerConns anymore", p.getIPPort(), p.getIPPort())
+					logger.Printf("CHANNEL_MINER: Wanted to send to %v, but %v is not in the peers.minerConns anymore", p, p)
 				}
 			}
-		case msg := <-clientBrdcstMsg:
-			for p := range peers.clientConns {
-				if peers.contains(p.getIPPort(),PEERTYPE_CLIENT) {
+		case item := <-n.invBrdcstMsg:
+			for _, p := range n.peers.getAllPeers(PEERTYPE_MINER) {
+				if n.peers.containsPeer(p) {
+					n.brdcstInv(p, item)
+				}
+			}
+		case msg := <-n.clientBrdcstMsg:
+			for _, p := range n.peers.getAllPeers(PEERTYPE_CLIENT) {
+				if n.peers.containsPeer(p) {
 					p.ch <- msg
 				} else {
-					logger.Printf("CHANNEL_CLIENT: Wanted to send to %v, but %v is not in the peers.minerConns anymore", p.getIPPort(), p.getIPPort())
+					logger.Printf("CHANNEL_CLIENT: Wanted to send to %v, but %v is not in the peers.clientConns anymore", p, p)
 				}
 			}
 		}
@@ -54,61 +63,52 @@ func peerBroadcast(p *peer) {
 	}
 }
 
-//Single goroutine that makes sure the system is well connected.
-func checkHealthService() {
-	for {
-		time.Sleep(HEALTH_CHECK_INTERVAL * time.Second)
+//Single goroutine that makes sure the system is well connected. Missing connections are established to miners of
+//the address book, which is refilled by asking the network for neighbors.
+func (n *Node) checkHealthService() {
+	ticker := time.NewTicker(n.healthCheckInterval)
+	defer ticker.Stop()
 
-		if Ipport != storage.Bootstrap_Server && !peers.contains(storage.Bootstrap_Server, PEERTYPE_MINER) {
-			p, err := initiateNewMinerConnection(storage.Bootstrap_Server)
-			if p == nil || err != nil {
-				logger.Printf("%v\n", err)
-			} else {
-				go peerConn(p)
-			}
+	for rounds := 1; ; rounds++ {
+		select {
+		case <-ticker.C:
+		case <-n.quit:
+			return
+		}
+
+		for _, p := range n.peers.getAllPeers(PEERTYPE_MINER) {
+			n.markSeen(p.getIPPort())
 		}
 
 		//Periodically check if we are well-connected
-		if len(peers.minerConns) >= MIN_MINERS {
-			continue
+		if n.peers.len(PEERTYPE_MINER) < MIN_MINERS {
+			n.connectOutbound(MIN_MINERS - n.peers.len(PEERTYPE_MINER))
 		}
 
-		//The only goto in the code (I promise), but best solution here IMHO.
-	RETRY:
-		select {
-		//iplistChan gets filled with every incoming neighborRes, they're consumed here.
-		case ipaddr := <-iplistChan:
-			p, err := initiateNewMinerConnection(ipaddr)
-			if err != nil {
-				logger.Printf("%v\n", err)
-			}
-			if p == nil || err != nil {
-				goto RETRY
-			}
-			go peerConn(p)
-			break
-		default:
-			//In case we don't have any ip addresses in the channel left, make a request to the network.
-			neighborReq()
-			break
+		//Learn new addresses if we are still not well-connected and every ADDR_GOSSIP_INTERVAL rounds otherwise
+		if n.peers.len(PEERTYPE_MINER) < MIN_MINERS || rounds%ADDR_GOSSIP_INTERVAL == 0 {
+			n.neighborReq()
 		}
 	}
 }
 
-//Calculates periodically system time from available sources and broadcasts the time to all connected peers.
-func timeService() {
-	//Initialize system time.
-	systemTime = time.Now().Unix()
-	go func() {
-		for {
-			time.Sleep(UPDATE_SYS_TIME * time.Second)
-			writeSystemTime()
-		}
-	}()
+//Calculates periodically the network time from the clock offsets of the miners and measures their offsets.
+func (n *Node) timeService() {
+	updateTicker := time.NewTicker(UPDATE_SYS_TIME * time.Second)
+	brdcstTicker := time.NewTicker(TIME_BRDCST_INTERVAL * time.Second)
+	defer updateTicker.Stop()
+	defer brdcstTicker.Stop()
 
 	for {
-		time.Sleep(TIME_BRDCST_INTERVAL * time.Second)
-		packet := BuildPacket(TIME_BRDCST, getTime())
-		minerBrdcstMsg <- packet
+		select {
+		case <-updateTicker.C:
+			n.updateTimeOffset()
+		case <-brdcstTicker.C:
+			for _, p := range n.peers.getAllPeers(PEERTYPE_MINER) {
+				n.timeReq(p)
+			}
+		case <-n.quit:
+			return
+		}
 	}
 }
@@ -0,0 +1,254 @@
+package p2p
+
+import (
+	"errors"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"sync"
+)
+
+//Store is the part of the storage a node reads blocks and txs from to answer its peers and writes received txs to.
+//Nodes of the miner use the storage package, nodes of a simulated network each have their own MemStore.
+type Store interface {
+	IsOpen() bool
+
+	ReadOpenTx(hash [32]byte) protocol.Transaction
+	ReadClosedTx(hash [32]byte) protocol.Transaction
+	ReadAllOpenTxs() []protocol.Transaction
+	WriteOpenTx(tx protocol.Transaction)
+
+	ReadOpenBlock(hash [32]byte) *protocol.Block
+	ReadClosedBlock(hash [32]byte) *protocol.Block
+	ReadClosedBlockWithoutTx(hash [32]byte) *protocol.Block
+	ReadLastClosedBlock() *protocol.Block
+	GetTxPubKeys(block *protocol.Block) [][32]byte
+
+	GetAccount(hash [32]byte) (*protocol.Account, error)
+	GetRootAccount(hash [32]byte) (*protocol.Account, error)
+	QueryIotData(query *protocol.IotQuery) (*protocol.IotQueryResult, error)
+	ReadParameterSchedule() *protocol.ParameterSchedule
+	ReadCurrentSupply() *protocol.Supply
+	ReadCurrentBaseFee() (baseFee uint64, found bool)
+	ReadCurrentValidatorSet() *protocol.ValidatorSet
+
+	ReadAllPeerAddresses() map[string][]byte
+	WritePeerAddress(ipport string, encoded []byte) error
+	DeletePeerAddress(ipport string)
+	ReadPeerBan(key []byte) (until int64, bans uint32, found bool)
+	WritePeerBan(key []byte, until int64, bans uint32) error
+}
+
+//Store of the miner, backed by the storage package.
+type dbStore struct{}
+
+func (dbStore) IsOpen() bool { return storage.IsOpen() }
+
+func (dbStore) ReadOpenTx(hash [32]byte) protocol.Transaction   { return storage.ReadOpenTx(hash) }
+func (dbStore) ReadClosedTx(hash [32]byte) protocol.Transaction { return storage.ReadClosedTx(hash) }
+func (dbStore) ReadAllOpenTxs() []protocol.Transaction          { return storage.ReadAllOpenTxs() }
+func (dbStore) WriteOpenTx(tx protocol.Transaction)             { storage.WriteOpenTx(tx) }
+
+func (dbStore) ReadOpenBlock(hash [32]byte) *protocol.Block   { return storage.ReadOpenBlock(hash) }
+func (dbStore) ReadClosedBlock(hash [32]byte) *protocol.Block { return storage.ReadClosedBlock(hash) }
+func (dbStore) ReadClosedBlockWithoutTx(hash [32]byte) *protocol.Block {
+	return storage.ReadClosedBlockWithoutTx(hash)
+}
+func (dbStore) ReadLastClosedBlock() *protocol.Block                 { return storage.ReadLastClosedBlock() }
+func (dbStore) GetTxPubKeys(block *protocol.Block) [][32]byte        { return storage.GetTxPubKeys(block) }
+
+func (dbStore) GetAccount(hash [32]byte) (*protocol.Account, error)     { return storage.GetAccount(hash) }
+func (dbStore) GetRootAccount(hash [32]byte) (*protocol.Account, error) { return storage.GetRootAccount(hash) }
+func (dbStore) QueryIotData(query *protocol.IotQuery) (*protocol.IotQueryResult, error) {
+	return storage.QueryIotData(query)
+}
+func (dbStore) ReadParameterSchedule() *protocol.ParameterSchedule { return storage.ReadParameterSchedule() }
+func (dbStore) ReadCurrentSupply() *protocol.Supply                 { return storage.ReadCurrentSupply() }
+func (dbStore) ReadCurrentBaseFee() (uint64, bool)                  { return storage.ReadCurrentBaseFee() }
+func (dbStore) ReadCurrentValidatorSet() *protocol.ValidatorSet     { return storage.ReadCurrentValidatorSet() }
+
+func (dbStore) ReadAllPeerAddresses() map[string][]byte { return storage.ReadAllPeerAddresses() }
+func (dbStore) WritePeerAddress(ipport string, encoded []byte) error {
+	return storage.WritePeerAddress(ipport, encoded)
+}
+func (dbStore) DeletePeerAddress(ipport string) { storage.DeletePeerAddress(ipport) }
+func (dbStore) ReadPeerBan(key []byte) (int64, uint32, bool) {
+	return storage.ReadPeerBan(key)
+}
+func (dbStore) WritePeerBan(key []byte, until int64, bans uint32) error {
+	return storage.WritePeerBan(key, until, bans)
+}
+
+//MemStore keeps blocks, txs, addresses and bans in memory. Accounts and IoT data are not available.
+type MemStore struct {
+	openTxs      map[[32]byte]protocol.Transaction
+	closedTxs    map[[32]byte]protocol.Transaction
+	closedBlocks map[[32]byte]*protocol.Block
+	lastBlock    *protocol.Block
+	addresses    map[string][]byte
+	bans         map[string][2]int64
+	l            sync.Mutex
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		openTxs:      make(map[[32]byte]protocol.Transaction),
+		closedTxs:    make(map[[32]byte]protocol.Transaction),
+		closedBlocks: make(map[[32]byte]*protocol.Block),
+		addresses:    make(map[string][]byte),
+		bans:         make(map[string][2]int64),
+	}
+}
+
+func (store *MemStore) IsOpen() bool {
+	return true
+}
+
+func (store *MemStore) ReadOpenTx(hash [32]byte) protocol.Transaction {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	return store.openTxs[hash]
+}
+
+func (store *MemStore) ReadClosedTx(hash [32]byte) protocol.Transaction {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	return store.closedTxs[hash]
+}
+
+func (store *MemStore) ReadAllOpenTxs() (txs []protocol.Transaction) {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	for _, tx := range store.openTxs {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+func (store *MemStore) WriteOpenTx(tx protocol.Transaction) {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	store.openTxs[tx.Hash()] = tx
+}
+
+func (store *MemStore) DeleteOpenTx(tx protocol.Transaction) {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	delete(store.openTxs, tx.Hash())
+}
+
+func (store *MemStore) ReadOpenBlock(hash [32]byte) *protocol.Block {
+	return nil
+}
+
+func (store *MemStore) ReadClosedBlock(hash [32]byte) *protocol.Block {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	return store.closedBlocks[hash]
+}
+
+func (store *MemStore) ReadClosedBlockWithoutTx(hash [32]byte) *protocol.Block {
+	return nil
+}
+
+func (store *MemStore) ReadLastClosedBlock() *protocol.Block {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	return store.lastBlock
+}
+
+//Closes the block, the txs of the mempool it contains are closed as well. It becomes the last block if it is higher
+//than the last one.
+func (store *MemStore) WriteClosedBlock(block *protocol.Block, txs []protocol.Transaction) {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	store.closedBlocks[block.Hash] = block
+	for _, tx := range txs {
+		delete(store.openTxs, tx.Hash())
+		store.closedTxs[tx.Hash()] = tx
+	}
+	if store.lastBlock == nil || block.Height > store.lastBlock.Height {
+		store.lastBlock = block
+	}
+}
+
+func (store *MemStore) GetTxPubKeys(block *protocol.Block) [][32]byte {
+	return nil
+}
+
+func (store *MemStore) GetAccount(hash [32]byte) (*protocol.Account, error) {
+	return nil, errors.New("Accounts are not stored.")
+}
+
+func (store *MemStore) GetRootAccount(hash [32]byte) (*protocol.Account, error) {
+	return nil, errors.New("Accounts are not stored.")
+}
+
+func (store *MemStore) QueryIotData(query *protocol.IotQuery) (*protocol.IotQueryResult, error) {
+	return nil, errors.New("IoT data is not stored.")
+}
+
+func (store *MemStore) ReadParameterSchedule() *protocol.ParameterSchedule {
+	return new(protocol.ParameterSchedule)
+}
+
+func (store *MemStore) ReadCurrentSupply() *protocol.Supply {
+	return nil
+}
+
+func (store *MemStore) ReadCurrentBaseFee() (baseFee uint64, found bool) {
+	return 0, false
+}
+
+func (store *MemStore) ReadCurrentValidatorSet() *protocol.ValidatorSet {
+	return nil
+}
+
+func (store *MemStore) ReadAllPeerAddresses() map[string][]byte {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	addresses := make(map[string][]byte)
+	for ipport, encoded := range store.addresses {
+		addresses[ipport] = encoded
+	}
+	return addresses
+}
+
+func (store *MemStore) WritePeerAddress(ipport string, encoded []byte) error {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	store.addresses[ipport] = encoded
+	return nil
+}
+
+func (store *MemStore) DeletePeerAddress(ipport string) {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	delete(store.addresses, ipport)
+}
+
+func (store *MemStore) ReadPeerBan(key []byte) (until int64, bans uint32, found bool) {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	ban, found := store.bans[string(key)]
+	return ban[0], uint32(ban[1]), found
+}
+
+func (store *MemStore) WritePeerBan(key []byte, until int64, bans uint32) error {
+	store.l.Lock()
+	defer store.l.Unlock()
+
+	store.bans[string(key)] = [2]int64{until, int64(bans)}
+	return nil
+}
@@ -6,54 +6,231 @@ import (
 	"time"
 )
 
-var (
-	systemTime int64
-)
+//The network time is the local time corrected by the median clock offset of the connected miners. Offsets are
+//measured per peer with the HELLO of the handshake and with TIME_REQ/TIME_RES pings, where the round-trip time is
+//taken into account. Offsets of peers deviating too far from the others are rejected.
+
+//Replaces the source of the local time, e.g. with a fixed time for testing.
+func (n *Node) SetClock(newClock func() time.Time) {
+	n.timeL.Lock()
+	defer n.timeL.Unlock()
+
+	n.clock = newClock
+}
+
+func SetClock(newClock func() time.Time) {
+	defaultNode.SetClock(newClock)
+}
+
+//Set by the miner to the accepted time difference of blocks, such that a drift is noticed before blocks are rejected.
+func (n *Node) SetAcceptedTimeDiff(seconds int64) {
+	n.timeL.Lock()
+	defer n.timeL.Unlock()
+
+	n.acceptedTimeDiff = seconds
+}
+
+func SetAcceptedTimeDiff(seconds int64) {
+	defaultNode.SetAcceptedTimeDiff(seconds)
+}
+
+//Local time in milliseconds.
+func (n *Node) localTime() int64 {
+	n.timeL.Lock()
+	defer n.timeL.Unlock()
+
+	return n.clock().UnixNano() / int64(time.Millisecond)
+}
+
+//Network time in milliseconds.
+func (n *Node) networkTime() int64 {
+	now := n.localTime()
 
-//Get current local time
-func getTime() []byte {
+	n.timeL.Lock()
+	defer n.timeL.Unlock()
+
+	return now + n.timeOffset
+}
+
+//Offset of the network time to the local clock.
+func (n *Node) TimeOffset() time.Duration {
+	n.timeL.Lock()
+	defer n.timeL.Unlock()
+
+	return time.Duration(n.timeOffset) * time.Millisecond
+}
 
+func TimeOffset() time.Duration {
+	return defaultNode.TimeOffset()
+}
+
+//Whether the local clock differs from the network time by more than the accepted time difference of blocks.
+func (n *Node) ClockDrifted() bool {
+	n.timeL.Lock()
+	defer n.timeL.Unlock()
+
+	return abs(n.timeOffset) > n.acceptedTimeDiff*1000
+}
+
+func ClockDrifted() bool {
+	return defaultNode.ClockDrifted()
+}
+
+//Get current local time, sent with TIME_BRDCST to miners not supporting TIME_REQ.
+func (n *Node) getTime() []byte {
 	var buf [8]byte
-	time := time.Now().Unix()
-	binary.BigEndian.PutUint64(buf[:], uint64(time))
+	binary.BigEndian.PutUint64(buf[:], uint64(n.localTime()/1000))
 	return buf[:]
 }
 
-func writeSystemTime() {
-	peerTimes := peers.getMinerTimes()
+//Sends a TIME_REQ with our local time, which the peer echoes together with its own time.
+func (n *Node) timeReq(p *peer) {
+	if !p.supports(TIME_REQ) {
+		sendData(p, BuildPacket(TIME_BRDCST, n.getTime()))
+		return
+	}
 
-	//Add our own time as well
-	peerTimes = append(peerTimes, time.Now().Unix())
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(n.localTime()))
+	sendData(p, BuildPacket(TIME_REQ, buf[:]))
+}
 
-	var ipeerTimes []int
-	//Remove all 0s and cast to int (needed to leverage sort.Ints)
-	for _, time := range peerTimes {
-		if time != 0 {
-			ipeerTimes = append(ipeerTimes, int(time))
-		}
+func (n *Node) timeRes(p *peer, payload []byte) {
+	if len(payload) != 8 {
+		p.penalize(SCORE_MALFORMED_PACKET, "Malformed TIME_REQ")
+		return
 	}
 
-	//If we don't have at least MIN_PEERS_FOR_TIME different time values, we take our own system time for reference
-	if len(ipeerTimes) < MIN_PEERS_FOR_TIME {
-		systemTime = time.Now().Unix()
+	var buf [16]byte
+	copy(buf[:8], payload)
+	binary.BigEndian.PutUint64(buf[8:], uint64(n.localTime()))
+	sendData(p, BuildPacket(TIME_RES, buf[:]))
+}
+
+func (n *Node) processTimeRes(p *peer, payload []byte) {
+	if len(payload) != 16 {
+		p.penalize(SCORE_MALFORMED_PACKET, "Malformed TIME_RES")
+		return
+	}
+
+	now := n.localTime()
+	sent := int64(binary.BigEndian.Uint64(payload[:8]))
+	remote := int64(binary.BigEndian.Uint64(payload[8:]))
+	addTimeSample(p, remote-(sent+now)/2, now-sent)
+}
+
+//TIME_BRDCST only carries the time of the peer in seconds, the transmission delay is unknown.
+func (n *Node) processTimeBrdcst(p *peer, payload []byte) {
+	if len(payload) != 8 {
+		p.penalize(SCORE_MALFORMED_PACKET, "Malformed TIME_BRDCST")
+		return
+	}
+
+	remote := int64(binary.BigEndian.Uint64(payload)) * 1000
+	addTimeSample(p, remote-n.localTime(), 0)
+}
+
+//Measured at the handshake and with every ping. Samples with a round-trip time above MAX_TIME_RTT are too inaccurate.
+func addTimeSample(p *peer, offset int64, rtt int64) {
+	if rtt < 0 || rtt > MAX_TIME_RTT {
 		return
 	}
 
-	systemTime = calcMedian(ipeerTimes)
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.timeOffsets = append(p.timeOffsets, offset)
+	if len(p.timeOffsets) > MAX_TIME_SAMPLES {
+		p.timeOffsets = p.timeOffsets[1:]
+	}
+}
+
+//Clock offset of the peer in milliseconds, the median of its last samples.
+func (p *peer) getTimeOffset() (offset int64, measured bool) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if len(p.timeOffsets) == 0 {
+		return 0, false
+	}
+	return calcMedian(p.timeOffsets), true
+}
+
+//Recalculates the network time offset from the offsets of all miners and our own clock.
+func (n *Node) updateTimeOffset() {
+	offsets := append(n.peers.getMinerTimeOffsets(), 0)
+
+	//If we don't have at least MIN_PEERS_FOR_TIME different time values, we take our own system time for reference
+	var offset int64
+	if len(offsets) >= MIN_PEERS_FOR_TIME {
+		offset = calcMedian(rejectOutliers(offsets))
+	}
+
+	if abs(offset) > MAX_TIME_ADJUSTMENT*1000 {
+		logger.Printf("WARNING: The network time differs by %v from the local clock, it is not adjusted further than %v.\n",
+			time.Duration(offset)*time.Millisecond, MAX_TIME_ADJUSTMENT*time.Second)
+		offset = 0
+	}
+
+	n.timeL.Lock()
+	n.timeOffset = offset
+	drifted := abs(offset) > n.acceptedTimeDiff*1000
+	n.timeL.Unlock()
+
+	if drifted {
+		logger.Printf("WARNING: The local clock is off by %v from the network time, blocks may be rejected. Please check the system clock.\n",
+			time.Duration(-offset)*time.Millisecond)
+	}
+}
+
+//Offsets further away from the median than TIME_OUTLIER_FACTOR times the median absolute deviation are dropped. A
+//deviation below MIN_TIME_OUTLIER milliseconds is never considered an outlier.
+func rejectOutliers(offsets []int64) (accepted []int64) {
+	median := calcMedian(offsets)
+
+	deviations := make([]int64, len(offsets))
+	for i, offset := range offsets {
+		deviations[i] = abs(offset - median)
+	}
+
+	limit := TIME_OUTLIER_FACTOR * calcMedian(deviations)
+	if limit < MIN_TIME_OUTLIER {
+		limit = MIN_TIME_OUTLIER
+	}
+
+	for i, offset := range offsets {
+		if deviations[i] <= limit {
+			accepted = append(accepted, offset)
+		}
+	}
+
+	return accepted
 }
 
 //To protect against outliers, get the median
-func calcMedian(ipeerTimes []int) (median int64) {
+func calcMedian(values []int64) (median int64) {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]int64{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
 
-	sort.Ints(ipeerTimes)
 	//odd number of entries
-	if len(ipeerTimes)%2 == 1 {
-		return int64(ipeerTimes[len(ipeerTimes)/2])
-	} else {
-		//even number of entries
-		low := int64(ipeerTimes[len(ipeerTimes)/2])
-		high := int64(ipeerTimes[len(ipeerTimes)/2+1])
+	if len(sorted)%2 == 1 {
+		return sorted[len(sorted)/2]
+	}
+
+	//even number of entries
+	low := sorted[len(sorted)/2-1]
+	high := sorted[len(sorted)/2]
+
+	return low + (high-low)/2
+}
 
-		return (high + low) / 2
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
 	}
+	return value
 }
@@ -0,0 +1,131 @@
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCalcMedian(t *testing.T) {
+
+	if median := calcMedian([]int64{5, 1, 3}); median != 3 {
+		t.Errorf("Median of odd number of values: %v\n", median)
+	}
+	if median := calcMedian([]int64{7, 1, 3, 5}); median != 4 {
+		t.Errorf("Median of even number of values: %v\n", median)
+	}
+	if median := calcMedian([]int64{-3, 3}); median != 0 {
+		t.Errorf("Median of two values: %v\n", median)
+	}
+	if median := calcMedian(nil); median != 0 {
+		t.Errorf("Median of no values: %v\n", median)
+	}
+
+	values := []int64{3, 2, 1}
+	calcMedian(values)
+	if values[0] != 3 {
+		t.Error("Calculating the median reordered the values.")
+	}
+}
+
+func TestRejectOutliers(t *testing.T) {
+
+	offsets := []int64{0, 100, -200, 300, 3600000}
+	if accepted := rejectOutliers(offsets); len(accepted) != 4 || calcMedian(accepted) != 50 {
+		t.Errorf("Outlier not rejected: %v\n", accepted)
+	}
+
+	//Offsets close to each other are never rejected
+	if accepted := rejectOutliers([]int64{0, 0, 0, 1500}); len(accepted) != 4 {
+		t.Errorf("Offset within MIN_TIME_OUTLIER rejected: %v\n", accepted)
+	}
+}
+
+//The network time follows the median offset of the miners, outliers (including our own clock) can't shift it
+func TestUpdateTimeOffset(t *testing.T) {
+
+	local := time.Unix(1000000, 0)
+	testNode.SetClock(func() time.Time { return local })
+	defer testNode.SetClock(time.Now)
+	defer func() {
+		testNode.timeL.Lock()
+		testNode.timeOffset = 0
+		testNode.timeL.Unlock()
+	}()
+
+	var miners []*peer
+	for _, offset := range []int64{90000, 91000, 92000, 93000, -3600000} {
+		p := testNode.newPeer(nil, "", PEERTYPE_MINER)
+		addTimeSample(p, offset, 100)
+		testNode.peers.add(p)
+		defer testNode.peers.delete(p)
+		miners = append(miners, p)
+	}
+
+	//Measurements with a large round-trip time are dropped
+	addTimeSample(miners[0], 0, MAX_TIME_RTT+1)
+
+	testNode.updateTimeOffset()
+	if testNode.TimeOffset() != 91500*time.Millisecond || testNode.ReadSystemTime() != 1000091 {
+		t.Errorf("Unexpected network time: %v, %v\n", testNode.TimeOffset(), testNode.ReadSystemTime())
+	}
+	if !testNode.ClockDrifted() {
+		t.Error("Drift of the local clock not detected.")
+	}
+
+	testNode.SetAcceptedTimeDiff(120)
+	defer testNode.SetAcceptedTimeDiff(ACCEPTED_TIME_DIFF)
+	if testNode.ClockDrifted() {
+		t.Error("Drift within the accepted time difference reported.")
+	}
+
+	//Without enough miners, the local clock is used
+	testNode.peers.delete(miners[0])
+	testNode.peers.delete(miners[1])
+	testNode.updateTimeOffset()
+	if testNode.TimeOffset() != 0 || testNode.ReadSystemTime() != local.Unix() {
+		t.Errorf("Network time with too few miners: %v\n", testNode.TimeOffset())
+	}
+}
+
+//The offset is measured with the round-trip time taken into account
+func TestTimeReq(t *testing.T) {
+
+	conn1, conn2 := net.Pipe()
+	p1 := testNode.newPeer(conn1, "", PEERTYPE_MINER)
+	p2 := testNode.newPeer(conn2, "", PEERTYPE_MINER)
+	defer conn1.Close()
+	defer conn2.Close()
+
+	local := time.Unix(2000000, 0)
+	testNode.SetClock(func() time.Time { return local })
+	defer testNode.SetClock(time.Now)
+
+	go testNode.timeReq(p1)
+	header, req, err := RcvData(p2)
+	if err != nil || header.TypeID != TIME_REQ || len(req) != 8 {
+		t.Fatalf("Unexpected TIME_REQ: %v, %v\n", header, err)
+	}
+
+	//The peer's clock is 5 seconds ahead, the answer takes 2 seconds
+	local = local.Add(6 * time.Second)
+	go testNode.timeRes(p2, req)
+	header, res, err := RcvData(p1)
+	if err != nil || header.TypeID != TIME_RES {
+		t.Fatalf("Unexpected TIME_RES: %v, %v\n", header, err)
+	}
+	local = local.Add(-4 * time.Second)
+
+	testNode.processTimeRes(p1, res)
+	if offset, measured := p1.getTimeOffset(); !measured || offset != 5000 {
+		t.Errorf("Unexpected clock offset: %v\n", offset)
+	}
+
+	//Peers which don't know TIME_REQ are sent their time with TIME_BRDCST
+	old := testNode.newPeer(conn1, "", PEERTYPE_MINER)
+	old.setHello(&Hello{MessageTypes: []uint8{TIME_BRDCST}})
+	go testNode.timeReq(old)
+	if header, _, err := RcvData(p2); err != nil || header.TypeID != TIME_BRDCST {
+		t.Errorf("Unexpected time message: %v, %v\n", header, err)
+	}
+}
@@ -0,0 +1,50 @@
+package p2p
+
+import (
+	"net"
+	"time"
+)
+
+//Transport opens the connections of a node. TCP is used in production, MemNetwork (memnet.go) connects nodes running
+//in the same process, such that whole networks can be simulated in tests.
+type Transport interface {
+	Dial(addr string) (net.Conn, error)
+	Listen(addr string) (net.Listener, error)
+}
+
+type tcpTransport struct{}
+
+//Transport over the network, used if no other transport is configured.
+func NewTCPTransport() Transport {
+	return tcpTransport{}
+}
+
+func (tcpTransport) Dial(addr string) (net.Conn, error) {
+	return net.Dial("tcp", addr)
+}
+
+func (tcpTransport) Listen(addr string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return tcpKeepAliveListener{listener.(*net.TCPListener)}, nil
+}
+
+//Keeps accepted connections alive, such that dead peers are detected.
+type tcpKeepAliveListener struct {
+	*net.TCPListener
+}
+
+func (listener tcpKeepAliveListener) Accept() (net.Conn, error) {
+	conn, err := listener.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetKeepAlive(true)
+	conn.SetKeepAlivePeriod(1 * time.Minute)
+
+	return conn, nil
+}
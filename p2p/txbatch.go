@@ -0,0 +1,237 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"math/rand"
+	"time"
+)
+
+//A TX_BATCH_REQ asks for many txs of any type at once. The answer contains all txs found and the hashes of all txs
+//which were not found, such that the requesting miner does not have to wait for a timeout.
+const (
+	//Pending batches are dropped after TXBATCH_TIMEOUT seconds
+	TXBATCH_TIMEOUT = 60
+)
+
+//Request types of the single tx requests and the broadcast types their txs are decoded with.
+var txReqTypes = map[uint8]uint8{
+	FUNDSTX_REQ:      FUNDSTX_BRDCST,
+	ACCTX_REQ:        ACCTX_BRDCST,
+	CONFIGTX_REQ:     CONFIGTX_BRDCST,
+	STAKETX_REQ:      STAKETX_BRDCST,
+	AGGTX_REQ:        AGGTX_BRDCST,
+	CONTRACTTX_REQ:   CONTRACTTX_BRDCST,
+	IOTBATCHTX_REQ:   IOTBATCHTX_BRDCST,
+	DELEGATETX_REQ:   DELEGATETX_BRDCST,
+	EVIDENCETX_REQ:   EVIDENCETX_BRDCST,
+	GOVERNANCETX_REQ: GOVERNANCETX_BRDCST,
+	IOTTX_REQ:        IOTTX_BRDCST,
+	IOTAGGTX_REQ:     IOTAGGTX_BRDCST,
+}
+
+//Result of a TxBatchReq. Txs are guaranteed to have the requested hash and type.
+type TxBatch struct {
+	Txs      map[[32]byte]protocol.Transaction
+	NotFound [][32]byte
+}
+
+type txBatchItem struct {
+	Type uint8
+	Hash [32]byte
+}
+
+type txBatchTx struct {
+	Type uint8
+	Tx   []byte
+}
+
+type txBatchReq struct {
+	ID    uint32
+	Items []txBatchItem
+}
+
+type txBatchRes struct {
+	ID       uint32
+	Txs      []txBatchTx
+	NotFound [][32]byte
+}
+
+type pendingBatch struct {
+	requested map[[32]byte]uint8
+	result    *TxBatch
+	asked     map[*peer]bool
+	answered  map[*peer]bool
+	ch        chan *TxBatch
+	time      int64
+}
+
+//Requests the txs (hash -> request type, e.g. FUNDSTX_REQ) from all miners. The result is sent on the returned
+//channel as soon as all txs were received or all miners answered.
+func (n *Node) TxBatchReq(reqs map[[32]byte]uint8) (<-chan *TxBatch, error) {
+	req := txBatchReq{ID: rand.Uint32()}
+	for hash, reqType := range reqs {
+		if _, exists := txReqTypes[reqType]; !exists {
+			return nil, errors.New("Unknown tx request type.")
+		}
+		req.Items = append(req.Items, txBatchItem{reqType, hash})
+	}
+	if len(req.Items) > MAX_INV_ITEMS {
+		return nil, errors.New("Too many txs requested at once.")
+	}
+
+	pending := &pendingBatch{
+		requested: reqs,
+		result:    &TxBatch{Txs: make(map[[32]byte]protocol.Transaction)},
+		asked:     make(map[*peer]bool),
+		answered:  make(map[*peer]bool),
+		ch:        make(chan *TxBatch, 1),
+		time:      time.Now().Unix(),
+	}
+
+	for _, p := range n.peers.getAllPeers(PEERTYPE_MINER) {
+		if p.supports(TX_BATCH_REQ) {
+			pending.asked[p] = true
+		}
+	}
+	if len(pending.asked) == 0 {
+		return nil, errors.New("Couldn't get a connection, request not transmitted.")
+	}
+
+	n.pendingBatchesL.Lock()
+	n.expirePendingBatches(pending.time)
+	n.pendingBatches[req.ID] = pending
+	n.pendingBatchesL.Unlock()
+
+	packet := BuildPacket(TX_BATCH_REQ, req.Encode())
+	for p := range pending.asked {
+		sendData(p, packet)
+	}
+
+	return pending.ch, nil
+}
+
+func TxBatchReq(reqs map[[32]byte]uint8) (<-chan *TxBatch, error) {
+	return defaultNode.TxBatchReq(reqs)
+}
+
+//Has to be called with pendingBatchesL locked.
+func (n *Node) expirePendingBatches(now int64) {
+	for id, pending := range n.pendingBatches {
+		if pending.time+TXBATCH_TIMEOUT < now {
+			delete(n.pendingBatches, id)
+		}
+	}
+}
+
+//Answers with all requested txs we have. Txs which would exceed MAX_MSG_SIZE are reported as not found.
+func (n *Node) processTxBatchReq(p *peer, payload []byte) {
+	req := new(txBatchReq).Decode(payload)
+	if req == nil || len(req.Items) > MAX_INV_ITEMS {
+		p.penalize(SCORE_MALFORMED_PACKET, "Malformed TX_BATCH_REQ")
+		return
+	}
+
+	res := txBatchRes{ID: req.ID}
+	size := 0
+	for _, item := range req.Items {
+		tx := n.readTx(item.Hash)
+		if tx == nil || txBrdcstType(tx) != txReqTypes[item.Type] {
+			res.NotFound = append(res.NotFound, item.Hash)
+			continue
+		}
+
+		encoded := tx.Encode()
+		if size+len(encoded) > MAX_MSG_SIZE/2 {
+			res.NotFound = append(res.NotFound, item.Hash)
+			continue
+		}
+		size += len(encoded)
+		res.Txs = append(res.Txs, txBatchTx{item.Type, encoded})
+	}
+
+	sendData(p, BuildPacket(TX_BATCH_RES, res.Encode()))
+}
+
+func (n *Node) processTxBatchRes(p *peer, payload []byte) {
+	res := new(txBatchRes).Decode(payload)
+	if res == nil {
+		p.penalize(SCORE_MALFORMED_PACKET, "Malformed TX_BATCH_RES")
+		return
+	}
+
+	n.pendingBatchesL.Lock()
+	defer n.pendingBatchesL.Unlock()
+
+	pending, exists := n.pendingBatches[res.ID]
+	if !exists || !pending.asked[p] || pending.answered[p] {
+		return
+	}
+	pending.answered[p] = true
+
+	//Txs we did not ask for (or of another type) are dropped
+	for _, batchTx := range res.Txs {
+		tx := decodeTx(txReqTypes[batchTx.Type], batchTx.Tx)
+		if tx == nil {
+			continue
+		}
+		if reqType, requested := pending.requested[tx.Hash()]; requested && reqType == batchTx.Type {
+			pending.result.Txs[tx.Hash()] = tx
+		}
+	}
+
+	if len(pending.result.Txs) < len(pending.requested) && len(pending.answered) < len(pending.asked) {
+		return
+	}
+
+	for hash := range pending.requested {
+		if _, found := pending.result.Txs[hash]; !found {
+			pending.result.NotFound = append(pending.result.NotFound, hash)
+		}
+	}
+
+	delete(n.pendingBatches, res.ID)
+	pending.ch <- pending.result
+}
+
+func (req *txBatchReq) Encode() []byte {
+	if req == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(req)
+	return buffer.Bytes()
+}
+
+func (*txBatchReq) Decode(encoded []byte) (req *txBatchReq) {
+	var decoded txBatchReq
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+func (res *txBatchRes) Encode() []byte {
+	if res == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(res)
+	return buffer.Bytes()
+}
+
+func (*txBatchRes) Decode(encoded []byte) (res *txBatchRes) {
+	var decoded txBatchRes
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
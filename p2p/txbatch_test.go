@@ -0,0 +1,115 @@
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func TestProcessTxBatchReq(t *testing.T) {
+
+	fundsTx := &protocol.FundsTx{Amount: 21, TxCnt: 1}
+	storage.WriteOpenTx(fundsTx)
+	defer storage.DeleteOpenTx(fundsTx)
+
+	conn1, conn2 := net.Pipe()
+	p1 := testNode.newPeer(conn1, "", PEERTYPE_MINER)
+	p2 := testNode.newPeer(conn2, "", PEERTYPE_MINER)
+	defer conn1.Close()
+	defer conn2.Close()
+
+	//The tx requested with the wrong type and the unknown tx are reported as not found
+	req := txBatchReq{ID: 7, Items: []txBatchItem{
+		{FUNDSTX_REQ, fundsTx.Hash()},
+		{ACCTX_REQ, fundsTx.Hash()},
+		{STAKETX_REQ, [32]byte{0xdd}},
+	}}
+	go testNode.processTxBatchReq(p1, req.Encode())
+
+	header, payload, err := RcvData(p2)
+	res := new(txBatchRes).Decode(payload)
+	if err != nil || header.TypeID != TX_BATCH_RES || res == nil || res.ID != req.ID {
+		t.Fatalf("Unexpected TX_BATCH_RES: %v, %v\n", header, res)
+	}
+	if len(res.Txs) != 1 || res.Txs[0].Type != FUNDSTX_REQ || decodeTx(FUNDSTX_BRDCST, res.Txs[0].Tx).Hash() != fundsTx.Hash() {
+		t.Errorf("Unexpected txs: %v\n", res.Txs)
+	}
+	if len(res.NotFound) != 2 || res.NotFound[0] != fundsTx.Hash() || res.NotFound[1] != [32]byte{0xdd} {
+		t.Errorf("Unexpected not found txs: %x\n", res.NotFound)
+	}
+}
+
+//All miners are asked at once, the result is delivered once every miner answered
+func TestTxBatchReq(t *testing.T) {
+
+	found1 := &protocol.FundsTx{Amount: 31, TxCnt: 1}
+	found2 := &protocol.AccTx{Fee: 32}
+	missing := &protocol.FundsTx{Amount: 33, TxCnt: 3}
+
+	var remotes []*peer
+	for i := 0; i < 2; i++ {
+		conn1, conn2 := net.Pipe()
+		p := testNode.newPeer(conn1, "", PEERTYPE_MINER)
+		testNode.peers.add(p)
+		defer testNode.peers.delete(p)
+		defer conn1.Close()
+		defer conn2.Close()
+		remotes = append(remotes, testNode.newPeer(conn2, "", PEERTYPE_MINER))
+	}
+	local := testNode.peers.getAllPeers(PEERTYPE_MINER)
+
+	reqs := make(chan *txBatchReq, len(remotes))
+	for _, remote := range remotes {
+		go func(remote *peer) {
+			_, payload, _ := RcvData(remote)
+			reqs <- new(txBatchReq).Decode(payload)
+		}(remote)
+	}
+
+	batchChan, err := testNode.TxBatchReq(map[[32]byte]uint8{
+		found1.Hash():  FUNDSTX_REQ,
+		found2.Hash():  ACCTX_REQ,
+		missing.Hash(): FUNDSTX_REQ,
+	})
+	if err != nil {
+		t.Fatalf("TxBatchReq failed: %v\n", err)
+	}
+
+	req, other := <-reqs, <-reqs
+	if req == nil || other == nil || len(req.Items) != 3 || other.ID != req.ID {
+		t.Fatalf("Unexpected TX_BATCH_REQ: %v, %v\n", req, other)
+	}
+
+	//Txs with a type we did not ask for are dropped
+	res := txBatchRes{ID: req.ID, Txs: []txBatchTx{{FUNDSTX_REQ, found1.Encode()}, {FUNDSTX_REQ, found2.Encode()}}}
+	testNode.processTxBatchRes(local[0], res.Encode())
+	testNode.processTxBatchRes(local[0], res.Encode())
+	if len(batchChan) != 0 {
+		t.Fatal("Batch completed before all miners answered.")
+	}
+
+	res = txBatchRes{ID: req.ID, Txs: []txBatchTx{{ACCTX_REQ, found2.Encode()}}, NotFound: [][32]byte{missing.Hash()}}
+	testNode.processTxBatchRes(local[1], res.Encode())
+
+	select {
+	case batch := <-batchChan:
+		if len(batch.Txs) != 2 || batch.Txs[found1.Hash()] == nil || batch.Txs[found2.Hash()] == nil {
+			t.Errorf("Unexpected txs: %v\n", batch.Txs)
+		}
+		if len(batch.NotFound) != 1 || batch.NotFound[0] != missing.Hash() {
+			t.Errorf("Unexpected not found txs: %x\n", batch.NotFound)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Batch not completed.")
+	}
+
+	testNode.pendingBatchesL.Lock()
+	_, pending := testNode.pendingBatches[req.ID]
+	testNode.pendingBatchesL.Unlock()
+	if pending {
+		t.Error("Completed batch still pending.")
+	}
+}
@@ -3,16 +3,24 @@ package p2p
 import (
 	"bufio"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
-	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/ed25519"
 	"net"
 	"strings"
 	"time"
 )
 
-func Connect(connectionString string) *net.TCPConn {
+var (
+	errUnknownType     = errors.New("Header: TypeID not found.")
+	errPayloadTooLarge = errors.New("Header: Payload exceeds maximum size.")
+)
+
+//Opens an authenticated and encrypted connection to a miner, identifying this side with key. Every packet written to
+//the returned connection is encrypted, packets can be read with RcvData_ as usual.
+func Connect(connectionString string, key ed25519.PrivateKey) net.Conn {
 	tcpAddr, err := net.ResolveTCPAddr("tcp", connectionString)
 	conn, err := net.DialTCP("tcp", nil, tcpAddr)
 
@@ -24,13 +32,30 @@ func Connect(connectionString string) *net.TCPConn {
 	conn.SetLinger(0)
 	conn.SetDeadline(time.Now().Add(20 * time.Second))
 
-	return conn
+	secure, nodeID, err := initiateHandshake(conn, key, CLIENT_PING, 0)
+	if err != nil {
+		logger.Printf("Handshake with %v failed: %v\n", connectionString, err)
+		conn.Close()
+		return nil
+	}
+
+	//The connection is closed if the HELLO fails
+	rcv := func() (*Header, []byte, error) { return RcvData_(secure) }
+	if _, err := swapHello(secure, newClientHello(), rcv); err != nil {
+		logger.Printf("HELLO with %x at %v failed: %v\n", nodeID[0:8], connectionString, err)
+		return nil
+	}
+
+	return secure
 }
 
 func RcvData(p *peer) (header *Header, payload []byte, err error) {
 	reader := bufio.NewReader(p.conn)
 	header, err = ReadHeader(reader)
 	if err != nil {
+		if err == errUnknownType || err == errPayloadTooLarge {
+			p.penalize(SCORE_MALFORMED_PACKET, err.Error())
+		}
 		p.conn.Close()
 		return nil, nil, errors.New(fmt.Sprintf("Connection to %v aborted: %v", p.getIPPort(), err))
 	}
@@ -73,14 +98,23 @@ func RcvData_(c net.Conn) (header *Header, payload []byte, err error) {
 func sendData(p *peer, payload []byte) {
 	//logger.Printf("Send message:\nReceiver: %v\nType: %v\nPayload length: %v\n", p.getIPPort(), LogMapping[payload[4]], len(payload)-HEADER_LEN)
 
+	//Don't send messages the peer can't parse, it would disconnect us
+	if len(payload) >= HEADER_LEN && !p.supports(payload[4]) {
+		return
+	}
+
+	if len(payload) >= HEADER_LEN && isRequest(payload[4]) {
+		p.requestSent()
+	}
+
 	p.l.Lock()
 	p.conn.Write(payload)
 	p.l.Unlock()
 }
 
 //Tested in server_test.go
-func peerExists(newIpport string) bool {
-	peerList := peers.getAllPeers(PEERTYPE_MINER)
+func (n *Node) peerExists(newIpport string) bool {
+	peerList := n.peers.getAllPeers(PEERTYPE_MINER)
 
 	for _, p := range peerList {
 		ipport := p.getIPPort()
@@ -93,8 +127,35 @@ func peerExists(newIpport string) bool {
 }
 
 //Tested in server_test.go
-func peerSelfConn(newIpport string) bool {
-	return newIpport == Ipport
+func (n *Node) peerSelfConn(newIpport string) bool {
+	return sameAddr(newIpport, n.addr) || sameAddr(newIpport, n.bindAddr) || n.isSelfAddr(newIpport)
+}
+
+//Addresses are the same if their ports match and their hosts are equal, equal ips or both loopback (e.g. localhost
+//and 127.0.0.1). Hostnames are not resolved, addresses without host (all interfaces) don't match any address.
+func sameAddr(addr1, addr2 string) bool {
+	host1, port1, err1 := net.SplitHostPort(addr1)
+	host2, port2, err2 := net.SplitHostPort(addr2)
+	if err1 != nil || err2 != nil || port1 != port2 || host1 == "" || host2 == "" {
+		return false
+	}
+	if strings.EqualFold(host1, host2) {
+		return true
+	}
+
+	ip1, ip2 := parseHost(host1), parseHost(host2)
+	if ip1 == nil || ip2 == nil {
+		return false
+	}
+
+	return ip1.Equal(ip2) || ip1.IsLoopback() && ip2.IsLoopback()
+}
+
+func parseHost(host string) net.IP {
+	if strings.EqualFold(host, "localhost") {
+		return net.IPv6loopback
+	}
+	return net.ParseIP(host)
 }
 
 func BuildPacket(typeID uint8, payload []byte) (packet []byte) {
@@ -128,17 +189,24 @@ func ReadHeader(reader *bufio.Reader) (*Header, error) {
 
 	//Check if the type is registered in the protocol.
 	if LogMapping[header.TypeID] == "" {
-		return nil, errors.New("Header: TypeID not found.")
+		return nil, errUnknownType
 	}
 
-	//Check if the payload length does not exceed the MAX_BLOCK_SIZE defined in configtx.go
-	if header.Len > protocol.MAX_BLOCK_SIZE {
-		return nil, errors.New("Header: Payload exceeds MAX_BLOCK_SIZE")
+	//Only blocks may be as large as MAX_BLOCK_SIZE defined in configtx.go, all other messages are bounded by MAX_MSG_SIZE
+	if header.Len > maxPayloadSize(header.TypeID) {
+		return nil, errPayloadTooLarge
 	}
 
 	return header, nil
 }
 
+func maxPayloadSize(typeID uint8) uint32 {
+	if typeID == BLOCK_BRDCST || typeID == BLOCK_RES {
+		return protocol.MAX_BLOCK_SIZE
+	}
+	return MAX_MSG_SIZE
+}
+
 //Decoupled functionality for testing reasons.
 func extractHeader(headerData []byte) *Header {
 	header := new(Header)
@@ -152,12 +220,34 @@ func extractHeader(headerData []byte) *Header {
 	return header
 }
 
+//The bootstrap server is recognized by its node id. If only its address is known, we are the bootstrap server if it
+//is our advertised or bind address.
+func (n *Node) IsBootstrap() bool {
+	if n.bootstrapID != [32]byte{} {
+		return n.key != nil && n.bootstrapID == n.NodeID()
+	}
+	return sameAddr(n.bootstrapAddr, n.addr) || sameAddr(n.bootstrapAddr, n.bindAddr)
+}
+
 func IsBootstrap() bool {
-	//Set thisPort global, this will be the listening port for incoming connection
-	bootstrapPort := strings.Split(storage.Bootstrap_Server, ":")[1]
-	thisPort := strings.Split(Ipport, ":")[1]
-	if thisPort == bootstrapPort {
-		return true
+	return defaultNode.IsBootstrap()
+}
+
+//Splits a node address of the form [NODEID@]IP:PORT, the node id is zero if it is not given.
+func ParseNodeAddress(address string) (nodeID [32]byte, ipport string, err error) {
+	ipport = address
+	if index := strings.LastIndex(address, "@"); index >= 0 {
+		decoded, err := hex.DecodeString(address[:index])
+		if err != nil || len(decoded) != len(nodeID) {
+			return nodeID, "", errors.New(fmt.Sprintf("Invalid node id: %v", address[:index]))
+		}
+		copy(nodeID[:], decoded)
+		ipport = address[index+1:]
 	}
-	return false
+
+	if _, _, err := net.SplitHostPort(ipport); err != nil {
+		return nodeID, "", err
+	}
+
+	return nodeID, ipport, nil
 }
\ No newline at end of file
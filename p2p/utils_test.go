@@ -1,6 +1,7 @@
 package p2p
 
 import (
+	"fmt"
 	"net"
 	"reflect"
 	"testing"
@@ -56,3 +57,70 @@ func TestRcvData(t *testing.T) {
 		t.Error("Receiving data routine failed\n")
 	}
 }
+
+func TestSameAddr(t *testing.T) {
+
+	same := [][2]string{
+		{"127.0.0.1:8000", "127.0.0.1:8000"},
+		{"localhost:8000", "127.0.0.1:8000"},
+		{"[::1]:8000", "127.0.0.2:8000"},
+		{"Seed.bazo.test:8000", "seed.bazo.test:8000"},
+		{"[2001:db8::1]:8000", "[2001:db8:0::1]:8000"},
+	}
+	different := [][2]string{
+		{"127.0.0.1:8000", "127.0.0.1:8001"},
+		{"10.0.0.1:8000", "10.0.0.2:8000"},
+		{":8000", "10.0.0.1:8000"},
+		{":8000", ":8000"},
+		{"seed.bazo.test:8000", "10.0.0.1:8000"},
+	}
+
+	for _, addrs := range same {
+		if !sameAddr(addrs[0], addrs[1]) {
+			t.Errorf("%v and %v not recognized as the same address.\n", addrs[0], addrs[1])
+		}
+	}
+	for _, addrs := range different {
+		if sameAddr(addrs[0], addrs[1]) {
+			t.Errorf("%v and %v recognized as the same address.\n", addrs[0], addrs[1])
+		}
+	}
+}
+
+//The bootstrap server is recognized by its node id, the port alone is not enough
+func TestIsBootstrap(t *testing.T) {
+
+	defer func(server string) { testNode.bootstrapAddr = server }(testNode.bootstrapAddr)
+	defer func() { testNode.bootstrapID = [32]byte{} }()
+
+	nodeID, ipport, err := ParseNodeAddress(fmt.Sprintf("%x@10.0.0.1:8000", testNode.NodeID()))
+	if err != nil || nodeID != testNode.NodeID() || ipport != "10.0.0.1:8000" {
+		t.Fatalf("Parsing node address failed: %x, %v, %v\n", nodeID, ipport, err)
+	}
+	if _, _, err := ParseNodeAddress("abcd@10.0.0.1:8000"); err == nil {
+		t.Error("Node address with invalid node id parsed.")
+	}
+	if nodeID, _, err := ParseNodeAddress("10.0.0.1:8000"); err != nil || nodeID != [32]byte{} {
+		t.Errorf("Parsing address without node id failed: %v\n", err)
+	}
+
+	testNode.bootstrapAddr = ipport
+	testNode.bootstrapID = nodeID
+	if !testNode.IsBootstrap() {
+		t.Error("Bootstrap server not recognized by its node id.")
+	}
+	testNode.bootstrapID = [32]byte{1}
+	testNode.bootstrapAddr = testNode.addr
+	if testNode.IsBootstrap() {
+		t.Error("Node recognized as bootstrap server with another node id.")
+	}
+
+	testNode.bootstrapID = [32]byte{}
+	if !testNode.IsBootstrap() {
+		t.Error("Bootstrap server not recognized by its address.")
+	}
+	testNode.bootstrapAddr = "10.0.0.1:9000"
+	if testNode.IsBootstrap() {
+		t.Error("Bootstrap server recognized by its port.")
+	}
+}
@@ -17,6 +17,12 @@ type Account struct {
 	StakingBlockHeight uint32                // 4 Byte
 	Contract           []byte                // Arbitrary length
 	ContractVariables  []ByteArray           // Arbitrary length
+	IsFrozen           bool                  // 1 Byte
+	Commission         uint8                 // 1 Byte, percent of the delegators' rewards kept by the validator
+	Delegations        map[[32]byte]uint64   // Coins bonded to this validator, by delegator
+	StakedAmount       uint64                // 8 Byte, coins locked while staking
+	UnbondingAmount    uint64                // 8 Byte, locked coins of a former validator which can still be slashed
+	UnbondingHeight    uint32                // 4 Byte, height from which the unbonding coins can be withdrawn
 }
 
 func NewAccount(address [32]byte,
@@ -37,6 +43,12 @@ func NewAccount(address [32]byte,
 		0,
 		contract,
 		contractVariables,
+		false,
+		0,
+		nil,
+		0,
+		0,
+		0,
 	}
 
 	return newAcc
@@ -50,6 +62,37 @@ func (acc *Account) Hash() [32]byte {
 	return SerializeHashContent(acc.Address)
 }
 
+//Sum of the coins delegated to the account.
+func (acc *Account) DelegatedStake() (stake uint64) {
+	for _, amount := range acc.Delegations {
+		stake += amount
+	}
+	return stake
+}
+
+//The stake a validator takes part in the proof of stake with, its own balance and locked stake plus the coins
+//delegated to it.
+func (acc *Account) EffectiveStake() uint64 {
+	return acc.Balance + acc.StakedAmount + acc.DelegatedStake()
+}
+
+//An account is unbonding from leaving the validator set until its locked stake is withdrawn (or slashed).
+func (acc *Account) IsUnbonding() bool {
+	return acc.UnbondingHeight != 0
+}
+
+//Copy of the account which does not share the delegations with the original.
+func (acc *Account) Copy() *Account {
+	newAcc := *acc
+	if acc.Delegations != nil {
+		newAcc.Delegations = make(map[[32]byte]uint64)
+		for delegator, amount := range acc.Delegations {
+			newAcc.Delegations[delegator] = amount
+		}
+	}
+	return &newAcc
+}
+
 func (acc *Account) Encode() []byte {
 	if acc == nil {
 		return nil
@@ -65,6 +108,12 @@ func (acc *Account) Encode() []byte {
 		StakingBlockHeight: acc.StakingBlockHeight,
 		Contract:           acc.Contract,
 		ContractVariables:  acc.ContractVariables,
+		IsFrozen:           acc.IsFrozen,
+		Commission:         acc.Commission,
+		Delegations:        acc.Delegations,
+		StakedAmount:       acc.StakedAmount,
+		UnbondingAmount:    acc.UnbondingAmount,
+		UnbondingHeight:    acc.UnbondingHeight,
 	}
 
 	buffer := new(bytes.Buffer)
@@ -92,7 +141,13 @@ func (acc Account) String() string {
 			"CommitmentKey: %x, " +
 			"StakingBlockHeight: %v, " +
 			"Contract: %v, " +
-			"ContractVariables: %v",
+			"ContractVariables: %v, " +
+			"IsFrozen: %v, " +
+			"Commission: %v, " +
+			"DelegatedStake: %v, " +
+			"StakedAmount: %v, " +
+			"UnbondingAmount: %v, " +
+			"UnbondingHeight: %v",
 		addressHash[0:8],
 		acc.Address[0:8],
 		acc.Issuer[0:8],
@@ -102,5 +157,11 @@ func (acc Account) String() string {
 		acc.CommitmentKey[0:8],
 		acc.StakingBlockHeight,
 		acc.Contract,
-		acc.ContractVariables)
+		acc.ContractVariables,
+		acc.IsFrozen,
+		acc.Commission,
+		acc.DelegatedStake(),
+		acc.StakedAmount,
+		acc.UnbondingAmount,
+		acc.UnbondingHeight)
 }
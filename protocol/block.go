@@ -13,7 +13,7 @@ const (
 	HASH_LEN                = 32
 	HEIGHT_LEN				= 4
 	//All fixed sizes form the Block struct are 254
-	MIN_BLOCKSIZE           = 254 + crypto.COMM_PROOF_LENGTH + 1
+	MIN_BLOCKSIZE           = 254 + 2*crypto.COMM_PROOF_LENGTH + 1
 	MIN_BLOCKHEADER_SIZE    = 104
 	BLOOM_FILTER_ERROR_RATE = 0.1
 )
@@ -42,9 +42,16 @@ type Block struct {
 	NrStakeTx             uint16
 	NrAggTx         	  uint16
 	NrIoTTx         	  uint16
+	NrContractTx     	  uint16
+	NrIoTBatchTx     	  uint16
+	NrIoTAggTx     	  	  uint16
+	NrDelegateTx     	  uint16
+	NrEvidenceTx     	  uint16
+	NrGovernanceTx   	  uint16
 
 	SlashedAddress        [32]byte
 	CommitmentProof       [crypto.COMM_PROOF_LENGTH]byte
+	ValidatorSignature    [crypto.COMM_PROOF_LENGTH]byte //signature of the SignedHeader with the commitment key
 	ConflictingBlockHash1 [32]byte
 	ConflictingBlockHash2 [32]byte
 	ConflictingBlockHashWithoutTx1 [32]byte
@@ -57,6 +64,13 @@ type Block struct {
 	StakeTxData  		 [][32]byte
 	AggTxData  	 		 [][32]byte
 	IoTTxData  	 		 [][32]byte
+	ContractTxData 		 [][32]byte
+	IoTBatchTxData 		 [][32]byte
+	IoTAggTxData 		 [][32]byte
+	DelegateTxData 		 [][32]byte
+	EvidenceTxData 		 [][32]byte
+	GovernanceTxData	 [][32]byte
+	//Encoded size of all IotBatchTxs of the block, their readings are part of the block.
 	SizeIoTData			 uint64
 
 }
@@ -140,6 +154,19 @@ func (block *Block) HashBlockWithoutMerkleRoot() [32]byte {
 	return SerializeHashContent(blockHash)
 }
 
+//The header of the block which the validator signs with its commitment key.
+func (block *Block) SignedHeader() SignedHeader {
+	return SignedHeader{
+		Height:            block.Height,
+		Hash:              block.Hash,
+		PrevHash:          block.PrevHash,
+		PrevHashWithoutTx: block.PrevHashWithoutTx,
+		Beneficiary:       block.Beneficiary,
+		CommitmentProof:   block.CommitmentProof,
+		Signature:         block.ValidatorSignature,
+	}
+}
+
 func (block *Block) InitBloomFilter(txPubKeys [][32]byte) {
 	block.NrElementsBF = uint16(len(txPubKeys))
 
@@ -191,8 +218,15 @@ func (block *Block) GetBodySize() uint64 {
 		reflect.TypeOf(block.NrStakeTx).Size() +
 		reflect.TypeOf(block.NrAggTx).Size() +
 		reflect.TypeOf(block.NrIoTTx).Size() +
+		reflect.TypeOf(block.NrContractTx).Size() +
+		reflect.TypeOf(block.NrIoTBatchTx).Size() +
+		reflect.TypeOf(block.NrIoTAggTx).Size() +
+		reflect.TypeOf(block.NrDelegateTx).Size() +
+		reflect.TypeOf(block.NrEvidenceTx).Size() +
+		reflect.TypeOf(block.NrGovernanceTx).Size() +
 		reflect.TypeOf(block.SlashedAddress).Size() +
 		reflect.TypeOf(block.CommitmentProof).Size() +
+		reflect.TypeOf(block.ValidatorSignature).Size() +
 		reflect.TypeOf(block.ConflictingBlockHash1).Size() +
 		reflect.TypeOf(block.ConflictingBlockHash2).Size() +
 		reflect.TypeOf(block.ConflictingBlockHashWithoutTx1).Size() +
@@ -210,7 +244,14 @@ func (block *Block) GetTxDataSize() uint64 {
 		int(block.NrConfigTx)*HASH_LEN +
 		int(block.NrStakeTx)*HASH_LEN +
 		int(block.NrAggTx)*HASH_LEN +
-		int(block.NrIoTTx)*HASH_LEN
+		int(block.NrIoTTx)*HASH_LEN +
+		int(block.NrContractTx)*HASH_LEN +
+		int(block.NrIoTBatchTx)*HASH_LEN +
+		int(block.NrIoTAggTx)*HASH_LEN +
+		int(block.NrDelegateTx)*HASH_LEN +
+		int(block.NrEvidenceTx)*HASH_LEN +
+		int(block.NrGovernanceTx)*HASH_LEN +
+		int(block.SizeIoTData)
 
 	return uint64(size)
 }
@@ -247,11 +288,18 @@ func (block *Block) Encode() []byte {
 		NrStakeTx:             			block.NrStakeTx,
 		NrAggTx:         				block.NrAggTx,
 		NrIoTTx:						block.NrIoTTx,
+		NrContractTx:					block.NrContractTx,
+		NrIoTBatchTx:					block.NrIoTBatchTx,
+		NrIoTAggTx:						block.NrIoTAggTx,
+		NrDelegateTx:					block.NrDelegateTx,
+		NrEvidenceTx:					block.NrEvidenceTx,
+		NrGovernanceTx:					block.NrGovernanceTx,
 		NrElementsBF:          			block.NrElementsBF,
 		BloomFilter:           			block.BloomFilter,
 		SlashedAddress:        			block.SlashedAddress,
 		Height:                			block.Height,
 		CommitmentProof:	   			block.CommitmentProof,
+		ValidatorSignature:				block.ValidatorSignature,
 		ConflictingBlockHash1: 			block.ConflictingBlockHash1,
 		ConflictingBlockHash2: 			block.ConflictingBlockHash2,
 		ConflictingBlockHashWithoutTx1: block.ConflictingBlockHashWithoutTx1,
@@ -263,6 +311,12 @@ func (block *Block) Encode() []byte {
 		StakeTxData:  		   			block.StakeTxData,
 		AggTxData:	   					block.AggTxData,
 		IoTTxData:	   					block.IoTTxData,
+		ContractTxData:					block.ContractTxData,
+		IoTBatchTxData:					block.IoTBatchTxData,
+		IoTAggTxData:					block.IoTAggTxData,
+		DelegateTxData:					block.DelegateTxData,
+		EvidenceTxData:					block.EvidenceTxData,
+		GovernanceTxData:				block.GovernanceTxData,
 		SizeIoTData:					block.SizeIoTData,
 
 	}
@@ -323,6 +377,12 @@ func (block Block) String() string {
 		"Amount of stakeTx: %v --> %x\n"+
 		"Amount of aggTx: %v --> %x\n"+
 		"Amount of IoTTx: %v --> %x\n"+
+		"Amount of contractTx: %v --> %x\n"+
+		"Amount of IoTBatchTx: %v --> %x\n"+
+		"Amount of IoTAggTx: %v --> %x\n"+
+		"Amount of delegateTx: %v --> %x\n"+
+		"Amount of evidenceTx: %v --> %x\n"+
+		"Amount of governanceTx: %v --> %x\n"+
 		"Total Transactions in this block: %v\n"+
 		"Height: %d\n"+
 		"Commitment Proof: %x\n"+
@@ -342,8 +402,14 @@ func (block Block) String() string {
 		block.NrStakeTx, block.StakeTxData,
 		block.NrAggTx, block.AggTxData,
 		block.NrIoTTx, block.IoTTxData,
-
-		uint16(block.NrFundsTx) + uint16(block.NrAccTx) + uint16(block.NrConfigTx) + uint16(block.NrStakeTx) + uint16(block.NrAggTx )+ uint16(block.NrIoTTx),
+		block.NrContractTx, block.ContractTxData,
+		block.NrIoTBatchTx, block.IoTBatchTxData,
+		block.NrIoTAggTx, block.IoTAggTxData,
+		block.NrDelegateTx, block.DelegateTxData,
+		block.NrEvidenceTx, block.EvidenceTxData,
+		block.NrGovernanceTx, block.GovernanceTxData,
+
+		uint16(block.NrFundsTx) + uint16(block.NrAccTx) + uint16(block.NrConfigTx) + uint16(block.NrStakeTx) + uint16(block.NrAggTx )+ uint16(block.NrIoTTx) + uint16(block.NrContractTx) + uint16(block.NrIoTBatchTx) + uint16(block.NrIoTAggTx) + uint16(block.NrDelegateTx) + uint16(block.NrEvidenceTx) + uint16(block.NrGovernanceTx),
 		block.Height,
 		block.CommitmentProof[0:8],
 		block.SlashedAddress[0:8],
@@ -8,18 +8,21 @@ import (
 )
 
 const (
-	CONFIGTX_SIZE = 83
-
-	BLOCK_SIZE_ID           = 1
-	DIFF_INTERVAL_ID        = 2
-	FEE_MINIMUM_ID          = 3
-	BLOCK_INTERVAL_ID       = 4
-	BLOCK_REWARD_ID         = 5
-	STAKING_MINIMUM_ID      = 6
-	WAITING_MINIMUM_ID      = 7
-	ACCEPTANCE_TIME_DIFF_ID = 8
-	SLASHING_WINDOW_SIZE_ID = 9
-	SLASHING_REWARD_ID      = 10
+	CONFIGTX_SIZE        = 87
+	CONFIGTX_SIZE_LEGACY = 83 //ConfigTxs without activation height
+
+	BLOCK_SIZE_ID               = 1
+	DIFF_INTERVAL_ID            = 2
+	FEE_MINIMUM_ID              = 3
+	BLOCK_INTERVAL_ID           = 4
+	BLOCK_REWARD_ID             = 5
+	STAKING_MINIMUM_ID          = 6
+	WAITING_MINIMUM_ID          = 7
+	ACCEPTANCE_TIME_DIFF_ID     = 8
+	SLASHING_WINDOW_SIZE_ID     = 9
+	SLASHING_REWARD_ID          = 10
+	UNBONDING_PERIOD_ID         = 11
+	TARGET_ADJUSTMENT_HEIGHT_ID = 12
 
 	MIN_BLOCK_SIZE = 1000      //1KB
 	MAX_BLOCK_SIZE = 100000000 //100MB
@@ -50,18 +53,27 @@ const (
 
 	MIN_SLASHING_REWARD = 0                   // reward for providing a valid slashing proof
 	MAX_SLASHING_REWARD = 1152921504606846976 //2^60
+
+	MIN_UNBONDING_PERIOD = 0      //number of blocks the stake of a former validator stays locked (and slashable)
+	MAX_UNBONDING_PERIOD = 100000
+
+	MIN_TARGET_ADJUSTMENT_HEIGHT = 0          //height from which the PoS target is adjusted proportionally
+	MAX_TARGET_ADJUSTMENT_HEIGHT = 4294967295 //2^32-1, whole-bit adjustments forever
 )
 
+//The parameter change of a ConfigTx is in force from ActivationHeight on. An activation height of 0 puts it in force
+//from the block after the one including the tx.
 type ConfigTx struct {
-	Header  byte
-	Id      uint8
-	Payload uint64
-	Fee     uint64
-	TxCnt   uint8
-	Sig     [64]byte
+	Header           byte
+	Id               uint8
+	Payload          uint64
+	Fee              uint64
+	TxCnt            uint8
+	ActivationHeight uint32
+	Sig              [64]byte
 }
 
-func ConstrConfigTx(header byte, id uint8, payload uint64, fee uint64, txCnt uint8, rootPrivKey ed25519.PrivateKey) (tx *ConfigTx, err error) {
+func ConstrConfigTx(header byte, id uint8, payload uint64, fee uint64, txCnt uint8, activationHeight uint32, rootPrivKey ed25519.PrivateKey) (tx *ConfigTx, err error) {
 
 	tx = new(ConfigTx)
 	tx.Header = header
@@ -69,6 +81,7 @@ func ConstrConfigTx(header byte, id uint8, payload uint64, fee uint64, txCnt uin
 	tx.Payload = payload
 	tx.Fee = fee
 	tx.TxCnt = txCnt
+	tx.ActivationHeight = activationHeight
 
 	txHash := tx.Hash()
 
@@ -86,6 +99,25 @@ func (tx *ConfigTx) Hash() (hash [32]byte) {
 		return [32]byte{}
 	}
 
+	//ConfigTxs without activation height keep the hash they had before it was introduced
+	if tx.ActivationHeight != 0 {
+		return SerializeHashContent(struct {
+			Header           byte
+			Id               uint8
+			Payload          uint64
+			Fee              uint64
+			TxCnt            uint8
+			ActivationHeight uint32
+		}{
+			tx.Header,
+			tx.Id,
+			tx.Payload,
+			tx.Fee,
+			tx.TxCnt,
+			tx.ActivationHeight,
+		})
+	}
+
 	txHash := struct {
 		Header  byte
 		Id      uint8
@@ -111,6 +143,7 @@ func (tx *ConfigTx) Encode() (encodedTx []byte) {
 	var buf bytes.Buffer
 	var payloadBuf [8]byte
 	var feeBuf [8]byte
+	var activationHeightBuf [4]byte
 
 	binary.Write(&buf, binary.BigEndian, tx.Payload)
 	copy(payloadBuf[:], buf.Bytes())
@@ -118,6 +151,9 @@ func (tx *ConfigTx) Encode() (encodedTx []byte) {
 	binary.Write(&buf, binary.BigEndian, tx.Fee)
 	copy(feeBuf[:], buf.Bytes())
 	buf.Reset()
+	binary.Write(&buf, binary.BigEndian, tx.ActivationHeight)
+	copy(activationHeightBuf[:], buf.Bytes())
+	buf.Reset()
 
 	encodedTx = make([]byte, CONFIGTX_SIZE)
 	encodedTx[0] = tx.Header
@@ -126,13 +162,14 @@ func (tx *ConfigTx) Encode() (encodedTx []byte) {
 	copy(encodedTx[10:18], feeBuf[:])
 	encodedTx[18] = byte(tx.TxCnt)
 	copy(encodedTx[19:83], tx.Sig[:])
+	copy(encodedTx[83:87], activationHeightBuf[:])
 
 	return encodedTx
 }
 
 func (*ConfigTx) Decode(encodedTx []byte) (tx *ConfigTx) {
 
-	if len(encodedTx) != CONFIGTX_SIZE {
+	if len(encodedTx) != CONFIGTX_SIZE && len(encodedTx) != CONFIGTX_SIZE_LEGACY {
 		return nil
 	}
 
@@ -143,6 +180,9 @@ func (*ConfigTx) Decode(encodedTx []byte) (tx *ConfigTx) {
 	tx.Fee = binary.BigEndian.Uint64(encodedTx[10:18])
 	tx.TxCnt = uint8(encodedTx[18])
 	copy(tx.Sig[:], encodedTx[19:83])
+	if len(encodedTx) == CONFIGTX_SIZE {
+		tx.ActivationHeight = binary.BigEndian.Uint32(encodedTx[83:87])
+	}
 
 	return tx
 }
@@ -158,10 +198,12 @@ func (tx ConfigTx) String() string {
 			"Id: %v\n"+
 			"Payload: %v\n"+
 			"Fee: %v\n"+
-			"TxCnt: %v\n",
+			"TxCnt: %v\n"+
+			"Activation height: %v\n",
 		tx.Id,
 		tx.Payload,
 		tx.Fee,
 		tx.TxCnt,
+		tx.ActivationHeight,
 	)
 }
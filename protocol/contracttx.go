@@ -0,0 +1,146 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+	"unsafe"
+)
+
+const (
+	CONTRACT_UPGRADE      = 1
+	CONTRACT_FREEZE       = 2
+	CONTRACT_SELFDESTRUCT = 3
+)
+
+//ContractTx changes the lifecycle of an already deployed contract account. It has to be signed by the issuer of the
+//account, i.e., the key whose hash is stored in the account's Issuer field. TxCnt refers to the contract account.
+type ContractTx struct {
+	Header            byte
+	Fee               uint64
+	TxCnt             uint32
+	Issuer            [32]byte
+	Account           [32]byte
+	Beneficiary       [32]byte
+	Sig               [64]byte
+	Contract          []byte
+	ContractVariables []ByteArray
+}
+
+func ConstrContractTx(header byte, fee uint64, txCnt uint32, account [32]byte, beneficiary [32]byte, contract []byte, contractVariables []ByteArray, issuerPrivKey ed25519.PrivateKey) (tx *ContractTx, err error) {
+	if header < CONTRACT_UPGRADE || header > CONTRACT_SELFDESTRUCT {
+		return nil, errors.New(fmt.Sprintf("Invalid contract operation: %v", header))
+	}
+
+	tx = new(ContractTx)
+	tx.Header = header
+	tx.Fee = fee
+	tx.TxCnt = txCnt
+	tx.Account = account
+	tx.Beneficiary = beneficiary
+	tx.Contract = contract
+	tx.ContractVariables = contractVariables
+	copy(tx.Issuer[:], issuerPrivKey[32:])
+
+	txHash := tx.Hash()
+	copy(tx.Sig[:], ed25519.Sign(issuerPrivKey, txHash[:]))
+
+	return tx, nil
+}
+
+func (tx *ContractTx) Hash() [32]byte {
+	if tx == nil {
+		return [32]byte{}
+	}
+
+	txHash := struct {
+		Header            byte
+		Fee               uint64
+		TxCnt             uint32
+		Issuer            [32]byte
+		Account           [32]byte
+		Beneficiary       [32]byte
+		Contract          []byte
+		ContractVariables []ByteArray
+	}{
+		tx.Header,
+		tx.Fee,
+		tx.TxCnt,
+		tx.Issuer,
+		tx.Account,
+		tx.Beneficiary,
+		tx.Contract,
+		tx.ContractVariables,
+	}
+
+	return SerializeHashContent(txHash)
+}
+
+func (tx *ContractTx) Encode() []byte {
+	if tx == nil {
+		return nil
+	}
+
+	encoded := ContractTx{
+		Header:            tx.Header,
+		Fee:               tx.Fee,
+		TxCnt:             tx.TxCnt,
+		Issuer:            tx.Issuer,
+		Account:           tx.Account,
+		Beneficiary:       tx.Beneficiary,
+		Sig:               tx.Sig,
+		Contract:          tx.Contract,
+		ContractVariables: tx.ContractVariables,
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(encoded)
+	return buffer.Bytes()
+}
+
+func (*ContractTx) Decode(encoded []byte) (tx *ContractTx) {
+	var decoded ContractTx
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	decoder.Decode(&decoded)
+	return &decoded
+}
+
+func (tx *ContractTx) TxFee() uint64 { return tx.Fee }
+
+func (tx *ContractTx) Size() uint64 {
+	size := int(unsafe.Sizeof(*tx)) + len(tx.Contract)
+	for _, variable := range tx.ContractVariables {
+		size += len(variable)
+	}
+	return uint64(size)
+}
+
+func (tx *ContractTx) Sender() [32]byte   { return tx.Issuer }
+func (tx *ContractTx) Receiver() [32]byte { return tx.Account }
+
+func (tx ContractTx) String() string {
+	return fmt.Sprintf(
+		"\n"+
+			"Header: %v\n"+
+			"Fee: %v\n"+
+			"TxCnt: %v\n"+
+			"Issuer: %x\n"+
+			"Account: %x\n"+
+			"Beneficiary: %x\n"+
+			"Sig: %x\n"+
+			"Contract: %v\n"+
+			"ContractVariables: %v\n",
+		tx.Header,
+		tx.Fee,
+		tx.TxCnt,
+		tx.Issuer[0:8],
+		tx.Account[0:8],
+		tx.Beneficiary[0:8],
+		tx.Sig[0:8],
+		tx.Contract,
+		tx.ContractVariables,
+	)
+}
@@ -0,0 +1,149 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+	"unsafe"
+)
+
+const (
+	DELEGATE   = 1
+	UNDELEGATE = 2
+)
+
+//DelegateTx bonds Amount of the sender's balance to a validator (DELEGATE) or returns bonded coins to the sender's
+//balance (UNDELEGATE). Bonded coins count towards the stake of the validator, in return the delegator gets its share
+//of the validator's block rewards and fees. TxCnt refers to the sender account.
+type DelegateTx struct {
+	Header    byte
+	Amount    uint64
+	Fee       uint64
+	TxCnt     uint32
+	From      [32]byte
+	Validator [32]byte
+	Sig       [64]byte
+}
+
+func ConstrDelegateTx(header byte, amount uint64, fee uint64, txCnt uint32, from, validator [32]byte, sigKey ed25519.PrivateKey) (tx *DelegateTx, err error) {
+	if header != DELEGATE && header != UNDELEGATE {
+		return nil, errors.New(fmt.Sprintf("Invalid delegation operation: %v", header))
+	}
+
+	tx = new(DelegateTx)
+	tx.Header = header
+	tx.Amount = amount
+	tx.Fee = fee
+	tx.TxCnt = txCnt
+	tx.From = from
+	tx.Validator = validator
+
+	txHash := tx.Hash()
+	copy(tx.Sig[:], ed25519.Sign(sigKey, txHash[:]))
+
+	return tx, nil
+}
+
+func (tx *DelegateTx) Hash() [32]byte {
+	if tx == nil {
+		return [32]byte{}
+	}
+
+	txHash := struct {
+		Header    byte
+		Amount    uint64
+		Fee       uint64
+		TxCnt     uint32
+		From      [32]byte
+		Validator [32]byte
+	}{
+		tx.Header,
+		tx.Amount,
+		tx.Fee,
+		tx.TxCnt,
+		tx.From,
+		tx.Validator,
+	}
+
+	return SerializeHashContent(txHash)
+}
+
+func (tx *DelegateTx) Encode() []byte {
+	if tx == nil {
+		return nil
+	}
+
+	encoded := DelegateTx{
+		Header:    tx.Header,
+		Amount:    tx.Amount,
+		Fee:       tx.Fee,
+		TxCnt:     tx.TxCnt,
+		From:      tx.From,
+		Validator: tx.Validator,
+		Sig:       tx.Sig,
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(encoded)
+	return buffer.Bytes()
+}
+
+func (*DelegateTx) Decode(encoded []byte) (tx *DelegateTx) {
+	var decoded DelegateTx
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	decoder.Decode(&decoded)
+	return &decoded
+}
+
+func (tx *DelegateTx) TxFee() uint64 { return tx.Fee }
+func (tx *DelegateTx) Size() uint64  { return uint64(unsafe.Sizeof(*tx)) }
+
+func (tx *DelegateTx) Sender() [32]byte   { return tx.From }
+func (tx *DelegateTx) Receiver() [32]byte { return tx.Validator }
+
+func (tx DelegateTx) String() string {
+	return fmt.Sprintf(
+		"\n"+
+			"Header: %v\n"+
+			"Amount: %v\n"+
+			"Fee: %v\n"+
+			"TxCnt: %v\n"+
+			"From: %x\n"+
+			"Validator: %x\n"+
+			"Sig: %x\n",
+		tx.Header,
+		tx.Amount,
+		tx.Fee,
+		tx.TxCnt,
+		tx.From[0:8],
+		tx.Validator[0:8],
+		tx.Sig[0:8],
+	)
+}
+
+//Delegation rewards the validator of a block paid out to its delegators, keyed by delegator account hash
+type DelegationPayouts struct {
+	Validator [32]byte
+	Payouts   map[[32]byte]uint64
+}
+
+func (payouts *DelegationPayouts) Encode() []byte {
+	if payouts == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(payouts)
+	return buffer.Bytes()
+}
+
+func (*DelegationPayouts) Decode(encoded []byte) (payouts *DelegationPayouts) {
+	var decoded DelegationPayouts
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	decoder.Decode(&decoded)
+	return &decoded
+}
@@ -0,0 +1,187 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"golang.org/x/crypto/ed25519"
+	"unsafe"
+)
+
+const (
+	//Two blocks at the same height, i.e. from the same commitment proof
+	EVIDENCE_DOUBLE_PROPOSAL = 1
+	//Two blocks on competing chains within the slashing window
+	EVIDENCE_CONFLICTING_CHAINS = 2
+)
+
+//The part of a block a validator signs with its commitment key. Two signed headers are enough to prove that the
+//validator proposed conflicting blocks, the blocks themselves are not needed.
+type SignedHeader struct {
+	Height            uint32
+	Hash              [32]byte
+	PrevHash          [32]byte
+	PrevHashWithoutTx [32]byte
+	Beneficiary       [32]byte
+	CommitmentProof   [crypto.COMM_PROOF_LENGTH]byte
+	Signature         [crypto.COMM_PROOF_LENGTH]byte
+}
+
+//Message signed by the validator with its commitment key.
+func (header *SignedHeader) SigningMessage() string {
+	signedHash := struct {
+		Height            uint32
+		Hash              [32]byte
+		PrevHash          [32]byte
+		PrevHashWithoutTx [32]byte
+		Beneficiary       [32]byte
+	}{
+		header.Height,
+		header.Hash,
+		header.PrevHash,
+		header.PrevHashWithoutTx,
+		header.Beneficiary,
+	}
+
+	return fmt.Sprintf("%x", SerializeHashContent(signedHash))
+}
+
+//EvidenceTx proves that the validator Offender misbehaved by means of two of its signed headers. Anyone can submit
+//evidence, the sender (From) receives the slashing reward and the offender looses its locked stake. TxCnt refers to
+//the sender account.
+type EvidenceTx struct {
+	Header   byte
+	Fee      uint64
+	TxCnt    uint32
+	From     [32]byte
+	Offender [32]byte
+	Header1  SignedHeader
+	Header2  SignedHeader
+	Sig      [64]byte
+}
+
+func ConstrEvidenceTx(header byte, fee uint64, txCnt uint32, from [32]byte, header1, header2 SignedHeader, sigKey ed25519.PrivateKey) (tx *EvidenceTx, err error) {
+	if header != EVIDENCE_DOUBLE_PROPOSAL && header != EVIDENCE_CONFLICTING_CHAINS {
+		return nil, errors.New(fmt.Sprintf("Invalid offence: %v", header))
+	}
+
+	tx = new(EvidenceTx)
+	tx.Header = header
+	tx.Fee = fee
+	tx.TxCnt = txCnt
+	tx.From = from
+	tx.Offender = header1.Beneficiary
+	tx.Header1 = header1
+	tx.Header2 = header2
+
+	txHash := tx.Hash()
+	copy(tx.Sig[:], ed25519.Sign(sigKey, txHash[:]))
+
+	return tx, nil
+}
+
+func (tx *EvidenceTx) Hash() [32]byte {
+	if tx == nil {
+		return [32]byte{}
+	}
+
+	txHash := struct {
+		Header   byte
+		Fee      uint64
+		TxCnt    uint32
+		From     [32]byte
+		Offender [32]byte
+		Header1  SignedHeader
+		Header2  SignedHeader
+	}{
+		tx.Header,
+		tx.Fee,
+		tx.TxCnt,
+		tx.From,
+		tx.Offender,
+		tx.Header1,
+		tx.Header2,
+	}
+
+	return SerializeHashContent(txHash)
+}
+
+//Identifies the offence independently of the sender and the order of the headers, such that the same misbehaviour
+//can only be punished once.
+func (tx *EvidenceTx) EvidenceID() [32]byte {
+	hash1, hash2 := tx.Header1.Hash, tx.Header2.Hash
+	if bytes.Compare(hash1[:], hash2[:]) > 0 {
+		hash1, hash2 = hash2, hash1
+	}
+
+	evidence := struct {
+		Offender [32]byte
+		Hash1    [32]byte
+		Hash2    [32]byte
+	}{
+		tx.Offender,
+		hash1,
+		hash2,
+	}
+
+	return SerializeHashContent(evidence)
+}
+
+func (tx *EvidenceTx) Encode() []byte {
+	if tx == nil {
+		return nil
+	}
+
+	encoded := EvidenceTx{
+		Header:   tx.Header,
+		Fee:      tx.Fee,
+		TxCnt:    tx.TxCnt,
+		From:     tx.From,
+		Offender: tx.Offender,
+		Header1:  tx.Header1,
+		Header2:  tx.Header2,
+		Sig:      tx.Sig,
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(encoded)
+	return buffer.Bytes()
+}
+
+func (*EvidenceTx) Decode(encoded []byte) (tx *EvidenceTx) {
+	var decoded EvidenceTx
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	decoder.Decode(&decoded)
+	return &decoded
+}
+
+func (tx *EvidenceTx) TxFee() uint64 { return tx.Fee }
+func (tx *EvidenceTx) Size() uint64  { return uint64(unsafe.Sizeof(*tx)) }
+
+func (tx *EvidenceTx) Sender() [32]byte   { return tx.From }
+func (tx *EvidenceTx) Receiver() [32]byte { return tx.Offender }
+
+func (tx EvidenceTx) String() string {
+	return fmt.Sprintf(
+		"\n"+
+			"Header: %v\n"+
+			"Fee: %v\n"+
+			"TxCnt: %v\n"+
+			"From: %x\n"+
+			"Offender: %x\n"+
+			"Block 1: %x (height %v)\n"+
+			"Block 2: %x (height %v)\n"+
+			"Sig: %x\n",
+		tx.Header,
+		tx.Fee,
+		tx.TxCnt,
+		tx.From[0:8],
+		tx.Offender[0:8],
+		tx.Header1.Hash[0:8], tx.Header1.Height,
+		tx.Header2.Hash[0:8], tx.Header2.Height,
+		tx.Sig[0:8],
+	)
+}
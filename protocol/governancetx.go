@@ -0,0 +1,212 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+	"unsafe"
+)
+
+const (
+	//Proposes Value for the system parameter ParameterId, to be activated at ActivationHeight
+	GOVERNANCE_PROPOSAL = 1
+	//Votes for the proposal with the hash ProposalHash
+	GOVERNANCE_VOTE = 2
+)
+
+//GovernanceTx changes system parameters instead of the root key, which can't send ConfigTxs anymore once validators
+//locked stake. Validators propose a new parameter value and vote for proposals with their locked stake, the proposal
+//of a validator counts as its vote. Once a quorum of the locked stake voted for a proposal, it is activated at its
+//activation height. TxCnt refers to the sender account.
+type GovernanceTx struct {
+	Header           byte
+	Fee              uint64
+	TxCnt            uint32
+	From             [32]byte
+	ProposalHash     [32]byte //only set for votes
+	ParameterId      uint8
+	Value            uint64
+	ActivationHeight uint32
+	Sig              [64]byte
+}
+
+func ConstrGovernanceProposal(fee uint64, txCnt uint32, from [32]byte, parameterId uint8, value uint64, activationHeight uint32, sigKey ed25519.PrivateKey) (tx *GovernanceTx, err error) {
+	tx = new(GovernanceTx)
+	tx.Header = GOVERNANCE_PROPOSAL
+	tx.Fee = fee
+	tx.TxCnt = txCnt
+	tx.From = from
+	tx.ParameterId = parameterId
+	tx.Value = value
+	tx.ActivationHeight = activationHeight
+
+	txHash := tx.Hash()
+	copy(tx.Sig[:], ed25519.Sign(sigKey, txHash[:]))
+
+	return tx, nil
+}
+
+func ConstrGovernanceVote(fee uint64, txCnt uint32, from [32]byte, proposalHash [32]byte, sigKey ed25519.PrivateKey) (tx *GovernanceTx, err error) {
+	if proposalHash == [32]byte{} {
+		return nil, errors.New("Votes need the hash of a proposal.")
+	}
+
+	tx = new(GovernanceTx)
+	tx.Header = GOVERNANCE_VOTE
+	tx.Fee = fee
+	tx.TxCnt = txCnt
+	tx.From = from
+	tx.ProposalHash = proposalHash
+
+	txHash := tx.Hash()
+	copy(tx.Sig[:], ed25519.Sign(sigKey, txHash[:]))
+
+	return tx, nil
+}
+
+func (tx *GovernanceTx) Hash() [32]byte {
+	if tx == nil {
+		return [32]byte{}
+	}
+
+	txHash := struct {
+		Header           byte
+		Fee              uint64
+		TxCnt            uint32
+		From             [32]byte
+		ProposalHash     [32]byte
+		ParameterId      uint8
+		Value            uint64
+		ActivationHeight uint32
+	}{
+		tx.Header,
+		tx.Fee,
+		tx.TxCnt,
+		tx.From,
+		tx.ProposalHash,
+		tx.ParameterId,
+		tx.Value,
+		tx.ActivationHeight,
+	}
+
+	return SerializeHashContent(txHash)
+}
+
+func (tx *GovernanceTx) Encode() []byte {
+	if tx == nil {
+		return nil
+	}
+
+	encoded := GovernanceTx{
+		Header:           tx.Header,
+		Fee:              tx.Fee,
+		TxCnt:            tx.TxCnt,
+		From:             tx.From,
+		ProposalHash:     tx.ProposalHash,
+		ParameterId:      tx.ParameterId,
+		Value:            tx.Value,
+		ActivationHeight: tx.ActivationHeight,
+		Sig:              tx.Sig,
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(encoded)
+	return buffer.Bytes()
+}
+
+func (*GovernanceTx) Decode(encoded []byte) (tx *GovernanceTx) {
+	var decoded GovernanceTx
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	decoder.Decode(&decoded)
+	return &decoded
+}
+
+func (tx *GovernanceTx) TxFee() uint64 { return tx.Fee }
+func (tx *GovernanceTx) Size() uint64  { return uint64(unsafe.Sizeof(*tx)) }
+
+func (tx *GovernanceTx) Sender() [32]byte   { return tx.From }
+func (tx *GovernanceTx) Receiver() [32]byte { return [32]byte{} }
+
+func (tx GovernanceTx) String() string {
+	return fmt.Sprintf(
+		"\n"+
+			"Header: %v\n"+
+			"Fee: %v\n"+
+			"TxCnt: %v\n"+
+			"From: %x\n"+
+			"Proposal: %x\n"+
+			"Parameter: %v\n"+
+			"Value: %v\n"+
+			"Activation height: %v\n"+
+			"Sig: %x\n",
+		tx.Header,
+		tx.Fee,
+		tx.TxCnt,
+		tx.From[0:8],
+		tx.ProposalHash[0:8],
+		tx.ParameterId,
+		tx.Value,
+		tx.ActivationHeight,
+		tx.Sig[0:8],
+	)
+}
+
+//Vote of a validator with its locked stake when it voted. The tally counts the stake the voter has locked now.
+type ProposalVote struct {
+	Voter [32]byte
+	Stake uint64
+}
+
+//State of a proposal, keyed by the hash of the proposing GovernanceTx. Tally is the stake of the voters at the last
+//vote. AcceptedBy is the hash of the tx which reached the quorum, such that its rollback can revoke the acceptance
+//again. An accepted proposal is only activated if its voters still hold the quorum at its activation height.
+type Proposal struct {
+	Proposer         [32]byte
+	ParameterId      uint8
+	Value            uint64
+	ActivationHeight uint32
+	Votes            []ProposalVote
+	Tally            uint64
+	AcceptedBy       [32]byte
+}
+
+func (proposal *Proposal) IsAccepted() bool {
+	return proposal.AcceptedBy != [32]byte{}
+}
+
+func (proposal *Proposal) HasVoted(voter [32]byte) bool {
+	for _, vote := range proposal.Votes {
+		if vote.Voter == voter {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (proposal *Proposal) Encode() []byte {
+	if proposal == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(proposal)
+	return buffer.Bytes()
+}
+
+func (*Proposal) Decode(encoded []byte) (proposal *Proposal) {
+	var decoded Proposal
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	decoder.Decode(&decoded)
+	return &decoded
+}
+
+func (proposal Proposal) String() string {
+	return fmt.Sprintf("Proposer: %x, Parameter: %v, Value: %v, Activation height: %v, Votes: %v, Tally: %v, Accepted: %v",
+		proposal.Proposer[0:8], proposal.ParameterId, proposal.Value, proposal.ActivationHeight, len(proposal.Votes),
+		proposal.Tally, proposal.IsAccepted())
+}
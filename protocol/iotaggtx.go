@@ -0,0 +1,104 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+//IotAggTx is built by the miner and replaces the hashes of all IotBatchTxs of one device in a block, like AggTx does
+//for fundsTxs. It is not signed, every aggregated batch still carries the signature and the fee of the device. The
+//hash is the Merkle root of the aggregated batch hashes, such that a batch can be proven to be part of the block.
+type IotAggTx struct {
+	From              [32]byte
+	AggregatedTxSlice [][32]byte
+}
+
+func ConstrIotAggTx(from [32]byte, transactions [][32]byte) *IotAggTx {
+	return &IotAggTx{
+		From:              from,
+		AggregatedTxSlice: transactions,
+	}
+}
+
+//Returns the intermediate nodes (sibling, parent, ...) from the aggregated batch up to the hash of the IotAggTx.
+func (tx *IotAggTx) BatchProof(txHash [32]byte) (proof [][32]byte, err error) {
+	tree, err := newTree(tx.AggregatedTxSlice)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := GetLeaf(tree, txHash)
+	if leaf == nil {
+		return nil, errors.New(fmt.Sprintf("IoT batch %x is not aggregated.", txHash[0:8]))
+	}
+	intermediates, err := GetIntermediate(leaf)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range intermediates {
+		proof = append(proof, node.Hash)
+	}
+
+	return proof, nil
+}
+
+func (tx *IotAggTx) Hash() [32]byte {
+	if tx == nil {
+		return [32]byte{}
+	}
+
+	tree, err := newTree(tx.AggregatedTxSlice)
+	if err != nil {
+		return [32]byte{}
+	}
+
+	return tree.MerkleRoot()
+}
+
+func (tx *IotAggTx) Encode() []byte {
+	if tx == nil {
+		return nil
+	}
+
+	encoded := IotAggTx{
+		From:              tx.From,
+		AggregatedTxSlice: tx.AggregatedTxSlice,
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(encoded)
+	return buffer.Bytes()
+}
+
+func (*IotAggTx) Decode(encoded []byte) (tx *IotAggTx) {
+	var decoded IotAggTx
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	decoder.Decode(&decoded)
+	return &decoded
+}
+
+//The fees are paid by the aggregated batches.
+func (tx *IotAggTx) TxFee() uint64 { return 0 }
+
+func (tx *IotAggTx) Size() uint64 {
+	return uint64(unsafe.Sizeof(*tx)) + uint64(len(tx.AggregatedTxSlice)*HASH_LEN)
+}
+
+func (tx *IotAggTx) Sender() [32]byte   { return tx.From }
+func (tx *IotAggTx) Receiver() [32]byte { return [32]byte{} }
+
+func (tx IotAggTx) String() string {
+	return fmt.Sprintf(
+		"\n"+
+			"From: %x\n"+
+			"Transactions: %x\n"+
+			"#Tx: %v\n",
+		tx.From[0:8],
+		tx.AggregatedTxSlice,
+		len(tx.AggregatedTxSlice),
+	)
+}
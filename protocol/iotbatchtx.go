@@ -0,0 +1,220 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ed25519"
+)
+
+const (
+	MAX_IOT_BATCH_READINGS = 1000
+)
+
+//A single timestamped sensor reading inside an IotBatchTx.
+type IotBatchReading struct {
+	Timestamp int64
+	Data      []byte
+}
+
+//IotBatchTx packs many readings of one device under one signature and one TxCnt. The tx hash only commits to the
+//Merkle root of the readings, such that every reading can be proven to be part of the batch on its own.
+type IotBatchTx struct {
+	Header       byte
+	Fee          uint64
+	TxCnt        uint32
+	From         [32]byte
+	To           [32]byte
+	ReadingsRoot [32]byte
+	Sig          [64]byte
+	Readings     []IotBatchReading
+}
+
+func ConstrIotBatchTx(header byte, fee uint64, txCnt uint32, from, to [32]byte, readings []IotBatchReading, sigKey ed25519.PrivateKey) (tx *IotBatchTx, err error) {
+	tx = new(IotBatchTx)
+	tx.Header = header
+	tx.Fee = fee
+	tx.TxCnt = txCnt
+	tx.From = from
+	tx.To = to
+	tx.Readings = readings
+
+	if err := tx.CheckReadings(); err != nil {
+		return nil, err
+	}
+	tx.ReadingsRoot = tx.CalcReadingsRoot()
+
+	txHash := tx.Hash()
+	copy(tx.Sig[:], ed25519.Sign(sigKey, txHash[:]))
+
+	return tx, nil
+}
+
+//Readings have to be present, within the batch limit and in strictly increasing time order.
+func (tx *IotBatchTx) CheckReadings() error {
+	if len(tx.Readings) == 0 || len(tx.Readings) > MAX_IOT_BATCH_READINGS {
+		return errors.New(fmt.Sprintf("Invalid number of readings in IoT batch: %v (maximum is: %v)", len(tx.Readings), MAX_IOT_BATCH_READINGS))
+	}
+
+	for i := 1; i < len(tx.Readings); i++ {
+		if tx.Readings[i].Timestamp <= tx.Readings[i-1].Timestamp {
+			return errors.New(fmt.Sprintf("IoT batch readings not in time order at index %v.", i))
+		}
+	}
+
+	return nil
+}
+
+//The hash of a reading includes the sender and its index, such that equal readings still have distinct hashes.
+func (tx *IotBatchTx) ReadingHash(index uint32, reading IotBatchReading) [32]byte {
+	readingHash := struct {
+		From      [32]byte
+		Index     uint32
+		Timestamp int64
+		Data      []byte
+	}{
+		tx.From,
+		index,
+		reading.Timestamp,
+		reading.Data,
+	}
+
+	return SerializeHashContent(readingHash)
+}
+
+func (tx *IotBatchTx) readingsTree() (*MerkleTree, error) {
+	var readingHashes [][32]byte
+	for i, reading := range tx.Readings {
+		readingHashes = append(readingHashes, tx.ReadingHash(uint32(i), reading))
+	}
+
+	return newTree(readingHashes)
+}
+
+func (tx *IotBatchTx) CalcReadingsRoot() [32]byte {
+	tree, err := tx.readingsTree()
+	if err != nil {
+		return [32]byte{}
+	}
+
+	return tree.MerkleRoot()
+}
+
+//Returns the intermediate nodes (sibling, parent, ...) from the reading at index up to the ReadingsRoot.
+func (tx *IotBatchTx) ReadingProof(index uint32) (proof [][32]byte, err error) {
+	if int(index) >= len(tx.Readings) {
+		return nil, errors.New(fmt.Sprintf("IoT batch has no reading at index %v.", index))
+	}
+
+	tree, err := tx.readingsTree()
+	if err != nil {
+		return nil, err
+	}
+
+	intermediates, err := GetIntermediate(tree.Leafs[index])
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range intermediates {
+		proof = append(proof, node.Hash)
+	}
+
+	return proof, nil
+}
+
+//Copy of the tx without the readings. It has the same hash and signature as the full tx.
+func (tx *IotBatchTx) HeaderOnly() *IotBatchTx {
+	return &IotBatchTx{
+		Header:       tx.Header,
+		Fee:          tx.Fee,
+		TxCnt:        tx.TxCnt,
+		From:         tx.From,
+		To:           tx.To,
+		ReadingsRoot: tx.ReadingsRoot,
+		Sig:          tx.Sig,
+	}
+}
+
+func (tx *IotBatchTx) Hash() [32]byte {
+	if tx == nil {
+		return [32]byte{}
+	}
+
+	txHash := struct {
+		Header       byte
+		Fee          uint64
+		TxCnt        uint32
+		From         [32]byte
+		To           [32]byte
+		ReadingsRoot [32]byte
+	}{
+		tx.Header,
+		tx.Fee,
+		tx.TxCnt,
+		tx.From,
+		tx.To,
+		tx.ReadingsRoot,
+	}
+
+	return SerializeHashContent(txHash)
+}
+
+func (tx *IotBatchTx) Encode() []byte {
+	if tx == nil {
+		return nil
+	}
+
+	encoded := IotBatchTx{
+		Header:       tx.Header,
+		Fee:          tx.Fee,
+		TxCnt:        tx.TxCnt,
+		From:         tx.From,
+		To:           tx.To,
+		ReadingsRoot: tx.ReadingsRoot,
+		Sig:          tx.Sig,
+		Readings:     tx.Readings,
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(encoded)
+	return buffer.Bytes()
+}
+
+func (*IotBatchTx) Decode(encoded []byte) (tx *IotBatchTx) {
+	var decoded IotBatchTx
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	decoder.Decode(&decoded)
+	return &decoded
+}
+
+func (tx *IotBatchTx) TxFee() uint64 { return tx.Fee }
+
+//The readings are part of the block, therefore the encoded size is counted against the block size.
+func (tx *IotBatchTx) Size() uint64 { return uint64(len(tx.Encode())) }
+
+func (tx *IotBatchTx) Sender() [32]byte   { return tx.From }
+func (tx *IotBatchTx) Receiver() [32]byte { return tx.To }
+
+func (tx IotBatchTx) String() string {
+	return fmt.Sprintf(
+		"\n"+
+			"Header: %v\n"+
+			"Fee: %v\n"+
+			"TxCnt: %v\n"+
+			"From: %x\n"+
+			"To: %x\n"+
+			"ReadingsRoot: %x\n"+
+			"Sig: %x\n"+
+			"Readings: %v\n",
+		tx.Header,
+		tx.Fee,
+		tx.TxCnt,
+		tx.From[0:8],
+		tx.To[0:8],
+		tx.ReadingsRoot[0:8],
+		tx.Sig[0:8],
+		len(tx.Readings),
+	)
+}
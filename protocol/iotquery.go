@@ -0,0 +1,148 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	IOT_QUERY_MAX_LIMIT = 100
+)
+
+//Asks for the IoT readings of a device within the time range [From, To] (inclusive). Single IotTxs are found by
+//their block timestamp, readings of an IotBatchTx by their own timestamp. If ByReceiver is set, the readings sent to
+//Account are returned instead of the ones sent by it. At most Limit readings are returned, the next ones are asked
+//for with the Cursor of the result.
+type IotQuery struct {
+	Account    [32]byte
+	ByReceiver bool
+	From       int64
+	To         int64
+	Cursor     []byte
+	Limit      uint32
+	WithProof  bool
+}
+
+//A single IoT reading together with the block it was included in. If requested, Proof contains the
+//intermediate nodes (sibling, parent, sibling, parent, ...) from the tx hash up to MerkleRoot.
+//Readings of a batch come with the batch without its other readings and ReadingProof from the reading up to
+//the ReadingsRoot of the batch. If the batch was aggregated, Proof leads over the hash of its IotAggTx.
+type IotReading struct {
+	Tx           *IotTx
+	BatchTx      *IotBatchTx
+	ReadingIndex uint32
+	Reading      *IotBatchReading
+	ReadingProof [][32]byte
+	BlockHash    [32]byte
+	Height       uint32
+	Timestamp    int64
+	MerkleRoot   [32]byte
+	Proof        [][32]byte
+}
+
+//Cursor is only set if there are more readings in the time range of the query.
+type IotQueryResult struct {
+	Readings []*IotReading
+	Cursor   []byte
+}
+
+func (query *IotQuery) Encode() []byte {
+	if query == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(query)
+	return buffer.Bytes()
+}
+
+func (*IotQuery) Decode(encoded []byte) (query *IotQuery) {
+	var decoded IotQuery
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+func (result *IotQueryResult) Encode() []byte {
+	if result == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(result)
+	return buffer.Bytes()
+}
+
+func (*IotQueryResult) Decode(encoded []byte) (result *IotQueryResult) {
+	var decoded IotQueryResult
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+//Recomputes the path from the reading's tx hash up to the Merkle root of its block. For batch readings, the path
+//from the reading up to the readings root of the batch is checked first.
+func (reading *IotReading) VerifyProof() bool {
+	if reading == nil {
+		return false
+	}
+
+	var txHash [32]byte
+	if reading.BatchTx != nil && reading.Reading != nil {
+		readingHash := reading.BatchTx.ReadingHash(reading.ReadingIndex, *reading.Reading)
+		if !verifyMerklePath(readingHash, reading.ReadingProof, reading.BatchTx.ReadingsRoot) {
+			return false
+		}
+		txHash = reading.BatchTx.Hash()
+	} else if reading.Tx != nil {
+		txHash = reading.Tx.Hash()
+	} else {
+		return false
+	}
+
+	return verifyMerklePath(txHash, reading.Proof, reading.MerkleRoot)
+}
+
+func verifyMerklePath(leaf [32]byte, path [][32]byte, root [32]byte) bool {
+	if len(path)%2 != 0 {
+		return false
+	}
+
+	current := leaf
+	for i := 0; i < len(path); i += 2 {
+		sibling, parent := path[i], path[i+1]
+		if sha3.Sum256(append(current[:], sibling[:]...)) != parent && sha3.Sum256(append(sibling[:], current[:]...)) != parent {
+			return false
+		}
+		current = parent
+	}
+
+	return current == root
+}
+
+func (query IotQuery) String() string {
+	return fmt.Sprintf(
+		"\nAccount: %x\n"+
+			"ByReceiver: %v\n"+
+			"From: %v\n"+
+			"To: %v\n"+
+			"Cursor: %x\n"+
+			"Limit: %v\n"+
+			"WithProof: %v\n",
+		query.Account[0:8],
+		query.ByReceiver,
+		query.From,
+		query.To,
+		query.Cursor,
+		query.Limit,
+		query.WithProof,
+	)
+}
@@ -5,11 +5,17 @@ import (
 	"encoding/binary"
 	"encoding/gob"
 	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/sha3"
 	"unsafe"
 )
 
+const (
+	//Header flag, Data is encrypted to the receiver's account key and only readable by the receiver
+	IOT_ENCRYPTED = 0x80
+)
+
 //when we broadcast transactions we need a way to distinguish with a type
 
 type IotTx struct {
@@ -25,7 +31,7 @@ type IotTx struct {
 func ConstrIotTx(header byte, fee uint64, txCnt uint32, from, to [32]byte, sigKey ed25519.PrivateKey, data []byte) (tx *IotTx, err error) {
 	tx = new(IotTx)
 	tx.Header = header
-	tx.Fee = tx.TxFee()
+	tx.Fee = fee
 	tx.From = from
 	tx.To = to
 	tx.TxCnt = txCnt
@@ -41,6 +47,21 @@ func ConstrIotTx(header byte, fee uint64, txCnt uint32, from, to [32]byte, sigKe
 	return tx, nil
 }
 
+//Encrypts data to receiverAddress and signs the ciphertext. Miners can verify the tx without being able to read the
+//data, the receiver decrypts it with crypto.DecryptIoTData.
+func ConstrEncryptedIotTx(header byte, fee uint64, txCnt uint32, from, to [32]byte, receiverAddress [32]byte, sigKey ed25519.PrivateKey, data []byte) (tx *IotTx, err error) {
+	encrypted, err := crypto.EncryptIoTData(receiverAddress, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return ConstrIotTx(header|IOT_ENCRYPTED, fee, txCnt, from, to, sigKey, encrypted)
+}
+
+func (tx *IotTx) IsEncrypted() bool {
+	return tx.Header&IOT_ENCRYPTED == IOT_ENCRYPTED
+}
+
 func (tx *IotTx) Hash() (hash [32]byte) {
 	if tx == nil {
 		//is returning nil better?
@@ -77,6 +77,36 @@ func BuildMerkleTree(b *Block) *MerkleTree {
 			txHashes = append(txHashes, txHash)
 		}
 	}
+	if b.ContractTxData != nil {
+		for _, txHash := range b.ContractTxData {
+			txHashes = append(txHashes, txHash)
+		}
+	}
+	if b.IoTBatchTxData != nil {
+		for _, txHash := range b.IoTBatchTxData {
+			txHashes = append(txHashes, txHash)
+		}
+	}
+	if b.IoTAggTxData != nil {
+		for _, txHash := range b.IoTAggTxData {
+			txHashes = append(txHashes, txHash)
+		}
+	}
+	if b.DelegateTxData != nil {
+		for _, txHash := range b.DelegateTxData {
+			txHashes = append(txHashes, txHash)
+		}
+	}
+	if b.EvidenceTxData != nil {
+		for _, txHash := range b.EvidenceTxData {
+			txHashes = append(txHashes, txHash)
+		}
+	}
+	if b.GovernanceTxData != nil {
+		for _, txHash := range b.GovernanceTxData {
+			txHashes = append(txHashes, txHash)
+		}
+	}
 
 	//Merkle root for no transactions is 0 hash
 	if len(txHashes) == 0 {
@@ -0,0 +1,68 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+//Change of a system parameter which is in force from ActivationHeight on. It is scheduled either by a ConfigTx or by
+//an accepted governance proposal, Source is the hash of the tx or the proposal respectively.
+type ParameterChange struct {
+	Id               uint8
+	Value            uint64
+	ActivationHeight uint32
+	Source           [32]byte
+	Proposal         bool
+}
+
+//Parameter changes which are not in force yet at the height after the last block, ordered by activation height.
+type ParameterSchedule struct {
+	Height  uint32
+	Changes []*ParameterChange
+}
+
+func (change *ParameterChange) Encode() []byte {
+	if change == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(change)
+	return buffer.Bytes()
+}
+
+func (*ParameterChange) Decode(encoded []byte) (change *ParameterChange) {
+	var decoded ParameterChange
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+func (change ParameterChange) String() string {
+	return fmt.Sprintf("Parameter: %v, Value: %v, Activation height: %v, Source: %x, Proposal: %v",
+		change.Id, change.Value, change.ActivationHeight, change.Source[0:8], change.Proposal)
+}
+
+func (schedule *ParameterSchedule) Encode() []byte {
+	if schedule == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(schedule)
+	return buffer.Bytes()
+}
+
+func (*ParameterSchedule) Decode(encoded []byte) (schedule *ParameterSchedule) {
+	var decoded ParameterSchedule
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
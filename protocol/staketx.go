@@ -9,7 +9,13 @@ import (
 )
 
 const (
-	STAKETX_SIZE = 106 + crypto.COMM_KEY_LENGTH
+	STAKETX_SIZE = 107 + crypto.COMM_KEY_LENGTH
+
+	//Upper bound of the commission of a validator, in percent
+	MAX_COMMISSION = 100
+
+	//Header bit of a StakeTx (with IsStaking false) which withdraws the stake after the unbonding period
+	STAKETX_WITHDRAW = 0x02
 )
 
 //when we broadcast transactions we need a way to distinguish with a type
@@ -21,15 +27,17 @@ type StakeTx struct {
 	Account       [32]byte              // 32 Byte
 	Sig           [64]byte              // 64 Byte
 	CommitmentKey [crypto.COMM_KEY_LENGTH]byte // the modulus N of the RSA public key
+	Commission    uint8                 // 1 Byte, percent of the delegators' rewards kept by the validator
 }
 
-func ConstrStakeTx(header byte, fee uint64, isStaking bool, account [32]byte, signKey ed25519.PrivateKey, commPubKey *rsa.PublicKey) (tx *StakeTx, err error) {
+func ConstrStakeTx(header byte, fee uint64, isStaking bool, commission uint8, account [32]byte, signKey ed25519.PrivateKey, commPubKey *rsa.PublicKey) (tx *StakeTx, err error) {
 
 	tx = new(StakeTx)
 
 	tx.Header = header
 	tx.Fee = fee
 	tx.IsStaking = isStaking
+	tx.Commission = commission
 	tx.Account = account
 
 	copy(tx.CommitmentKey[:], commPubKey.N.Bytes())
@@ -55,12 +63,14 @@ func (tx *StakeTx) Hash() (hash [32]byte) {
 		IsStaking  bool
 		Account    [32]byte
 		CommKey    [crypto.COMM_KEY_LENGTH]byte
+		Commission uint8
 	}{
 		tx.Header,
 		tx.Fee,
 		tx.IsStaking,
 		tx.Account,
 		tx.CommitmentKey,
+		tx.Commission,
 	}
 
 	return SerializeHashContent(txHash)
@@ -92,6 +102,7 @@ func (tx *StakeTx) Encode() (encodedTx []byte) {
 	copy(encodedTx[10:42], tx.Account[:])
 	copy(encodedTx[42:106], tx.Sig[:])
 	copy(encodedTx[106:106+crypto.COMM_KEY_LENGTH], tx.CommitmentKey[:])
+	encodedTx[106+crypto.COMM_KEY_LENGTH] = tx.Commission
 
 	return encodedTx
 }
@@ -111,6 +122,7 @@ func (*StakeTx) Decode(encodedTx []byte) (tx *StakeTx) {
 	copy(tx.Account[:], encodedTx[10:42])
 	copy(tx.Sig[:], encodedTx[42:106])
 	copy(tx.CommitmentKey[:], encodedTx[106:106+crypto.COMM_KEY_LENGTH])
+	tx.Commission = encodedTx[106+crypto.COMM_KEY_LENGTH]
 
 	if isStakingAsByte == 0 {
 		tx.IsStaking = false
@@ -133,12 +145,14 @@ func (tx StakeTx) String() string {
 			"IsStaking: %v\n"+
 			"Account: %x\n"+
 			"Sig: %x\n"+
-			"CommitmentKey: %x\n",
+			"CommitmentKey: %x\n"+
+			"Commission: %v\n",
 		tx.Header,
 		tx.Fee,
 		tx.IsStaking,
 		tx.Account[0:8],
 		tx.Sig[0:8],
 		tx.CommitmentKey[0:8],
+		tx.Commission,
 	)
 }
@@ -0,0 +1,55 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+//Coins in existence after a block. Minted coins are paid out as block rewards, issued coins are created by the root
+//accounts (their genesis balance, coins they send and fees paid for root txs) and as slashing rewards. Burned coins
+//are the stakes destroyed by slashing. Block rewards are capped such that the total never exceeds MaxSupply.
+type Supply struct {
+	Minted    uint64
+	Issued    uint64
+	Burned    uint64
+	MaxSupply uint64
+}
+
+//Circulating supply
+func (supply Supply) Total() uint64 {
+	return supply.Minted + supply.Issued - supply.Burned
+}
+
+//Adds the coins created and destroyed by change, the maximum supply is kept.
+func (supply Supply) Add(change Supply) Supply {
+	supply.Minted += change.Minted
+	supply.Issued += change.Issued
+	supply.Burned += change.Burned
+	return supply
+}
+
+func (supply *Supply) Encode() []byte {
+	if supply == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(supply)
+	return buffer.Bytes()
+}
+
+func (*Supply) Decode(encoded []byte) (supply *Supply) {
+	var decoded Supply
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+func (supply Supply) String() string {
+	return fmt.Sprintf("Total: %v, Minted: %v, Issued: %v, Burned: %v, Max supply: %v",
+		supply.Total(), supply.Minted, supply.Issued, supply.Burned, supply.MaxSupply)
+}
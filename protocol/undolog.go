@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+)
+
+//Operations which change the consensus related fields of an account
+const (
+	UNDO_STAKETX         = 1
+	UNDO_EVIDENCETX      = 2
+	UNDO_SLASH           = 3
+	UNDO_STAKING_HEIGHT  = 4
+	UNDO_STAKING_MINIMUM = 5
+)
+
+//Consensus related fields of an account, i.e. everything which decides if and with what stake it validates.
+type StakeState struct {
+	IsStaking          bool
+	CommitmentKey      [crypto.COMM_KEY_LENGTH]byte
+	StakingBlockHeight uint32
+	Commission         uint8
+	StakedAmount       uint64
+	UnbondingAmount    uint64
+	UnbondingHeight    uint32
+}
+
+//The state of an account before it was changed by Op.
+type UndoEntry struct {
+	Op      uint8
+	Account [32]byte
+	Before  StakeState
+}
+
+//All consensus related account changes of a block, in the order they were applied. Rolling back the block replays
+//the log in reverse order.
+type UndoLog struct {
+	Entries []UndoEntry
+}
+
+func (acc *Account) StakeState() StakeState {
+	return StakeState{
+		IsStaking:          acc.IsStaking,
+		CommitmentKey:      acc.CommitmentKey,
+		StakingBlockHeight: acc.StakingBlockHeight,
+		Commission:         acc.Commission,
+		StakedAmount:       acc.StakedAmount,
+		UnbondingAmount:    acc.UnbondingAmount,
+		UnbondingHeight:    acc.UnbondingHeight,
+	}
+}
+
+func (acc *Account) RestoreStakeState(state StakeState) {
+	acc.IsStaking = state.IsStaking
+	acc.CommitmentKey = state.CommitmentKey
+	acc.StakingBlockHeight = state.StakingBlockHeight
+	acc.Commission = state.Commission
+	acc.StakedAmount = state.StakedAmount
+	acc.UnbondingAmount = state.UnbondingAmount
+	acc.UnbondingHeight = state.UnbondingHeight
+}
+
+func (log *UndoLog) Encode() []byte {
+	if log == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(log)
+	return buffer.Bytes()
+}
+
+func (*UndoLog) Decode(encoded []byte) (log *UndoLog) {
+	var decoded UndoLog
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	decoder.Decode(&decoded)
+	return &decoded
+}
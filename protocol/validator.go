@@ -0,0 +1,93 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+)
+
+const (
+	//Staking, but the waiting minimum is not over yet
+	VALIDATOR_WAITING = iota
+	VALIDATOR_ACTIVE
+	//Left the validator set, the stake might still be unbonding
+	VALIDATOR_INACTIVE
+	//Lost the locked stake for an offence and did not stake again since
+	VALIDATOR_SLASHED
+)
+
+//Expected blocks are counted in millionths of a block
+const EXPECTED_BLOCKS_PRECISION = 1000000
+
+//Entry of the validator registry. Stake is the stake the validator takes part in the PoS with. ExpectedBlocks is the
+//number of blocks it should have produced while active according to its share of the stake.
+type ValidatorInfo struct {
+	Account            [32]byte
+	CommitmentKey      [crypto.COMM_KEY_LENGTH]byte
+	Status             uint8
+	Stake              uint64
+	StakingBlockHeight uint32
+	BlocksProduced     uint32
+	LastProducedHeight uint32
+	ExpectedBlocks     uint64
+	MissedSlots        uint32
+}
+
+//Validators after the block with the given height, ordered by account.
+type ValidatorSet struct {
+	Height     uint32
+	Validators []*ValidatorInfo
+}
+
+func (set *ValidatorSet) Get(account [32]byte) *ValidatorInfo {
+	for _, validator := range set.Validators {
+		if validator.Account == account {
+			return validator
+		}
+	}
+
+	return nil
+}
+
+func (set *ValidatorSet) Encode() []byte {
+	if set == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(set)
+	return buffer.Bytes()
+}
+
+func (*ValidatorSet) Decode(encoded []byte) (set *ValidatorSet) {
+	var decoded ValidatorSet
+	buffer := bytes.NewBuffer(encoded)
+	decoder := gob.NewDecoder(buffer)
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+func StatusName(status uint8) string {
+	switch status {
+	case VALIDATOR_WAITING:
+		return "waiting"
+	case VALIDATOR_ACTIVE:
+		return "active"
+	case VALIDATOR_INACTIVE:
+		return "inactive"
+	case VALIDATOR_SLASHED:
+		return "slashed"
+	}
+
+	return "unknown"
+}
+
+func (validator ValidatorInfo) String() string {
+	return fmt.Sprintf("Account: %x, Status: %v, CommitmentKey: %x, Stake: %v, StakingBlockHeight: %v, "+
+		"BlocksProduced: %v, LastProducedHeight: %v, MissedSlots: %v",
+		validator.Account[0:8], StatusName(validator.Status), validator.CommitmentKey[0:8], validator.Stake,
+		validator.StakingBlockHeight, validator.BlocksProduced, validator.LastProducedHeight, validator.MissedSlots)
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"encoding/binary"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/boltdb/bolt"
 )
@@ -86,6 +87,18 @@ func DeleteClosedTx(transaction protocol.Transaction) {
 		bucket = "closedaggregations"
 	case *protocol.IotTx:
 		bucket = "closediotts"
+	case *protocol.ContractTx:
+		bucket = "closedcontracts"
+	case *protocol.IotBatchTx:
+		bucket = "closediotbatches"
+	case *protocol.IotAggTx:
+		bucket = "closediotaggregations"
+	case *protocol.DelegateTx:
+		bucket = "closeddelegations"
+	case *protocol.EvidenceTx:
+		bucket = "closedevidences"
+	case *protocol.GovernanceTx:
+		bucket = "closedgovernance"
 	}
 
 	hash := transaction.Hash()
@@ -100,6 +113,120 @@ func DeleteClosedTx(transaction protocol.Transaction) {
 	averageTxSize = totalTransactionSize/nrClosedTransactions
 }
 
+func DeleteIotIndex(iotTx *protocol.IotTx, block *protocol.Block) {
+	txHash := iotTx.Hash()
+	db.Update(func(tx *bolt.Tx) error {
+		tx.Bucket([]byte("iotbysender")).Delete(iotIndexKey(iotTx.From, block.Timestamp, txHash))
+		tx.Bucket([]byte("iotbyreceiver")).Delete(iotIndexKey(iotTx.To, block.Timestamp, txHash))
+		return nil
+	})
+}
+
+func DeleteIotBatchIndex(iotBatchTx *protocol.IotBatchTx, block *protocol.Block) {
+	db.Update(func(tx *bolt.Tx) error {
+		for i, reading := range iotBatchTx.Readings {
+			readingHash := iotBatchTx.ReadingHash(uint32(i), reading)
+			tx.Bucket([]byte("iotbysender")).Delete(iotIndexKey(iotBatchTx.From, reading.Timestamp, readingHash))
+			tx.Bucket([]byte("iotbyreceiver")).Delete(iotIndexKey(iotBatchTx.To, reading.Timestamp, readingHash))
+		}
+		return nil
+	})
+}
+
+func DeleteContractSnapshot(height uint32, txHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("contractsnapshots"))
+		err := b.Delete(heightKey(height, txHash))
+		return err
+	})
+}
+
+//Deletes the snapshots of ContractTxs in blocks below the given height, also those of blocks on other chains
+func DeleteContractSnapshotsBelow(height uint32) {
+	deleteBelowHeight("contractsnapshots", height)
+}
+
+func DeleteUndoLog(blockHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("undologs"))
+		err := b.Delete(blockHash[:])
+		return err
+	})
+}
+
+func DeleteEvidence(evidenceID [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("evidences"))
+		err := b.Delete(evidenceID[:])
+		return err
+	})
+}
+
+func DeleteProposal(proposalHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("proposals"))
+		err := b.Delete(proposalHash[:])
+		return err
+	})
+}
+
+func DeleteParameterChange(source [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("parameterchanges"))
+		err := b.Delete(source[:])
+		return err
+	})
+}
+
+func DeleteSupply(blockHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("supply"))
+		err := b.Delete(blockHash[:])
+		return err
+	})
+}
+
+func DeleteBaseFee(blockHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("basefees"))
+		err := b.Delete(blockHash[:])
+		return err
+	})
+}
+
+func DeleteValidatorSet(height uint32, blockHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("validatorsets"))
+		err := b.Delete(heightKey(height, blockHash))
+		return err
+	})
+}
+
+//Deletes the validator registries after blocks below the given height, also those of blocks on other chains
+func DeleteValidatorSetsBelow(height uint32) {
+	deleteBelowHeight("validatorsets", height)
+}
+
+func deleteBelowHeight(bucket string, height uint32) {
+	db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(bucket)).Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint32(k[0:4]) < height; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func DeleteDelegationPayouts(blockHash [32]byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("delegationpayouts"))
+		err := b.Delete(blockHash[:])
+		return err
+	})
+}
+
 func DeleteBootstrapReceivedMempool() {
 	//Delete in-memory storage
 	for key := range txMemPool {
@@ -170,6 +297,142 @@ func DeleteAll() {
 		})
 		return nil
 	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closedcontracts"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closediotbatches"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closediotaggregations"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closeddelegations"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closedevidences"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("undologs"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("evidences"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closedgovernance"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("proposals"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("parameterchanges"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("supply"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("basefees"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("validatorsets"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("delegationpayouts"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("contractsnapshots"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("iotbysender"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("iotbyreceiver"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
 	db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte("lastclosedblock"))
 		b.ForEach(func(k, v []byte) error {
@@ -179,3 +442,15 @@ func DeleteAll() {
 		return nil
 	})
 }
+
+func DeletePeerBan(key []byte) {
+	db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("peerbans")).Delete(key)
+	})
+}
+
+func DeletePeerAddress(ipport string) {
+	db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("peeraddresses")).Delete([]byte(ipport))
+	})
+}
@@ -1,6 +1,10 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/boltdb/bolt"
 	"sort"
@@ -154,6 +158,8 @@ func ReadINVALIDOpenTx(hash [32]byte) (transaction protocol.Transaction) {
 
 //Needed for the miner to prepare a new block
 func ReadAllOpenTxs() (allOpenTxs []protocol.Transaction) {
+	openTxMutex.Lock()
+	defer openTxMutex.Unlock()
 
 	for key := range txMemPool {
 		allOpenTxs = append(allOpenTxs, txMemPool[key])
@@ -224,9 +230,456 @@ func ReadClosedTx(hash [32]byte) (transaction protocol.Transaction) {
 		return ioTTx.Decode(encodedTx)
 	}
 
+	var contractTx *protocol.ContractTx
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closedcontracts"))
+		encodedTx = b.Get(hash[:])
+		return nil
+	})
+	if encodedTx != nil {
+		return contractTx.Decode(encodedTx)
+	}
+
+	var iotBatchTx *protocol.IotBatchTx
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closediotbatches"))
+		encodedTx = b.Get(hash[:])
+		return nil
+	})
+	if encodedTx != nil {
+		return iotBatchTx.Decode(encodedTx)
+	}
+
+	var iotAggTx *protocol.IotAggTx
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closediotaggregations"))
+		encodedTx = b.Get(hash[:])
+		return nil
+	})
+	if encodedTx != nil {
+		return iotAggTx.Decode(encodedTx)
+	}
+
+	var delegateTx *protocol.DelegateTx
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closeddelegations"))
+		encodedTx = b.Get(hash[:])
+		return nil
+	})
+	if encodedTx != nil {
+		return delegateTx.Decode(encodedTx)
+	}
+
+	var evidenceTx *protocol.EvidenceTx
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closedevidences"))
+		encodedTx = b.Get(hash[:])
+		return nil
+	})
+	if encodedTx != nil {
+		return evidenceTx.Decode(encodedTx)
+	}
+
+	var governanceTx *protocol.GovernanceTx
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("closedgovernance"))
+		encodedTx = b.Get(hash[:])
+		return nil
+	})
+	if encodedTx != nil {
+		return governanceTx.Decode(encodedTx)
+	}
+
 	return nil
 }
 
+func ReadContractSnapshot(height uint32, txHash [32]byte) (account *protocol.Account) {
+	var encodedAcc []byte
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("contractsnapshots"))
+		encodedAcc = b.Get(heightKey(height, txHash))
+		return nil
+	})
+
+	if encodedAcc == nil {
+		return nil
+	}
+
+	return account.Decode(encodedAcc)
+}
+
+func ReadUndoLog(blockHash [32]byte) (log *protocol.UndoLog) {
+	var encoded []byte
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("undologs"))
+		encoded = b.Get(blockHash[:])
+		return nil
+	})
+
+	if encoded == nil {
+		return nil
+	}
+
+	return log.Decode(encoded)
+}
+
+//Returns the hash of the EvidenceTx which punished the offence, if any
+func ReadEvidence(evidenceID [32]byte) (txHash [32]byte, found bool) {
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("evidences"))
+		if encoded := b.Get(evidenceID[:]); encoded != nil {
+			copy(txHash[:], encoded)
+			found = true
+		}
+		return nil
+	})
+
+	return txHash, found
+}
+
+func ReadProposal(proposalHash [32]byte) (proposal *protocol.Proposal) {
+	var encoded []byte
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("proposals"))
+		encoded = b.Get(proposalHash[:])
+		return nil
+	})
+
+	if encoded == nil {
+		return nil
+	}
+
+	return proposal.Decode(encoded)
+}
+
+//Returns all governance proposals, keyed by the hash of the proposing tx
+func ReadAllProposals() (proposals map[[32]byte]*protocol.Proposal) {
+	proposals = make(map[[32]byte]*protocol.Proposal)
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("proposals"))
+		b.ForEach(func(k, v []byte) error {
+			var proposalHash [32]byte
+			var proposal *protocol.Proposal
+			copy(proposalHash[:], k)
+			proposals[proposalHash] = proposal.Decode(v)
+			return nil
+		})
+		return nil
+	})
+
+	return proposals
+}
+
+//Returns all parameter changes scheduled by ConfigTxs, also those which are in force already
+func ReadAllParameterChanges() (changes []*protocol.ParameterChange) {
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("parameterchanges"))
+		b.ForEach(func(k, v []byte) error {
+			var change *protocol.ParameterChange
+			if change = change.Decode(v); change != nil {
+				changes = append(changes, change)
+			}
+			return nil
+		})
+		return nil
+	})
+
+	return changes
+}
+
+//Returns the changes scheduled by ConfigTxs and accepted proposals, ordered by activation height and then by the hash
+//of their source. Changes with the same activation height are put in force in this order.
+func ReadScheduledParameterChanges() (changes []*protocol.ParameterChange) {
+	changes = ReadAllParameterChanges()
+	for proposalHash, proposal := range ReadAllProposals() {
+		if proposal.IsAccepted() {
+			changes = append(changes, &protocol.ParameterChange{
+				Id:               proposal.ParameterId,
+				Value:            proposal.Value,
+				ActivationHeight: proposal.ActivationHeight,
+				Source:           proposalHash,
+				Proposal:         true,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].ActivationHeight != changes[j].ActivationHeight {
+			return changes[i].ActivationHeight < changes[j].ActivationHeight
+		}
+		return bytes.Compare(changes[i].Source[:], changes[j].Source[:]) < 0
+	})
+
+	return changes
+}
+
+//Returns the scheduled changes which are not in force yet at the height after the last closed block.
+func ReadParameterSchedule() (schedule *protocol.ParameterSchedule) {
+	schedule = new(protocol.ParameterSchedule)
+	if lastBlock := ReadLastClosedBlock(); lastBlock != nil {
+		schedule.Height = lastBlock.Height
+	}
+
+	for _, change := range ReadScheduledParameterChanges() {
+		if change.ActivationHeight > schedule.Height+1 {
+			schedule.Changes = append(schedule.Changes, change)
+		}
+	}
+
+	return schedule
+}
+
+func ReadSupply(blockHash [32]byte) (supply *protocol.Supply) {
+	var encoded []byte
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("supply"))
+		encoded = b.Get(blockHash[:])
+		return nil
+	})
+
+	if encoded == nil {
+		return nil
+	}
+
+	return supply.Decode(encoded)
+}
+
+//Returns the supply after the last closed block
+func ReadCurrentSupply() (supply *protocol.Supply) {
+	if lastBlock := ReadLastClosedBlock(); lastBlock != nil {
+		supply = ReadSupply(lastBlock.Hash)
+	}
+
+	return supply
+}
+
+func ReadValidatorSet(height uint32, blockHash [32]byte) (set *protocol.ValidatorSet) {
+	var encoded []byte
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("validatorsets"))
+		encoded = b.Get(heightKey(height, blockHash))
+		return nil
+	})
+
+	if encoded == nil {
+		return nil
+	}
+
+	return set.Decode(encoded)
+}
+
+//Returns the validator registry after the last closed block
+func ReadCurrentValidatorSet() (set *protocol.ValidatorSet) {
+	if lastBlock := ReadLastClosedBlock(); lastBlock != nil {
+		set = ReadValidatorSet(lastBlock.Height, lastBlock.Hash)
+	}
+
+	return set
+}
+
+//Returns the base fee of the block following the given one
+func ReadBaseFee(blockHash [32]byte) (baseFee uint64, found bool) {
+	db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket([]byte("basefees")).Get(blockHash[:])
+		if len(value) == 8 {
+			baseFee = binary.BigEndian.Uint64(value)
+			found = true
+		}
+		return nil
+	})
+
+	return baseFee, found
+}
+
+//Returns the base fee the next block is validated with
+func ReadCurrentBaseFee() (baseFee uint64, found bool) {
+	if lastBlock := ReadLastClosedBlock(); lastBlock != nil {
+		baseFee, found = ReadBaseFee(lastBlock.Hash)
+	}
+
+	return baseFee, found
+}
+
+func ReadDelegationPayouts(blockHash [32]byte) (payouts *protocol.DelegationPayouts) {
+	var encoded []byte
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("delegationpayouts"))
+		encoded = b.Get(blockHash[:])
+		return nil
+	})
+
+	if encoded == nil {
+		return nil
+	}
+
+	return payouts.Decode(encoded)
+}
+
+//Entry of the IoT sender/receiver index, points to the block an IotTx was included in. For readings of an
+//IotBatchTx, TxHash is the hash of the batch and ReadingIndex the position of the reading within it.
+type IotIndexEntry struct {
+	TxHash       [32]byte
+	BlockHash    [32]byte
+	Height       uint32
+	Timestamp    int64
+	InBatch      bool
+	ReadingIndex uint32
+}
+
+//Returns the index entries of the account within [from, to] in chronological order, starting after the entry the
+//cursor points to. At most limit entries are returned, next points to the last of them if there are more entries in
+//the time range. Only the returned entries and the one after them are read.
+func ReadIotIndex(account [32]byte, byReceiver bool, from, to int64, cursor []byte, limit int) (entries []*IotIndexEntry, next []byte) {
+	bucket := "iotbysender"
+	if byReceiver {
+		bucket = "iotbyreceiver"
+	}
+
+	db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(bucket)).Cursor()
+		start := iotIndexKey(account, from, [32]byte{})
+		var after []byte
+		if len(cursor) > 0 {
+			after = append(append([]byte{}, account[:]...), cursor...)
+			if bytes.Compare(after, start) > 0 {
+				start = after
+			}
+		}
+
+		var last []byte
+		for k, v := c.Seek(start); k != nil && bytes.HasPrefix(k, account[:]); k, v = c.Next() {
+			if bytes.Equal(k, after) {
+				continue
+			}
+			timestamp := iotIndexTimestamp(k)
+			if timestamp > to {
+				break
+			}
+			if len(entries) == limit {
+				next = last
+				break
+			}
+
+			entry := new(IotIndexEntry)
+			copy(entry.TxHash[:], k[40:72])
+			copy(entry.BlockHash[:], v[0:32])
+			entry.Height = binary.BigEndian.Uint32(v[32:36])
+			entry.Timestamp = timestamp
+			if len(v) == 72 {
+				entry.InBatch = true
+				copy(entry.TxHash[:], v[36:68])
+				entry.ReadingIndex = binary.BigEndian.Uint32(v[68:72])
+			}
+			entries = append(entries, entry)
+			//Keys are only valid during the transaction
+			last = append([]byte{}, k[32:]...)
+		}
+		return nil
+	})
+
+	return entries, next
+}
+
+//Looks up the IoT readings matching the query. Needed by the miner and the p2p package.
+func QueryIotData(query *protocol.IotQuery) (result *protocol.IotQueryResult, err error) {
+	if query == nil {
+		return nil, errors.New("IoT query is empty.")
+	}
+	if query.From > query.To {
+		return nil, errors.New(fmt.Sprintf("Invalid IoT query time range: %v > %v.", query.From, query.To))
+	}
+	//The cursor is an index key without the account, i.e. the timestamp and the tx hash
+	if len(query.Cursor) != 0 && len(query.Cursor) != 40 {
+		return nil, errors.New("Invalid IoT query cursor.")
+	}
+
+	limit := int(query.Limit)
+	if limit == 0 || limit > protocol.IOT_QUERY_MAX_LIMIT {
+		limit = protocol.IOT_QUERY_MAX_LIMIT
+	}
+
+	entries, next := ReadIotIndex(query.Account, query.ByReceiver, query.From, query.To, query.Cursor, limit)
+
+	result = new(protocol.IotQueryResult)
+	result.Cursor = next
+
+	//Readings of a device are often in the same block, build each tree only once
+	merkleTrees := make(map[[32]byte]*protocol.MerkleTree)
+	blocks := make(map[[32]byte]*protocol.Block)
+	for _, entry := range entries {
+		reading := &protocol.IotReading{
+			BlockHash: entry.BlockHash,
+			Height:    entry.Height,
+			Timestamp: entry.Timestamp,
+		}
+
+		if entry.InBatch {
+			batchTx, ok := ReadClosedTx(entry.TxHash).(*protocol.IotBatchTx)
+			if !ok || int(entry.ReadingIndex) >= len(batchTx.Readings) {
+				return nil, errors.New(fmt.Sprintf("Indexed IoT batch %x not in the closed tx storage.", entry.TxHash[0:8]))
+			}
+			reading.BatchTx = batchTx.HeaderOnly()
+			reading.ReadingIndex = entry.ReadingIndex
+			reading.Reading = &batchTx.Readings[entry.ReadingIndex]
+			if query.WithProof {
+				if reading.ReadingProof, err = batchTx.ReadingProof(entry.ReadingIndex); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			tx, ok := ReadClosedTx(entry.TxHash).(*protocol.IotTx)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Indexed IoT tx %x not in the closed tx storage.", entry.TxHash[0:8]))
+			}
+			reading.Tx = tx
+		}
+
+		if query.WithProof {
+			merkleTree, exists := merkleTrees[entry.BlockHash]
+			if !exists {
+				block := ReadClosedBlock(entry.BlockHash)
+				if block == nil {
+					return nil, errors.New(fmt.Sprintf("Block %x of IoT tx %x not in the closed block storage.", entry.BlockHash[0:8], entry.TxHash[0:8]))
+				}
+				merkleTree = protocol.BuildMerkleTree(block)
+				merkleTrees[entry.BlockHash] = merkleTree
+				blocks[entry.BlockHash] = block
+			}
+
+			leaf := protocol.GetLeaf(merkleTree, entry.TxHash)
+			//An aggregated batch is proven up to its IotAggTx first, which is a leaf of the block.
+			if leaf == nil && entry.InBatch {
+				for _, aggTxHash := range blocks[entry.BlockHash].IoTAggTxData {
+					iotAggTx, ok := ReadClosedTx(aggTxHash).(*protocol.IotAggTx)
+					if !ok {
+						continue
+					}
+					if batchProof, err := iotAggTx.BatchProof(entry.TxHash); err == nil {
+						reading.Proof = batchProof
+						leaf = protocol.GetLeaf(merkleTree, aggTxHash)
+						break
+					}
+				}
+			}
+			if leaf == nil {
+				return nil, errors.New(fmt.Sprintf("IoT tx %x not in the Merkle tree of block %x.", entry.TxHash[0:8], entry.BlockHash[0:8]))
+			}
+			intermediates, err := protocol.GetIntermediate(leaf)
+			if err != nil {
+				return nil, err
+			}
+			for _, node := range intermediates {
+				reading.Proof = append(reading.Proof, node.Hash)
+			}
+			reading.MerkleRoot = merkleTree.MerkleRoot()
+		}
+
+		result.Readings = append(result.Readings, reading)
+	}
+
+	return result, nil
+}
+
 func ReadMempool(){
 	logger.Printf("MemPool_________")
 	//for tx := range txMemPool {
@@ -238,3 +691,30 @@ func ReadMempool(){
 
 }
 
+
+func ReadPeerBan(key []byte) (until int64, bans uint32, found bool) {
+	db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket([]byte("peerbans")).Get(key)
+		if len(value) == 12 {
+			until = int64(binary.BigEndian.Uint64(value[0:8]))
+			bans = binary.BigEndian.Uint32(value[8:12])
+			found = true
+		}
+		return nil
+	})
+
+	return until, bans, found
+}
+
+func ReadAllPeerAddresses() (addresses map[string][]byte) {
+	addresses = make(map[string][]byte)
+
+	db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("peeraddresses")).ForEach(func(k, v []byte) error {
+			addresses[string(k)] = append([]byte{}, v...)
+			return nil
+		})
+	})
+
+	return addresses
+}
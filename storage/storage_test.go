@@ -44,7 +44,7 @@ func TestReadWriteDeleteTx(t *testing.T) {
 	//Restricted to 256, because the number of configTxs is stored in a uint8 in blocks
 	loopMax = 256
 	for cnt := 0; cnt < loopMax; cnt++ {
-		tx, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), uint8(rand.Uint32()%5+1), rand.Uint64()%2342873423, rand.Uint64()%1000+1, uint8(cnt), &RootPrivKey)
+		tx, _ := protocol.ConstrConfigTx(uint8(rand.Uint32()%256), uint8(rand.Uint32()%5+1), rand.Uint64()%2342873423, rand.Uint64()%1000+1, uint8(cnt), 0, &RootPrivKey)
 		hashConfigSlice = append(hashConfigSlice, tx)
 		WriteOpenTx(tx)
 	}
@@ -55,7 +55,7 @@ func TestReadWriteDeleteTx(t *testing.T) {
 		if math.Mod(float64(cnt), 2.00) == 1 {
 			isStaking = true
 		}
-		tx, _ := protocol.ConstrStakeTx(0, uint64(cnt), isStaking, accAHash, &PrivKeyA, &CommitmentKeyA.PublicKey)
+		tx, _ := protocol.ConstrStakeTx(0, uint64(cnt), isStaking, 0, accAHash, &PrivKeyA, &CommitmentKeyA.PublicKey)
 		hashStakeSlice = append(hashStakeSlice, tx)
 		WriteOpenTx(tx)
 	}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
@@ -90,3 +91,25 @@ func GetFundsTxPubKeys(fundsTxData [][32]byte) (fundsTxPubKeys [][32]byte) {
 
 	return fundsTxPubKeys
 }
+
+//Records kept per block for rollbacks are keyed by height | hash, such that a cursor finds those of old blocks first
+func heightKey(height uint32, hash [32]byte) []byte {
+	key := make([]byte, 36)
+	binary.BigEndian.PutUint32(key[0:4], height)
+	copy(key[4:36], hash[:])
+	return key
+}
+
+//Index keys are built as account | timestamp | txHash, such that a cursor iterates the readings of an account in
+//chronological order. The sign bit of the timestamp is flipped, otherwise negative timestamps would sort last.
+func iotIndexKey(account [32]byte, timestamp int64, txHash [32]byte) []byte {
+	key := make([]byte, 72)
+	copy(key[0:32], account[:])
+	binary.BigEndian.PutUint64(key[32:40], uint64(timestamp)^(1<<63))
+	copy(key[40:72], txHash[:])
+	return key
+}
+
+func iotIndexTimestamp(key []byte) int64 {
+	return int64(binary.BigEndian.Uint64(key[32:40]) ^ (1 << 63))
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"encoding/binary"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/boltdb/bolt"
 )
@@ -109,6 +110,18 @@ func WriteClosedTx(transaction protocol.Transaction) (err error) {
 		bucket = "closedaggregations"
 	case *protocol.IotTx:
 		bucket = "closediotts"
+	case *protocol.ContractTx:
+		bucket = "closedcontracts"
+	case *protocol.IotBatchTx:
+		bucket = "closediotbatches"
+	case *protocol.IotAggTx:
+		bucket = "closediotaggregations"
+	case *protocol.DelegateTx:
+		bucket = "closeddelegations"
+	case *protocol.EvidenceTx:
+		bucket = "closedevidences"
+	case *protocol.GovernanceTx:
+		bucket = "closedgovernance"
 	}
 
 
@@ -124,6 +137,184 @@ func WriteClosedTx(transaction protocol.Transaction) (err error) {
 	return err
 }
 
+//Keeps the account as it was before a ContractTx was applied, such that the change can be rolled back. The height of
+//the block containing the tx is part of the key, such that snapshots of blocks past the rollback depth can be pruned.
+func WriteContractSnapshot(height uint32, txHash [32]byte, account *protocol.Account) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("contractsnapshots"))
+		err := b.Put(heightKey(height, txHash), account.Encode())
+		return err
+	})
+
+	return err
+}
+
+//Stores the consensus related account changes of a block, needed to roll them back
+func WriteUndoLog(blockHash [32]byte, log *protocol.UndoLog) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("undologs"))
+		err := b.Put(blockHash[:], log.Encode())
+		return err
+	})
+
+	return err
+}
+
+//Marks an offence as punished by the EvidenceTx with the given hash, such that it can't be punished again
+func WriteEvidence(evidenceID [32]byte, txHash [32]byte) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("evidences"))
+		err := b.Put(evidenceID[:], txHash[:])
+		return err
+	})
+
+	return err
+}
+
+//Stores the state of a governance proposal, keyed by the hash of the proposing tx
+func WriteProposal(proposalHash [32]byte, proposal *protocol.Proposal) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("proposals"))
+		err := b.Put(proposalHash[:], proposal.Encode())
+		return err
+	})
+
+	return err
+}
+
+//Stores a parameter change scheduled by a ConfigTx, keyed by the hash of the tx
+func WriteParameterChange(change *protocol.ParameterChange) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("parameterchanges"))
+		err := b.Put(change.Source[:], change.Encode())
+		return err
+	})
+
+	return err
+}
+
+//Stores the supply after the block with the given hash
+func WriteSupply(blockHash [32]byte, supply *protocol.Supply) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("supply"))
+		err := b.Put(blockHash[:], supply.Encode())
+		return err
+	})
+
+	return err
+}
+
+//Stores the base fee of the block following the given one
+func WriteBaseFee(blockHash [32]byte, baseFee uint64) (err error) {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, baseFee)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("basefees")).Put(blockHash[:], value)
+	})
+
+	return err
+}
+
+//Stores the validator registry after the given block, the height of the block is the one of the set
+func WriteValidatorSet(blockHash [32]byte, set *protocol.ValidatorSet) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("validatorsets"))
+		err := b.Put(heightKey(set.Height, blockHash), set.Encode())
+		return err
+	})
+
+	return err
+}
+
+//Stores the delegation rewards paid out by the validator of a block, needed to roll them back
+func WriteDelegationPayouts(blockHash [32]byte, payouts *protocol.DelegationPayouts) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("delegationpayouts"))
+		err := b.Put(blockHash[:], payouts.Encode())
+		return err
+	})
+
+	return err
+}
+
+//Indexes a validated IotTx by sender and receiver, such that the readings of a device can be found by time
+func WriteIotIndex(iotTx *protocol.IotTx, block *protocol.Block) (err error) {
+	txHash := iotTx.Hash()
+	value := make([]byte, 36)
+	copy(value[0:32], block.Hash[:])
+	binary.BigEndian.PutUint32(value[32:36], block.Height)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("iotbysender"))
+		if err := b.Put(iotIndexKey(iotTx.From, block.Timestamp, txHash), value); err != nil {
+			return err
+		}
+		b = tx.Bucket([]byte("iotbyreceiver"))
+		return b.Put(iotIndexKey(iotTx.To, block.Timestamp, txHash), value)
+	})
+
+	return err
+}
+
+//Indexes every reading of a validated IotBatchTx by its own timestamp. The value additionally points to the batch
+//and the position of the reading within it.
+func WriteIotBatchIndex(iotBatchTx *protocol.IotBatchTx, block *protocol.Block) (err error) {
+	txHash := iotBatchTx.Hash()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		senderBucket := tx.Bucket([]byte("iotbysender"))
+		receiverBucket := tx.Bucket([]byte("iotbyreceiver"))
+		for i, reading := range iotBatchTx.Readings {
+			readingHash := iotBatchTx.ReadingHash(uint32(i), reading)
+			value := make([]byte, 72)
+			copy(value[0:32], block.Hash[:])
+			binary.BigEndian.PutUint32(value[32:36], block.Height)
+			copy(value[36:68], txHash[:])
+			binary.BigEndian.PutUint32(value[68:72], uint32(i))
+
+			if err := senderBucket.Put(iotIndexKey(iotBatchTx.From, reading.Timestamp, readingHash), value); err != nil {
+				return err
+			}
+			if err := receiverBucket.Put(iotIndexKey(iotBatchTx.To, reading.Timestamp, readingHash), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return err
+}
+
 func WriteAccount(account *protocol.Account) {
 	State[account.Address] = account
-}
\ No newline at end of file
+}
+//Bans of misbehaving peers, keyed by node id or IP address. Until is a unix timestamp, 0 means the ban is persistent.
+func WritePeerBan(key []byte, until int64, bans uint32) (err error) {
+	value := make([]byte, 12)
+	binary.BigEndian.PutUint64(value[0:8], uint64(until))
+	binary.BigEndian.PutUint32(value[8:12], bans)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("peerbans")).Put(key, value)
+	})
+
+	return err
+}
+
+//Address book of the p2p package, keyed by IP:Port.
+func WritePeerAddress(ipport string, encoded []byte) (err error) {
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("peeraddresses")).Put([]byte(ipport), encoded)
+	})
+
+	return err
+}